@@ -0,0 +1,67 @@
+package modbus
+
+import (
+	"bytes"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ApplyRule declares a register the slave requires to be written after
+// its configuration changes, e.g. writing 1 to an "apply configuration"
+// register after any change to registers 2000-2999.
+type ApplyRule struct {
+	// Guarded is the range of registers whose change triggers the rule.
+	Guarded RegisterRange
+	// Register and Value make the write performed by the rule.
+	Register uint16
+	Value    types.Value
+}
+
+// WithApplyRules adds rules declaring registers written after every
+// write operation that changes registers of a guarded range. The apply
+// writes are performed after all the other requests of the operation,
+// one request per rule, and are never merged. Writes suppressed by
+// differential optimization don't trigger rules.
+func WithApplyRules(rules ...ApplyRule) Option {
+	return func(c *Config) {
+		c.ApplyRules = append(c.ApplyRules[:len(c.ApplyRules):len(c.ApplyRules)], rules...)
+	}
+}
+
+// applyWrites returns the writes of rules triggered by ops, in order of
+// rules. Every rule fires at most once, and rules sharing the same
+// write fire it once.
+func applyWrites(rules []ApplyRule, ops []writeOp) ([]writeOp, error) {
+	var res []writeOp
+	for _, rule := range rules {
+		triggered := false
+		for _, op := range ops {
+			if rule.Guarded.Overlaps(RegisterRange{op.register, op.quantity}) {
+				triggered = true
+				break
+			}
+		}
+		if !triggered {
+			continue
+		}
+
+		if err := validateValue(rule.Register, rule.Value); err != nil {
+			return nil, err
+		}
+		wop, err := newWriteOp(rule.Register, rule.Value.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		duplicate := false
+		for _, w := range res {
+			if w.register == wop.register && bytes.Equal(w.value, wop.value) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			res = append(res, wop)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package modbus_test
+
+import (
+	"testing"
+
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchWrite_applyRules(t *testing.T) {
+	rules := []modbus.ApplyRule{
+		{modbus.RegisterRange{Register: 2000, Quantity: 1000}, 3000, types.Uint16(1)},
+		{modbus.RegisterRange{Register: 2500, Quantity: 1000}, 3000, types.Uint16(1)},
+		{modbus.RegisterRange{Register: 4000, Quantity: 100}, 4100, types.Uint16(2)},
+	}
+	tests := []struct {
+		name    string
+		ops     []modbus.Write
+		oldData modbus.Registers
+		want    []modbustest.WireExpectation
+	}{
+		{
+			"fired once for overlapping rules",
+			[]modbus.Write{
+				testWrite{2600, types.Uint16(5)},
+				testWrite{2601, types.Uint16(6)},
+			},
+			nil,
+			[]modbustest.WireExpectation{
+				modbustest.Write(2600, 0, 5, 0, 6),
+				modbustest.Write(3000, 0, 1),
+			},
+		},
+		{
+			"suppressed when all writes are skipped by diff",
+			[]modbus.Write{testWrite{2600, types.Uint16(5)}},
+			modbus.Registers{2600: types.Uint16(5)},
+			nil,
+		},
+		{
+			"multiple rules",
+			[]modbus.Write{
+				testWrite{4010, types.Uint16(7)},
+				testWrite{2000, types.Uint16(8)},
+				testWrite{10, types.Uint16(9)},
+			},
+			nil,
+			[]modbustest.WireExpectation{
+				modbustest.Write(10, 0, 9),
+				modbustest.Write(2000, 0, 8),
+				modbustest.Write(4010, 0, 7),
+				modbustest.Write(3000, 0, 1),
+				modbustest.Write(4100, 0, 2),
+			},
+		},
+		{
+			"not fired outside of guarded ranges",
+			[]modbus.Write{testWrite{10, types.Uint16(9)}},
+			nil,
+			[]modbustest.WireExpectation{modbustest.Write(10, 0, 9)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := modbus.NewClient(modbustest.NewSlave(), modbus.WithApplyRules(rules...))
+			modbustest.ExpectWrites(t, client, tt.ops, tt.oldData, tt.want)
+		})
+	}
+}
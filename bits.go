@@ -0,0 +1,283 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ErrConflictingBits is returned when a batch sets the same bit to
+// different values.
+var ErrConflictingBits = errors.New("conflicting bit writes")
+
+// ErrMaskWriteUnsupported is returned when the slave rejects function
+// 22 as an illegal function. Use WithMaskWriteEmulation for such
+// slaves.
+var ErrMaskWriteUnsupported = errors.New("mask write not supported by the slave")
+
+// WithMaskWriteEmulation makes BatchWriteBits and MaskWrite update
+// registers with a read-modify-write sequence instead of function 22,
+// for slaves that don't implement it.
+func WithMaskWriteEmulation() Option {
+	return func(c *Config) {
+		c.EmulateMaskWrite = true
+	}
+}
+
+// Bit addresses a single bit of a holding register. Index 0 is the
+// least significant bit. With Invert set, the bit is stored inverted,
+// i.e. true is transmitted as 0.
+type Bit struct {
+	Register uint16
+	Index    uint
+	Invert   bool
+}
+
+func (b Bit) mask() uint16 {
+	return 1 << b.Index
+}
+
+// BitWrite sets a single bit of a holding register.
+type BitWrite struct {
+	Bit
+	Value bool
+}
+
+// BatchReadBits reads the registers holding bits with function 3 and
+// returns the values of bits. Registers are read once no matter how
+// many bits they hold, and are merged the same way as in BatchRead.
+func (c *Client) BatchReadBits(bits []Bit) (map[Bit]bool, error) {
+	ops := make([]Read, 0, len(bits))
+	seen := make(map[uint16]bool)
+	for _, b := range bits {
+		if b.Index > 15 {
+			return nil, fmt.Errorf("%w: bit %d", types.ErrInvalidInput, b.Index)
+		}
+		if !seen[b.Register] {
+			seen[b.Register] = true
+			ops = append(ops, bitRegister(b.Register))
+		}
+	}
+
+	registers, err := c.BatchRead(ops)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[Bit]bool, len(bits))
+	for _, b := range bits {
+		word := binary.BigEndian.Uint16(registers[b.Register].Bytes())
+		res[b] = (word&b.mask() != 0) != b.Invert
+	}
+
+	return res, nil
+}
+
+// BatchWriteBits sets bits of holding registers. All bits of a single
+// register are coalesced into one function 22 (Mask Write Register)
+// request, so other bits of the register are left untouched. Setting
+// the same bit to different values in one batch fails with
+// ErrConflictingBits.
+//
+// With WithMaskWriteEmulation, every register is updated by a
+// read-modify-write sequence instead, which is only atomic with respect
+// to other operations of this client.
+func (c *Client) BatchWriteBits(ops []BitWrite) error {
+	return c.BatchWriteBitsWith(ops, BatchOptions{})
+}
+
+// BatchWriteBitsWith is BatchWriteBits with settings of the client
+// overridden by opts. Options of differential optimization and of
+// read-back don't apply to bit writes.
+func (c *Client) BatchWriteBitsWith(ops []BitWrite, opts BatchOptions) (err error) {
+	defer recoverPanic(&err)
+	stats := &BatchStats{Batches: 1, Ops: len(ops)}
+	defer c.account(stats, time.Now(), opts.Stats)
+
+	masks, err := coalesceBits(ops)
+	if err != nil {
+		return err
+	}
+	cfg, err := c.resolve(opts)
+	if err != nil {
+		return err
+	}
+	cfg.stats, cfg.unit = stats, opts.Unit
+	wops, reads := c.planMasks(masks)
+	cfg.batch = interceptedOps(wops)
+	if err := cfg.Limits.checkOps(len(ops)); err != nil {
+		return err
+	}
+	if err := cfg.Limits.checkRequests(len(wops) + len(reads)); err != nil {
+		return err
+	}
+	if err := c.checkSpecPlan(cfg.Config, reads, nil); err != nil {
+		return err
+	}
+
+	if err := c.lockFor(cfg); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(opts.context(), cfg.BatchTimeout)
+	if err := c.budgets.check(c.config.WriteBudgets, wops, c.config.OverrideWriteBudgets); err != nil {
+		return done(err)
+	}
+	for i, m := range masks {
+		if err := c.pace(ctx, i); err != nil {
+			return done(err)
+		}
+		if err := c.maskWrite(m, cfg.Retry); err != nil {
+			return done(fmt.Errorf("mask write request %d at %d: %w", i+1, m.register, err))
+		}
+	}
+	return done(nil)
+}
+
+// WriteBit sets a single bit of register, leaving its other bits
+// untouched. See BatchWriteBits.
+func (c *Client) WriteBit(register uint16, bit uint, value bool) error {
+	return c.BatchWriteBits([]BitWrite{{Bit{Register: register, Index: bit}, value}})
+}
+
+// MaskWrite updates register with function 22 (Mask Write Register):
+// the register becomes (current AND andMask) OR (orMask AND NOT
+// andMask). With WithMaskWriteEmulation, the register is updated by a
+// read-modify-write sequence instead, see BatchWriteBits.
+func (c *Client) MaskWrite(register, andMask, orMask uint16) (err error) {
+	defer recoverPanic(&err)
+
+	if err := c.lock(); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	wops, reads := c.planMasks([]bitMask{{register, andMask, orMask}})
+	if err := c.checkSpecPlan(c.config, reads, nil); err != nil {
+		return err
+	}
+	if err := c.budgets.check(c.config.WriteBudgets, wops, c.config.OverrideWriteBudgets); err != nil {
+		return err
+	}
+	if err := c.maskWrite(bitMask{register, andMask, orMask}, c.config.Retry); err != nil {
+		return fmt.Errorf("mask write at %d: %w", register, err)
+	}
+	return nil
+}
+
+// planMasks returns the registers written by masks, and the reads of
+// emulated mask writes.
+func (c *Client) planMasks(masks []bitMask) (writes []writeOp, reads []readOp) {
+	writes = make([]writeOp, len(masks))
+	for i, m := range masks {
+		writes[i] = writeOp{register: m.register, quantity: 1}
+		if c.config.EmulateMaskWrite {
+			reads = append(reads, readOp{m.register, 1, HoldingRegisters})
+		}
+	}
+	return writes, reads
+}
+
+// bitMask describes a function 22 request: the register becomes
+// (current AND and) OR (or AND NOT and).
+type bitMask struct {
+	register uint16
+	and, or  uint16
+}
+
+// coalesceBits builds one mask per register, in ascending register
+// order.
+func coalesceBits(ops []BitWrite) ([]bitMask, error) {
+	type state struct {
+		mask  bitMask
+		touch uint16
+	}
+	registers := make(map[uint16]*state)
+	for _, op := range ops {
+		if op.Index > 15 {
+			return nil, fmt.Errorf("%w: bit %d", types.ErrInvalidInput, op.Index)
+		}
+		s, ok := registers[op.Register]
+		if !ok {
+			s = &state{mask: bitMask{op.Register, 0xFFFF, 0}}
+			registers[op.Register] = s
+		}
+		bit := op.mask()
+		set := op.Value != op.Invert
+		if s.touch&bit != 0 {
+			if (s.mask.or&bit != 0) != set {
+				return nil, fmt.Errorf("%w: register %d bit %d", ErrConflictingBits, op.Register, op.Index)
+			}
+			continue
+		}
+		s.touch |= bit
+		s.mask.and &^= bit
+		if set {
+			s.mask.or |= bit
+		}
+	}
+
+	masks := make([]bitMask, 0, len(registers))
+	for _, s := range registers {
+		masks = append(masks, s.mask)
+	}
+	sort.Slice(masks, func(i, j int) bool { return masks[i].register < masks[j].register })
+	return masks, nil
+}
+
+// maskWrite performs a single mask write with retry, emulated with
+// read and write if Config.EmulateMaskWrite is set. The caller must
+// hold the client mutex.
+func (c *Client) maskWrite(m bitMask, retry Retry) error {
+	if c.config.EmulateMaskWrite {
+		b, err := c.read(readOp{m.register, 1, HoldingRegisters}, retry)
+		if err != nil {
+			return err
+		}
+		word := types.Uint16(binary.BigEndian.Uint16(b)&m.and | m.or&^m.and).Bytes()
+		return c.write(writeOp{m.register, 1, word}, retry)
+	}
+	if c.sched != nil {
+		c.sched.yield()
+	}
+	c.chunks.invalidate(RegisterRange{m.register, 1})
+	c.cache.invalidate(RegisterRange{m.register, 1})
+	c.swr.invalidate(RegisterRange{m.register, 1})
+	err := c.attempts(retry, func() error {
+		start := time.Now()
+		_, err := c.intercept(OpInfo{Function: modbus.FuncCodeMaskWriteRegister, RegisterRange: RegisterRange{m.register, 1}}, nil, func() ([]byte, error) {
+			return c.MaskWriteRegister(m.register, m.and, m.or)
+		})
+		c.observe(m.register, 1, time.Since(start), err)
+		return err
+	})
+	if isIllegalFunction(err) {
+		return fmt.Errorf("%w: %v", ErrMaskWriteUnsupported, err)
+	}
+	if err == nil {
+		c.budgets.charge(c.config.WriteBudgets, RegisterRange{m.register, 1})
+	}
+	return err
+}
+
+// bitRegister reads a whole register holding bits.
+type bitRegister uint16
+
+func (r bitRegister) Register() uint16 {
+	return uint16(r)
+}
+
+func (bitRegister) Type() types.Type {
+	return types.Uint16Type
+}
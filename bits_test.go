@@ -0,0 +1,161 @@
+package modbus_test
+
+import (
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+)
+
+func TestClient_BatchWriteBits(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(4010, 0x80, 0x0F)
+	client := modbus.NewClient(slave)
+
+	err := client.BatchWriteBits([]modbus.BitWrite{
+		{modbus.Bit{Register: 4010, Index: 0}, false},
+		{modbus.Bit{Register: 4010, Index: 3}, true},
+		{modbus.Bit{Register: 4010, Index: 4}, true},
+		{modbus.Bit{Register: 4010, Index: 5, Invert: true}, false},
+		{modbus.Bit{Register: 4010, Index: 15, Invert: true}, true},
+		{modbus.Bit{Register: 4010, Index: 4}, true},
+	})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, slave.calls(), "five bits coalesce into one request") {
+		req := slave.requests[0]
+		assert.EqualValues(t, goburrow.FuncCodeMaskWriteRegister, req.FunctionCode)
+		// and: bits 0, 3, 4, 5, 15 cleared; or: bits 3, 4, 5 set
+		assert.Equal(t, []byte{0x0F, 0xAA, 0x7F, 0xC6, 0x00, 0x38}, req.Data)
+	}
+	assert.Equal(t, []byte{0x00, 0x3E}, slave.get(4010, 1))
+
+	bits := []modbus.Bit{
+		{Register: 4010, Index: 1},
+		{Register: 4010, Index: 5, Invert: true},
+		{Register: 4010, Index: 15},
+		{Register: 4011, Index: 0},
+	}
+	res, err := client.BatchReadBits(bits)
+	assert.NoError(t, err)
+	assert.Equal(t, map[modbus.Bit]bool{
+		bits[0]: true,
+		bits[1]: false,
+		bits[2]: false,
+		bits[3]: false,
+	}, res)
+}
+
+func TestClient_BatchWriteBits_conflict(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+
+	err := client.BatchWriteBits([]modbus.BitWrite{
+		{modbus.Bit{Register: 10, Index: 2}, true},
+		{modbus.Bit{Register: 10, Index: 2, Invert: true}, true},
+	})
+	assert.ErrorIs(t, err, modbus.ErrConflictingBits)
+	assert.Zero(t, slave.calls())
+}
+
+func TestClient_BatchWriteBits_emulated(t *testing.T) {
+	slave := newTestSlave()
+	slave.noMaskWrite = true
+	slave.set(10, 0xFF, 0x00)
+	slave.set(12, 0x00, 0x01)
+	client := modbus.NewClient(slave, modbus.WithMaskWriteEmulation())
+
+	err := client.BatchWriteBits([]modbus.BitWrite{
+		{modbus.Bit{Register: 10, Index: 8}, false},
+		{modbus.Bit{Register: 10, Index: 0}, true},
+		{modbus.Bit{Register: 12, Index: 0, Invert: true}, true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xFE, 0x01}, slave.get(10, 1))
+	assert.Equal(t, []byte{0x00, 0x00}, slave.get(12, 1))
+}
+
+func TestClient_BatchWriteBitsWith(t *testing.T) {
+	slave := newUnitSlave(0, 1)
+	slave.units[1].set(10, 0x00, 0x01)
+	slave.units[1].failures = 1
+	var log []string
+	r := &recorder{log: &log}
+	client := modbus.NewClient(slave, modbus.WithRetry(modbus.Retry{Retries: 1}), modbus.WithInterceptors(r), modbus.WithMaskWriteEmulation())
+
+	unit := byte(1)
+	var stats modbus.BatchStats
+	err := client.BatchWriteBitsWith([]modbus.BitWrite{{modbus.Bit{Register: 10, Index: 2}, true}},
+		modbus.BatchOptions{Unit: &unit, Stats: &stats})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x05}, slave.units[1].get(10, 1))
+	assert.Zero(t, slave.units[0].calls())
+	assert.Zero(t, slave.SlaveId, "the unit is restored")
+	assert.Equal(t, 3, stats.Requests, "the failed read is retried")
+	if assert.Len(t, r.ops, 3) {
+		assert.EqualValues(t, goburrow.FuncCodeReadHoldingRegisters, r.ops[1].Function)
+		assert.EqualValues(t, goburrow.FuncCodeWriteMultipleRegisters, r.ops[2].Function)
+		assert.True(t, r.ops[2].Batch)
+	}
+
+	err = client.BatchWriteBitsWith([]modbus.BitWrite{{modbus.Bit{Register: 10, Index: 0}, true}},
+		modbus.BatchOptions{Limits: &modbus.Limits{MaxRequests: 1}})
+	var serr *modbus.BatchSizeError
+	assert.ErrorAs(t, err, &serr, "emulated mask writes take two requests")
+}
+
+func TestClient_MaskWrite(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(20, 0x12, 0x34)
+	client := modbus.NewClient(slave)
+
+	assert.NoError(t, client.MaskWrite(20, 0xFF00, 0x0081))
+	if assert.Equal(t, 1, slave.calls()) {
+		assert.EqualValues(t, goburrow.FuncCodeMaskWriteRegister, slave.requests[0].FunctionCode)
+		assert.Equal(t, []byte{0x00, 0x14, 0xFF, 0x00, 0x00, 0x81}, slave.requests[0].Data)
+	}
+	assert.Equal(t, []byte{0x12, 0x81}, slave.get(20, 1))
+
+	assert.NoError(t, client.WriteBit(20, 12, true))
+	assert.NoError(t, client.WriteBit(20, 0, false))
+	assert.Equal(t, []byte{0x12, 0x80}, slave.get(20, 1))
+}
+
+func TestClient_MaskWrite_unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		emulate bool
+		wantErr error
+		want    []byte
+	}{
+		{"illegal function", false, modbus.ErrMaskWriteUnsupported, []byte{0x00, 0x01}},
+		{"emulated", true, nil, []byte{0x00, 0x05}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.noMaskWrite = true
+			slave.set(10, 0x00, 0x01)
+			var opts []modbus.Option
+			if tt.emulate {
+				opts = append(opts, modbus.WithMaskWriteEmulation())
+			}
+			client := modbus.NewClient(slave, opts...)
+
+			err := client.WriteBit(10, 2, true)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, slave.get(10, 1))
+
+			err = client.MaskWrite(10, 0xFFFF, 0)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
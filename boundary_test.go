@@ -0,0 +1,325 @@
+package modbus_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// consecutiveReads returns n Uint16 reads starting at register.
+func consecutiveReads(register uint16, n int) []modbus.Read {
+	ops := make([]modbus.Read, n)
+	for i := range ops {
+		ops[i] = testRead{register + uint16(i), types.Uint16Type}
+	}
+	return ops
+}
+
+// consecutiveWrites returns n Uint16 writes starting at register, each
+// writing the offset of its register.
+func consecutiveWrites(register uint16, n int) ([]modbus.Write, []byte) {
+	ops := make([]modbus.Write, n)
+	var payload []byte
+	for i := range ops {
+		ops[i] = testWrite{register + uint16(i), types.Uint16(i)}
+		payload = append(payload, types.Uint16(i).Bytes()...)
+	}
+	return ops, payload
+}
+
+func TestClient_BatchRead_boundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []modbus.Read
+		want []modbustest.WireExpectation
+	}{
+		{"merged below the limit", consecutiveReads(0, 124), []modbustest.WireExpectation{modbustest.Read(0, 124)}},
+		{"merged at the limit", consecutiveReads(0, 125), []modbustest.WireExpectation{modbustest.Read(0, 125)}},
+		{"merged above the limit", consecutiveReads(0, 126), []modbustest.WireExpectation{
+			modbustest.Read(0, 125), modbustest.Read(125, 1),
+		}},
+		{"single op at the limit", []modbus.Read{testRead{0, types.NewRaw(125)}}, []modbustest.WireExpectation{
+			modbustest.Read(0, 125),
+		}},
+		{"upper half of the address space", consecutiveReads(40000, 2), []modbustest.WireExpectation{
+			modbustest.Read(40000, 2),
+		}},
+		{"last register", []modbus.Read{testRead{65535, types.Uint16Type}}, []modbustest.WireExpectation{
+			modbustest.Read(65535, 1),
+		}},
+		{"merged up to the last register", consecutiveReads(65411, 125), []modbustest.WireExpectation{
+			modbustest.Read(65411, 125),
+		}},
+		{"merged across the last register", consecutiveReads(65410, 126), []modbustest.WireExpectation{
+			modbustest.Read(65410, 125), modbustest.Read(65535, 1),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			seed := make(modbus.Registers)
+			for _, op := range tt.ops {
+				if op.Type() == types.Uint16Type {
+					seed[op.Register()] = types.Uint16(op.Register())
+				}
+			}
+			slave.Seed(seed)
+			client := modbus.NewClient(slave)
+
+			res := modbustest.ExpectPlan(t, client, tt.ops, tt.want)
+			assert.Len(t, res, len(tt.ops))
+			for register, value := range seed {
+				assert.Equal(t, value, res[register], "register %d", register)
+			}
+		})
+	}
+}
+
+func TestClient_BatchWrite_boundaries(t *testing.T) {
+	below, belowPayload := consecutiveWrites(0, 122)
+	at, atPayload := consecutiveWrites(0, 123)
+	above, abovePayload := consecutiveWrites(0, 124)
+	last, lastPayload := consecutiveWrites(65413, 123)
+	raw := make([]byte, 123*2)
+	tests := []struct {
+		name string
+		ops  []modbus.Write
+		want []modbustest.WireExpectation
+	}{
+		{"merged below the limit", below, []modbustest.WireExpectation{modbustest.Write(0, belowPayload...)}},
+		{"merged at the limit", at, []modbustest.WireExpectation{modbustest.Write(0, atPayload...)}},
+		{"merged above the limit", above, []modbustest.WireExpectation{
+			modbustest.Write(0, abovePayload[:246]...), modbustest.Write(123, abovePayload[246:]...),
+		}},
+		{"single op at the limit", []modbus.Write{testWrite{0, types.NewRaw(123).With(raw)}}, []modbustest.WireExpectation{
+			modbustest.Write(0, raw...),
+		}},
+		{"last register", []modbus.Write{testWrite{65535, types.Uint16(7)}}, []modbustest.WireExpectation{
+			modbustest.Write(65535, 0, 7),
+		}},
+		{"merged up to the last register", last, []modbustest.WireExpectation{modbustest.Write(65413, lastPayload...)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			client := modbus.NewClient(slave)
+
+			modbustest.ExpectWrites(t, client, tt.ops, nil, tt.want)
+			for _, pdu := range slave.Requests() {
+				assert.LessOrEqual(t, 1+len(pdu.Data), 253, "PDU size")
+			}
+		})
+	}
+}
+
+func TestClient_boundaryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(*modbus.Client) error
+		err  error
+	}{
+		{
+			name: "strict read above the limit",
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchReadWith([]modbus.Read{testRead{0, types.NewRaw(126)}}, modbus.BatchOptions{
+					Limits: &modbus.Limits{StrictReadSize: true},
+				})
+				return err
+			},
+			err: modbus.ErrTooManyRegisters,
+		},
+		{
+			name: "split read past the last register",
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{65500, types.NewRaw(200)}})
+				return err
+			},
+			err: modbus.ErrAddressSpace,
+		},
+		{
+			name: "read past the last register",
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{65535, types.Uint32Type}})
+				return err
+			},
+			err: modbus.ErrAddressSpace,
+		},
+		{
+			name: "single read past the last register",
+			call: func(c *modbus.Client) error {
+				_, err := c.Read(65535, types.Uint32Type)
+				return err
+			},
+			err: modbus.ErrAddressSpace,
+		},
+		{
+			name: "strict write above the limit",
+			call: func(c *modbus.Client) error {
+				return c.BatchWriteWith([]modbus.Write{testWrite{0, types.NewRaw(124).With(make([]byte, 248))}}, nil, modbus.BatchOptions{
+					Limits: &modbus.Limits{StrictWriteSize: true},
+				})
+			},
+			err: modbus.ErrTooManyRegisters,
+		},
+		{
+			name: "split write past the last register",
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{testWrite{65500, rawWrite(make([]byte, 400))}}, nil)
+			},
+			err: modbus.ErrAddressSpace,
+		},
+		{
+			name: "split write of odd length",
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{testWrite{0, rawWrite(make([]byte, 301))}}, nil)
+			},
+			err: types.ErrInvalidInput,
+		},
+		{
+			name: "write past the last register",
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{testWrite{65535, types.Uint32(1)}}, nil)
+			},
+			err: modbus.ErrAddressSpace,
+		},
+		{
+			name: "single write past the last register",
+			call: func(c *modbus.Client) error {
+				return c.Write(65534, types.Uint64(1))
+			},
+			err: modbus.ErrAddressSpace,
+		},
+		{
+			name: "zero-quantity read",
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.NewRaw(0)}})
+				return err
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+		{
+			name: "zero-quantity single read",
+			call: func(c *modbus.Client) error {
+				_, err := c.Read(10, types.NewRaw(0))
+				return err
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+		{
+			name: "empty write",
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{11, types.NewRaw(0)}}, nil)
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+		{
+			name: "empty swap",
+			call: func(c *modbus.Client) error {
+				_, err := c.Swap([]modbus.Write{testWrite{10, types.NewRaw(0)}})
+				return err
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+		{
+			name: "nil write",
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{testWrite{10, nil}}, modbus.Registers{10: types.Uint16(1)})
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+		{
+			name: "nil single write",
+			call: func(c *modbus.Client) error {
+				return c.Write(10, nil)
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+		{
+			name: "nil unverified write",
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{modbus.UnverifiedWrite(testWrite{10, nil})}, nil)
+			},
+			err: modbus.ErrEmptyOperation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			assert.ErrorIs(t, tt.call(modbus.NewClient(slave)), tt.err)
+			assert.Empty(t, slave.Requests(), "nothing is sent")
+		})
+	}
+}
+
+func TestClient_BatchRead_split(t *testing.T) {
+	// every register holds its own number
+	seed := make(modbus.Registers)
+	for r := 0; r < 2000; r++ {
+		seed[uint16(r)] = types.Uint16(r)
+	}
+	slave := modbustest.NewSlave()
+	slave.Seed(seed)
+	client := modbus.NewClient(slave)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{1300, types.Uint16Type},
+		testRead{1000, types.NewRaw(300)},
+	}, []modbustest.WireExpectation{
+		modbustest.Read(1000, 125),
+		modbustest.Read(1125, 125),
+		// the last piece is merged with the adjacent op
+		modbustest.Read(1250, 51),
+	})
+	assert.Equal(t, slave.Get(1000, 300), res[1000].Bytes(), "pieces are reassembled in order")
+	assert.Equal(t, types.Uint16(1300), res[1300])
+
+	v, err := client.Read(10, types.NewRaw(300))
+	assert.NoError(t, err)
+	assert.Equal(t, slave.Get(10, 300), v.Bytes())
+	assertCoilRequests(t, slave.Requests()[3:], []modbustest.WireExpectation{
+		modbustest.Read(10, 125), modbustest.Read(135, 125), modbustest.Read(260, 50),
+	})
+}
+
+func TestClient_BatchWrite_split(t *testing.T) {
+	block := make([]byte, 600)
+	for i := range block {
+		block[i] = byte(i)
+	}
+	ops := []modbus.Write{testWrite{1000, rawWrite(block)}}
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+
+	modbustest.ExpectWrites(t, client, ops, nil, []modbustest.WireExpectation{
+		modbustest.Write(1000, block[:246]...),
+		modbustest.Write(1123, block[246:492]...),
+		modbustest.Write(1246, block[492:]...),
+	})
+	assert.Equal(t, block, slave.Get(1000, 300))
+
+	// differential optimization compares the op as a whole
+	modbustest.ExpectWrites(t, client, append(ops, testWrite{1300, types.Uint16(1)}), modbus.Registers{
+		1000: rawWrite(block),
+	}, []modbustest.WireExpectation{
+		modbustest.Write(1300, 0, 1),
+	})
+	changed := append([]byte{0xFF}, block[1:]...)
+	modbustest.ExpectWrites(t, client, []modbus.Write{testWrite{1000, rawWrite(changed)}}, modbus.Registers{
+		1000: rawWrite(block),
+	}, []modbustest.WireExpectation{
+		modbustest.Write(1000, changed[:246]...),
+		modbustest.Write(1123, changed[246:492]...),
+		modbustest.Write(1246, changed[492:]...),
+	})
+
+	assert.NoError(t, client.Write(0, rawWrite(block)))
+	assert.Equal(t, block, slave.Get(0, 300))
+	assertCoilRequests(t, slave.Requests()[7:], []modbustest.WireExpectation{
+		modbustest.Write(0, block[:246]...),
+		modbustest.Write(123, block[246:492]...),
+		modbustest.Write(246, block[492:]...),
+	})
+}
@@ -0,0 +1,278 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tdemin/opmodbus/store"
+)
+
+// ErrWriteBudgetExceeded is returned when a write would exceed the
+// write budget of a register. See WithWriteBudgets.
+var ErrWriteBudgetExceeded = errors.New("write budget exceeded")
+
+// WriteBudget limits how often each register of a range may be written,
+// e.g. to protect EEPROM-backed registers from wearing out. Zero fields
+// are not enforced.
+type WriteBudget struct {
+	RegisterRange
+	// MinInterval is the minimum time between two writes to a register.
+	MinInterval time.Duration
+	// PerDay is the maximum number of writes to a register within 24
+	// hours, counted from the first write of the period.
+	PerDay int
+}
+
+// WithWriteBudgets adds budgets limiting how often registers may be
+// written. Writes exceeding a budget fail with ErrWriteBudgetExceeded
+// before any request of the batch is sent. Only writes the slave
+// acknowledged are charged against the budgets. A register is covered
+// by the first budget containing it. See WriteBudgetStats.
+func WithWriteBudgets(budgets ...WriteBudget) Option {
+	return func(c *Config) {
+		c.WriteBudgets = append(c.WriteBudgets[:len(c.WriteBudgets):len(c.WriteBudgets)], budgets...)
+	}
+}
+
+// WithWriteBudgetOverride disables enforcing write budgets, e.g. for
+// deliberate maintenance. Writes are still accounted for.
+func WithWriteBudgetOverride() Option {
+	return func(c *Config) {
+		c.OverrideWriteBudgets = true
+	}
+}
+
+func (b WriteBudget) String() string {
+	return fmt.Sprintf("registers %v: interval %v, %d per day", b.rng(), b.MinInterval, b.PerDay)
+}
+
+// WriteBudgetStats holds the number of writes a budget has suppressed.
+type WriteBudgetStats struct {
+	Budget     WriteBudget
+	Suppressed uint64
+}
+
+type budgetState struct {
+	last       time.Time
+	dayStart   time.Time
+	dayCount   int
+	suppressed uint64
+}
+
+// budgetRecord is the persisted form of budgetState.
+type budgetRecord struct {
+	Last       time.Time
+	DayStart   time.Time
+	DayCount   int
+	Suppressed uint64
+}
+
+const (
+	budgetNamespace = "budgets"
+	budgetVersion   = 1
+)
+
+// budgetTracker keeps write history of registers covered by budgets.
+// If store is set, the history is loaded from it on first use and saved
+// to it on every change, so that budgets survive restarts. Store
+// failures never fail writes and are reported to onError.
+type budgetTracker struct {
+	mtx       sync.Mutex
+	now       func() time.Time
+	registers map[uint16]*budgetState
+	store     store.Store
+	onError   func(error)
+}
+
+// check checks every op against budgets before they are written. Writes
+// of a register by several ops count against its budget together. With
+// override set, budgets are not enforced. The history only changes by
+// the suppressed writes; completed writes are recorded with charge.
+func (t *budgetTracker) check(budgets []WriteBudget, ops []writeOp, override bool) error {
+	if len(budgets) == 0 || override {
+		return nil
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	now := t.init()
+	// spending is the history of a register as of the writes of ops
+	// checked so far
+	type spending struct {
+		state    *budgetState
+		dayCount int
+		writes   int
+	}
+	spent := make(map[uint16]*spending)
+	for _, op := range ops {
+		for r := int(op.register); r < int(op.register)+int(op.quantity); r++ {
+			budget, ok := findBudget(budgets, uint16(r))
+			if !ok {
+				continue
+			}
+			s, ok := spent[uint16(r)]
+			if !ok {
+				state, ok := t.registers[uint16(r)]
+				if !ok {
+					state = new(budgetState)
+				}
+				s = &spending{state: state, dayCount: state.dayCount}
+				if now.Sub(state.dayStart) >= 24*time.Hour {
+					s.dayCount = 0
+				}
+				spent[uint16(r)] = s
+			}
+			last := s.state.last
+			if s.writes > 0 {
+				last = now
+			}
+			exceeded := budget.MinInterval > 0 && !last.IsZero() && now.Sub(last) < budget.MinInterval ||
+				budget.PerDay > 0 && s.dayCount >= budget.PerDay
+			if exceeded {
+				s.state.suppressed++
+				t.registers[uint16(r)] = s.state
+				t.save(uint16(r))
+				return fmt.Errorf("%w: register %d: %v", ErrWriteBudgetExceeded, r, budget)
+			}
+			s.dayCount++
+			s.writes++
+		}
+	}
+
+	return nil
+}
+
+// charge records a completed write of the registers of r covered by
+// budgets, overridden or not.
+func (t *budgetTracker) charge(budgets []WriteBudget, r RegisterRange) {
+	if len(budgets) == 0 {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	now := t.init()
+	var registers []uint16
+	for i := int(r.Register); i < int(r.Register)+int(r.Quantity); i++ {
+		if _, ok := findBudget(budgets, uint16(i)); !ok {
+			continue
+		}
+		state, ok := t.registers[uint16(i)]
+		if !ok {
+			state = new(budgetState)
+			t.registers[uint16(i)] = state
+		}
+		if now.Sub(state.dayStart) >= 24*time.Hour {
+			state.dayStart, state.dayCount = now, 0
+		}
+		state.last = now
+		state.dayCount++
+		registers = append(registers, uint16(i))
+	}
+	t.save(registers...)
+}
+
+// init loads the history on first use and returns the current time.
+// The caller must hold the mutex.
+func (t *budgetTracker) init() time.Time {
+	if t.registers == nil {
+		t.registers = make(map[uint16]*budgetState)
+		t.load()
+	}
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// load restores the history from the store. Entries that can't be read
+// are skipped. The caller must hold the mutex.
+func (t *budgetTracker) load() {
+	if t.store == nil {
+		return
+	}
+	keys, err := t.store.List(budgetNamespace)
+	if err != nil {
+		t.report(fmt.Errorf("loading write budgets: %w", err))
+		return
+	}
+	for _, k := range keys {
+		register, err := strconv.ParseUint(k, 10, 16)
+		if err != nil {
+			t.report(fmt.Errorf("loading write budget of %q: %w", k, err))
+			continue
+		}
+		var rec budgetRecord
+		b, err := t.store.Get(budgetNamespace, k)
+		if err == nil {
+			err = store.Decode(b, budgetVersion, &rec)
+		}
+		if err != nil {
+			t.report(fmt.Errorf("loading write budget of register %d: %w", register, err))
+			continue
+		}
+		t.registers[uint16(register)] = &budgetState{rec.Last, rec.DayStart, rec.DayCount, rec.Suppressed}
+	}
+}
+
+// save stores the history of registers. The caller must hold the
+// mutex.
+func (t *budgetTracker) save(registers ...uint16) {
+	if t.store == nil || len(registers) == 0 {
+		return
+	}
+	entries := make(map[string][]byte, len(registers))
+	for _, r := range registers {
+		state := t.registers[r]
+		b, err := store.Encode(budgetVersion, budgetRecord{state.last, state.dayStart, state.dayCount, state.suppressed})
+		if err != nil {
+			t.report(fmt.Errorf("saving write budget of register %d: %w", r, err))
+			return
+		}
+		entries[strconv.Itoa(int(r))] = b
+	}
+	if err := t.store.Put(budgetNamespace, entries); err != nil {
+		t.report(fmt.Errorf("saving write budgets: %w", err))
+	}
+}
+
+func (t *budgetTracker) report(err error) {
+	if t.onError != nil {
+		t.onError(err)
+	}
+}
+
+func (t *budgetTracker) stats(budgets []WriteBudget) []WriteBudgetStats {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	res := make([]WriteBudgetStats, len(budgets))
+	for i, b := range budgets {
+		res[i].Budget = b
+		for r := int(b.Register); r < int(b.Register)+int(b.Quantity); r++ {
+			if budget, ok := findBudget(budgets, uint16(r)); !ok || budget != b {
+				continue
+			}
+			if state, ok := t.registers[uint16(r)]; ok {
+				res[i].Suppressed += state.suppressed
+			}
+		}
+	}
+
+	return res
+}
+
+// findBudget returns the first budget covering register.
+func findBudget(budgets []WriteBudget, register uint16) (WriteBudget, bool) {
+	for _, b := range budgets {
+		if b.Contains(register) {
+			return b, true
+		}
+	}
+	return WriteBudget{}, false
+}
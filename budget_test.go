@@ -0,0 +1,132 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tdemin/opmodbus/store"
+)
+
+// spend checks ops and charges them as if all of them were written.
+func (t *budgetTracker) spend(budgets []WriteBudget, ops []writeOp, override bool) error {
+	if err := t.check(budgets, ops, override); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		t.charge(budgets, RegisterRange{op.register, op.quantity})
+	}
+	return nil
+}
+
+func Test_budgetTracker(t *testing.T) {
+	now := time.Unix(0, 0)
+	advance := func(d time.Duration) { now = now.Add(d) }
+	budgets := []WriteBudget{
+		{RegisterRange: RegisterRange{100, 2}, MinInterval: time.Minute},
+		{RegisterRange: RegisterRange{200, 1}, PerDay: 3},
+	}
+	tracker := budgetTracker{now: func() time.Time { return now }}
+	write := func(register, quantity uint16, override bool) error {
+		return tracker.spend(budgets, []writeOp{{register, quantity, nil}}, override)
+	}
+
+	// frequency window
+	assert.NoError(t, write(100, 1, false))
+	advance(30 * time.Second)
+	assert.ErrorIs(t, write(100, 1, false), ErrWriteBudgetExceeded)
+	assert.NoError(t, write(101, 1, false), "registers are budgeted separately")
+	advance(30 * time.Second)
+	assert.NoError(t, write(100, 1, false), "window passed")
+	assert.ErrorIs(t, write(99, 2, false), ErrWriteBudgetExceeded, "multi-register write")
+
+	// daily cap
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, write(200, 1, false))
+		advance(time.Hour)
+	}
+	assert.ErrorIs(t, write(200, 1, false), ErrWriteBudgetExceeded)
+	advance(21 * time.Hour)
+	assert.NoError(t, write(200, 1, false), "next day")
+
+	// override
+	assert.NoError(t, write(100, 1, true))
+	assert.ErrorIs(t, write(100, 1, false), ErrWriteBudgetExceeded, "override writes are accounted")
+
+	assert.NoError(t, write(300, 1, false), "unbudgeted register")
+
+	assert.Equal(t, []WriteBudgetStats{
+		{budgets[0], 3},
+		{budgets[1], 1},
+	}, tracker.stats(budgets))
+}
+
+func Test_budgetTracker_allOrNothing(t *testing.T) {
+	budgets := []WriteBudget{{RegisterRange: RegisterRange{10, 1}, PerDay: 1}}
+	var tracker budgetTracker
+	assert.NoError(t, tracker.spend(budgets, []writeOp{{10, 1, nil}}, false))
+
+	ops := []writeOp{{5, 1, nil}, {10, 1, nil}}
+	assert.ErrorIs(t, tracker.spend(budgets, ops, false), ErrWriteBudgetExceeded)
+	assert.NotContains(t, tracker.registers, uint16(5))
+}
+
+func Test_budgetTracker_store(t *testing.T) {
+	now := time.Unix(1000, 0)
+	budgets := []WriteBudget{{RegisterRange: RegisterRange{100, 2}, MinInterval: time.Minute, PerDay: 10}}
+	s := store.NewMemory()
+	var reported []error
+	newTracker := func() *budgetTracker {
+		return &budgetTracker{
+			now:     func() time.Time { return now },
+			store:   s,
+			onError: func(err error) { reported = append(reported, err) },
+		}
+	}
+
+	tracker := newTracker()
+	assert.NoError(t, tracker.spend(budgets, []writeOp{{100, 2, nil}}, false))
+	assert.ErrorIs(t, tracker.spend(budgets, []writeOp{{101, 1, nil}}, false), ErrWriteBudgetExceeded)
+
+	// restart
+	tracker = newTracker()
+	now = now.Add(30 * time.Second)
+	assert.ErrorIs(t, tracker.spend(budgets, []writeOp{{100, 1, nil}}, false), ErrWriteBudgetExceeded,
+		"history survives restarts")
+	assert.Equal(t, []WriteBudgetStats{{budgets[0], 2}}, tracker.stats(budgets))
+
+	// corrupt entries are skipped
+	assert.NoError(t, s.Put(budgetNamespace, map[string][]byte{"100": []byte("{"), "bad": {}}))
+	tracker = newTracker()
+	assert.NoError(t, tracker.spend(budgets, []writeOp{{100, 1, nil}}, false))
+	assert.ErrorIs(t, tracker.spend(budgets, []writeOp{{101, 1, nil}}, false), ErrWriteBudgetExceeded)
+	assert.Len(t, reported, 2)
+}
+
+func Test_budgetTracker_batch(t *testing.T) {
+	now := time.Unix(0, 0)
+	budgets := []WriteBudget{
+		{RegisterRange: RegisterRange{10, 1}, PerDay: 2},
+		{RegisterRange: RegisterRange{20, 1}, MinInterval: time.Minute},
+	}
+	tracker := budgetTracker{now: func() time.Time { return now }}
+
+	assert.NoError(t, tracker.spend(budgets, []writeOp{{10, 1, nil}}, false))
+	assert.ErrorIs(t, tracker.spend(budgets, []writeOp{{10, 1, nil}, {10, 1, nil}}, false), ErrWriteBudgetExceeded,
+		"writes of a batch count together")
+	assert.Equal(t, 1, tracker.registers[10].dayCount)
+	assert.ErrorIs(t, tracker.spend(budgets, []writeOp{{20, 1, nil}, {20, 1, nil}}, false), ErrWriteBudgetExceeded,
+		"a second write of a batch is within the interval")
+	assert.True(t, tracker.registers[20].last.IsZero())
+
+	// the day period of register 10 isn't restarted by a failed batch
+	now = now.Add(25 * time.Hour)
+	assert.NoError(t, tracker.spend(budgets, []writeOp{{20, 1, nil}}, false))
+	assert.ErrorIs(t, tracker.spend(budgets, []writeOp{{10, 1, nil}, {20, 1, nil}}, false), ErrWriteBudgetExceeded)
+	assert.Equal(t, time.Unix(0, 0), tracker.registers[10].dayStart)
+	assert.Equal(t, 1, tracker.registers[10].dayCount)
+
+	assert.NoError(t, tracker.spend(budgets, []writeOp{{10, 1, nil}, {10, 1, nil}}, false))
+	assert.Equal(t, now, tracker.registers[10].dayStart)
+	assert.Equal(t, 2, tracker.registers[10].dayCount)
+}
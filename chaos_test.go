@@ -0,0 +1,110 @@
+package modbus
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	chaosSeed  = flag.Int64("chaos.seed", 0, "run chaos tests with this seed only")
+	chaosSeeds = flag.Int("chaos.seeds", 8, "number of seeds swept by chaos tests")
+)
+
+// chaosOptions tunes chaos.
+type chaosOptions struct {
+	// Duplicate makes chaos send some reads twice. Tests counting
+	// requests should leave it off.
+	Duplicate bool
+}
+
+// chaos is a scheduler taking random decisions from a seeded source:
+// it shuffles the read requests of every batch, yields or sleeps
+// before wire requests and optionally duplicates reads. The decisions
+// are a function of the seed and the sequence of calls, so a failing
+// seed reproduces as far as the Go scheduler allows.
+type chaos struct {
+	mtx  sync.Mutex
+	rng  *rand.Rand
+	opts chaosOptions
+}
+
+func newChaos(seed int64, opts chaosOptions) *chaos {
+	return &chaos{rng: rand.New(rand.NewSource(seed)), opts: opts}
+}
+
+func (c *chaos) shuffle(ops []readOp) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.rng.Shuffle(len(ops), func(i, j int) { ops[i], ops[j] = ops[j], ops[i] })
+}
+
+func (c *chaos) yield() {
+	c.mtx.Lock()
+	n := c.rng.Intn(8)
+	sleep := time.Duration(c.rng.Intn(100)) * time.Microsecond
+	c.mtx.Unlock()
+
+	switch {
+	case n == 0:
+		time.Sleep(sleep)
+	case n < 4:
+		runtime.Gosched()
+	}
+}
+
+func (c *chaos) duplicate() bool {
+	if !c.opts.Duplicate {
+		return false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.rng.Intn(4) == 0
+}
+
+// sweepChaos runs f as a subtest for every seed of the sweep, or only
+// for -chaos.seed if set. f installs chaos into the clients it tests
+// with install. The seed of a failing subtest is logged.
+func sweepChaos(t *testing.T, opts chaosOptions, f func(t *testing.T, install func(*Client))) {
+	seeds := []int64{*chaosSeed}
+	if *chaosSeed == 0 {
+		seeds = seeds[:0]
+		for i := 1; i <= *chaosSeeds; i++ {
+			seeds = append(seeds, int64(i))
+		}
+	}
+	for _, seed := range seeds {
+		seed := seed
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			t.Cleanup(func() {
+				if t.Failed() {
+					t.Logf("reproduce with -chaos.seed=%d", seed)
+				}
+			})
+			f(t, func(c *Client) { c.sched = newChaos(seed, opts) })
+		})
+	}
+}
+
+func TestChaos_deterministic(t *testing.T) {
+	decisions := func(seed int64) []interface{} {
+		c := newChaos(seed, chaosOptions{Duplicate: true})
+		var res []interface{}
+		for i := 0; i < 20; i++ {
+			ops := []readOp{{1, 1, HoldingRegisters}, {2, 1, HoldingRegisters}, {3, 1, HoldingRegisters}, {4, 1, HoldingRegisters}}
+			c.shuffle(ops)
+			res = append(res, ops, c.duplicate())
+		}
+		return res
+	}
+	if fmt.Sprint(decisions(1)) != fmt.Sprint(decisions(1)) {
+		t.Error("decisions differ for the same seed")
+	}
+	if fmt.Sprint(decisions(1)) == fmt.Sprint(decisions(2)) {
+		t.Error("decisions match for different seeds")
+	}
+}
@@ -0,0 +1,108 @@
+package modbus
+
+import (
+	"sync"
+	"time"
+)
+
+// ChunkCacheStats holds counters of the chunk response cache.
+type ChunkCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// WithChunkCache enables caching of raw function 3 responses issued by
+// BatchRead for ttl. A cached response is reused by later batches
+// containing a wire request for exactly the same registers of the same
+// unit, which saves traffic when several callers read the same data
+// shortly one after another, at the price of getting data up to ttl
+// old. Writes made through the client drop cached responses they
+// overlap with. Input registers are never cached.
+func WithChunkCache(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ChunkCacheTTL = ttl
+	}
+}
+
+type chunkEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// chunkKey identifies a cached response by the unit it was read from,
+// or defaultUnit for the unit of the handler, and the exact wire request
+// range.
+type chunkKey struct {
+	unit int
+	RegisterRange
+}
+
+const defaultUnit = -1
+
+// chunkCache is a short-lived cache of raw function 3 responses.
+type chunkCache struct {
+	mtx     sync.Mutex
+	now     func() time.Time
+	entries map[chunkKey]chunkEntry
+	stats   ChunkCacheStats
+}
+
+func (c *chunkCache) get(k chunkKey) ([]byte, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[k]
+	if ok && !c.clock().Before(e.expires) {
+		delete(c.entries, k)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	return append([]byte(nil), e.data...), true
+}
+
+func (c *chunkCache) put(k chunkKey, data []byte, ttl time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.clock()
+	if c.entries == nil {
+		c.entries = make(map[chunkKey]chunkEntry)
+	}
+	// drop expired entries so that the cache never outgrows the set of
+	// chunks read within one TTL
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[k] = chunkEntry{append([]byte(nil), data...), now.Add(ttl)}
+}
+
+// invalidate drops every entry overlapping r, of any unit.
+func (c *chunkCache) invalidate(r RegisterRange) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for k := range c.entries {
+		if k.Overlaps(r) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+func (c *chunkCache) counters() ChunkCacheStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.stats
+}
+
+func (c *chunkCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
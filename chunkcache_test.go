@@ -0,0 +1,56 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_chunkCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := chunkCache{now: func() time.Time { return now }}
+	chunk := chunkKey{defaultUnit, RegisterRange{10, 2}}
+
+	_, ok := cache.get(chunk)
+	assert.False(t, ok, "miss on empty cache")
+
+	cache.put(chunk, []byte{1, 2, 3, 4}, time.Second)
+	data, ok := cache.get(chunk)
+	assert.True(t, ok, "hit within TTL")
+	assert.Equal(t, []byte{1, 2, 3, 4}, data)
+	data[0] = 9
+	data, _ = cache.get(chunk)
+	assert.Equal(t, []byte{1, 2, 3, 4}, data, "hits are copies")
+	_, ok = cache.get(chunkKey{defaultUnit, RegisterRange{10, 1}})
+	assert.False(t, ok, "miss on a different range")
+	_, ok = cache.get(chunkKey{1, RegisterRange{10, 2}})
+	assert.False(t, ok, "miss on a different unit")
+
+	now = now.Add(time.Second)
+	_, ok = cache.get(chunk)
+	assert.False(t, ok, "miss after TTL expiry")
+
+	cache.put(chunk, []byte{1, 2, 3, 4}, time.Second)
+	cache.invalidate(RegisterRange{11, 5})
+	_, ok = cache.get(chunk)
+	assert.False(t, ok, "miss after invalidation")
+
+	cache.put(chunkKey{1, RegisterRange{10, 2}}, []byte{1, 2, 3, 4}, time.Second)
+	cache.invalidate(RegisterRange{10, 1})
+	_, ok = cache.get(chunkKey{1, RegisterRange{10, 2}})
+	assert.False(t, ok, "writes invalidate every unit")
+
+	assert.Equal(t, ChunkCacheStats{Hits: 2, Misses: 6}, cache.counters())
+}
+
+func Test_readCache_copies(t *testing.T) {
+	var cache readCache
+	op := readOp{10, 2, HoldingRegisters}
+	cache.put(op, []byte{1, 2, 3, 4}, time.Minute)
+	data, ok := cache.get(op)
+	assert.True(t, ok)
+	data[0] = 9
+	data, _ = cache.get(op)
+	assert.Equal(t, []byte{1, 2, 3, 4}, data, "hits are copies")
+}
@@ -23,15 +23,18 @@
 //
 //  A.register + A.quantity = B.register
 //
-// and the total quantity after merge does not exceed 2047 for reads and
-// 123 for writes, merge operations.
+// and the total quantity after merge does not exceed 125 for reads and
+// 123 for writes, or the limits configured with WithLimits, merge
+// operations.
 package modbus
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/goburrow/modbus"
 	"github.com/tdemin/opmodbus/internal/containers"
@@ -39,37 +42,120 @@ import (
 )
 
 // Client is an optimizing Modbus client that operates on chains of
-// requests. It can only execute functions 3 and 16.
+// requests. It can only execute functions 1 to 4, 15, 16 and 23.
 //
 // Client is only thread-safe if Client and ClientHandler are untouched.
 type Client struct {
 	modbus.Client
 	modbus.ClientHandler
 
-	mtx sync.Mutex
+	config    Config
+	settle    settleTracker
+	mtx       sync.Mutex
+	closed    int32
+	stopping  int32 // set while Shutdown stops the components
+	hooksMtx  sync.Mutex
+	hooks     []shutdownHook
+	aborts    abortTracker // of in-flight batches, see ShutdownCancel
+	latencies latencyTracker
+	chunks    chunkCache
+	cache     readCache
+	stats     statsCounter
+	swr       swrCache
+	budgets   budgetTracker
+	timeouts  timeoutTuner
+	// the handler timeout before tuning
+	outerTimeout time.Duration
+	// sched perturbs wire requests in tests, see scheduler
+	sched scheduler
+	// noReadWrite is set once the slave rejected function 23
+	noReadWrite int32
+	// noDiagnostics is set once the slave rejected function 8, see Ping
+	noDiagnostics bool
+	// lastContact holds the time.Time of the last response, see
+	// LastContact
+	lastContact atomic.Value
+	// initErr fails all operations of a client with an unusable handler
+	// or invalid options, see checkHandler and ErrInvalidOption
+	initErr   error
+	connected bool
+	// wire sends the requests of the client, see NewClient
+	wire modbus.Transporter
+	// lastResponse is when the last wire request completed, see
+	// WithRequestSpacing, and completed counts the completed requests
+	lastResponse time.Time
+	completed    int
+	// unit is the unit a batch addresses if not nil, see address
+	unit *byte
+	// batching is set while a batch of batchOps is performed, see
+	// track
+	batching   bool
+	batchOps   []readOp
+	batchStats *BatchStats
+	sent       *[]sentWrite
+	ctx        context.Context // of the requests sent, see bind
+	// attempt counts the attempts of the current request while
+	// logging, see attempts
+	attempt int
+	// invariants checks Guarantees in builds with the opmodbus_check
+	// tag
+	invariants invariants
 }
 
-// NewClient builds a Modbus client from ClientHandler.
-func NewClient(handler modbus.ClientHandler) *Client {
-	return &Client{modbus.NewClient(handler), handler, sync.Mutex{}}
+// NewClient builds a Modbus client from ClientHandler. Options are
+// applied in order, so later options override earlier ones. Handlers
+// implementing Connector are connected on first use. Operations of a
+// client built from a nil handler fail with ErrNilHandler, and those of
+// a client built with invalid options with ErrInvalidOption.
+//
+// NewClient doesn't return an error, so that existing callers keep
+// building clients the same way. Open is the constructor returning the
+// error right away, once options have been validated.
+func NewClient(handler modbus.ClientHandler, opts ...Option) *Client {
+	c := &Client{ClientHandler: handler, initErr: checkHandler(handler)}
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+	c.config.check()
+	if c.initErr == nil {
+		c.initErr = c.config.err
+	}
+	c.wire = c.invariants.transporter(handler)
+	if c.wire != nil {
+		c.wire = trackedTransporter{c.wire, c}
+	}
+	c.Client = modbus.NewClient2(handler, c.wire)
+	c.budgets.store, c.budgets.onError = c.config.Store, c.config.StoreErrors
+	return c
 }
 
-// Read represents Modbus function 3 call for a single value.
+// Read represents Modbus function 3 call for a single value. Reads of
+// input registers with function 4 implement SpaceRead, see InputRead.
+// ReadRequest implements Read for batches built from literals.
 type Read interface {
 	Register() uint16
 	Type() types.Type
 }
 
 // Write represents Modbus function 16 call for a single value.
+// WriteRequest implements Write for batches built from literals.
 type Write interface {
 	Register() uint16
 	Value() types.Value
 }
 
 // ErrTooManyRegisters is returned when a number of registers exceeds
-// 123 for writes, and 2047 for reads.
+// 123 for writes and 125 for reads that are not split, see Limits.
 var ErrTooManyRegisters = errors.New("too many registers in an operation")
 
+// ErrAddressSpace is returned for operations extending past register
+// 65535.
+var ErrAddressSpace = errors.New("registers outside of the address space")
+
+// ErrEmptyOperation is returned for ops of no registers, such as reads
+// of zero-sized types and writes of empty or nil values.
+var ErrEmptyOperation = errors.New("operation of zero registers")
+
 const maxUint16 int = 65536 // covers the maximum number of Modbus registers in place
 
 // Registers holds a mapping of a Modbus registers set to their values.
@@ -79,34 +165,154 @@ type Registers map[uint16]types.Value
 // function 3 and returns a map of Modbus registers with their
 // corresponding values.
 //
+// Ops of input registers are read with function 4 and never merged
+// with holding register ops, see SpaceRead. A batch fails with
+// ErrSpaceConflict if it reads the same register from both spaces.
+//
+// Results of optional ops rejected by the slave are Unavailable values,
+// see Optional.
+//
 // See package documentation for the optimization algoritm.
 func (c *Client) BatchRead(ops []Read) (Registers, error) {
+	return c.BatchReadWith(ops, BatchOptions{})
+}
+
+// BatchReadContext is BatchRead checking ctx before every wire request.
+// Once ctx is done, it returns ctx.Err() wrapped with the number of
+// requests completed. A request in flight is not interrupted.
+func (c *Client) BatchReadContext(ctx context.Context, ops []Read) (Registers, error) {
+	return c.BatchReadWith(ops, BatchOptions{Context: ctx})
+}
+
+// BatchReadWith is BatchRead with settings of the client overridden by
+// opts.
+func (c *Client) BatchReadWith(ops []Read, opts BatchOptions) (_ Registers, err error) {
+	defer recoverPanic(&err)
+	stats := &BatchStats{Batches: 1, Ops: len(ops)}
+	defer c.account(stats, time.Now(), opts.Stats)
+
+	cfg, err := c.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.stats = stats
+	if cfg.unit, err = batchUnit(opts.Unit, len(ops), func(i int) interface{} { return ops[i] }); err != nil {
+		return nil, err
+	}
+	hits, misses := c.cachedReads(ops, cfg)
+	var expiry time.Time
+	cfg.cacheable, cfg.expiry = misses, &expiry
+	p, err := c.planRead(misses, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.batch = p.ops
+	stats.RequestedRegisters, stats.ReadRegisters = p.amplification()
+
+	var failed map[readOp]error
+	if opts.Partial {
+		failed = make(map[readOp]error)
+	}
+	results, unavailable, err := c.batchRead(opts.context(), p.requests, p.opt, cfg, failed)
+	readOps(err, ops)
+	for _, ferr := range failed {
+		readOps(ferr, ops)
+	}
+	if results != nil {
+		for r, b := range hits {
+			results[r] = b
+		}
+	}
+	var terr *BatchTimeoutError
+	if errors.As(err, &terr) {
+		terr.Values, _ = decodeRead(covered(ops, results), results)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	available, absent := splitUnavailable(ops, unavailable)
+	available, berr := splitFailed(available, failed)
+	res, err := decodeRead(available, results)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := boundedBy(opts.context(), expiry)
+	defer cancel()
+	verr := validate(ctx, c.config.ValidationRules, res)
+	if verr != nil && verr.Strict() {
+		return nil, verr
+	}
+	for _, u := range absent {
+		res[u.Op.Register()] = u
+	}
+	if berr != nil {
+		berr.Validation = verr
+		return res, berr
+	}
+	if verr != nil {
+		return res, verr
+	}
+	return res, nil
+}
+
+// convertReads checks and converts read ops of a batch.
+func (c *Client) convertReads(ops []Read, cfg batchConfig) ([]readOp, optionalPlan, error) {
 	preopt := make([]readOp, 0, len(ops))
+	var opt optionalPlan
 	for _, op := range ops {
+		if err := checkOrigin(c.config.Identity, op); err != nil {
+			return nil, opt, err
+		}
+		if whole := (readOp{op.Register(), op.Type().Size(), spaceOf(op)}); cfg.Limits.splits(whole) && !isOptional(op) {
+			pieces, err := splitRead(whole, cfg.Limits.read())
+			if err != nil {
+				return nil, opt, err
+			}
+			for _, piece := range pieces {
+				preopt = append(preopt, piece)
+				opt.add(op, piece)
+			}
+			continue
+		}
 		rop, err := convertReadOp(op)
 		if err != nil {
-			return nil, err
+			return nil, opt, err
+		}
+		if err := cfg.Limits.checkRead(rop); err != nil {
+			return nil, opt, err
 		}
 		preopt = append(preopt, rop)
+		opt.add(op, rop)
 	}
-
-	optimized := optimizeRead(preopt)
-	results, err := c.batchRead(optimized)
-	if err != nil {
-		return nil, err
+	if err := checkSpaces(preopt); err != nil {
+		return nil, opt, err
 	}
+	return preopt, opt, nil
+}
 
-	// align results in a flat map, get and convert results by offset
-	// which is equal to Modbus register number
-	mem := containers.NewSlice(maxUint16)
+// decodeRead converts raw results of wire requests into values of ops.
+func decodeRead(ops []Read, results map[readOp][]byte) (Registers, error) {
+	// align results in a flat map per register space, get and convert
+	// results by offset which is equal to Modbus register number
+	mem := make(map[Space]containers.Slice)
 	resultMap := make(Registers)
-	for index, result := range results {
-		mem.Set(int(index)*2, result)
+	for r, result := range results {
+		if mem[r.space] == nil {
+			mem[r.space] = containers.NewSlice(maxUint16 * 2)
+		}
+		mem[r.space].Set(int(r.register)*2, result)
 	}
 	for _, op := range ops {
-		result, err := op.Type().Converter()(mem.Get(int(op.Register())*2, int(op.Type().Size())*2))
+		rop := readOp{op.Register(), op.Type().Size(), spaceOf(op)}
+		var b []byte
+		if m := mem[rop.space]; m != nil {
+			b = m.Get(int(rop.register)*2, int(rop.quantity)*2)
+		}
+		result, err := op.Type().Converter()(b)
 		if err != nil {
-			return nil, fmt.Errorf("%v: %w", op, err)
+			return nil, fmt.Errorf("converting %v of %v in %v: %w", op, rop, requestOf(results, rop), err)
 		}
 		resultMap[op.Register()] = result
 	}
@@ -114,59 +320,268 @@ func (c *Client) BatchRead(ops []Read) (Registers, error) {
 	return resultMap, nil
 }
 
+// requestOf returns the wire request among results covering op.
+func requestOf(results map[readOp][]byte, op readOp) readOp {
+	for r := range results {
+		if r.space == op.space && r.rng().ContainsRange(op.rng()) {
+			return r
+		}
+	}
+	return op
+}
+
 // BatchWrite optimizes a batch of write operations, performs them with
 // function 16 and returns on the first error encountered.
 //
 // If oldData is not nil, BatchWrite will perform differential
 // optimization. See package documentation for the optimization
-// algorithm.
+// algorithm. An op is skipped if oldData holds the same contents for
+// every register it writes, including registers in the middle of a
+// multi-register value of oldData.
 //
 // Only use differential optimization if it is well-known that the slave
 // registers values never change between BatchWrite invocations.
+// BatchWriteTracked returns the oldData of the next invocation.
+//
+// If type checks are enabled with WithTypeChecks, oldData is also used
+// to detect writes that do not match previously read values.
+//
+// Ops writing overlapping registers fail the batch with
+// ErrConflictingWrites unless the client resolves them, see
+// WithWriteConflicts.
 func (c *Client) BatchWrite(ops []Write, oldData Registers) error {
+	return c.BatchWriteWith(ops, oldData, BatchOptions{})
+}
+
+// BatchWriteContext is BatchWrite checking ctx before every wire
+// request, see BatchReadContext.
+func (c *Client) BatchWriteContext(ctx context.Context, ops []Write, oldData Registers) error {
+	return c.BatchWriteWith(ops, oldData, BatchOptions{Context: ctx})
+}
+
+// BatchWriteWith is BatchWrite with settings of the client overridden
+// by opts.
+func (c *Client) BatchWriteWith(ops []Write, oldData Registers, opts BatchOptions) (err error) {
+	defer recoverPanic(&err)
+	return c.batchWriteWith(ops, oldData, opts, nil)
+}
+
+// batchWriteWith is BatchWriteWith recording the attempted write
+// requests in sent if not nil.
+func (c *Client) batchWriteWith(ops []Write, oldData Registers, opts BatchOptions, sent *[]sentWrite) (err error) {
+	stats := &BatchStats{Batches: 1, Ops: len(ops)}
+	defer c.account(stats, time.Now(), opts.Stats)
+	defer func() { writeOps(err, ops, imageOf(oldData, opts.Tolerance), !opts.DisableDiff && !opts.ReadBack) }()
+
+	cfg, err := c.resolve(opts)
+	if err != nil {
+		return err
+	}
+	cfg.stats, cfg.sent, cfg.unverified = stats, sent, unverifiedRanges(ops)
+	if cfg.unit, err = batchUnit(opts.Unit, len(ops), func(i int) interface{} { return ops[i] }); err != nil {
+		return err
+	}
+	p, err := c.planWrite(ops, oldData, opts, cfg)
+	if err != nil {
+		return err
+	}
+	stats.SkippedOps = p.skipped
+	cfg.batch = interceptedOps(p.ops)
+
+	if p.readBack {
+		return c.batchWriteReadBack(opts.context(), p.ops, p.backPlan, cfg, opts.ContinueOnError)
+	}
+	if opts.ContinueOnError {
+		return c.batchWriteAll(opts.context(), p.ops, p.requests, p.applies, cfg)
+	}
+	return c.batchWrite(opts.context(), p.requests, p.applies, cfg)
+}
+
+// convertWrites checks and converts write ops of a batch, excluding ops
+// matching oldData if diff is set.
+func (c *Client) convertWrites(ops []Write, oldData Registers, diff bool, cfg batchConfig) (_ []writeOp, skipped int, err error) {
+	for _, op := range ops {
+		if err := checkOrigin(c.config.Identity, op); err != nil {
+			return nil, 0, err
+		}
+		if err := checkEmpty(op); err != nil {
+			return nil, 0, err
+		}
+	}
+	if ops, err = resolveConflicts(ops, cfg.WriteConflicts); err != nil {
+		return nil, 0, err
+	}
+	if c.config.CheckTypes {
+		if err := checkTypes(ops, oldData); err != nil {
+			return nil, 0, err
+		}
+	}
+
 	diffOpt := make([]writeOp, 0, len(ops))
 
-	if oldData != nil {
-		for _, op := range ops {
-			value, ok := oldData[op.Register()]
-			if !ok || !bytes.Equal(op.Value().Bytes(), value.Bytes()) {
-				wop, err := convertWriteOp(op)
-				if err != nil {
-					return err
-				}
-				diffOpt = append(diffOpt, wop)
-			}
+	image := imageOf(oldData, cfg.tolerance)
+	for _, op := range ops {
+		if diff && unchanged(op, image) {
+			skipped++
+			continue
 		}
-	} else {
-		// skip differential optimization
-		for _, op := range ops {
-			wop, err := convertWriteOp(op)
+		if err := validateValue(op.Register(), op.Value()); err != nil {
+			return nil, 0, err
+		}
+		b := op.Value().Bytes()
+		if whole := (writeOp{op.Register(), uint16(len(b) / 2), b}); cfg.Limits.splitsWrite(whole) {
+			pieces, err := splitWrite(whole, cfg.Limits.write())
 			if err != nil {
-				return err
+				return nil, 0, err
 			}
-			diffOpt = append(diffOpt, wop)
+			diffOpt = append(diffOpt, pieces...)
+			continue
+		}
+		wop, err := convertWriteOp(op)
+		if err != nil {
+			return nil, 0, err
 		}
+		diffOpt = append(diffOpt, wop)
 	}
 
-	optimized := optimizeWrite(diffOpt)
-	return c.batchWrite(optimized)
+	for _, wop := range diffOpt {
+		if err := cfg.Limits.checkWrite(wop); err != nil {
+			return nil, 0, err
+		}
+	}
+	return diffOpt, skipped, nil
+}
+
+// registerImage holds the contents of every register covered by a
+// value of oldData, see imageOf.
+type registerImage struct {
+	words map[uint16][2]byte
+	// oldData and tolerance compare floating-point values if tolerance
+	// is not nil, see Tolerance
+	oldData   Registers
+	tolerance *Tolerance
+}
+
+// imageOf expands the values of oldData across the registers they
+// cover. Registers of overlapping values that disagree are left out.
+func imageOf(oldData Registers, tolerance *Tolerance) registerImage {
+	image := registerImage{make(map[uint16][2]byte), oldData, tolerance}
+	conflicting := make(map[uint16]bool)
+	for register, value := range oldData {
+		if value == nil {
+			continue
+		}
+		b := value.Bytes()
+		for i := 0; i+1 < len(b) && int(register)+i/2 < maxUint16; i += 2 {
+			r, word := register+uint16(i/2), [2]byte{b[i], b[i+1]}
+			if known, ok := image.words[r]; ok && known != word {
+				conflicting[r] = true
+			}
+			image.words[r] = word
+		}
+	}
+	for r := range conflicting {
+		delete(image.words, r)
+	}
+	return image
+}
+
+// unchanged reports whether op writes the contents its registers have
+// in image, whichever values of oldData they belong to, or a value
+// within the tolerance of image.
+func unchanged(op Write, image registerImage) bool {
+	if op.Value() == nil {
+		return false
+	}
+	if t := image.tolerance; t != nil {
+		if equal, ok := t.tolerated(op, image.oldData); ok {
+			return equal
+		}
+	}
+	b := op.Value().Bytes()
+	if len(b) == 0 || len(b)%2 != 0 || int(op.Register())+len(b)/2 > maxUint16 {
+		return false
+	}
+	for i := 0; i < len(b); i += 2 {
+		if known, ok := image.words[op.Register()+uint16(i/2)]; !ok || known != [2]byte{b[i], b[i+1]} {
+			return false
+		}
+	}
+	return true
 }
 
 // Read reads a single value from one or more Modbus registers with
 // function 3 and converts it to Value. The number of Modbus registers
 // is automatically picked based on provided type.
 func (c *Client) Read(register uint16, t types.Type) (types.Value, error) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	return c.ReadContext(context.Background(), register, t)
+}
 
-	op, err := newReadOp(register, t.Size())
-	if err != nil {
+// ReadContext is Read failing with ctx.Err() if ctx is done before the
+// request is made.
+func (c *Client) ReadContext(ctx context.Context, register uint16, t types.Type) (types.Value, error) {
+	return c.readSingle(ctx, register, t, HoldingRegisters)
+}
+
+// ReadInput is Read of input registers with function 4.
+func (c *Client) ReadInput(register uint16, t types.Type) (types.Value, error) {
+	return c.ReadInputContext(context.Background(), register, t)
+}
+
+// ReadInputContext is ReadInput failing with ctx.Err() if ctx is done
+// before the request is made.
+func (c *Client) ReadInputContext(ctx context.Context, register uint16, t types.Type) (types.Value, error) {
+	return c.readSingle(ctx, register, t, InputRegisters)
+}
+
+func (c *Client) readSingle(ctx context.Context, register uint16, t types.Type, space Space) (_ types.Value, err error) {
+	defer recoverPanic(&err)
+
+	if err := c.lock(); err != nil {
 		return nil, err
 	}
-	res, err := c.read(op)
-	if err != nil {
+	defer c.unlock()
+	defer c.bind(ctx)()
+
+	var pieces []readOp
+	if whole := (readOp{register, t.Size(), space}); c.config.Limits.splits(whole) {
+		if pieces, err = splitRead(whole, c.config.Limits.read()); err != nil {
+			return nil, err
+		}
+	} else {
+		op, err := newReadOp(register, t.Size(), space)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.config.Limits.checkRead(op); err != nil {
+			return nil, err
+		}
+		pieces = []readOp{op}
+	}
+	if err := c.checkSpecPlan(c.config, pieces, nil); err != nil {
 		return nil, err
 	}
+	whole := readOp{register, t.Size(), space}
+	cached := c.config.ReadCacheTTL > 0
+	if cached {
+		if b, ok := c.cache.get(whole); ok {
+			return t.Converter()(b)
+		}
+	}
+	var res []byte
+	for i, op := range pieces {
+		if err := c.pace(ctx, i); err != nil {
+			return nil, err
+		}
+		b, err := c.read(op, c.config.Retry)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, b...)
+	}
+	if cached {
+		c.cache.put(whole, res, c.config.ReadCacheTTL)
+	}
 
 	return t.Converter()(res)
 }
@@ -175,51 +590,293 @@ func (c *Client) Read(register uint16, t types.Type) (types.Value, error) {
 // function 16. The number of Modbus registers is automatically picked
 // based on value size.
 func (c *Client) Write(register uint16, value types.Value) error {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	return c.WriteContext(context.Background(), register, value)
+}
+
+// WriteContext is Write checking ctx before every wire request, see
+// BatchReadContext.
+func (c *Client) WriteContext(ctx context.Context, register uint16, value types.Value) (err error) {
+	defer recoverPanic(&err)
+
+	if err := c.lock(); err != nil {
+		return err
+	}
+	defer c.unlock()
+	defer c.bind(ctx)()
 
-	op, err := newWriteOp(register, value.Bytes())
+	if err := validateValue(register, value); err != nil {
+		return err
+	}
+	var pieces []writeOp
+	if whole := (writeOp{register, uint16(len(value.Bytes()) / 2), value.Bytes()}); c.config.Limits.splitsWrite(whole) {
+		if pieces, err = splitWrite(whole, c.config.Limits.write()); err != nil {
+			return err
+		}
+	} else {
+		op, err := newWriteOp(register, value.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := c.config.Limits.checkWrite(op); err != nil {
+			return err
+		}
+		pieces = []writeOp{op}
+	}
+	applies, err := applyWrites(c.config.ApplyRules, pieces)
 	if err != nil {
 		return err
 	}
+	all := append(pieces[:len(pieces):len(pieces)], applies...)
+	if err := c.checkSpecPlan(c.config, nil, all); err != nil {
+		return err
+	}
+	if err := c.budgets.check(c.config.WriteBudgets, all, c.config.OverrideWriteBudgets); err != nil {
+		return err
+	}
 
-	return c.write(op)
+	for i, op := range pieces {
+		if err := c.pace(ctx, i); err != nil {
+			return err
+		}
+		if err := c.write(op, c.config.Retry); err != nil {
+			return err
+		}
+	}
+	written := time.Now()
+	if _, err := c.writeChunks(ctx, applies, c.config.Retry); err != nil {
+		return err
+	}
+	return c.verify(ctx, batchConfig{Config: c.config}, pieces, nil, written)
 }
 
-func (c *Client) batchRead(ops []readOp) (map[uint16][]byte, error) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+// batchRead performs read ops. If failed is not nil, failed requests
+// are recorded in it and the batch goes on. The results of the
+// completed requests are returned along with a *BatchTimeoutError.
+func (c *Client) batchRead(ctx context.Context, ops []readOp, opt optionalPlan, cfg batchConfig, failed map[readOp]error) (map[readOp][]byte, map[uint16]error, error) {
+	if err := c.lock(); err != nil {
+		return nil, nil, err
+	}
+	defer c.unlock()
 
-	results := make(map[uint16][]byte)
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(ctx, cfg.BatchTimeout)
+	if expiry, ok := ctx.Deadline(); ok && cfg.expiry != nil {
+		*cfg.expiry = expiry
+	}
+	if c.sched != nil {
+		c.sched.shuffle(ops)
+	}
+	var results map[readOp][]byte
+	var unavailable map[uint16]error
+	if len(opt.optional) > 0 {
+		results, unavailable, err = c.readChunksOptional(ctx, ops, opt, c.readChunk, cfg.Retry, failed)
+	} else {
+		results, err = c.readChunks(ctx, ops, cfg.Retry, failed)
+	}
+	// cached under the mutex, so that no write can invalidate the
+	// registers before their values are cached
+	if results != nil {
+		c.cacheReads(cfg.cacheable, results, cfg)
+	}
+	return results, unavailable, done(err)
+}
+
+// readChunks performs read ops one by one, stopping once ctx is done
+// with the results read so far. If failed is not nil, failed requests
+// are recorded in it instead of stopping. The caller must hold the
+// client mutex.
+func (c *Client) readChunks(ctx context.Context, ops []readOp, retry Retry, failed map[readOp]error) (map[readOp][]byte, error) {
+	results := make(map[readOp][]byte)
 	for i, v := range ops {
-		b, err := c.read(v)
+		if err := c.pace(ctx, i); err != nil {
+			return results, err
+		}
+		b, err := c.readChunk(v, retry)
 		if err != nil {
-			return nil, fmt.Errorf("read request %d at %d: %w", i+1, v.register, err)
+			err = c.readError(v, fmt.Errorf("read request %d of %v: %w", i+1, v, err))
+			if failed == nil {
+				return nil, err
+			}
+			failed[v] = err
+			continue
 		}
-		results[v.register] = b
+		results[v] = b
 	}
 
 	return results, nil
 }
 
-func (c *Client) batchWrite(ops []writeOp) error {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+// readChunk performs a single read op, using the chunk cache if
+// enabled.
+func (c *Client) readChunk(v readOp, retry Retry) ([]byte, error) {
+	chunk := chunkKey{defaultUnit, RegisterRange{v.register, v.quantity}}
+	if c.unit != nil {
+		chunk.unit = int(*c.unit)
+	}
+	cached := c.config.ChunkCacheTTL > 0 && v.space == HoldingRegisters
+	if cached {
+		if b, ok := c.chunks.get(chunk); ok {
+			return b, nil
+		}
+	}
+	b, err := c.read(v, retry)
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		c.chunks.put(chunk, b, c.config.ChunkCacheTTL)
+	}
+	return b, nil
+}
+
+// batchWrite performs ops followed by applies, and verifies ops if
+// enabled.
+func (c *Client) batchWrite(ctx context.Context, ops, applies []writeOp, cfg batchConfig) error {
+	if err := c.lockFor(cfg); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(ctx, cfg.BatchTimeout)
+	return done(c.writeVerified(ctx, ops, applies, ops, nil, cfg))
+}
 
+// writeVerified performs ops followed by applies, and verifies the
+// verified ops with plan if enabled. The caller must hold the client
+// mutex.
+func (c *Client) writeVerified(ctx context.Context, ops, applies, verified []writeOp, plan []readOp, cfg batchConfig) error {
+	all := append(ops[:len(ops):len(ops)], applies...)
+	if err := c.budgets.check(c.config.WriteBudgets, all, c.config.OverrideWriteBudgets); err != nil {
+		return err
+	}
+	if _, err := c.writeChunks(ctx, all, cfg.Retry); err != nil {
+		return err
+	}
+	if len(verified) == 0 {
+		return nil
+	}
+	return c.verify(ctx, cfg, verified, plan, time.Now())
+}
+
+// writeChunks performs write ops one by one, stopping once ctx is done,
+// and returns the number of ops completed. The caller must hold the
+// client mutex.
+func (c *Client) writeChunks(ctx context.Context, ops []writeOp, retry Retry) (int, error) {
 	for i, v := range ops {
-		if err := c.write(v); err != nil {
-			return fmt.Errorf("write request %d at %d: %w", i+1, v.register, err)
+		if err := c.pace(ctx, i); err != nil {
+			return i, err
+		}
+		if err := c.write(v, retry); err != nil {
+			return i, writeError(v, fmt.Errorf("write request %d of %v: %w", i+1, v, err))
 		}
 	}
 
+	return len(ops), nil
+}
+
+// cancelled returns the error of ctx if it is done, noting the number
+// of wire requests completed before.
+func cancelled(ctx context.Context, completed int) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cancelled after %d requests: %w", completed, err)
+	}
 	return nil
 }
 
-func (c *Client) read(r readOp) ([]byte, error) {
-	return c.ReadHoldingRegisters(r.register, r.quantity)
+// Latencies returns response time statistics of wire requests grouped
+// by register ranges. The number of tracked ranges is limited; requests
+// that don't fit are collected in a single bucket with Other set.
+func (c *Client) Latencies() []LatencyStats {
+	return c.latencies.stats()
 }
 
-func (c *Client) write(w writeOp) error {
-	_, err := c.WriteMultipleRegisters(w.register, w.quantity, w.value)
+// WriteBudgetStats returns the number of writes suppressed by each of
+// WriteBudgets.
+func (c *Client) WriteBudgetStats() []WriteBudgetStats {
+	return c.budgets.stats(c.config.WriteBudgets)
+}
+
+// ChunkCacheStats returns counters of the chunk response cache. See
+// WithChunkCache.
+func (c *Client) ChunkCacheStats() ChunkCacheStats {
+	return c.chunks.counters()
+}
+
+func (c *Client) read(r readOp, retry Retry) (b []byte, err error) {
+	if c.sched != nil {
+		c.sched.yield()
+		if c.sched.duplicate() {
+			// the first response is discarded; reads are idempotent
+			_, _ = c.readOnce(r, retry)
+		}
+	}
+	return c.readOnce(r, retry)
+}
+
+func (c *Client) readOnce(r readOp, retry Retry) (b []byte, err error) {
+	err = c.attempts(retry, func() error {
+		start := time.Now()
+		info := OpInfo{Function: modbus.FuncCodeReadHoldingRegisters, Space: r.space, RegisterRange: RegisterRange{r.register, r.quantity}}
+		region, custom := c.customRegion(r)
+		switch {
+		case custom:
+			info.Function = 0
+		case r.space == InputRegisters:
+			info.Function = modbus.FuncCodeReadInputRegisters
+		}
+		b, err = c.intercept(info, nil, func() ([]byte, error) {
+			if custom {
+				return c.fetch(region, r)
+			} else if r.space == InputRegisters {
+				return c.ReadInputRegisters(r.register, r.quantity)
+			}
+			return c.ReadHoldingRegisters(r.register, r.quantity)
+		})
+		c.observe(r.register, r.quantity, time.Since(start), err)
+		return err
+	})
+	return b, err
+}
+
+func (c *Client) write(w writeOp, retry Retry) error {
+	if c.sched != nil {
+		c.sched.yield()
+	}
+	c.chunks.invalidate(RegisterRange{w.register, w.quantity})
+	c.cache.invalidate(RegisterRange{w.register, w.quantity})
+	c.swr.invalidate(RegisterRange{w.register, w.quantity})
+	err := c.attempts(retry, func() error {
+		start := time.Now()
+		_, err := c.intercept(OpInfo{Function: modbus.FuncCodeWriteMultipleRegisters, RegisterRange: RegisterRange{w.register, w.quantity}}, w.value, func() ([]byte, error) {
+			return c.WriteMultipleRegisters(w.register, w.quantity, w.value)
+		})
+		c.observe(w.register, w.quantity, time.Since(start), err)
+		if c.sent != nil {
+			*c.sent = append(*c.sent, sentWrite{w, err == nil})
+		}
+		return err
+	})
+	if err == nil {
+		c.budgets.charge(c.config.WriteBudgets, RegisterRange{w.register, w.quantity})
+	}
 	return err
 }
+
+// observe accounts for a completed wire request.
+func (c *Client) observe(register, quantity uint16, d time.Duration, err error) {
+	c.latencies.observe(c.config.LatencyRanges, register, quantity, d)
+	if err == nil {
+		c.tuneTimeout(d)
+	}
+}
@@ -0,0 +1,594 @@
+package modbus_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+type testRead struct {
+	register uint16
+	t        types.Type
+}
+
+func (r testRead) Register() uint16 { return r.register }
+func (r testRead) Type() types.Type { return r.t }
+
+type testWrite struct {
+	register uint16
+	v        types.Value
+}
+
+func (w testWrite) Register() uint16   { return w.register }
+func (w testWrite) Value() types.Value { return w.v }
+
+func TestClient_BatchWrite_checkTypes(t *testing.T) {
+	known := modbus.Registers{
+		10: types.Float32CDAB(1.5),
+		12: types.Uint16(3),
+	}
+	tests := []struct {
+		name    string
+		ops     []modbus.Write
+		wantErr error
+	}{
+		{
+			"matching writes",
+			[]modbus.Write{
+				testWrite{10, types.Float32CDAB(2.5)},
+				testWrite{12, types.Uint16(4)},
+				testWrite{20, types.Uint16(5)},
+			},
+			nil,
+		},
+		{
+			"write in the middle of a known value",
+			[]modbus.Write{testWrite{11, types.Uint16(4)}},
+			modbus.ErrTypeMismatch,
+		},
+		{
+			"write overlapping the next known value",
+			[]modbus.Write{testWrite{12, types.Float32(4)}},
+			modbus.ErrTypeMismatch,
+		},
+		{
+			"write of a different type of the same size",
+			[]modbus.Write{testWrite{10, types.Float32(2.5)}},
+			modbus.ErrTypeMismatch,
+		},
+	}
+	for _, tt := range tests {
+		slave := newTestSlave()
+		client := modbus.NewClient(slave, modbus.WithTypeChecks())
+		err := client.BatchWrite(tt.ops, known)
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+		if tt.wantErr != nil {
+			assert.Zero(t, slave.calls(), tt.name)
+		}
+	}
+}
+
+func TestClient_BatchWrite_checkTypesNil(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithTypeChecks())
+	err := client.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, modbus.Registers{10: nil})
+	assert.ErrorIs(t, err, modbus.ErrInvalidValue)
+	assert.NotErrorIs(t, err, modbus.ErrPanic)
+	assert.Zero(t, slave.calls())
+}
+
+func TestClient_BatchWrite_checkTypesPassThrough(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithTypeChecks())
+	err := client.BatchWrite([]modbus.Write{
+		testWrite{10, types.Float32CDAB(2.5)},
+		testWrite{12, types.Uint16(4)},
+	}, modbus.Registers{10: types.Float32CDAB(1.5)})
+	assert.NoError(t, err)
+	assert.Equal(t, append(types.Float32CDAB(2.5).Bytes(), 0, 4), slave.get(10, 3))
+}
+
+func TestClient_BatchWrite_differential(t *testing.T) {
+	known := types.Float32CDAB(1.5).Bytes()
+	tests := []struct {
+		name    string
+		oldData modbus.Registers
+		op      modbus.Write
+		written bool
+	}{
+		{"middle of a value", modbus.Registers{10: types.Float32CDAB(1.5)}, testWrite{11, rawWrite(known[2:])}, false},
+		{"middle of a value changed", modbus.Registers{10: types.Float32CDAB(1.5)}, testWrite{11, types.Uint16(1)}, true},
+		{"start of a value", modbus.Registers{10: types.Float32CDAB(1.5)}, testWrite{10, rawWrite(known[:2])}, false},
+		{"other type", modbus.Registers{10: types.Float32CDAB(1.5)}, testWrite{10, rawWrite(known)}, false},
+		{"past a value", modbus.Registers{10: types.Float32CDAB(1.5)}, testWrite{11, rawWrite(append(known[2:4:4], 0, 0))}, true},
+		{"across values", modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, testWrite{10, types.Uint32(0x00010002)}, false},
+		{"disagreeing values", modbus.Registers{10: types.Uint32(1), 11: types.Uint16(2)}, testWrite{11, types.Uint16(2)}, true},
+		{"next to disagreeing values", modbus.Registers{10: types.Uint32(1), 11: types.Uint16(2)}, testWrite{10, types.Uint16(0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			client := modbus.NewClient(slave)
+
+			assert.NoError(t, client.BatchWrite([]modbus.Write{tt.op}, tt.oldData))
+			if tt.written {
+				assert.Equal(t, 1, slave.writes())
+			} else {
+				assert.Zero(t, slave.calls(), "the op is skipped")
+			}
+			plan, err := client.PlanWrite([]modbus.Write{tt.op}, tt.oldData)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.written, len(plan) > 0)
+		})
+	}
+}
+
+func TestClient_BatchRead_chunkCache(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 1, 0, 2)
+	client := modbus.NewClient(slave, modbus.WithChunkCache(time.Minute))
+	ops := []modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+	}
+
+	_, err := client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, slave.calls(), "first batch goes to the wire")
+
+	slave.set(10, 0, 5)
+	res, err := client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, slave.calls(), "second batch is served from cache")
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res)
+
+	_, err = client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, slave.calls(), "different chunk misses")
+
+	assert.NoError(t, client.Write(11, types.Uint16(7)))
+	res, err = client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, slave.calls(), "overlapping write invalidates the chunk")
+	assert.Equal(t, modbus.Registers{10: types.Uint16(5), 11: types.Uint16(7)}, res)
+
+	assert.Equal(t, modbus.ChunkCacheStats{Hits: 1, Misses: 3}, client.ChunkCacheStats())
+}
+
+func TestClient_BatchWrite_writeBudgets(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithWriteBudgets(
+		modbus.WriteBudget{RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10}, PerDay: 1},
+	))
+	ops := []modbus.Write{
+		testWrite{90, types.Uint16(1)},
+		testWrite{100, types.Uint16(2)},
+	}
+
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.Equal(t, 2, slave.calls())
+	assert.ErrorIs(t, client.BatchWrite(ops, nil), modbus.ErrWriteBudgetExceeded)
+	assert.Equal(t, 2, slave.calls(), "exceeded batch is not sent at all")
+	assert.EqualValues(t, 1, client.WriteBudgetStats()[0].Suppressed)
+
+	client = modbus.NewClient(slave, modbus.WithWriteBudgets(
+		modbus.WriteBudget{RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10}, PerDay: 1},
+	), modbus.WithWriteBudgetOverride())
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.Equal(t, 6, slave.calls(), "budgets are not enforced with an override")
+}
+
+func TestClient_BatchWrite_writeBudgetsFailure(t *testing.T) {
+	slave := newTestSlave()
+	slave.failures = 1
+	client := modbus.NewClient(slave, modbus.WithWriteBudgets(
+		modbus.WriteBudget{RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10}, PerDay: 1},
+	))
+	ops := []modbus.Write{testWrite{100, types.Uint16(1)}}
+
+	assert.ErrorIs(t, client.BatchWrite(ops, nil), errTransport)
+	assert.NoError(t, client.BatchWrite(ops, nil), "failed writes are not charged")
+	assert.ErrorIs(t, client.BatchWrite(ops, nil), modbus.ErrWriteBudgetExceeded)
+	assert.Equal(t, 2, slave.calls())
+}
+
+type labeledRead struct {
+	testRead
+	origin string
+}
+
+func (r labeledRead) Origin() string { return r.origin }
+
+func TestClient_BatchRead_origin(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity string
+		ops      []modbus.Read
+		wantErr  error
+	}{
+		{
+			"unlabeled ops pass",
+			"meter-a",
+			[]modbus.Read{testRead{10, types.Uint16Type}},
+			nil,
+		},
+		{
+			"matching origin passes",
+			"meter-a",
+			[]modbus.Read{labeledRead{testRead{10, types.Uint16Type}, "meter-a"}},
+			nil,
+		},
+		{
+			"mismatching origin fails",
+			"meter-b",
+			[]modbus.Read{
+				testRead{10, types.Uint16Type},
+				labeledRead{testRead{11, types.Uint16Type}, "meter-a"},
+			},
+			modbus.ErrWrongDevice,
+		},
+		{
+			"no identity configured",
+			"",
+			[]modbus.Read{labeledRead{testRead{10, types.Uint16Type}, "meter-a"}},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		slave := newTestSlave()
+		client := modbus.NewClient(slave, modbus.WithIdentity(tt.identity))
+		_, err := client.BatchRead(tt.ops)
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+		if tt.wantErr != nil {
+			assert.Contains(t, err.Error(), `"meter-a"`, tt.name)
+			assert.Contains(t, err.Error(), `"meter-b"`, tt.name)
+			assert.Zero(t, slave.calls(), tt.name)
+		}
+	}
+}
+
+func TestClient_BatchRead_uint64(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{100: types.Uint64(1), 104: types.Uint64(0x0102030405060708)})
+	client := modbus.NewClient(slave)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{104, types.Uint64Type},
+		testRead{100, types.Uint64Type},
+	}, []modbustest.WireExpectation{modbustest.Read(100, 8)})
+	assert.Equal(t, modbus.Registers{100: types.Uint64(1), 104: types.Uint64(0x0102030405060708)}, res)
+}
+
+func TestClient_BatchRead_uint16LE(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{
+		10: types.Uint16(0x1234),
+		11: types.Uint16(0x1234),
+		12: types.Uint16(0x1234),
+	})
+	client := modbus.NewClient(slave)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16LEType},
+		testRead{12, types.Uint16Type},
+	}, []modbustest.WireExpectation{modbustest.Read(10, 3)})
+	assert.Equal(t, modbus.Registers{
+		10: types.Uint16(0x1234),
+		11: types.Uint16LE(0x3412),
+		12: types.Uint16(0x1234),
+	}, res)
+}
+
+func TestClient_BatchRead_overlapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []modbus.Option
+		ops      []modbus.Read
+		expected []modbustest.WireExpectation
+		want     modbus.Registers
+	}{
+		{
+			"exact duplicates",
+			nil,
+			[]modbus.Read{testRead{100, types.Uint32Type}, testRead{100, types.Uint32Type}},
+			[]modbustest.WireExpectation{modbustest.Read(100, 2)},
+			modbus.Registers{100: types.Uint32(0x00010002)},
+		},
+		{
+			"contained",
+			nil,
+			[]modbus.Read{testRead{101, types.Uint16Type}, testRead{100, types.Uint32Type}},
+			[]modbustest.WireExpectation{modbustest.Read(100, 2)},
+			modbus.Registers{100: types.Uint32(0x00010002), 101: types.Uint16(2)},
+		},
+		{
+			"partial overlap",
+			nil,
+			[]modbus.Read{testRead{101, types.Uint32Type}, testRead{100, types.Uint32Type}},
+			[]modbustest.WireExpectation{modbustest.Read(100, 3)},
+			modbus.Registers{100: types.Uint32(0x00010002), 101: types.Uint32(0x00020003)},
+		},
+		{
+			"partial overlap over the limit",
+			[]modbus.Option{modbus.WithMaxReadQuantity(4)},
+			[]modbus.Read{testRead{100, types.Uint64Type}, testRead{102, types.Uint64Type}},
+			[]modbustest.WireExpectation{modbustest.Read(100, 4), modbustest.Read(102, 4)},
+			modbus.Registers{100: types.Uint64(0x0001000200030004), 102: types.Uint64(0x0003000400050006)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			slave.Seed(modbus.Registers{100: types.Uint64(0x0001000200030004), 104: types.Uint32(0x00050006)})
+			client := modbus.NewClient(slave, tt.opts...)
+
+			res := modbustest.ExpectPlan(t, client, tt.ops, tt.expected)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestClient_scaled(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{10: types.Int16(-123), 11: types.Uint16(500)})
+	client := modbus.NewClient(slave)
+	temperature := types.NewScaled(types.Int16Type, 0.1, 0)
+	level := types.NewScaled(types.Uint16Type, 0.5, -10)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{10, temperature},
+		testRead{11, level},
+	}, []modbustest.WireExpectation{modbustest.Read(10, 2)})
+	assert.InDelta(t, -12.3, res[10].(types.Scaled).Float64(), 1e-9)
+	assert.InDelta(t, 240, res[11].(types.Scaled).Float64(), 1e-9)
+
+	modbustest.ExpectWrites(t, client, []modbus.Write{
+		testWrite{10, temperature.With(21.55)},
+	}, nil, []modbustest.WireExpectation{modbustest.Write(10, 0, 216)})
+
+	err := client.BatchWrite([]modbus.Write{testWrite{11, level.With(-11)}}, nil)
+	assert.ErrorIs(t, err, types.ErrScaledRange)
+	assert.ErrorIs(t, client.Write(11, level.With(1e6)), types.ErrScaledRange)
+	assert.Len(t, slave.Requests(), 2, "out of range values are not written")
+}
+
+func TestClient_raw(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{10: types.Uint32(0x01020304), 12: types.Uint16(0x0506)})
+	client := modbus.NewClient(slave)
+	block := types.NewRaw(3)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{10, block},
+		testRead{13, types.Uint16Type},
+	}, []modbustest.WireExpectation{modbustest.Read(10, 4)})
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, res[10].Bytes())
+
+	modbustest.ExpectWrites(t, client, []modbus.Write{
+		testWrite{20, res[10]},
+	}, nil, []modbustest.WireExpectation{modbustest.Write(20, 1, 2, 3, 4, 5, 6)})
+
+	err := client.BatchWrite([]modbus.Write{testWrite{20, block.With([]byte{1, 2})}}, nil)
+	assert.ErrorIs(t, err, types.ErrRawSize)
+	assert.Len(t, slave.Requests(), 2, "mismatched raw values are not written")
+}
+
+func TestClient_BatchRead_timestamp(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{
+		10: types.Timestamp{Time: at},
+		12: types.Uint16(230),
+		13: types.TimestampCDAB{Time: at.Add(time.Hour)},
+	})
+	client := modbus.NewClient(slave)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{13, types.TimestampCDABType},
+		testRead{12, types.Uint16Type},
+		testRead{10, types.TimestampType},
+	}, []modbustest.WireExpectation{modbustest.Read(10, 5)})
+	assert.Equal(t, modbus.Registers{
+		10: types.Timestamp{Time: at},
+		12: types.Uint16(230),
+		13: types.TimestampCDAB{Time: at.Add(time.Hour)},
+	}, res)
+}
+
+func TestClient_BatchRead_uint8Pair(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{
+		10: types.Uint16(0x1234),
+		11: types.NewUint8Pair(14, 7),
+		12: types.Uint16(0x5678),
+	})
+	client := modbus.NewClient(slave)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint8PairType},
+		testRead{12, types.Uint16Type},
+	}, []modbustest.WireExpectation{modbustest.Read(10, 3)})
+	assert.Equal(t, types.Uint16(0x1234), res[10])
+	assert.Equal(t, byte(14), res[11].(types.Uint8Pair).High())
+	assert.Equal(t, byte(7), res[11].(types.Uint8Pair).Low())
+	assert.Equal(t, types.Uint16(0x5678), res[12])
+}
+
+func TestClient_bounded(t *testing.T) {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+	setpoint := types.NewBounded(types.Uint16Type, 0, 500)
+
+	err := client.BatchWrite([]modbus.Write{
+		testWrite{10, setpoint.With(types.Uint16(100))},
+		testWrite{11, setpoint.With(types.Uint16(501))},
+	}, nil)
+	assert.ErrorIs(t, err, types.ErrOutOfBounds)
+	assert.Contains(t, err.Error(), "register 11")
+	assert.Contains(t, err.Error(), "501")
+	assert.ErrorIs(t, client.Write(10, setpoint.With(types.Uint16(1000))), types.ErrOutOfBounds)
+	assert.Empty(t, slave.Requests(), "nothing is sent")
+
+	modbustest.ExpectWrites(t, client, []modbus.Write{
+		testWrite{10, setpoint.With(types.Uint16(500))},
+	}, nil, []modbustest.WireExpectation{modbustest.Write(10, 0x01, 0xF4)})
+}
+
+func TestClient_errorMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*testSlave)
+		call  func(*modbus.Client) error
+		want  []string
+	}{
+		{
+			name:  "read",
+			setup: func(s *testSlave) { s.failures = 1 },
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint32Type}})
+				return err
+			},
+			want: []string{"read request 1 of 3 registers at 10-12 (0x000A-0x000C)"},
+		},
+		{
+			name:  "conversion",
+			setup: func(s *testSlave) { s.set(300, 0, 2) },
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{299, types.Uint16Type}, testRead{300, types.BoolStrict(false)}})
+				return err
+			},
+			want: []string{"register 300 (0x012C)", "in 2 registers at 299-300 (0x012B-0x012C)"},
+		},
+		{
+			name:  "write",
+			setup: func(s *testSlave) { s.readOnly[20] = true },
+			call: func(c *modbus.Client) error {
+				return c.BatchWrite([]modbus.Write{testWrite{20, types.Uint16(0xBEEF)}}, nil)
+			},
+			want: []string{"write request 1 of register 20 (0x0014), value 0xBEEF"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			tt.setup(slave)
+			err := tt.call(modbus.NewClient(slave))
+			if assert.Error(t, err) {
+				for _, want := range tt.want {
+					assert.Contains(t, err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+// blockingSlave is a testSlave answering requests only once ctx is done.
+type blockingSlave struct {
+	*testSlave
+	ctx context.Context
+}
+
+func (s blockingSlave) Send(adu []byte) ([]byte, error) {
+	<-s.ctx.Done()
+	return s.testSlave.Send(adu)
+}
+
+func TestClient_context(t *testing.T) {
+	tests := []struct {
+		name  string
+		call  func(context.Context, *modbus.Client) error
+		calls int
+	}{
+		{
+			name: "BatchReadContext",
+			call: func(ctx context.Context, c *modbus.Client) error {
+				_, err := c.BatchReadContext(ctx, []modbus.Read{testRead{10, types.Uint16Type}, testRead{1000, types.Uint16Type}})
+				return err
+			},
+			calls: 1,
+		},
+		{
+			name: "BatchWriteContext",
+			call: func(ctx context.Context, c *modbus.Client) error {
+				return c.BatchWriteContext(ctx, []modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{1000, types.Uint16(2)}}, nil)
+			},
+			calls: 1,
+		},
+		{
+			name: "ReadContext",
+			call: func(ctx context.Context, c *modbus.Client) error {
+				_, err := c.ReadContext(ctx, 10, types.Uint16Type)
+				return err
+			},
+		},
+		{
+			name: "WriteContext",
+			call: func(ctx context.Context, c *modbus.Client) error {
+				return c.WriteContext(ctx, 10, types.Uint16(1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			slave := newTestSlave()
+			client := modbus.NewClient(blockingSlave{slave, ctx})
+			if tt.calls == 0 {
+				cancel()
+			} else {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+
+			err := tt.call(ctx, client)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Contains(t, err.Error(), fmt.Sprintf("cancelled after %d requests", tt.calls))
+			assert.Equal(t, tt.calls, slave.calls())
+		})
+	}
+}
+
+func TestClient_concurrentBatches(t *testing.T) {
+	const rounds = 100
+	modbus.SweepChaos(t, modbus.ChaosOptions{Duplicate: true}, func(t *testing.T, chaos func(*modbus.Client)) {
+		client := modbus.NewClient(newTestSlave())
+		chaos(client)
+
+		// the writes of a batch land in separate requests, and a batch
+		// read must never observe only some of them
+		ops := []modbus.Read{testRead{10, types.Uint16Type}, testRead{50, types.Uint16Type}, testRead{90, types.Uint16Type}}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= rounds; i++ {
+				v := types.Uint16(i)
+				err := client.BatchWrite([]modbus.Write{testWrite{10, v}, testWrite{50, v}, testWrite{90, v}}, nil)
+				if !assert.NoError(t, err) {
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= rounds; i++ {
+				res, err := client.BatchRead(ops)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, res[10], res[50])
+				assert.Equal(t, res[10], res[90])
+			}
+		}()
+		wg.Wait()
+	})
+}
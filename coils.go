@@ -0,0 +1,267 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/tdemin/opmodbus/internal/regrange"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ErrConflictingCoils is returned when a batch sets the same coil to
+// different values.
+var ErrConflictingCoils = errors.New("conflicting coil writes")
+
+const (
+	// limits to how many coils and discrete inputs you can read / write
+	// with Modbus at once
+	maxFunc1Quantity  = 2000
+	maxFunc2Quantity  = 2000
+	maxFunc15Quantity = 1968
+)
+
+// CoilRead represents Modbus function 1 call for a single coil.
+type CoilRead interface {
+	Register() uint16
+}
+
+// CoilWrite represents Modbus function 15 call for a single coil.
+type CoilWrite interface {
+	Register() uint16
+	Value() bool
+}
+
+// DiscreteRead represents Modbus function 2 call for a single discrete
+// input.
+type DiscreteRead interface {
+	Register() uint16
+}
+
+// BatchReadCoils reads coils with function 1 and returns their values
+// keyed by coil address. Adjacent coils are merged into requests of up
+// to 2000 coils, and a coil is read once no matter how many ops address
+// it.
+func (c *Client) BatchReadCoils(ops []CoilRead) (_ map[uint16]bool, err error) {
+	defer recoverPanic(&err)
+
+	coils := make([]uint16, len(ops))
+	for i, op := range ops {
+		coils[i] = op.Register()
+	}
+	return c.batchReadBits(coils, maxFunc1Quantity, modbus.FuncCodeReadCoils, "coil", c.ReadCoils)
+}
+
+// BatchReadDiscrete reads discrete inputs with function 2 and returns
+// their values keyed by input address. Inputs are merged the same way
+// as coils in BatchReadCoils.
+func (c *Client) BatchReadDiscrete(ops []DiscreteRead) (_ map[uint16]bool, err error) {
+	defer recoverPanic(&err)
+
+	inputs := make([]uint16, len(ops))
+	for i, op := range ops {
+		inputs[i] = op.Register()
+	}
+	return c.batchReadBits(inputs, maxFunc2Quantity, modbus.FuncCodeReadDiscreteInputs, "discrete input", c.ReadDiscreteInputs)
+}
+
+// batchReadBits reads single-bit addresses with read, merging them into
+// requests of function of up to max bits. Errors name the bits with
+// noun.
+func (c *Client) batchReadBits(addresses []uint16, max uint16, function byte, noun string, read func(address, quantity uint16) ([]byte, error)) (map[uint16]bool, error) {
+	cfg, err := c.resolve(BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Limits.checkOps(len(addresses)); err != nil {
+		return nil, err
+	}
+	cfg.batch = bitOps(addresses)
+	ranges := mergeBits(addresses, max)
+	if err := cfg.Limits.checkRequests(len(ranges)); err != nil {
+		return nil, err
+	}
+
+	if err := c.lockFor(cfg); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(context.Background(), cfg.BatchTimeout)
+	res := make(map[uint16]bool, len(addresses))
+	for i, r := range ranges {
+		if err := c.pace(ctx, i); err != nil {
+			return nil, done(err)
+		}
+		var b []byte
+		err := c.attempts(cfg.Retry, func() (err error) {
+			start := time.Now()
+			info := OpInfo{Function: function, RegisterRange: RegisterRange{r.Start, r.Quantity}}
+			b, err = c.intercept(info, nil, func() ([]byte, error) {
+				return read(r.Start, r.Quantity)
+			})
+			if err == nil {
+				c.tuneTimeout(time.Since(start))
+			}
+			return err
+		})
+		if err != nil {
+			return nil, done(fmt.Errorf("%s read request %d of %v: %w", noun, i+1, describeBits(r, noun), err))
+		}
+		values, err := unpackBits(b, r.Quantity)
+		if err != nil {
+			return nil, done(fmt.Errorf("%s read request %d of %v: %w", noun, i+1, describeBits(r, noun), err))
+		}
+		for offset, v := range values {
+			res[r.Start+uint16(offset)] = v
+		}
+	}
+
+	return res, done(nil)
+}
+
+// BatchWriteCoils writes coils with function 15. Adjacent coils are
+// merged into requests of up to 1968 coils. Setting the same coil to
+// different values in one batch fails with ErrConflictingCoils. The
+// batch stops on the first error.
+func (c *Client) BatchWriteCoils(ops []CoilWrite) (err error) {
+	defer recoverPanic(&err)
+
+	cfg, err := c.resolve(BatchOptions{})
+	if err != nil {
+		return err
+	}
+	if err := cfg.Limits.checkOps(len(ops)); err != nil {
+		return err
+	}
+	values := make(map[uint16]bool, len(ops))
+	coils := make([]uint16, 0, len(ops))
+	for _, op := range ops {
+		v, ok := values[op.Register()]
+		if ok && v != op.Value() {
+			return fmt.Errorf("%w: coil %d", ErrConflictingCoils, op.Register())
+		}
+		values[op.Register()] = op.Value()
+		coils = append(coils, op.Register())
+	}
+	cfg.batch = bitOps(coils)
+	ranges := mergeBits(coils, maxFunc15Quantity)
+	if err := cfg.Limits.checkRequests(len(ranges)); err != nil {
+		return err
+	}
+
+	if err := c.lockFor(cfg); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(context.Background(), cfg.BatchTimeout)
+	for i, r := range ranges {
+		if err := c.pace(ctx, i); err != nil {
+			return done(err)
+		}
+		states := make([]bool, r.Quantity)
+		for offset := range states {
+			states[offset] = values[r.Start+uint16(offset)]
+		}
+		b := packBits(states)
+		err := c.attempts(cfg.Retry, func() error {
+			start := time.Now()
+			info := OpInfo{Function: modbus.FuncCodeWriteMultipleCoils, RegisterRange: RegisterRange{r.Start, r.Quantity}}
+			_, err := c.intercept(info, b, func() ([]byte, error) {
+				return c.WriteMultipleCoils(r.Start, r.Quantity, b)
+			})
+			if err == nil {
+				c.tuneTimeout(time.Since(start))
+			}
+			return err
+		})
+		if err != nil {
+			return done(fmt.Errorf("coil write request %d of %v: %w", i+1, describeBits(r, "coil"), err))
+		}
+	}
+
+	return done(nil)
+}
+
+// mergeBits sorts and deduplicates coil or discrete input addresses,
+// and merges runs of adjacent ones into ranges of at most max.
+func mergeBits(coils []uint16, max uint16) []regrange.Range {
+	sorted := append([]uint16(nil), coils...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var ranges []regrange.Range
+	for i, coil := range sorted {
+		if i > 0 && coil == sorted[i-1] {
+			continue
+		}
+		if n := len(ranges); n > 0 {
+			if merged, ok := mergeAdjacent(ranges[n-1], regrange.Range{Start: coil, Quantity: 1}, max); ok {
+				ranges[n-1] = merged
+				continue
+			}
+		}
+		ranges = append(ranges, regrange.Range{Start: coil, Quantity: 1})
+	}
+	return ranges
+}
+
+// bitOps converts coil or discrete input addresses to the ops tracked
+// for interceptors, one bit each.
+func bitOps(addresses []uint16) []readOp {
+	res := make([]readOp, len(addresses))
+	for i, a := range addresses {
+		res[i] = readOp{a, 1, HoldingRegisters}
+	}
+	return res
+}
+
+// packBits encodes coil states in the function 15 layout: the first
+// coil is the least significant bit of the first byte, unused high
+// bits of the last byte are zero.
+func packBits(states []bool) []byte {
+	b := make([]byte, (len(states)+7)/8)
+	for i, on := range states {
+		if on {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+// unpackBits decodes quantity states from a function 1 or 2 response,
+// which share the layout of packBits.
+func unpackBits(b []byte, quantity uint16) ([]bool, error) {
+	if want := (int(quantity) + 7) / 8; len(b) != want {
+		return nil, fmt.Errorf("%w: %d bytes for %d bits", types.ErrInvalidInput, len(b), quantity)
+	}
+	states := make([]bool, quantity)
+	for i := range states {
+		states[i] = b[i/8]&(1<<(i%8)) != 0
+	}
+	return states, nil
+}
+
+// describeBits formats a range of bits named noun, e.g. "3 coils at
+// 10-12".
+func describeBits(r regrange.Range, noun string) string {
+	if r.Quantity == 1 {
+		return fmt.Sprintf("%s %d", noun, r.Start)
+	}
+	return fmt.Sprintf("%d %ss at %v", r.Quantity, noun, r)
+}
@@ -0,0 +1,261 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+)
+
+type testCoil struct {
+	coil uint16
+	on   bool
+}
+
+func (c testCoil) Register() uint16 { return c.coil }
+func (c testCoil) Value() bool      { return c.on }
+
+// coilRun returns writes of n coils starting at coil, with coils whose
+// address is a multiple of every set.
+func coilRun(coil uint16, n, every int) []modbus.CoilWrite {
+	ops := make([]modbus.CoilWrite, n)
+	for i := range ops {
+		c := coil + uint16(i)
+		ops[i] = testCoil{c, int(c)%every == 0}
+	}
+	return ops
+}
+
+func TestClient_BatchWriteCoils(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []modbus.CoilWrite
+		want []modbustest.WireExpectation
+	}{
+		{"single coil", []modbus.CoilWrite{testCoil{7, true}}, []modbustest.WireExpectation{
+			modbustest.WriteCoils(7, 1, 0x01),
+		}},
+		{"single coil off", []modbus.CoilWrite{testCoil{7, false}}, []modbustest.WireExpectation{
+			modbustest.WriteCoils(7, 1, 0x00),
+		}},
+		{"unaligned full byte", coilRun(3, 8, 1), []modbustest.WireExpectation{
+			modbustest.WriteCoils(3, 8, 0xFF),
+		}},
+		{"one coil past a byte", coilRun(0, 9, 1), []modbustest.WireExpectation{
+			modbustest.WriteCoils(0, 9, 0xFF, 0x01),
+		}},
+		{"unaligned across a byte boundary", coilRun(5, 13, 2), []modbustest.WireExpectation{
+			// coils 6, 8, ..., 16 are on
+			modbustest.WriteCoils(5, 13, 0xAA, 0x0A),
+		}},
+		{"unaligned across two byte boundaries", coilRun(1005, 20, 3), []modbustest.WireExpectation{
+			modbustest.WriteCoils(1005, 20, 0x49, 0x92, 0x04),
+		}},
+		{"unsorted with a gap", []modbus.CoilWrite{
+			testCoil{12, true}, testCoil{10, true}, testCoil{14, true}, testCoil{11, false}, testCoil{10, true},
+		}, []modbustest.WireExpectation{
+			modbustest.WriteCoils(10, 3, 0x05),
+			modbustest.WriteCoils(14, 1, 0x01),
+		}},
+		{"merged at the limit", coilRun(0, 1968, 1), []modbustest.WireExpectation{
+			modbustest.WriteCoils(0, 1968),
+		}},
+		{"merged above the limit", coilRun(0, 1969, 1), []modbustest.WireExpectation{
+			modbustest.WriteCoils(0, 1968), modbustest.WriteCoils(1968, 1, 0x01),
+		}},
+		{"last coil", []modbus.CoilWrite{testCoil{65535, true}}, []modbustest.WireExpectation{
+			modbustest.WriteCoils(65535, 1, 0x01),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			client := modbus.NewClient(slave)
+
+			assert.NoError(t, client.BatchWriteCoils(tt.ops))
+			assertCoilRequests(t, slave.Requests(), tt.want)
+			for _, op := range tt.ops {
+				assert.Equal(t, op.Value(), slave.Coils(op.Register(), 1)[0], "coil %d", op.Register())
+			}
+		})
+	}
+}
+
+func TestClient_BatchWriteCoils_conflict(t *testing.T) {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+
+	err := client.BatchWriteCoils([]modbus.CoilWrite{testCoil{3, true}, testCoil{3, false}})
+	assert.ErrorIs(t, err, modbus.ErrConflictingCoils)
+	assert.Empty(t, slave.Requests(), "nothing is sent")
+}
+
+func TestClient_BatchReadCoils(t *testing.T) {
+	tests := []struct {
+		name  string
+		coils []uint16
+		want  []modbustest.WireExpectation
+	}{
+		{"single coil", []uint16{9}, []modbustest.WireExpectation{modbustest.ReadCoils(9, 1)}},
+		{"unaligned across a byte boundary", []uint16{13, 5, 6, 7, 8, 9, 10, 11, 12, 14, 15, 16, 17}, []modbustest.WireExpectation{
+			modbustest.ReadCoils(5, 13),
+		}},
+		{"duplicates and a gap", []uint16{20, 21, 21, 23, 20}, []modbustest.WireExpectation{
+			modbustest.ReadCoils(20, 2), modbustest.ReadCoils(23, 1),
+		}},
+		{"merged at the limit", runOf(3, 2000), []modbustest.WireExpectation{modbustest.ReadCoils(3, 2000)}},
+		{"merged above the limit", runOf(3, 2001), []modbustest.WireExpectation{
+			modbustest.ReadCoils(3, 2000), modbustest.ReadCoils(2003, 1),
+		}},
+		{"last coil", []uint16{65534, 65535}, []modbustest.WireExpectation{modbustest.ReadCoils(65534, 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			seed := make(map[uint16]bool)
+			for _, coil := range tt.coils {
+				seed[coil] = coil%3 != 1
+			}
+			slave.SeedCoils(seed)
+			client := modbus.NewClient(slave)
+
+			ops := make([]modbus.CoilRead, len(tt.coils))
+			for i, coil := range tt.coils {
+				ops[i] = testCoil{coil: coil}
+			}
+			res, err := client.BatchReadCoils(ops)
+			assert.NoError(t, err)
+			assert.Equal(t, seed, res)
+			assertCoilRequests(t, slave.Requests(), tt.want)
+		})
+	}
+}
+
+func TestClient_coilsRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 50; round++ {
+		slave := modbustest.NewSlave()
+		client := modbus.NewClient(slave)
+
+		want := make(map[uint16]bool)
+		var writes []modbus.CoilWrite
+		var reads []modbus.CoilRead
+		start := uint16(rng.Intn(64))
+		for i := 0; i < 1+rng.Intn(100); i++ {
+			coil := start + uint16(rng.Intn(80))
+			on, ok := want[coil]
+			if !ok {
+				on = rng.Intn(2) == 1
+				want[coil] = on
+			}
+			writes = append(writes, testCoil{coil, on})
+			reads = append(reads, testCoil{coil: coil})
+		}
+
+		assert.NoError(t, client.BatchWriteCoils(writes))
+		for coil, on := range want {
+			assert.Equal(t, on, slave.Coils(coil, 1)[0], "round %d coil %d", round, coil)
+		}
+		res, err := client.BatchReadCoils(reads)
+		assert.NoError(t, err)
+		assert.Equal(t, want, res, "round %d", round)
+	}
+}
+
+// assertCoilRequests checks function, address and quantity of coil
+// requests, and the packed coils of writes expecting a payload.
+func assertCoilRequests(t *testing.T, requests []goburrow.ProtocolDataUnit, want []modbustest.WireExpectation) {
+	t.Helper()
+	if !assert.Len(t, requests, len(want)) {
+		return
+	}
+	for i, pdu := range requests {
+		assert.EqualValues(t, want[i].Function, pdu.FunctionCode, "request %d", i+1)
+		assert.Equal(t, want[i].Register, binary.BigEndian.Uint16(pdu.Data[0:2]), "request %d address", i+1)
+		assert.Equal(t, want[i].Quantity, binary.BigEndian.Uint16(pdu.Data[2:4]), "request %d quantity", i+1)
+		if want[i].Payload != nil {
+			assert.Equal(t, byte(len(want[i].Payload)), pdu.Data[4], "request %d byte count", i+1)
+			assert.Equal(t, want[i].Payload, pdu.Data[5:], "request %d payload", i+1)
+		}
+	}
+}
+
+// runOf returns n consecutive coil addresses starting at coil.
+func runOf(coil uint16, n int) []uint16 {
+	coils := make([]uint16, n)
+	for i := range coils {
+		coils[i] = coil + uint16(i)
+	}
+	return coils
+}
+
+func TestClient_BatchReadDiscrete(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.SeedDiscrete(map[uint16]bool{101: true, 103: true})
+	slave.SeedCoils(map[uint16]bool{102: true})
+	client := modbus.NewClient(slave)
+
+	res, err := client.BatchReadDiscrete([]modbus.DiscreteRead{
+		testCoil{coil: 103}, testCoil{coil: 101}, testCoil{coil: 102}, testCoil{coil: 3000},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint16]bool{101: true, 102: false, 103: true, 3000: false}, res)
+	assertCoilRequests(t, slave.Requests(), []modbustest.WireExpectation{
+		// three adjacent inputs collapse into one request
+		modbustest.ReadDiscrete(101, 3),
+		modbustest.ReadDiscrete(3000, 1),
+	})
+}
+
+func TestClient_coilBatches(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	// three requests, the deadline passes during the second one
+	addresses := []uint16{10, 20, 30}
+	reads := make([]modbus.CoilRead, len(addresses))
+	writes := make([]modbus.CoilWrite, len(addresses))
+	for i, a := range addresses {
+		reads[i], writes[i] = testCoil{coil: a}, testCoil{a, true}
+	}
+	tests := []struct {
+		name     string
+		function byte
+		run      func(*modbus.Client) error
+	}{
+		{"read", goburrow.FuncCodeReadCoils, func(c *modbus.Client) error {
+			_, err := c.BatchReadCoils(reads)
+			return err
+		}},
+		{"write", goburrow.FuncCodeWriteMultipleCoils, func(c *modbus.Client) error {
+			return c.BatchWriteCoils(writes)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log []string
+			r := &recorder{log: &log}
+			link := &slowLink{Slave: modbustest.NewSlave(), delay: delay}
+			client := modbus.NewClient(link, modbus.WithInterceptors(r), modbus.WithBatchTimeout(80*time.Millisecond))
+
+			err := tt.run(client)
+			var terr *modbus.BatchTimeoutError
+			if assert.True(t, errors.As(err, &terr), "%v", err) {
+				assert.Equal(t, 2, terr.Completed)
+			}
+			assert.Len(t, link.Requests(), 2)
+			if assert.Len(t, r.ops, 2) {
+				assert.Equal(t, modbus.OpInfo{
+					Function:      tt.function,
+					RegisterRange: modbus.RegisterRange{Register: 20, Quantity: 1},
+					Batch:         true,
+					Ops:           []modbus.RegisterRange{{Register: 20, Quantity: 1}},
+				}, r.ops[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/goburrow/modbus"
+)
+
+// ErrCompressedRegion is wrapped by a *CompressedRegionError.
+var ErrCompressedRegion = errors.New("inconsistent compressed region data")
+
+// Decompressor restores data compressed by a side channel, e.g. with
+// gzip or zstd.
+type Decompressor interface {
+	Decompress(b []byte) ([]byte, error)
+}
+
+// DecompressorFunc adapts a function to Decompressor.
+type DecompressorFunc func(b []byte) ([]byte, error)
+
+func (f DecompressorFunc) Decompress(b []byte) ([]byte, error) {
+	return f(b)
+}
+
+// CompressedBlock is a register window as returned by a compressed side
+// channel.
+type CompressedBlock struct {
+	Data []byte
+	// Length is the declared size of the decompressed data in bytes.
+	Length int
+	// Checksum is the declared CRC-32 (IEEE) of the decompressed data.
+	Checksum uint32
+}
+
+// CompressedRegionError reports a block of a compressed region that
+// doesn't match its declaration or the requested register window.
+type CompressedRegionError struct {
+	Window RegisterRange
+	Reason string
+}
+
+func (e *CompressedRegionError) Error() string {
+	return fmt.Sprintf("%v: %d registers at %d: %s", ErrCompressedRegion, e.Window.Quantity, e.Window.Register, e.Reason)
+}
+
+func (e *CompressedRegionError) Unwrap() error {
+	return ErrCompressedRegion
+}
+
+// CompressedRegion returns a custom region reading r with fetch, which
+// gets a compressed block of the requested registers from a side
+// channel. Blocks are decompressed with d and verified before they are
+// decoded: the declared length must cover the requested registers
+// exactly, and the decompressed data must match the declared length
+// and checksum. Inconsistent blocks fail with a *CompressedRegionError,
+// which is retried like transport errors.
+func CompressedRegion(r RegisterRange, d Decompressor, fetch func(send func(pdu modbus.ProtocolDataUnit) ([]byte, error), register, quantity uint16) (CompressedBlock, error)) CustomRegion {
+	return CustomRegion{
+		RegisterRange: r,
+		Fetch: func(send func(pdu modbus.ProtocolDataUnit) ([]byte, error), register, quantity uint16) ([]byte, error) {
+			block, err := fetch(send, register, quantity)
+			if err != nil {
+				return nil, err
+			}
+			window := RegisterRange{register, quantity}
+			if want := int(quantity) * 2; block.Length != want {
+				return nil, &CompressedRegionError{window, fmt.Sprintf("declared %d bytes instead of %d", block.Length, want)}
+			}
+			b, err := d.Decompress(block.Data)
+			if err != nil {
+				return nil, &CompressedRegionError{window, fmt.Sprintf("decompressing: %v", err)}
+			}
+			if len(b) != block.Length {
+				return nil, &CompressedRegionError{window, fmt.Sprintf("decompressed %d bytes instead of %d", len(b), block.Length)}
+			}
+			if sum := crc32.ChecksumIEEE(b); sum != block.Checksum {
+				return nil, &CompressedRegionError{window, fmt.Sprintf("checksum 0x%08X instead of 0x%08X", sum, block.Checksum)}
+			}
+			return b, nil
+		},
+	}
+}
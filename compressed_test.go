@@ -0,0 +1,91 @@
+package modbus_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+var gunzip = modbus.DecompressorFunc(func(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+})
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write(b)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func TestCompressedRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(b *modbus.CompressedBlock)
+		wantErr bool
+	}{
+		{"valid", func(*modbus.CompressedBlock) {}, false},
+		{"checksum mismatch", func(b *modbus.CompressedBlock) { b.Checksum++ }, true},
+		{"declared length off the window", func(b *modbus.CompressedBlock) { b.Length -= 2 }, true},
+		{"truncated data", func(b *modbus.CompressedBlock) { b.Data = b.Data[:len(b.Data)/2] }, true},
+		{"data shorter than declared", func(b *modbus.CompressedBlock) {
+			short := make([]byte, b.Length-2)
+			*b = modbus.CompressedBlock{Data: gzipped(short), Length: b.Length, Checksum: crc32.ChecksumIEEE(short)}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			for r := uint16(0); r < 300; r++ {
+				slave.set(r, 0, byte(r))
+			}
+			fetches := 0
+			client := modbus.NewClient(slave, modbus.WithRetry(modbus.Retry{Retries: 1}), modbus.WithCustomRegions(modbus.CompressedRegion(
+				modbus.RegisterRange{Register: 200, Quantity: 50},
+				gunzip,
+				func(_ func(goburrow.ProtocolDataUnit) ([]byte, error), register, quantity uint16) (modbus.CompressedBlock, error) {
+					fetches++
+					b := slave.get(register, quantity)
+					block := modbus.CompressedBlock{Data: gzipped(b), Length: len(b), Checksum: crc32.ChecksumIEEE(b)}
+					tt.corrupt(&block)
+					return block, nil
+				},
+			)))
+
+			res, err := client.BatchRead([]modbus.Read{
+				testRead{10, types.Uint16Type},
+				testRead{200, types.Uint16Type},
+				testRead{201, types.Uint32Type},
+			})
+			assert.Equal(t, 1, slave.calls(), "ops outside of the region are read as usual")
+			if tt.wantErr {
+				var cerr *modbus.CompressedRegionError
+				if assert.True(t, errors.As(err, &cerr), "%v", err) {
+					assert.Equal(t, modbus.RegisterRange{Register: 200, Quantity: 3}, cerr.Window)
+				}
+				assert.ErrorIs(t, err, modbus.ErrCompressedRegion)
+				assert.Equal(t, 2, fetches, "inconsistent blocks are retried")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, modbus.Registers{
+				10:  types.Uint16(10),
+				200: types.Uint16(200),
+				201: types.Uint32(201<<16 | 202),
+			}, res)
+			assert.Equal(t, 1, fetches)
+		})
+	}
+}
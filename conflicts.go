@@ -0,0 +1,104 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tdemin/opmodbus/internal/regrange"
+)
+
+// ErrConflictingWrites is returned by write batches holding ops whose
+// registers overlap, unless the client resolves them, see
+// WithWriteConflicts.
+var ErrConflictingWrites = errors.New("conflicting register writes")
+
+// ConflictPolicy decides on write ops of a batch whose registers
+// overlap, see WithWriteConflicts.
+type ConflictPolicy int
+
+const (
+	// RejectConflicts fails the batch with ErrConflictingWrites before
+	// sending anything, naming the first pair of overlapping ops. This is
+	// the default.
+	RejectConflicts ConflictPolicy = iota
+	// LastWins drops every op overlapping an op that comes later in the
+	// batch, so that only the later value is written. An op is dropped
+	// whole, even if the later op covers only a part of its registers.
+	LastWins
+)
+
+// WithWriteConflicts sets how write batches treat ops of overlapping
+// registers, including exact duplicates. Overlaps are those of the
+// registers the values cover, not of start registers only, so a 32-bit
+// value at register 100 conflicts with a write to register 101.
+//
+// The policy applies to BatchWrite, BatchReadWrite and Swap.
+func WithWriteConflicts(p ConflictPolicy) Option {
+	return func(c *Config) {
+		c.WriteConflicts = p
+	}
+}
+
+// resolveConflicts checks ops for overlapping registers as set by
+// policy. It returns ops itself if there are none. Ops of no registers
+// are left to checkEmpty.
+func resolveConflicts(ops []Write, policy ConflictPolicy) ([]Write, error) {
+	ranges := make([]regrange.Range, len(ops))
+	order := make([]int, 0, len(ops))
+	for i, op := range ops {
+		if v := op.Value(); v != nil {
+			ranges[i] = regrange.Range{Start: op.Register(), Quantity: uint16(len(v.Bytes()) / 2)}
+		}
+		if !ranges[i].Empty() {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ranges[order[a]].Start < ranges[order[b]].Start
+	})
+
+	var dropped map[int]bool
+	// clusters are runs of ops of transitively overlapping registers,
+	// only ops of the same cluster can conflict
+	for start := 0; start < len(order); {
+		end, last := start+1, ranges[order[start]].End()
+		for ; end < len(order) && int(ranges[order[end]].Start) < last; end++ {
+			if e := ranges[order[end]].End(); e > last {
+				last = e
+			}
+		}
+		cluster := order[start:end]
+		start = end
+		if len(cluster) == 1 {
+			continue
+		}
+		sort.Ints(cluster)
+		for a, i := range cluster {
+			for _, j := range cluster[a+1:] {
+				if !ranges[i].Overlaps(ranges[j]) {
+					continue
+				}
+				if policy != LastWins {
+					return nil, fmt.Errorf("%w: %v of op %d overlaps %v of op %d",
+						ErrConflictingWrites, describeRange(ranges[i]), i, describeRange(ranges[j]), j)
+				}
+				if dropped == nil {
+					dropped = make(map[int]bool)
+				}
+				dropped[i] = true
+				break
+			}
+		}
+	}
+	if len(dropped) == 0 {
+		return ops, nil
+	}
+	kept := make([]Write, 0, len(ops)-len(dropped))
+	for i, op := range ops {
+		if !dropped[i] {
+			kept = append(kept, op)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,107 @@
+package modbus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchWrite_conflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []modbus.Write
+		// want is the contents of registers 100-103 with LastWins
+		want    []byte
+		wantErr string
+	}{
+		{
+			"exact duplicate",
+			[]modbus.Write{testWrite{100, types.Uint16(1)}, testWrite{100, types.Uint16(2)}},
+			[]byte{0, 2, 0, 0, 0, 0, 0, 0},
+			"conflicting register writes: register 100 (0x0064) of op 0 overlaps register 100 (0x0064) of op 1",
+		},
+		{
+			"partial overlap",
+			[]modbus.Write{testWrite{100, types.Uint32(0x00010002)}, testWrite{101, types.Uint32(0x00030004)}},
+			[]byte{0, 0, 0, 3, 0, 4, 0, 0},
+			"conflicting register writes: 2 registers at 100-101 (0x0064-0x0065) of op 0 overlaps 2 registers at 101-102 (0x0065-0x0066) of op 1",
+		},
+		{
+			"later op inside",
+			[]modbus.Write{testWrite{100, types.Uint64(1)}, testWrite{103, types.Uint16(2)}, testWrite{101, types.Uint16(3)}},
+			[]byte{0, 0, 0, 3, 0, 0, 0, 2},
+			"conflicting register writes: 4 registers at 100-103 (0x0064-0x0067) of op 0 overlaps register 103 (0x0067) of op 1",
+		},
+		{
+			"earlier op inside",
+			[]modbus.Write{testWrite{101, types.Uint16(3)}, testWrite{100, types.Uint32(0x00010002)}},
+			[]byte{0, 1, 0, 2, 0, 0, 0, 0},
+			"conflicting register writes: register 101 (0x0065) of op 0 overlaps 2 registers at 100-101 (0x0064-0x0065) of op 1",
+		},
+		{
+			"adjacent",
+			[]modbus.Write{testWrite{102, types.Uint16(3)}, testWrite{100, types.Uint32(0x00010002)}},
+			[]byte{0, 1, 0, 2, 0, 3, 0, 0},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			err := modbus.NewClient(slave).BatchWrite(tt.ops, nil)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, modbus.ErrConflictingWrites)
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Zero(t, slave.calls(), "nothing is sent")
+			}
+
+			slave = newTestSlave()
+			client := modbus.NewClient(slave, modbus.WithWriteConflicts(modbus.LastWins))
+			assert.NoError(t, client.BatchWrite(tt.ops, nil))
+			assert.Equal(t, tt.want, slave.get(100, 4))
+		})
+	}
+}
+
+func TestClient_BatchWrite_lastWins(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithWriteConflicts(modbus.LastWins))
+
+	// op 1 is dropped for op 2, which leaves op 0 overlapping nothing
+	// that comes later.
+	ops := []modbus.Write{
+		testWrite{98, types.Uint16(1)},
+		testWrite{99, types.Uint32(2)},
+		testWrite{100, types.Uint16(3)},
+		testWrite{100, types.Uint16(4)},
+	}
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.Equal(t, []byte{0, 1, 0, 0, 0, 4}, slave.get(98, 3))
+	assert.Equal(t, 2, slave.writes(), "register 99 is not written")
+
+	_, err := client.Swap([]modbus.Write{testWrite{10, types.Uint16(5)}, testWrite{10, types.Uint16(6)}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 6}, slave.get(10, 1))
+}
+
+func TestClient_Swap_conflicts(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+
+	_, err := client.Swap([]modbus.Write{testWrite{10, types.Uint32(5)}, testWrite{11, types.Uint16(6)}})
+	assert.ErrorIs(t, err, modbus.ErrConflictingWrites)
+	err = client.BatchWriteWith([]modbus.Write{testWrite{10, types.Uint16(5)}, testWrite{10, types.Uint16(5)}},
+		nil, modbus.BatchOptions{Context: context.Background()})
+	assert.ErrorIs(t, err, modbus.ErrConflictingWrites, "equal values conflict as well")
+	assert.Zero(t, slave.calls())
+}
+
+func TestWithWriteConflicts_invalid(t *testing.T) {
+	err := modbus.NewClient(newTestSlave(), modbus.WithWriteConflicts(2)).BatchWrite(nil, nil)
+	assert.ErrorIs(t, err, modbus.ErrInvalidOption)
+}
@@ -0,0 +1,81 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/goburrow/modbus"
+)
+
+// ErrNilHandler is returned by operations of a client built from a nil
+// ClientHandler.
+var ErrNilHandler = errors.New("nil modbus.ClientHandler")
+
+// Connector is implemented by handlers that need to be connected before
+// use, such as goburrow TCP and RTU handlers. The client connects them
+// on first use, see Open.
+type Connector interface {
+	Connect() error
+}
+
+// WithProbe makes Open read register with function 3 to check that the
+// slave responds. Modbus exceptions count as responses.
+func WithProbe(register uint16) Option {
+	return func(c *Config) {
+		c.Probe = &register
+	}
+}
+
+// Open is the error-returning constructor: NewClient failing with a
+// descriptive error for handlers the client can't work with instead of
+// deferring the failure to the first operation. Nil handlers fail with
+// ErrNilHandler, invalid options with ErrInvalidOption, and handlers
+// implementing Connector are connected right away. If a probe is
+// configured with WithProbe, the slave must respond to it.
+func Open(handler modbus.ClientHandler, opts ...Option) (*Client, error) {
+	c := NewClient(handler, opts...)
+	if err := c.lock(); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+
+	if p := c.config.Probe; p != nil {
+		_, err := c.read(readOp{*p, 1, HoldingRegisters}, c.config.Retry)
+		if err != nil && !isException(err) {
+			return nil, fmt.Errorf("probing register %d: %w", *p, err)
+		}
+	}
+	return c, nil
+}
+
+// checkHandler returns ErrNilHandler for nil handlers, including nil
+// pointers of handler types.
+func checkHandler(handler modbus.ClientHandler) error {
+	if handler == nil {
+		return ErrNilHandler
+	}
+	if v := reflect.ValueOf(handler); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("%w: %T", ErrNilHandler, handler)
+	}
+	return nil
+}
+
+// connect connects the handler once if it implements Connector. A
+// failed attempt is repeated on the next operation. The caller must
+// hold the client mutex.
+func (c *Client) connect() error {
+	if c.initErr != nil {
+		return c.initErr
+	}
+	if c.connected {
+		return nil
+	}
+	if connector, ok := c.ClientHandler.(Connector); ok {
+		if err := connector.Connect(); err != nil {
+			return fmt.Errorf("connecting handler: %w", err)
+		}
+	}
+	c.connected = true
+	return nil
+}
@@ -0,0 +1,124 @@
+package modbus_test
+
+import (
+	"errors"
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+var errRefused = errors.New("connection refused")
+
+// connectingSlave is a slave that has to be connected before use, like
+// goburrow handlers with auto-connect disabled.
+type connectingSlave struct {
+	*modbustest.Slave
+	connected bool
+	connects  int
+	refuse    bool
+}
+
+func (s *connectingSlave) Connect() error {
+	s.connects++
+	if s.refuse {
+		return errRefused
+	}
+	s.connected = true
+	return nil
+}
+
+func (s *connectingSlave) Send(adu []byte) ([]byte, error) {
+	if !s.connected {
+		return nil, errors.New("use of closed network connection")
+	}
+	return s.Slave.Send(adu)
+}
+
+func TestNewClient_nilHandler(t *testing.T) {
+	for _, handler := range []goburrow.ClientHandler{nil, (*goburrow.TCPClientHandler)(nil)} {
+		client := modbus.NewClient(handler)
+		_, err := client.Read(10, types.Uint16Type)
+		assert.ErrorIs(t, err, modbus.ErrNilHandler)
+		assert.ErrorIs(t, client.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil), modbus.ErrNilHandler)
+
+		_, err = modbus.Open(handler)
+		assert.ErrorIs(t, err, modbus.ErrNilHandler)
+	}
+}
+
+func TestNewClient_lazyConnect(t *testing.T) {
+	slave := &connectingSlave{Slave: modbustest.NewSlave(), refuse: true}
+	client := modbus.NewClient(slave)
+	assert.Zero(t, slave.connects, "nothing happens on construction")
+
+	_, err := client.Read(10, types.Uint16Type)
+	assert.ErrorIs(t, err, errRefused)
+	assert.Empty(t, slave.Requests())
+
+	// a failed attempt is repeated, a successful one is not
+	slave.refuse = false
+	for i := 0; i < 3; i++ {
+		_, err = client.Read(10, types.Uint16Type)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, slave.connects)
+	assert.Len(t, slave.Requests(), 3)
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		slave   *connectingSlave
+		opts    []modbus.Option
+		wantErr error
+	}{
+		{"connects", &connectingSlave{Slave: modbustest.NewSlave()}, nil, nil},
+		{"connect failure", &connectingSlave{Slave: modbustest.NewSlave(), refuse: true}, nil, errRefused},
+		{"probe", &connectingSlave{Slave: modbustest.NewSlave()}, []modbus.Option{modbus.WithProbe(65535)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := modbus.Open(tt.slave, tt.opts...)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, client)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.slave.connected)
+			assert.Len(t, tt.slave.Requests(), len(tt.opts), "one request per probe")
+		})
+	}
+}
+
+func TestOpen_probe(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(s *testSlave)
+		wantErr bool
+	}{
+		{"response", func(s *testSlave) {}, false},
+		{"exception", func(s *testSlave) { s.missing = map[uint16]bool{100: true} }, false},
+		{"transport failure", func(s *testSlave) { s.failures = 5 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			tt.setup(slave)
+
+			client, err := modbus.Open(slave, modbus.WithProbe(100), modbus.WithRetry(modbus.Retry{Retries: 1}))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errTransport)
+				assert.Nil(t, client)
+				assert.Equal(t, 2, slave.calls(), "the probe is retried")
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, slave.calls())
+			}
+		})
+	}
+}
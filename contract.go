@@ -0,0 +1,50 @@
+package modbus
+
+// Guarantee is a concurrency guarantee every Client upholds for the
+// operations made through its methods. Calling the embedded
+// modbus.Client or ClientHandler directly voids all of them.
+//
+// Builds with the opmodbus_check tag check the guarantees at runtime,
+// on every wire request.
+type Guarantee int
+
+const (
+	// WireUnderMutex: every wire request of a client is made while an
+	// operation holds the client mutex, including background refreshes
+	// of BatchReadSWR, verification and read-back reads, and the probe
+	// of Open.
+	WireUnderMutex Guarantee = iota + 1
+	// AtomicOperations: an operation holds the client mutex from its
+	// first wire request to its last one, so the requests of different
+	// operations are never interleaved, and no operation observes
+	// registers half-written by another one.
+	AtomicOperations
+	// RegisterWriteOrder: writes to the same register or coil are sent
+	// in the order their operations acquired the client mutex, and in
+	// plan order within an operation.
+	RegisterWriteOrder
+	// ShutdownDrains: once Shutdown is called, operations that haven't
+	// acquired the client mutex yet fail with ErrClientClosed without
+	// making wire requests, and the handler is only closed after the
+	// operations in flight complete or the Shutdown context is done.
+	ShutdownDrains
+)
+
+// Guarantees returns all the guarantees of Client.
+func Guarantees() []Guarantee {
+	return []Guarantee{WireUnderMutex, AtomicOperations, RegisterWriteOrder, ShutdownDrains}
+}
+
+func (g Guarantee) String() string {
+	switch g {
+	case WireUnderMutex:
+		return "wire requests under the client mutex"
+	case AtomicOperations:
+		return "atomic operations"
+	case RegisterWriteOrder:
+		return "register write order"
+	case ShutdownDrains:
+		return "shutdown drains operations"
+	}
+	return "unknown guarantee"
+}
@@ -0,0 +1,104 @@
+//go:build opmodbus_check
+// +build opmodbus_check
+
+package modbus_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// TestGuarantees stresses a client with concurrent operations of every
+// kind and checks that none of Guarantees is violated. Run it with
+//
+//	go test -race -tags opmodbus_check -run TestGuarantees
+func TestGuarantees(t *testing.T) {
+	const (
+		rounds = 50
+		batch  = 10
+	)
+	modbus.SweepChaos(t, modbus.ChaosOptions{}, func(t *testing.T, chaos func(*modbus.Client)) {
+		slave := modbustest.NewSlave()
+		// batches take several write requests, which must not be
+		// interleaved with requests of other operations
+		client := modbus.NewClient(slave, modbus.WithLimits(modbus.Limits{MaxWriteQuantity: 2}))
+		chaos(client)
+
+		batchOf := func(v uint16) []modbus.Write {
+			ops := make([]modbus.Write, batch)
+			for i := range ops {
+				ops[i] = testWrite{uint16(100 + i), types.Uint16(v)}
+			}
+			return ops
+		}
+		reads := make([]modbus.Read, batch)
+		for i := range reads {
+			reads[i] = testRead{uint16(100 + i), types.Uint16Type}
+		}
+		consistent := func(res modbus.Registers) {
+			for _, r := range reads {
+				assert.Equal(t, res[100], res[r.Register()], "half-written batch: %v", res)
+			}
+		}
+
+		var wg sync.WaitGroup
+		run := func(f func(i int)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 1; i <= rounds; i++ {
+					f(i)
+				}
+			}()
+		}
+		for w := 0; w < 2; w++ {
+			base := uint16(w * 1000)
+			run(func(i int) { assert.NoError(t, client.BatchWrite(batchOf(base+uint16(i)), nil)) })
+		}
+		run(func(int) {
+			res, err := client.BatchRead(reads)
+			if assert.NoError(t, err) {
+				consistent(res)
+			}
+		})
+		run(func(int) {
+			res, err := client.Swap(batchOf(0))
+			if assert.NoError(t, err) {
+				consistent(res)
+			}
+		})
+		run(func(i int) { assert.NoError(t, client.Write(200, types.Uint16(i))) })
+		run(func(i int) { assert.NoError(t, client.MaskWrite(201, 0xFF00, uint16(i))) })
+		run(func(i int) {
+			assert.NoError(t, client.BatchWriteCoils([]modbus.CoilWrite{testCoil{1, i%2 == 0}, testCoil{2, i%2 == 1}}))
+		})
+		run(func(int) {
+			res, err := client.BatchReadSWR(reads, modbus.SWROptions{MaxStale: time.Microsecond, Block: true})
+			if assert.NoError(t, err) {
+				consistent(res.Values)
+				if res.Refresh != nil {
+					<-res.Refresh
+				}
+			}
+		})
+		wg.Wait()
+
+		assert.Empty(t, modbus.Violations(client))
+	})
+}
+
+func TestGuarantees_violation(t *testing.T) {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+
+	// the embedded client bypasses the client mutex
+	_, err := client.ReadHoldingRegisters(0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, modbus.Violations(client), 1)
+}
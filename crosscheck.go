@@ -0,0 +1,188 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+// CrossCheckOptions configures BatchReadCrossCheck.
+type CrossCheckOptions struct {
+	// NaiveFirst runs the naive plan before the optimized one in every
+	// round.
+	NaiveFirst bool
+	// Rounds is the number of times both plans are run. Defaults to 1.
+	Rounds int
+	// Stable reports whether the value of op is expected to stay the
+	// same between the runs. Differences in unstable ops are reported
+	// but don't fail the check. All ops are considered stable if nil.
+	Stable func(op Read) bool
+}
+
+// CrossCheckReport is the result of BatchReadCrossCheck.
+type CrossCheckReport struct {
+	Started time.Time
+	Rounds  []CrossCheckRound
+}
+
+// Passed reports whether no stable op returned different data in any
+// round.
+func (r *CrossCheckReport) Passed() bool {
+	for _, round := range r.Rounds {
+		for _, m := range round.Mismatches {
+			if m.Stable {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CrossCheckRound holds the comparison of a single optimized run with a
+// single naive run.
+type CrossCheckRound struct {
+	OptimizedRequests int
+	OptimizedTime     time.Duration
+	NaiveRequests     int
+	NaiveTime         time.Duration
+	// Matches holds ops that decoded to byte-identical values.
+	Matches    []Read
+	Mismatches []CrossCheckMismatch
+}
+
+// CrossCheckMismatch describes an op that decoded to different values
+// in the optimized and the naive run.
+type CrossCheckMismatch struct {
+	Op        Read
+	Stable    bool
+	Optimized []byte
+	Naive     []byte
+}
+
+// BatchReadCrossCheck reads ops both the optimized way, planned exactly
+// as BatchRead would plan them, and the naive way, with separate
+// function 3 requests per op, and compares the decoded values of every
+// op. It is meant for commissioning: checking that the slave returns
+// the same data for merged requests as for separate ones.
+//
+// The client lock is held for the whole check. The chunk cache is never
+// used. A read error aborts the check, except for rejections of
+// optional ops, which decode to Unavailable values in both runs. Ops
+// exceeding the read limit of the client are split in both runs, or
+// fail the check with ErrTooManyRegisters if the limit is strict, see
+// Limits.
+func (c *Client) BatchReadCrossCheck(ops []Read, opts CrossCheckOptions) (_ *CrossCheckReport, err error) {
+	defer recoverPanic(&err)
+
+	cfg, err := c.resolve(BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	plan, err := c.planRead(ops, cfg)
+	if err != nil {
+		return nil, err
+	}
+	rounds := opts.Rounds
+	if rounds < 1 {
+		rounds = 1
+	}
+
+	if err := c.lock(); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+
+	report := &CrossCheckReport{Started: time.Now()}
+	for i := 0; i < rounds; i++ {
+		round := CrossCheckRound{OptimizedRequests: len(plan.requests), NaiveRequests: len(plan.ops)}
+		var (
+			opt, naive Registers
+			err        error
+		)
+		runOptimized := func() error {
+			start := time.Now()
+			opt, err = c.crossCheckOptimized(ops, plan, cfg)
+			round.OptimizedTime = time.Since(start)
+			return err
+		}
+		runNaive := func() error {
+			start := time.Now()
+			naive, err = c.crossCheckNaive(ops, plan, cfg)
+			round.NaiveTime = time.Since(start)
+			return err
+		}
+		first, second := runOptimized, runNaive
+		if opts.NaiveFirst {
+			first, second = runNaive, runOptimized
+		}
+		if err := first(); err != nil {
+			return nil, err
+		}
+		if err := second(); err != nil {
+			return nil, err
+		}
+
+		for _, op := range ops {
+			a, b := opt[op.Register()].Bytes(), naive[op.Register()].Bytes()
+			if bytes.Equal(a, b) {
+				round.Matches = append(round.Matches, op)
+				continue
+			}
+			round.Mismatches = append(round.Mismatches, CrossCheckMismatch{
+				Op:        op,
+				Stable:    opts.Stable == nil || opts.Stable(op),
+				Optimized: a,
+				Naive:     b,
+			})
+		}
+		report.Rounds = append(report.Rounds, round)
+	}
+
+	return report, nil
+}
+
+func (c *Client) crossCheckOptimized(ops []Read, plan readPlan, cfg batchConfig) (Registers, error) {
+	results, unavailable, err := c.readChunksOptional(context.Background(), plan.requests, plan.opt, c.read, cfg.Retry, nil)
+	if err != nil {
+		return nil, err
+	}
+	available, absent := splitUnavailable(ops, unavailable)
+	res, err := decodeRead(available, results)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range absent {
+		res[u.Op.Register()] = u
+	}
+	return res, nil
+}
+
+// crossCheckNaive reads every op of plan on its own, with the requests
+// of the pieces it was split into if any.
+func (c *Client) crossCheckNaive(ops []Read, plan readPlan, cfg batchConfig) (Registers, error) {
+	res := make(Registers, len(ops))
+	pieces := plan.ops
+	for i, op := range ops {
+		results := make(map[readOp][]byte)
+		var rerr error
+		for left := int(op.Type().Size()); left > 0 && rerr == nil; pieces = pieces[1:] {
+			v := pieces[0]
+			left -= int(v.quantity)
+			b, err := c.read(v, cfg.Retry)
+			if err != nil && !(isOptional(op) && isException(err)) {
+				return nil, c.readError(v, err)
+			}
+			results[v], rerr = b, err
+		}
+		if rerr != nil {
+			res[op.Register()] = Unavailable{op, rerr}
+			continue
+		}
+		decoded, err := decodeRead(ops[i:i+1], results)
+		if err != nil {
+			return nil, err
+		}
+		res[op.Register()] = decoded[op.Register()]
+	}
+	return res, nil
+}
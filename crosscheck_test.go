@@ -0,0 +1,83 @@
+package modbus_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchReadCrossCheck(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, types.Float32CDAB(1.5).Bytes()...)
+	slave.set(12, 0, 7)
+	slave.volatile[13] = true
+	client := modbus.NewClient(slave)
+	ops := []modbus.Read{
+		testRead{10, types.Float32CDABType},
+		testRead{12, types.Uint16Type},
+		testRead{13, types.Uint16Type},
+	}
+
+	report, err := client.BatchReadCrossCheck(ops, modbus.CrossCheckOptions{Rounds: 2})
+	assert.NoError(t, err)
+	assert.False(t, report.Passed(), "counter changes between runs")
+	if assert.Len(t, report.Rounds, 2) {
+		round := report.Rounds[0]
+		assert.Equal(t, 1, round.OptimizedRequests)
+		assert.Equal(t, 3, round.NaiveRequests)
+		assert.Equal(t, ops[:2], round.Matches)
+		assert.Equal(t, []modbus.CrossCheckMismatch{
+			{Op: ops[2], Stable: true, Optimized: []byte{0, 0}, Naive: []byte{0, 3}},
+		}, round.Mismatches)
+	}
+	assert.Equal(t, 8, slave.calls())
+
+	report, err = client.BatchReadCrossCheck(ops, modbus.CrossCheckOptions{
+		NaiveFirst: true,
+		Stable:     func(op modbus.Read) bool { return op.Register() != 13 },
+	})
+	assert.NoError(t, err)
+	assert.True(t, report.Passed(), "unstable ops don't fail the check")
+	if assert.Len(t, report.Rounds, 1) && assert.Len(t, report.Rounds[0].Mismatches, 1) {
+		assert.False(t, report.Rounds[0].Mismatches[0].Stable)
+	}
+}
+
+func TestClient_BatchReadCrossCheck_limits(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithMaxReadQuantity(2))
+
+	ops := []modbus.Read{testRead{10, types.Uint64Type}}
+	report, err := client.BatchReadCrossCheck(ops, modbus.CrossCheckOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, report.Rounds, 1) {
+		assert.Equal(t, 2, report.Rounds[0].OptimizedRequests, "ops are split as in BatchRead")
+		assert.Equal(t, 2, report.Rounds[0].NaiveRequests)
+		assert.Equal(t, ops, report.Rounds[0].Matches)
+	}
+	assert.Equal(t, 4, slave.calls())
+
+	client = modbus.NewClient(slave, modbus.WithLimits(modbus.Limits{MaxReadQuantity: 2, StrictReadSize: true}))
+	_, err = client.BatchReadCrossCheck(ops, modbus.CrossCheckOptions{})
+	assert.ErrorIs(t, err, modbus.ErrTooManyRegisters)
+	assert.Equal(t, 4, slave.calls(), "nothing is sent")
+}
+
+func TestClient_BatchReadCrossCheck_optional(t *testing.T) {
+	slave := newTestSlave()
+	slave.missing[12] = true
+	client := modbus.NewClient(slave)
+
+	ops := []modbus.Read{testRead{10, types.Uint16Type}, modbus.OptionalRead(testRead{12, types.Uint16Type})}
+	report, err := client.BatchReadCrossCheck(ops, modbus.CrossCheckOptions{})
+	assert.NoError(t, err, "rejected optional ops don't abort the check")
+	assert.True(t, report.Passed())
+	if assert.Len(t, report.Rounds, 1) {
+		assert.Equal(t, ops, report.Rounds[0].Matches)
+	}
+
+	_, err = client.BatchReadCrossCheck([]modbus.Read{testRead{12, types.Uint16Type}}, modbus.CrossCheckOptions{})
+	assert.Error(t, err, "required ops do")
+}
@@ -0,0 +1,104 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goburrow/modbus"
+)
+
+// ErrCustomRegionSize is returned when CustomRegion.Fetch returns data
+// of a size other than the one requested.
+var ErrCustomRegionSize = errors.New("custom region returned wrong number of bytes")
+
+// CustomRegion routes reads of a register range to Fetch instead of
+// function 3, e.g. for devices serving bulk data more efficiently with a
+// vendor-specific function. Ops in the region are merged with each
+// other but never with ops outside of it, like ops of slow ranges.
+//
+// Fetch runs under the client mutex like any other request, its errors
+// are retried as configured with WithRetry unless they are Modbus
+// exceptions, and its response times are tracked in Latencies.
+type CustomRegion struct {
+	RegisterRange
+	// Fetch returns the data of quantity registers starting at register,
+	// 2 bytes per register in the layout function 3 would return them.
+	// send performs a raw request with the client handler and returns
+	// the data of the response; exception responses are returned as
+	// *modbus.ModbusError.
+	Fetch func(send func(pdu modbus.ProtocolDataUnit) ([]byte, error), register, quantity uint16) ([]byte, error)
+}
+
+// WithCustomRegions routes reads of holding register ranges to
+// user-supplied functions instead of function 3, see CustomRegion.
+func WithCustomRegions(regions ...CustomRegion) Option {
+	return func(c *Config) {
+		c.CustomRegions = append(c.CustomRegions[:len(c.CustomRegions):len(c.CustomRegions)], regions...)
+	}
+}
+
+// readRegions returns the ranges ops must not be merged across: custom
+// regions first, so that they take precedence, then slow ranges.
+func (c *Client) readRegions() []SlowRange {
+	if len(c.config.CustomRegions) == 0 {
+		return c.config.SlowRanges
+	}
+	regions := make([]SlowRange, 0, len(c.config.CustomRegions)+len(c.config.SlowRanges))
+	for _, r := range c.config.CustomRegions {
+		regions = append(regions, SlowRange{RegisterRange: r.RegisterRange})
+	}
+	return append(regions, c.config.SlowRanges...)
+}
+
+// customRegion returns the custom region containing all of r. Custom
+// regions only cover holding registers.
+func (c *Client) customRegion(r readOp) (CustomRegion, bool) {
+	if r.space != HoldingRegisters {
+		return CustomRegion{}, false
+	}
+	for _, region := range c.config.CustomRegions {
+		if region.rng().ContainsRange(r.rng()) {
+			return region, true
+		}
+	}
+	return CustomRegion{}, false
+}
+
+// fetch reads r from a custom region.
+func (c *Client) fetch(region CustomRegion, r readOp) ([]byte, error) {
+	b, err := region.Fetch(c.send, r.register, r.quantity)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != int(r.quantity)*2 {
+		return nil, fmt.Errorf("%w: %d bytes for %d registers at %d", ErrCustomRegionSize, len(b), r.quantity, r.register)
+	}
+	return b, nil
+}
+
+// send performs a raw request with the client handler, the way
+// goburrow/modbus does for standard functions.
+func (c *Client) send(pdu modbus.ProtocolDataUnit) ([]byte, error) {
+	adu, err := c.Encode(&pdu)
+	if err != nil {
+		return nil, err
+	}
+	aduResponse, err := c.wire.Send(adu)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Verify(adu, aduResponse); err != nil {
+		return nil, err
+	}
+	response, err := c.Decode(aduResponse)
+	if err != nil {
+		return nil, err
+	}
+	if response.FunctionCode != pdu.FunctionCode {
+		if response.FunctionCode == pdu.FunctionCode|0x80 && len(response.Data) > 0 {
+			return nil, &modbus.ModbusError{FunctionCode: response.FunctionCode, ExceptionCode: response.Data[0]}
+		}
+		return nil, fmt.Errorf("modbus: response function code %d does not match request %d", response.FunctionCode, pdu.FunctionCode)
+	}
+	return response.Data, nil
+}
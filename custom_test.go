@@ -0,0 +1,99 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// vendorRegion serves registers 100-109 with vendorRead.
+func vendorRegion() modbus.CustomRegion {
+	return modbus.CustomRegion{
+		RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10},
+		Fetch: func(send func(goburrow.ProtocolDataUnit) ([]byte, error), register, quantity uint16) ([]byte, error) {
+			data := make([]byte, 4)
+			binary.BigEndian.PutUint16(data[0:], register)
+			binary.BigEndian.PutUint16(data[2:], quantity)
+			res, err := send(goburrow.ProtocolDataUnit{FunctionCode: vendorRead, Data: data})
+			if err != nil {
+				return nil, err
+			}
+			b := make([]byte, 0, len(res)-1)
+			for _, x := range res[1:] {
+				b = append(b, x^0xFF)
+			}
+			return b, nil
+		},
+	}
+}
+
+func TestClient_BatchRead_customRegion(t *testing.T) {
+	slave := newTestSlave()
+	for r := uint16(0); r < 120; r++ {
+		slave.set(r, 0, byte(r))
+	}
+	client := modbus.NewClient(slave, modbus.WithCustomRegions(vendorRegion()))
+
+	res, err := client.BatchRead([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+		testRead{99, types.Uint16Type},
+		testRead{100, types.Uint16Type},
+		testRead{101, types.Uint32Type},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{
+		10:  types.Uint16(10),
+		11:  types.Uint16(11),
+		99:  types.Uint16(99),
+		100: types.Uint16(100),
+		101: types.Uint32(101<<16 | 102),
+	}, res)
+
+	want := []goburrow.ProtocolDataUnit{
+		{FunctionCode: goburrow.FuncCodeReadHoldingRegisters, Data: []byte{0, 10, 0, 2}},
+		{FunctionCode: goburrow.FuncCodeReadHoldingRegisters, Data: []byte{0, 99, 0, 1}},
+		{FunctionCode: vendorRead, Data: []byte{0, 100, 0, 3}},
+	}
+	assert.Equal(t, want, slave.requests, "region ops are merged with each other only")
+
+	v, err := client.Read(105, types.Uint16Type)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Uint16(105), v)
+	assert.Equal(t, byte(vendorRead), slave.requests[3].FunctionCode)
+}
+
+func TestClient_BatchRead_customRegionErrors(t *testing.T) {
+	ops := []modbus.Read{testRead{100, types.Uint16Type}}
+
+	slave := newTestSlave()
+	slave.missing[100] = true
+	client := modbus.NewClient(slave, modbus.WithRetry(modbus.Retry{Retries: 2}), modbus.WithCustomRegions(vendorRegion()))
+	_, err := client.BatchRead(ops)
+	var exception *goburrow.ModbusError
+	if assert.True(t, errors.As(err, &exception), "%v", err) {
+		assert.Equal(t, byte(goburrow.ExceptionCodeIllegalDataAddress), exception.ExceptionCode)
+	}
+	assert.Equal(t, 1, slave.calls(), "exceptions are not retried")
+
+	slave = newTestSlave()
+	slave.failures = 2
+	client = modbus.NewClient(slave, modbus.WithRetry(modbus.Retry{Retries: 2}), modbus.WithCustomRegions(vendorRegion()))
+	_, err = client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, slave.calls(), "transport failures are retried")
+
+	client = modbus.NewClient(newTestSlave(), modbus.WithCustomRegions(modbus.CustomRegion{
+		RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10},
+		Fetch: func(func(goburrow.ProtocolDataUnit) ([]byte, error), uint16, uint16) ([]byte, error) {
+			return []byte{1}, nil
+		},
+	}))
+	_, err = client.BatchRead(ops)
+	assert.True(t, errors.Is(err, modbus.ErrCustomRegionSize), "%v", err)
+}
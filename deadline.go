@@ -0,0 +1,114 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WithBatchTimeout bounds the total duration of every BatchRead and
+// BatchWrite operation of the client by d, unlike the handler timeout
+// bounding a single wire request. The timeout starts once the batch
+// acquires the client mutex, so time spent waiting for other
+// operations doesn't count. Batches exceeding it stop before their next
+// wire request with a *BatchTimeoutError; a request in flight is not
+// interrupted. BatchOptions.Timeout overrides it for a single batch.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.BatchTimeout = d
+	}
+}
+
+// BatchTimeoutError is returned by batches exceeding their timeout, see
+// WithBatchTimeout. It wraps context.DeadlineExceeded.
+type BatchTimeoutError struct {
+	Timeout time.Duration
+	// Completed is the number of wire requests the batch completed,
+	// including retries.
+	Completed int
+	// Values holds the values of the read ops of BatchReadWith whose
+	// requests completed in time.
+	Values Registers
+	// Err is the error the batch stopped with.
+	Err error
+}
+
+func (e *BatchTimeoutError) Error() string {
+	return fmt.Sprintf("batch timeout of %v exceeded after %d requests: %v", e.Timeout, e.Completed, e.Err)
+}
+
+func (e *BatchTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// deadline bounds ctx by timeout from now if positive, makes it
+// abortable by Shutdown, and binds the bounded context to the requests
+// sent until done, see bind. done releases the bounded context and
+// converts errors caused by the timeout or an abort, as opposed to ctx,
+// into a *BatchTimeoutError or an error wrapping ErrClientClosed. The
+// caller must hold the client mutex.
+func (c *Client) deadline(ctx context.Context, timeout time.Duration) (_ context.Context, done func(error) error) {
+	abortable, release := c.aborts.track(ctx)
+	if timeout <= 0 {
+		unbind := c.bind(abortable)
+		return abortable, func(err error) error {
+			unbind()
+			release()
+			if ctx.Err() != nil {
+				return err
+			}
+			return c.aborts.err(err)
+		}
+	}
+	bounded, cancel := context.WithTimeout(abortable, timeout)
+	unbind := c.bind(bounded)
+	start := c.completed
+	return bounded, func(err error) error {
+		cancel()
+		unbind()
+		release()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return c.aborts.err(err)
+		}
+		return &BatchTimeoutError{Timeout: timeout, Completed: c.completed - start, Err: err}
+	}
+}
+
+// boundedBy bounds ctx by expiry, the deadline of the batch it is the
+// context of, so that steps of the batch done after its requests see
+// the same deadline. Zero expiry leaves ctx unbounded.
+func boundedBy(ctx context.Context, expiry time.Time) (context.Context, context.CancelFunc) {
+	if expiry.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, expiry)
+}
+
+// covered returns the ops whose registers were all read by results.
+func covered(ops []Read, results map[readOp][]byte) []Read {
+	read := make(map[Space][]bool)
+	for r := range results {
+		if read[r.space] == nil {
+			read[r.space] = make([]bool, maxUint16)
+		}
+		for i := int(r.register); i < int(r.register)+int(r.quantity); i++ {
+			read[r.space][i] = true
+		}
+	}
+	res := ops[:0:0]
+	for _, op := range ops {
+		s := read[spaceOf(op)]
+		ok := s != nil
+		for i := int(op.Register()); ok && i < int(op.Register())+int(op.Type().Size()); i++ {
+			ok = i < len(s) && s[i]
+		}
+		if ok {
+			res = append(res, op)
+		}
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestWithBatchTimeout(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	// three requests, the deadline passes during the second one
+	reads := []modbus.Read{testRead{10, types.Uint16Type}, testRead{20, types.Uint16Type}, testRead{30, types.Uint16Type}}
+	writes := []modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{20, types.Uint16(2)}, testWrite{30, types.Uint16(3)}}
+	slave := newTestSlave()
+	slave.delay = delay
+	slave.set(10, 0, 7)
+	slave.set(20, 0, 8)
+	client := modbus.NewClient(slave, modbus.WithBatchTimeout(80*time.Millisecond))
+
+	_, err := client.BatchRead(reads)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var terr *modbus.BatchTimeoutError
+	if assert.True(t, errors.As(err, &terr), "%v", err) {
+		assert.Equal(t, 2, terr.Completed)
+		assert.Equal(t, modbus.Registers{10: types.Uint16(7), 20: types.Uint16(8)}, terr.Values)
+	}
+	assert.Equal(t, 2, slave.calls())
+
+	err = client.BatchWrite(writes, nil)
+	if assert.True(t, errors.As(err, &terr), "%v", err) {
+		assert.Equal(t, 2, terr.Completed)
+	}
+	assert.Equal(t, 2, slave.writes())
+
+	timeout := time.Duration(0)
+	_, err = client.BatchReadWith(reads, modbus.BatchOptions{Timeout: &timeout})
+	assert.NoError(t, err, "batches override the client timeout")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.BatchReadContext(ctx, reads)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, errors.As(err, &terr), "context deadlines are not batch timeouts")
+}
+
+func TestWithBatchTimeout_queued(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	slave := newTestSlave()
+	slave.delay = delay
+	client := modbus.NewClient(slave)
+
+	busy := make(chan error)
+	go func() {
+		_, err := client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{20, types.Uint16Type}, testRead{30, types.Uint16Type}})
+		busy <- err
+	}()
+	for slave.calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// queued for about 100 ms behind the busy batch, which is more than
+	// its timeout
+	timeout := 80 * time.Millisecond
+	start := time.Now()
+	_, err := client.BatchReadWith([]modbus.Read{testRead{10, types.Uint16Type}, testRead{20, types.Uint16Type}}, modbus.BatchOptions{Timeout: &timeout})
+	assert.NoError(t, err, "the timeout starts once the batch holds the mutex")
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(3*delay))
+	assert.NoError(t, <-busy)
+}
@@ -0,0 +1,178 @@
+package modbus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ChangeKind tells how a register differs between two snapshots.
+type ChangeKind int
+
+const (
+	// Changed registers are present in both snapshots with different
+	// values.
+	Changed ChangeKind = iota
+	// Added registers are only present in the newer snapshot.
+	Added
+	// Removed registers are only present in the older snapshot.
+	Removed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Changed:
+		return "changed"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a register differing between two snapshots. Old fields are
+// zero for added registers, New fields for removed ones.
+type Change struct {
+	Register uint16
+	// Name is the name of the register in CompareOptions.Names, if any.
+	Name     string
+	Kind     ChangeKind
+	Old, New types.Value
+	// OldBytes and NewBytes are the encoded values.
+	OldBytes, NewBytes []byte
+	// OldTime and NewTime are the times of the snapshots.
+	OldTime, NewTime time.Time
+}
+
+// CompareOptions tunes CompareSnapshots.
+type CompareOptions struct {
+	// Names maps registers to names reported in changes.
+	Names map[uint16]string
+	// Ignore lists ranges of registers never reported.
+	Ignore []RegisterRange
+	// Deadband suppresses changes of numeric values smaller than it,
+	// see types.Float64Of. Added and removed registers are always
+	// reported.
+	Deadband float64
+}
+
+// CompareSnapshots returns the registers differing between a and b,
+// ordered by register. Values are equal if they are of the same type
+// and encode to the same bytes.
+func CompareSnapshots(a, b Snapshot, opts CompareOptions) []Change {
+	var changes []Change
+	report := func(register uint16, kind ChangeKind, before, after types.Value) {
+		for _, r := range opts.Ignore {
+			if r.Contains(register) {
+				return
+			}
+		}
+		c := Change{
+			Register: register,
+			Name:     opts.Names[register],
+			Kind:     kind,
+			Old:      before,
+			New:      after,
+			OldTime:  a.Time,
+			NewTime:  b.Time,
+		}
+		if before != nil {
+			c.OldBytes = before.Bytes()
+		}
+		if after != nil {
+			c.NewBytes = after.Bytes()
+		}
+		changes = append(changes, c)
+	}
+
+	for register, before := range a.Values {
+		after, ok := b.Values[register]
+		switch {
+		case !ok:
+			report(register, Removed, before, nil)
+		case !sameValue(before, after) && !withinDeadband(before, after, opts.Deadband):
+			report(register, Changed, before, after)
+		}
+	}
+	for register, after := range b.Values {
+		if _, ok := a.Values[register]; !ok {
+			report(register, Added, nil, after)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Register < changes[j].Register
+	})
+	return changes
+}
+
+func sameValue(a, b types.Value) bool {
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+func withinDeadband(a, b types.Value, deadband float64) bool {
+	if deadband <= 0 {
+		return false
+	}
+	x, ok := types.Float64Of(a)
+	if !ok {
+		return false
+	}
+	y, ok := types.Float64Of(b)
+	return ok && math.Abs(x-y) < deadband
+}
+
+// ChangeFormat selects the output of FormatChanges.
+type ChangeFormat int
+
+const (
+	// ChangeText formats every change as a line of plain text.
+	ChangeText ChangeFormat = iota
+	// ChangeMarkdown formats changes as a Markdown table.
+	ChangeMarkdown
+)
+
+// FormatChanges renders changes for humans, e.g.
+//
+//	10 (pressure): changed 5 -> 7 (0x0005 -> 0x0007)
+func FormatChanges(changes []Change, format ChangeFormat) string {
+	var sb strings.Builder
+	if format == ChangeMarkdown {
+		sb.WriteString("| Register | Name | Change | Old | New |\n")
+		sb.WriteString("|---|---|---|---|---|\n")
+	}
+	for _, c := range changes {
+		before, after := formatChangeValue(c.Old, c.OldBytes), formatChangeValue(c.New, c.NewBytes)
+		if format == ChangeMarkdown {
+			fmt.Fprintf(&sb, "| %d | %s | %v | %s | %s |\n", c.Register, c.Name, c.Kind, before, after)
+			continue
+		}
+		register := fmt.Sprint(c.Register)
+		if c.Name != "" {
+			register = fmt.Sprintf("%d (%s)", c.Register, c.Name)
+		}
+		switch c.Kind {
+		case Added:
+			fmt.Fprintf(&sb, "%s: added %s\n", register, after)
+		case Removed:
+			fmt.Fprintf(&sb, "%s: removed %s\n", register, before)
+		default:
+			fmt.Fprintf(&sb, "%s: changed %v -> %v (0x%X -> 0x%X)\n", register, c.Old, c.New, c.OldBytes, c.NewBytes)
+		}
+	}
+	return sb.String()
+}
+
+func formatChangeValue(v types.Value, b []byte) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v (0x%X)", v, b)
+}
@@ -0,0 +1,73 @@
+package modbus_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	before := snapshotAt(0, modbus.Registers{
+		10: types.Uint16(5),
+		11: types.Float32(20.5),
+		12: types.Uint16(4),
+		13: types.Bool(true),
+		20: types.Uint16(1),
+	})
+	after := snapshotAt(3600, modbus.Registers{
+		10: types.Uint16(7),
+		11: types.Float32(20.6),
+		13: types.Bool(true),
+		14: types.Uint16(3),
+		20: types.Uint16(2),
+	})
+	opts := modbus.CompareOptions{
+		Names:    map[uint16]string{10: "pressure"},
+		Ignore:   []modbus.RegisterRange{{Register: 20, Quantity: 1}},
+		Deadband: 0.5,
+	}
+
+	changes := modbus.CompareSnapshots(before, after, opts)
+	assert.Equal(t, []modbus.Change{
+		{
+			Register: 10, Name: "pressure", Kind: modbus.Changed,
+			Old: types.Uint16(5), New: types.Uint16(7),
+			OldBytes: []byte{0, 5}, NewBytes: []byte{0, 7},
+			OldTime: before.Time, NewTime: after.Time,
+		},
+		{
+			Register: 12, Kind: modbus.Removed,
+			Old: types.Uint16(4), OldBytes: []byte{0, 4},
+			OldTime: before.Time, NewTime: after.Time,
+		},
+		{
+			Register: 14, Kind: modbus.Added,
+			New: types.Uint16(3), NewBytes: []byte{0, 3},
+			OldTime: before.Time, NewTime: after.Time,
+		},
+	}, changes)
+
+	assert.Equal(t, "10 (pressure): changed 5 -> 7 (0x0005 -> 0x0007)\n"+
+		"12: removed 4 (0x0004)\n"+
+		"14: added 3 (0x0003)\n", modbus.FormatChanges(changes, modbus.ChangeText))
+	assert.Equal(t, "| Register | Name | Change | Old | New |\n"+
+		"|---|---|---|---|---|\n"+
+		"| 10 | pressure | changed | 5 (0x0005) | 7 (0x0007) |\n"+
+		"| 12 |  | removed | 4 (0x0004) |  |\n"+
+		"| 14 |  | added |  | 3 (0x0003) |\n", modbus.FormatChanges(changes, modbus.ChangeMarkdown))
+
+	opts.Deadband = 0
+	opts.Ignore = nil
+	var registers []uint16
+	for _, c := range modbus.CompareSnapshots(before, after, opts) {
+		registers = append(registers, c.Register)
+	}
+	assert.Equal(t, []uint16{10, 11, 12, 14, 20}, registers)
+
+	assert.Empty(t, modbus.CompareSnapshots(before, before, modbus.CompareOptions{}))
+	retyped := snapshotAt(0, modbus.Registers{10: types.Int16(5)})
+	assert.Len(t, modbus.CompareSnapshots(snapshotAt(0, modbus.Registers{10: types.Uint16(5)}), retyped, modbus.CompareOptions{}), 1,
+		"values of different types differ")
+}
@@ -0,0 +1,275 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/store"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// lossyLink is a slave behind a noisy line losing the first drop
+// requests.
+type lossyLink struct {
+	*modbustest.Slave
+	drop int
+}
+
+func (l *lossyLink) Send(adu []byte) ([]byte, error) {
+	if l.drop > 0 {
+		l.drop--
+		return nil, errors.New("timeout")
+	}
+	return l.Slave.Send(adu)
+}
+
+// slowLink is a slave behind a slow line.
+type slowLink struct {
+	*modbustest.Slave
+	delay time.Duration
+}
+
+func (l *slowLink) Send(adu []byte) ([]byte, error) {
+	time.Sleep(l.delay)
+	return l.Slave.Send(adu)
+}
+
+// printRequests prints the function, address and quantity of the
+// requests slave received.
+func printRequests(slave *modbustest.Slave) {
+	for _, pdu := range slave.Requests() {
+		fmt.Printf("function %d: % x\n", pdu.FunctionCode, pdu.Data[:4])
+	}
+}
+
+func ExampleWithLimits() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave, modbus.WithLimits(modbus.Limits{MaxReadQuantity: 2}))
+
+	_, err := client.BatchRead([]modbus.Read{
+		point{register: 10, t: types.Uint16Type},
+		point{register: 11, t: types.Uint16Type},
+		point{register: 12, t: types.Uint16Type},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRequests(slave)
+	// Output:
+	// function 3: 00 0a 00 02
+	// function 3: 00 0c 00 01
+}
+
+func ExampleWithMaxReadQuantity() {
+	_, err := modbus.Open(modbustest.NewSlave(), modbus.WithMaxReadQuantity(150))
+	fmt.Println(err)
+
+	slave := modbustest.NewSlave()
+	client, err := modbus.Open(slave, modbus.WithMaxReadQuantity(100))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// 150 registers don't fit a single request
+	if _, err := client.Read(0, types.NewRaw(150)); err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRequests(slave)
+	// Output:
+	// invalid option: max read quantity of 150 is out of 1-125
+	// function 3: 00 00 00 64
+	// function 3: 00 64 00 32
+}
+
+func ExampleWithMaxWriteQuantity() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave, modbus.WithMaxWriteQuantity(2))
+
+	err := client.BatchWrite([]modbus.Write{
+		point{register: 10, v: types.Uint32(1)},
+		point{register: 12, v: types.Uint32(2)},
+	}, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRequests(slave)
+	// Output:
+	// function 16: 00 0a 00 02
+	// function 16: 00 0c 00 02
+}
+
+func ExampleWithRetry() {
+	link := &lossyLink{Slave: modbustest.NewSlave(), drop: 1}
+	client := modbus.NewClient(link, modbus.WithRetry(modbus.Retry{Retries: 2, Delay: time.Millisecond}))
+
+	v, err := client.Read(10, types.Uint16Type)
+	fmt.Println(v, err)
+	// Output:
+	// 0 <nil>
+}
+
+func ExampleWithRetryPolicy() {
+	link := &lossyLink{Slave: modbustest.NewSlave(), drop: 2}
+	link.Seed(modbus.Registers{10: types.Uint16(7)})
+	client := modbus.NewClient(link, modbus.WithRetryPolicy(modbus.ExponentialBackoff{
+		Retries: 3,
+		Initial: time.Millisecond,
+		Max:     10 * time.Millisecond,
+	}))
+
+	v, err := client.Read(10, types.Uint16Type)
+	fmt.Println(v, err)
+
+	// exceptions are not retried by default
+	link.Disable(3)
+	_, err = client.Read(10, types.Uint16Type)
+	fmt.Println(err)
+	// Output:
+	// 7 <nil>
+	// modbus: exception '1' (illegal function), function '131'
+}
+
+func ExampleWithRequestSpacing() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave, modbus.WithRequestSpacing(20*time.Millisecond))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.Read(10, types.Uint16Type); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	fmt.Println(time.Since(start) >= 40*time.Millisecond)
+	// Output:
+	// true
+}
+
+func ExampleWithBatchTimeout() {
+	link := &slowLink{Slave: modbustest.NewSlave(), delay: 40 * time.Millisecond}
+	client := modbus.NewClient(link, modbus.WithBatchTimeout(60*time.Millisecond))
+	ops := []modbus.Read{
+		point{register: 10, t: types.Uint16Type},
+		point{register: 20, t: types.Uint16Type},
+		point{register: 30, t: types.Uint16Type},
+	}
+
+	_, err := client.BatchRead(ops)
+	var terr *modbus.BatchTimeoutError
+	if errors.As(err, &terr) {
+		fmt.Println(terr.Completed, "requests and", len(terr.Values), "values in time")
+	}
+
+	// a single batch may override the timeout
+	timeout := time.Duration(0)
+	_, err = client.BatchReadWith(ops, modbus.BatchOptions{Timeout: &timeout})
+	fmt.Println(err)
+	// Output:
+	// 2 requests and 2 values in time
+	// <nil>
+}
+
+// requestLog prints the requests a client sends and the ops merged
+// into them.
+type requestLog struct{}
+
+func (requestLog) BeforeRequest(context.Context, modbus.OpInfo) {}
+
+func (requestLog) AfterRequest(_ context.Context, op modbus.OpInfo, _ []byte, err error, _ time.Duration) {
+	fmt.Printf("function %d of %d registers at %d for %v: %v\n", op.Function, op.Quantity, op.Register, op.Ops, err)
+}
+
+func ExampleWithInterceptors() {
+	client := modbus.NewClient(modbustest.NewSlave(), modbus.WithInterceptors(requestLog{}))
+
+	_, err := client.BatchRead([]modbus.Read{
+		point{register: 10, t: types.Uint16Type},
+		point{register: 11, t: types.Uint32Type},
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// function 3 of 3 registers at 10 for [{10 1} {11 2}]: <nil>
+}
+
+func ExampleWithLogger() {
+	logger := modbus.LoggerFunc(func(_ context.Context, e modbus.LogEntry) {
+		fmt.Printf("%v: %s, function %d of %d registers at %d: %s\n", e.Level, e.Message, e.Function, e.Quantity, e.Register, e.Payload)
+	})
+	client := modbus.NewClient(modbustest.NewSlave(), modbus.WithLogger(logger))
+
+	if err := client.Write(10, types.Uint32(0xCAFE)); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// debug: request, function 16 of 2 registers at 10: 0000cafe
+}
+
+func ExampleWithReadCache() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave, modbus.WithReadCache(time.Minute))
+
+	for _, ops := range [][]modbus.Read{
+		{point{register: 10, t: types.Uint16Type}},
+		{point{register: 10, t: types.Uint16Type}, point{register: 11, t: types.Uint16Type}},
+	} {
+		if _, err := client.BatchRead(ops); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	printRequests(slave)
+	// Output:
+	// function 3: 00 0a 00 01
+	// function 3: 00 0b 00 01
+}
+
+func ExampleWithProbe() {
+	slave := modbustest.NewSlave()
+	if _, err := modbus.Open(slave, modbus.WithProbe(0)); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	link := &lossyLink{Slave: modbustest.NewSlave(), drop: 1}
+	_, err := modbus.Open(link, modbus.WithProbe(0))
+	fmt.Println(err)
+	// Output:
+	// probing register 0: timeout
+}
+
+func ExampleWithVerify() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{Retries: 2, Interval: time.Millisecond}))
+
+	if err := client.Write(10, types.Uint16(5)); err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRequests(slave)
+	// Output:
+	// function 16: 00 0a 00 01
+	// function 3: 00 0a 00 01
+}
+
+func ExampleWithStrictSpec() {
+	client := modbus.NewClient(modbustest.NewSlave(), modbus.WithStrictSpec(), modbus.WithCustomRegions(modbus.CustomRegion{RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10}}))
+	fmt.Println(client.CheckSpec())
+	// Output:
+	// modbus specification violated: custom region {100 10} uses vendor functions
+}
+
+func ExampleWithStore() {
+	s := store.NewMemory()
+	// both clients keep their write budget history in s
+	_ = modbus.NewClient(modbustest.NewSlave(), modbus.WithStore(store.WithPrefix(s, "boiler/"), nil))
+	_ = modbus.NewClient(modbustest.NewSlave(), modbus.WithStore(store.WithPrefix(s, "pump/"), nil))
+}
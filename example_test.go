@@ -0,0 +1,165 @@
+package modbus_test
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// point is a register of a device register map, usable both as Read
+// and as Write.
+type point struct {
+	register uint16
+	t        types.Type
+	v        types.Value
+}
+
+func (p point) Register() uint16   { return p.register }
+func (p point) Type() types.Type   { return p.t }
+func (p point) Value() types.Value { return p.v }
+
+// printRegisters prints values in register order.
+func printRegisters(res modbus.Registers) {
+	registers := make([]int, 0, len(res))
+	for r := range res {
+		registers = append(registers, int(r))
+	}
+	sort.Ints(registers)
+	for _, r := range registers {
+		fmt.Printf("%d: %v\n", r, res[uint16(r)])
+	}
+}
+
+func Example() {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{
+		100: types.Uint16(230),
+		101: types.Float32(49.98),
+		103: types.Int16(-12),
+		200: types.Bool(true),
+	})
+	client := modbus.NewClient(slave)
+
+	res, err := client.BatchRead([]modbus.Read{
+		point{register: 100, t: types.Uint16Type},
+		point{register: 101, t: types.Float32Type},
+		point{register: 103, t: types.Int16Type},
+		point{register: 200, t: types.BoolType},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRegisters(res)
+	fmt.Println("requests:", len(slave.Requests()))
+	// Output:
+	// 100: 230
+	// 101: 49.98
+	// 103: -12
+	// 200: true
+	// requests: 2
+}
+
+func ExampleClient_BatchWrite() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+	setpoints := []modbus.Write{
+		point{register: 10, v: types.Uint16(50)},
+		point{register: 11, v: types.Uint16(60)},
+		point{register: 12, v: types.Uint16(70)},
+	}
+	if err := client.BatchWrite(setpoints, nil); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// only the changed setpoint is written the second time
+	written := modbus.Registers{10: types.Uint16(50), 11: types.Uint16(60), 12: types.Uint16(70)}
+	setpoints[1] = point{register: 11, v: types.Uint16(65)}
+	if err := client.BatchWrite(setpoints, written); err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, pdu := range slave.Requests() {
+		fmt.Printf("function %d: % x\n", pdu.FunctionCode, pdu.Data)
+	}
+	// Output:
+	// function 16: 00 0a 00 03 06 00 32 00 3c 00 46
+	// function 16: 00 0b 00 01 02 00 41
+}
+
+func ExampleReadRequest() {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{100: types.Uint16(230), 101: types.Float32(49.98)})
+	client := modbus.NewClient(slave)
+
+	res, err := client.BatchRead([]modbus.Read{
+		modbus.ReadRequest{Address: 100, DataType: types.Uint16Type},
+		modbus.NewRead(101, types.Float32Type),
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRegisters(res)
+	// Output:
+	// 100: 230
+	// 101: 49.98
+}
+
+func ExampleWriteRequest() {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+
+	err := client.BatchWrite([]modbus.Write{
+		modbus.WriteRequest{Address: 10, Data: types.Uint16(50)},
+		modbus.NewWrite(11, types.Uint16(60)),
+	}, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, pdu := range slave.Requests() {
+		fmt.Printf("function %d: % x\n", pdu.FunctionCode, pdu.Data)
+	}
+	// Output:
+	// function 16: 00 0a 00 02 04 00 32 00 3c
+}
+
+func ExampleValidationRule() {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{10: types.Uint16(7), 11: types.Uint16(3), 20: types.Uint16(1)})
+	client := modbus.NewClient(slave, modbus.WithValidationRules(modbus.ValidationRule{
+		Name:  "min below max",
+		Range: modbus.RegisterRange{Register: 10, Quantity: 2},
+		Check: func(w modbus.Registers) error {
+			if w[10].(types.Uint16) > w[11].(types.Uint16) {
+				return errors.New("min exceeds max")
+			}
+			return nil
+		},
+	}))
+
+	res, err := client.BatchRead([]modbus.Read{
+		point{register: 10, t: types.Uint16Type},
+		point{register: 11, t: types.Uint16Type},
+		point{register: 20, t: types.Uint16Type},
+	})
+	var verr *modbus.ValidationError
+	if errors.As(err, &verr) {
+		for _, v := range verr.Violations {
+			fmt.Printf("dropped %s: %v\n", v.Rule.Name, v.Err)
+		}
+	} else if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printRegisters(res)
+	// Output:
+	// dropped min below max: min exceeds max
+	// 20: 1
+}
@@ -0,0 +1,77 @@
+// Command configpush pushes a configuration to a simulated device. It
+// reads the current configuration first, writes only the changed
+// registers, commits the change through an apply register and verifies
+// the result by reading it back.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+type setting struct {
+	register uint16
+	value    types.Value
+}
+
+func (s setting) Register() uint16   { return s.register }
+func (s setting) Value() types.Value { return s.value }
+func (s setting) Type() types.Type   { return s.value.(types.Type) }
+
+const applyRegister = 2999
+
+var config = []setting{
+	{2000, types.Uint16(1)},
+	{2001, types.Uint16(9600)},
+	{2002, types.NewBounded(types.Uint16Type, 0, 500).With(types.Uint16(250))},
+	{2010, types.DurationSeconds(30 * time.Second)},
+}
+
+func main() {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{2000: types.Uint16(1), 2001: types.Uint16(19200)})
+	client := modbus.NewClient(slave,
+		modbus.WithVerify(modbus.Verify{
+			Settle:   10 * time.Millisecond,
+			Retries:  3,
+			Interval: 10 * time.Millisecond,
+		}),
+		modbus.WithApplyRules(modbus.ApplyRule{
+			Guarded:  modbus.RegisterRange{Register: 2000, Quantity: 999},
+			Register: applyRegister,
+			Value:    types.Uint16(1),
+		}),
+	)
+
+	reads := make([]modbus.Read, len(config))
+	writes := make([]modbus.Write, len(config))
+	for i, s := range config {
+		reads[i], writes[i] = s, s
+	}
+	current, err := client.BatchRead(reads)
+	if err != nil {
+		log.Fatalf("reading the current configuration: %v", err)
+	}
+
+	before := len(slave.Requests())
+	err = client.BatchWrite(writes, current)
+	switch {
+	case errors.Is(err, types.ErrOutOfBounds):
+		log.Fatalf("invalid configuration: %v", err)
+	case errors.Is(err, modbus.ErrWriteVerificationFailed):
+		log.Fatalf("the device did not apply the configuration: %v", err)
+	case err != nil:
+		log.Fatalf("pushing the configuration: %v", err)
+	}
+
+	for _, pdu := range slave.Requests()[before:] {
+		fmt.Printf("function %d: % x\n", pdu.FunctionCode, pdu.Data)
+	}
+	fmt.Println("configuration applied")
+}
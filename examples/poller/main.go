@@ -0,0 +1,87 @@
+// Command poller periodically reads a block of measurements from a
+// simulated device and prints the values along with wire request
+// statistics.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+type measurement struct {
+	name     string
+	register uint16
+	t        types.Type
+}
+
+func (m measurement) Register() uint16 { return m.register }
+func (m measurement) Type() types.Type { return m.t }
+
+var measurements = []measurement{
+	{"voltage", 100, types.NewScaled(types.Uint16Type, 0.1, 0)},
+	{"current", 101, types.NewScaled(types.Uint16Type, 0.01, 0)},
+	{"frequency", 102, types.Float32Type},
+	{"energy", 200, types.Uint32Type},
+}
+
+func main() {
+	interval := flag.Duration("interval", time.Second, "polling interval")
+	count := flag.Int("count", 3, "number of polls")
+	flag.Parse()
+
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave,
+		modbus.WithRetry(modbus.Retry{Retries: 2, Delay: 100 * time.Millisecond}),
+		modbus.WithLatencyRanges(
+			modbus.RegisterRange{Register: 100, Quantity: 100},
+			modbus.RegisterRange{Register: 200, Quantity: 100},
+		),
+	)
+	ops := make([]modbus.Read, len(measurements))
+	for i, m := range measurements {
+		ops[i] = m
+	}
+
+	for i := 0; i < *count; i++ {
+		simulate(slave, i)
+		res, err := client.BatchRead(ops)
+		if err != nil {
+			log.Printf("poll %d: %v", i+1, err)
+		}
+		for _, m := range measurements {
+			v, ok := res[m.register]
+			if !ok {
+				continue
+			}
+			if x, ok := types.Float64Of(v); ok {
+				fmt.Printf("%s=%.6g ", m.name, x)
+			} else {
+				fmt.Printf("%s=%v ", m.name, v)
+			}
+		}
+		fmt.Println()
+		time.Sleep(*interval)
+	}
+
+	fmt.Printf("%d wire requests for %d polls of %d values\n", len(slave.Requests()), *count, len(ops))
+	for _, s := range client.Latencies() {
+		fmt.Printf("registers %d-%d: %d requests, p50 %v, max %v\n",
+			s.Range.Register, int(s.Range.Register)+int(s.Range.Quantity)-1, s.Count, s.P50, s.Max)
+	}
+}
+
+// simulate updates the device registers for poll i.
+func simulate(slave *modbustest.Slave, i int) {
+	slave.Seed(modbus.Registers{
+		100: types.Uint16(2300 + i),
+		101: types.Uint16(1250 - 10*i),
+		102: types.Float32(49.95 + 0.01*float32(i)),
+		200: types.Uint32(100000 + 42*i),
+	})
+}
@@ -0,0 +1,69 @@
+// Command scanner looks for units on an RS-485 bus by reading an
+// identification block from every unit address in turn.
+//
+//	scanner -port /dev/ttyUSB0 -baud 9600 -register 0 -quantity 4
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/goburrow/modbus"
+	opmodbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+type block struct {
+	register uint16
+	t        types.Type
+}
+
+func (b block) Register() uint16 { return b.register }
+func (b block) Type() types.Type { return b.t }
+
+func main() {
+	port := flag.String("port", "/dev/ttyUSB0", "serial port")
+	baud := flag.Int("baud", 9600, "baud rate")
+	first := flag.Int("first", 1, "first unit address")
+	last := flag.Int("last", 247, "last unit address")
+	register := flag.Uint("register", 0, "first register of the identification block")
+	quantity := flag.Uint("quantity", 4, "registers in the identification block")
+	timeout := flag.Duration("timeout", 200*time.Millisecond, "response timeout per unit")
+	flag.Parse()
+
+	handler := modbus.NewRTUClientHandler(*port)
+	handler.BaudRate = *baud
+	handler.DataBits = 8
+	handler.Parity = "N"
+	handler.StopBits = 1
+	handler.Timeout = *timeout
+
+	// one client per bus: every batch is addressed to its unit, the
+	// client switches the handler slave id under its mutex
+	client, err := opmodbus.Open(handler)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer handler.Close()
+	id := []opmodbus.Read{block{uint16(*register), types.NewRaw(uint16(*quantity))}}
+	found := 0
+	for unit := *first; unit <= *last; unit++ {
+		res, err := client.BatchReadUnit(byte(unit), id)
+		var exception *modbus.ModbusError
+		switch {
+		case errors.As(err, &exception):
+			// the unit is present but has no such block
+			fmt.Printf("unit %d: present, %v\n", unit, exception)
+			found++
+		case err != nil:
+			continue
+		default:
+			fmt.Printf("unit %d: %v\n", unit, res[uint16(*register)])
+			found++
+		}
+	}
+	fmt.Printf("%d units found\n", found)
+}
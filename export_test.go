@@ -0,0 +1,20 @@
+package modbus
+
+import "time"
+
+// Test helpers exported to package modbus_test.
+
+type ChaosOptions = chaosOptions
+
+var SweepChaos = sweepChaos
+
+// Violations returns the guarantees violated by c so far. It is always
+// empty without the opmodbus_check build tag.
+func Violations(c *Client) []string {
+	return c.invariants.report()
+}
+
+// SetSWRClock makes the BatchReadSWR cache of c take the time from now.
+func SetSWRClock(c *Client, now func() time.Time) {
+	c.swr.now = now
+}
@@ -0,0 +1,176 @@
+package modbus
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// Snapshot holds values of registers read at the same time.
+type Snapshot struct {
+	Time   time.Time
+	Values Registers
+}
+
+// TimedValue is a value of a register at a point in time.
+type TimedValue struct {
+	Time  time.Time
+	Value types.Value
+}
+
+// HistoryOptions bounds a History. Zero fields mean no bound.
+type HistoryOptions struct {
+	// MaxSnapshots is the number of snapshots kept.
+	MaxSnapshots int
+	// MaxAge is the time span of snapshots kept, relative to the most
+	// recent one.
+	MaxAge time.Duration
+	// MaxBytes bounds the accounted memory of snapshots kept. A snapshot
+	// is accounted for 2 bytes per register plus the bytes of its
+	// values. See History.Size.
+	MaxBytes int
+}
+
+// History is a bounded buffer of recent snapshots, answering what
+// registers read at a given time. It is safe for concurrent use.
+//
+// When a bound is exceeded, the oldest snapshots are evicted until all
+// the bounds hold again. The most recent snapshot is never evicted.
+type History struct {
+	opts      HistoryOptions
+	mtx       sync.Mutex
+	snapshots []Snapshot
+	sizes     []int
+	size      int
+}
+
+// NewHistory returns an empty history bounded by opts.
+func NewHistory(opts HistoryOptions) *History {
+	return &History{opts: opts}
+}
+
+// Record adds a snapshot. Snapshots must be recorded in time order;
+// a snapshot older than the most recent one is ignored. Nil values are
+// not kept.
+func (h *History) Record(s Snapshot) {
+	values := make(Registers, len(s.Values))
+	size := 0
+	for register, value := range s.Values {
+		if value == nil {
+			continue
+		}
+		values[register] = value
+		size += 2 + len(value.Bytes())
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if n := len(h.snapshots); n > 0 && s.Time.Before(h.snapshots[n-1].Time) {
+		return
+	}
+	h.snapshots = append(h.snapshots, Snapshot{s.Time, values})
+	h.sizes = append(h.sizes, size)
+	h.size += size
+	for len(h.snapshots) > 1 && h.exceeded() {
+		h.size -= h.sizes[0]
+		// release references before dropping the element
+		h.snapshots[0] = Snapshot{}
+		h.snapshots, h.sizes = h.snapshots[1:], h.sizes[1:]
+	}
+}
+
+func (h *History) exceeded() bool {
+	n := len(h.snapshots)
+	switch {
+	case h.opts.MaxSnapshots > 0 && n > h.opts.MaxSnapshots:
+		return true
+	case h.opts.MaxAge > 0 && h.snapshots[n-1].Time.Sub(h.snapshots[0].Time) > h.opts.MaxAge:
+		return true
+	case h.opts.MaxBytes > 0 && h.size > h.opts.MaxBytes:
+		return true
+	}
+	return false
+}
+
+// Len returns the number of snapshots kept.
+func (h *History) Len() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return len(h.snapshots)
+}
+
+// Size returns the accounted memory of snapshots kept, in bytes.
+func (h *History) Size() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.size
+}
+
+// At returns the most recent snapshot taken at or before t.
+func (h *History) At(t time.Time) (Snapshot, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i := sort.Search(len(h.snapshots), func(i int) bool { return h.snapshots[i].Time.After(t) })
+	if i == 0 {
+		return Snapshot{}, false
+	}
+	return h.snapshots[i-1], true
+}
+
+// Series returns the values of register in snapshots taken between from
+// and to inclusive, oldest first. Snapshots without the register are
+// skipped.
+func (h *History) Series(register uint16, from, to time.Time) []TimedValue {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	var res []TimedValue
+	i := sort.Search(len(h.snapshots), func(i int) bool { return !h.snapshots[i].Time.Before(from) })
+	for ; i < len(h.snapshots) && !h.snapshots[i].Time.After(to); i++ {
+		if value, ok := h.snapshots[i].Values[register]; ok {
+			res = append(res, TimedValue{h.snapshots[i].Time, value})
+		}
+	}
+	return res
+}
+
+type jsonValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Bytes string `json:"bytes"`
+}
+
+type jsonSnapshot struct {
+	Time   time.Time            `json:"time"`
+	Values map[string]jsonValue `json:"values"`
+}
+
+// MarshalJSON encodes the snapshots kept, oldest first. Values are
+// encoded with their Go type, their formatted value and their bytes in
+// hex.
+func (h *History) MarshalJSON() ([]byte, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	res := make([]jsonSnapshot, len(h.snapshots))
+	for i, s := range h.snapshots {
+		values := make(map[string]jsonValue, len(s.Values))
+		for register, value := range s.Values {
+			values[strconv.Itoa(int(register))] = jsonValue{
+				Type:  fmt.Sprintf("%T", value),
+				Value: fmt.Sprint(value),
+				Bytes: hex.EncodeToString(value.Bytes()),
+			}
+		}
+		res[i] = jsonSnapshot{s.Time, values}
+	}
+	return json.Marshal(res)
+}
@@ -0,0 +1,126 @@
+package modbus_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+var historyEpoch = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func snapshotAt(seconds int, values modbus.Registers) modbus.Snapshot {
+	return modbus.Snapshot{Time: historyEpoch.Add(time.Duration(seconds) * time.Second), Values: values}
+}
+
+func TestHistory_lookup(t *testing.T) {
+	h := modbus.NewHistory(modbus.HistoryOptions{})
+	h.Record(snapshotAt(0, modbus.Registers{4001: types.Uint16(1)}))
+	h.Record(snapshotAt(60, modbus.Registers{4001: types.Uint16(2), 4002: types.Uint16(7)}))
+	h.Record(snapshotAt(120, modbus.Registers{4001: types.Uint16(3)}))
+	h.Record(snapshotAt(90, modbus.Registers{4001: types.Uint16(9)}))
+	assert.Equal(t, 3, h.Len(), "out of order snapshots are ignored")
+
+	_, ok := h.At(historyEpoch.Add(-time.Second))
+	assert.False(t, ok)
+	s, ok := h.At(historyEpoch.Add(119 * time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, snapshotAt(60, modbus.Registers{4001: types.Uint16(2), 4002: types.Uint16(7)}), s)
+	s, ok = h.At(historyEpoch.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, types.Uint16(3), s.Values[4001])
+
+	assert.Equal(t, []modbus.TimedValue{
+		{historyEpoch.Add(60 * time.Second), types.Uint16(2)},
+		{historyEpoch.Add(120 * time.Second), types.Uint16(3)},
+	}, h.Series(4001, historyEpoch.Add(time.Second), historyEpoch.Add(120*time.Second)))
+	assert.Equal(t, []modbus.TimedValue{
+		{historyEpoch.Add(60 * time.Second), types.Uint16(7)},
+	}, h.Series(4002, historyEpoch, historyEpoch.Add(time.Hour)))
+	assert.Nil(t, h.Series(4003, historyEpoch, historyEpoch.Add(time.Hour)))
+}
+
+func TestHistory_eviction(t *testing.T) {
+	large := make(modbus.Registers, 10000)
+	for r := 0; r < 10000; r++ {
+		large[uint16(r)] = types.Uint32(r)
+	}
+	const snapshotSize = 10000 * (2 + 4)
+
+	tests := []struct {
+		name  string
+		opts  modbus.HistoryOptions
+		first int
+		len   int
+	}{
+		{"unbounded", modbus.HistoryOptions{}, 0, 10},
+		{"snapshots", modbus.HistoryOptions{MaxSnapshots: 3}, 7, 3},
+		{"age", modbus.HistoryOptions{MaxAge: 4 * time.Second}, 5, 5},
+		{"memory", modbus.HistoryOptions{MaxBytes: 2*snapshotSize + 1}, 8, 2},
+		{"memory below a snapshot", modbus.HistoryOptions{MaxBytes: 10}, 9, 1},
+		{"tightest bound wins", modbus.HistoryOptions{MaxSnapshots: 4, MaxBytes: 3 * snapshotSize}, 7, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := modbus.NewHistory(tt.opts)
+			for i := 0; i < 10; i++ {
+				h.Record(snapshotAt(i, large))
+			}
+			assert.Equal(t, tt.len, h.Len())
+			assert.Equal(t, tt.len*snapshotSize, h.Size())
+			_, ok := h.At(historyEpoch.Add(time.Duration(tt.first)*time.Second - 1))
+			assert.False(t, ok, "older snapshots are evicted")
+			_, ok = h.At(historyEpoch.Add(time.Duration(tt.first) * time.Second))
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestHistory_nilValues(t *testing.T) {
+	h := modbus.NewHistory(modbus.HistoryOptions{MaxBytes: 100})
+	h.Record(snapshotAt(0, modbus.Registers{4001: types.Uint16(1), 4002: nil}))
+	assert.Equal(t, 1, h.Len())
+	assert.Equal(t, 4, h.Size(), "nil values are not accounted for")
+	s, ok := h.At(historyEpoch)
+	assert.True(t, ok)
+	assert.Equal(t, modbus.Registers{4001: types.Uint16(1)}, s.Values, "nor kept")
+	_, err := h.MarshalJSON()
+	assert.NoError(t, err)
+}
+
+func TestHistory_concurrent(t *testing.T) {
+	h := modbus.NewHistory(modbus.HistoryOptions{MaxSnapshots: 10})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			h.Record(snapshotAt(i, modbus.Registers{1: types.Uint16(i)}))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			h.At(historyEpoch.Add(time.Duration(i) * time.Second))
+			h.Series(1, historyEpoch, historyEpoch.Add(time.Hour))
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, 10, h.Len())
+}
+
+func TestHistory_MarshalJSON(t *testing.T) {
+	h := modbus.NewHistory(modbus.HistoryOptions{})
+	h.Record(snapshotAt(0, modbus.Registers{4001: types.Uint16(10)}))
+
+	b, err := json.Marshal(h)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"time": "2021-03-01T12:00:00Z",
+		"values": {"4001": {"type": "types.Uint16", "value": "10", "bytes": "000a"}}
+	}]`, string(b))
+}
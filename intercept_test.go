@@ -0,0 +1,20 @@
+package modbus
+
+import (
+	"testing"
+
+	"github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntercept_allocs(t *testing.T) {
+	c := &Client{}
+	info := OpInfo{Function: modbus.FuncCodeReadHoldingRegisters, RegisterRange: RegisterRange{10, 2}}
+	response := []byte{0, 1, 0, 2}
+	send := func() ([]byte, error) { return response, nil }
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = c.intercept(info, nil, send)
+	})
+	assert.Zero(t, allocs, "without interceptors and logger")
+}
@@ -0,0 +1,140 @@
+package modbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// Interceptor observes the register requests a client sends: requests
+// of functions 3, 4, 16, 22 and 23, and fetches of custom regions.
+// BeforeRequest is called right before a request is sent and
+// AfterRequest once it completes, with the response data and the
+// duration of the round trip. Retried requests are intercepted once per
+// attempt.
+//
+// ctx is the context of the operation, bounded by its batch timeout if
+// any, see WithBatchTimeout. Interceptors are called with the client
+// mutex held, must not use the client and must not block past
+// cancellation of ctx.
+type Interceptor interface {
+	BeforeRequest(ctx context.Context, op OpInfo)
+	AfterRequest(ctx context.Context, op OpInfo, result []byte, err error, d time.Duration)
+}
+
+// OpInfo describes a request seen by an Interceptor.
+type OpInfo struct {
+	// Function is the function code of the request, or zero for fetches
+	// of custom regions.
+	Function byte
+	Space    Space
+	// RegisterRange is the registers of the request, or its coils or
+	// discrete inputs for functions 1, 2 and 15. For function 23, it is
+	// the read registers and Written the written ones.
+	RegisterRange
+	Written RegisterRange
+	// Batch is set for requests of batch operations.
+	Batch bool
+	// Ops are the ops of the operation the request serves, before
+	// merging: for batches, the ops overlapping the request, otherwise
+	// the registers of the request.
+	Ops []RegisterRange
+}
+
+// WithInterceptors adds interceptors to the client. Interceptors are
+// called in the order they were added, for BeforeRequest and
+// AfterRequest alike.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Config) {
+		c.Interceptors = append(c.Interceptors[:len(c.Interceptors):len(c.Interceptors)], interceptors...)
+	}
+}
+
+// track makes the requests sent until untrack is called appear as
+// requests of a batch of cfg.batch to the interceptors, accounts for
+// them in cfg.stats and records the attempted writes in cfg.sent. The
+// caller must hold the client mutex.
+func (c *Client) track(cfg batchConfig) (untrack func()) {
+	c.batching, c.batchOps, c.batchStats, c.sent = true, cfg.batch, cfg.stats, cfg.sent
+	return func() {
+		c.batching, c.batchOps, c.batchStats, c.sent = false, nil, nil, nil
+	}
+}
+
+// bind makes ctx the context of the requests sent until unbind is
+// called, as passed to the interceptors and the logger. The caller must
+// hold the client mutex.
+func (c *Client) bind(ctx context.Context) (unbind func()) {
+	previous := c.ctx
+	c.ctx = ctx
+	return func() {
+		c.ctx = previous
+	}
+}
+
+// intercept sends the request of info writing value, if any, with
+// send, passing it through the interceptors and the logger. The caller
+// must hold the client mutex.
+func (c *Client) intercept(info OpInfo, value []byte, send func() ([]byte, error)) ([]byte, error) {
+	if s := c.batchStats; s != nil {
+		s.Requests++
+		s.Registers += int(info.Quantity) + int(info.Written.Quantity)
+	}
+	interceptors, logger := c.config.Interceptors, c.config.Logger
+	if len(interceptors) == 0 && logger == nil {
+		return send()
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	info.Batch = c.batching
+	info.Ops = c.mergedOps(info)
+	if logger != nil && c.attempt > 1 {
+		c.logRequest(ctx, info, nil, nil, 0, false)
+	}
+	for _, i := range interceptors {
+		i.BeforeRequest(ctx, info)
+	}
+	start := time.Now()
+	b, err := send()
+	d := time.Since(start)
+	for _, i := range interceptors {
+		i.AfterRequest(ctx, info, b, err, d)
+	}
+	if logger != nil {
+		if value == nil {
+			value = b
+		}
+		c.logRequest(ctx, info, value, err, d, true)
+	}
+	return b, err
+}
+
+// mergedOps returns the ops of the current batch served by the request
+// of info.
+func (c *Client) mergedOps(info OpInfo) []RegisterRange {
+	if !c.batching {
+		return []RegisterRange{info.RegisterRange}
+	}
+	var ops []RegisterRange
+	for _, op := range c.batchOps {
+		if op.space != info.Space {
+			continue
+		}
+		if r := (RegisterRange{op.register, op.quantity}); r.Overlaps(info.RegisterRange) || info.Function == modbus.FuncCodeReadWriteMultipleRegisters && r.Overlaps(info.Written) {
+			ops = append(ops, r)
+		}
+	}
+	return ops
+}
+
+// interceptedOps converts write ops to the ops tracked for interceptors.
+func interceptedOps(ops []writeOp) []readOp {
+	res := make([]readOp, len(ops))
+	for i, op := range ops {
+		res[i] = readOp{op.register, op.quantity, HoldingRegisters}
+	}
+	return res
+}
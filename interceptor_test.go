@@ -0,0 +1,179 @@
+package modbus_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// recorder is an Interceptor logging the calls it gets under a name.
+type recorder struct {
+	name string
+	log  *[]string
+	ops  []modbus.OpInfo
+	errs []error
+	ctxs []context.Context
+}
+
+func (r *recorder) BeforeRequest(ctx context.Context, op modbus.OpInfo) {
+	*r.log = append(*r.log, fmt.Sprintf("%s before %d", r.name, op.Register))
+}
+
+func (r *recorder) AfterRequest(ctx context.Context, op modbus.OpInfo, _ []byte, err error, d time.Duration) {
+	*r.log = append(*r.log, fmt.Sprintf("%s after %d", r.name, op.Register))
+	r.ops = append(r.ops, op)
+	r.errs = append(r.errs, err)
+	r.ctxs = append(r.ctxs, ctx)
+}
+
+func TestWithInterceptors(t *testing.T) {
+	slave := newTestSlave()
+	var log []string
+	first, second := &recorder{name: "first", log: &log}, &recorder{name: "second", log: &log}
+	client := modbus.NewClient(slave, modbus.WithInterceptors(first), modbus.WithInterceptors(second))
+
+	_, err := client.BatchRead([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint32Type},
+		testRead{20, types.Uint16Type},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"first before 10", "second before 10", "first after 10", "second after 10",
+		"first before 20", "second before 20", "first after 20", "second after 20",
+	}, log)
+	assert.Equal(t, first.ops, second.ops)
+	assert.Equal(t, []modbus.OpInfo{
+		{
+			Function:      goburrow.FuncCodeReadHoldingRegisters,
+			RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 3},
+			Batch:         true,
+			Ops:           []modbus.RegisterRange{{Register: 10, Quantity: 1}, {Register: 11, Quantity: 2}},
+		},
+		{
+			Function:      goburrow.FuncCodeReadHoldingRegisters,
+			RegisterRange: modbus.RegisterRange{Register: 20, Quantity: 1},
+			Batch:         true,
+			Ops:           []modbus.RegisterRange{{Register: 20, Quantity: 1}},
+		},
+	}, first.ops)
+}
+
+func TestWithInterceptors_requests(t *testing.T) {
+	tests := []struct {
+		name string
+		do   func(c *modbus.Client) error
+		want []modbus.OpInfo
+	}{
+		{"write", func(c *modbus.Client) error {
+			return c.Write(10, types.Uint32(1))
+		}, []modbus.OpInfo{{
+			Function:      goburrow.FuncCodeWriteMultipleRegisters,
+			RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 2},
+			Ops:           []modbus.RegisterRange{{Register: 10, Quantity: 2}},
+		}}},
+		{"batch write", func(c *modbus.Client) error {
+			return c.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{11, types.Uint16(2)}}, nil)
+		}, []modbus.OpInfo{{
+			Function:      goburrow.FuncCodeWriteMultipleRegisters,
+			RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 2},
+			Batch:         true,
+			Ops:           []modbus.RegisterRange{{Register: 10, Quantity: 1}, {Register: 11, Quantity: 1}},
+		}}},
+		{"input registers", func(c *modbus.Client) error {
+			_, err := c.BatchRead([]modbus.Read{modbus.InputRead(testRead{10, types.Uint16Type})})
+			return err
+		}, []modbus.OpInfo{{
+			Function:      goburrow.FuncCodeReadInputRegisters,
+			Space:         modbus.InputRegisters,
+			RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 1},
+			Batch:         true,
+			Ops:           []modbus.RegisterRange{{Register: 10, Quantity: 1}},
+		}}},
+		{"mask write", func(c *modbus.Client) error {
+			return c.MaskWrite(10, 0xFF00, 1)
+		}, []modbus.OpInfo{{
+			Function:      goburrow.FuncCodeMaskWriteRegister,
+			RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 1},
+			Ops:           []modbus.RegisterRange{{Register: 10, Quantity: 1}},
+		}}},
+		{"read-write", func(c *modbus.Client) error {
+			_, err := c.BatchReadWrite([]modbus.Read{testRead{20, types.Uint16Type}}, []modbus.Write{testWrite{10, types.Uint16(1)}}, nil)
+			return err
+		}, []modbus.OpInfo{{
+			Function:      goburrow.FuncCodeReadWriteMultipleRegisters,
+			RegisterRange: modbus.RegisterRange{Register: 20, Quantity: 1},
+			Written:       modbus.RegisterRange{Register: 10, Quantity: 1},
+			Batch:         true,
+			Ops:           []modbus.RegisterRange{{Register: 20, Quantity: 1}, {Register: 10, Quantity: 1}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log []string
+			r := &recorder{log: &log}
+			client := modbus.NewClient(modbustest.NewSlave(), modbus.WithInterceptors(r))
+			assert.NoError(t, tt.do(client))
+			assert.Equal(t, tt.want, r.ops)
+		})
+	}
+}
+
+func TestWithInterceptors_retry(t *testing.T) {
+	slave := newTestSlave()
+	slave.failures = 1
+	var log []string
+	r := &recorder{log: &log}
+	client := modbus.NewClient(slave, modbus.WithInterceptors(r), modbus.WithRetry(modbus.Retry{Retries: 1}))
+
+	_, err := client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+	if assert.Len(t, r.errs, 2, "every attempt is intercepted") {
+		assert.Error(t, r.errs[0])
+		assert.NoError(t, r.errs[1])
+	}
+}
+
+func TestWithInterceptors_context(t *testing.T) {
+	var log []string
+	r := &recorder{log: &log}
+	var logged []context.Context
+	logger := modbus.LoggerFunc(func(ctx context.Context, _ modbus.LogEntry) {
+		logged = append(logged, ctx)
+	})
+	var validated context.Context
+	client := modbus.NewClient(newTestSlave(), modbus.WithInterceptors(r), modbus.WithLogger(logger),
+		modbus.WithBatchTimeout(time.Minute), modbus.WithValidationRules(modbus.ValidationRule{
+			Range: modbus.RegisterRange{Register: 10, Quantity: 1},
+			CheckContext: func(ctx context.Context, _ modbus.Registers) error {
+				validated = ctx
+				return ctx.Err()
+			},
+		}))
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "trace")
+	_, err := client.BatchReadWith([]modbus.Read{testRead{10, types.Uint16Type}}, modbus.BatchOptions{Context: ctx})
+	assert.NoError(t, err)
+	if assert.Len(t, r.ctxs, 1) && assert.Len(t, logged, 1) && assert.NotNil(t, validated) {
+		deadline, ok := r.ctxs[0].Deadline()
+		assert.True(t, ok, "the context is bounded by the batch timeout")
+		for _, ctx := range []context.Context{r.ctxs[0], logged[0], validated} {
+			assert.Equal(t, "trace", ctx.Value(contextKey{}))
+			d, _ := ctx.Deadline()
+			assert.Equal(t, deadline, d)
+		}
+	}
+
+	_, err = client.ReadContext(ctx, 10, types.Uint16Type)
+	assert.NoError(t, err)
+	if assert.Len(t, r.ctxs, 2) {
+		assert.Equal(t, "trace", r.ctxs[1].Value(contextKey{}))
+	}
+}
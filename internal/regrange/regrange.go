@@ -0,0 +1,112 @@
+// Package regrange implements arithmetic on ranges of Modbus registers
+// that is safe near the end of the address space: no operation
+// overflows uint16.
+package regrange
+
+import "fmt"
+
+// Space is the number of addressable registers.
+const Space = 65536
+
+// Range is a contiguous range of Quantity registers starting at Start.
+// A range may extend past the address space, see Valid and Clamp.
+type Range struct {
+	Start    uint16
+	Quantity uint16
+}
+
+// End returns the register following the last one of r, which is Space
+// for ranges ending at register 65535, or more for invalid ranges.
+func (r Range) End() int {
+	return int(r.Start) + int(r.Quantity)
+}
+
+// Last returns the last register of r, or false if r is empty or the
+// last register is outside of the address space.
+func (r Range) Last() (uint16, bool) {
+	if r.Empty() || r.End() > Space {
+		return 0, false
+	}
+	return uint16(r.End() - 1), true
+}
+
+// Empty reports whether r has no registers.
+func (r Range) Empty() bool {
+	return r.Quantity == 0
+}
+
+// Valid reports whether r fits the address space.
+func (r Range) Valid() bool {
+	return r.End() <= Space
+}
+
+// Contains reports whether register belongs to r.
+func (r Range) Contains(register uint16) bool {
+	return register >= r.Start && int(register) < r.End()
+}
+
+// ContainsRange reports whether every register of o belongs to r. An
+// empty o is contained in any range.
+func (r Range) ContainsRange(o Range) bool {
+	return o.Empty() || (o.Start >= r.Start && o.End() <= r.End())
+}
+
+// Overlaps reports whether r and o have a register in common. Empty
+// ranges overlap nothing.
+func (r Range) Overlaps(o Range) bool {
+	return !r.Empty() && !o.Empty() && int(r.Start) < o.End() && int(o.Start) < r.End()
+}
+
+// Adjacent reports whether o starts right after the end of r.
+func (r Range) Adjacent(o Range) bool {
+	return r.End() == int(o.Start)
+}
+
+// Merge returns the union of r and o if they overlap or are adjacent in
+// either order, and the union's quantity fits uint16.
+func (r Range) Merge(o Range) (Range, bool) {
+	if !r.Overlaps(o) && !r.Adjacent(o) && !o.Adjacent(r) {
+		return Range{}, false
+	}
+	start, end := r.Start, r.End()
+	if o.Start < start {
+		start = o.Start
+	}
+	if o.End() > end {
+		end = o.End()
+	}
+	if end-int(start) > 0xFFFF {
+		return Range{}, false
+	}
+	return Range{start, uint16(end - int(start))}, true
+}
+
+// SplitAt splits r into the registers before register and the rest.
+// If register is outside of r, one of the parts is an empty range
+// starting at register.
+func (r Range) SplitAt(register uint16) (Range, Range) {
+	switch {
+	case register <= r.Start:
+		return Range{register, 0}, r
+	case int(register) >= r.End():
+		return r, Range{register, 0}
+	}
+	n := register - r.Start
+	return Range{r.Start, n}, Range{register, r.Quantity - n}
+}
+
+// Clamp returns r truncated to the address space.
+func (r Range) Clamp() Range {
+	if r.End() > Space {
+		return Range{r.Start, uint16(Space - int(r.Start))}
+	}
+	return r
+}
+
+// String formats r as its first and last registers.
+func (r Range) String() string {
+	if r.Empty() {
+		return fmt.Sprintf("%d (empty)", r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End()-1)
+}
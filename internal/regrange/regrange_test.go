@@ -0,0 +1,156 @@
+package regrange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRange_End(t *testing.T) {
+	tests := []struct {
+		r     Range
+		end   int
+		last  uint16
+		ok    bool
+		valid bool
+	}{
+		{Range{10, 5}, 15, 14, true, true},
+		{Range{10, 0}, 10, 0, false, true},
+		{Range{0, 0}, 0, 0, false, true},
+		{Range{65535, 1}, Space, 65535, true, true},
+		{Range{65535, 0}, 65535, 0, false, true},
+		{Range{65535, 2}, Space + 1, 0, false, false},
+		{Range{0, 65535}, 65535, 65534, true, true},
+		{Range{1, 65535}, Space, 65535, true, true},
+		{Range{65535, 65535}, 131070, 0, false, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.end, tt.r.End(), "%+v", tt.r)
+		last, ok := tt.r.Last()
+		assert.Equal(t, tt.last, last, "%+v", tt.r)
+		assert.Equal(t, tt.ok, ok, "%+v", tt.r)
+		assert.Equal(t, tt.valid, tt.r.Valid(), "%+v", tt.r)
+	}
+}
+
+func TestRange_Contains(t *testing.T) {
+	tests := []struct {
+		r        Range
+		register uint16
+		want     bool
+	}{
+		{Range{10, 5}, 9, false},
+		{Range{10, 5}, 10, true},
+		{Range{10, 5}, 14, true},
+		{Range{10, 5}, 15, false},
+		{Range{10, 0}, 10, false},
+		{Range{65535, 1}, 65535, true},
+		{Range{65534, 1}, 65535, false},
+		{Range{65535, 2}, 0, false},
+		{Range{65535, 2}, 65535, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.r.Contains(tt.register), "%+v contains %d", tt.r, tt.register)
+	}
+}
+
+func TestRange_ContainsRange(t *testing.T) {
+	tests := []struct {
+		r, o Range
+		want bool
+	}{
+		{Range{10, 5}, Range{10, 5}, true},
+		{Range{10, 5}, Range{11, 2}, true},
+		{Range{10, 5}, Range{9, 2}, false},
+		{Range{10, 5}, Range{14, 2}, false},
+		{Range{10, 5}, Range{100, 0}, true},
+		{Range{10, 0}, Range{10, 1}, false},
+		{Range{65530, 6}, Range{65535, 1}, true},
+		{Range{65530, 6}, Range{65535, 2}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.r.ContainsRange(tt.o), "%+v contains %+v", tt.r, tt.o)
+	}
+}
+
+func TestRange_Overlaps(t *testing.T) {
+	tests := []struct {
+		r, o Range
+		want bool
+	}{
+		{Range{10, 5}, Range{14, 1}, true},
+		{Range{10, 5}, Range{15, 1}, false},
+		{Range{10, 5}, Range{5, 5}, false},
+		{Range{10, 5}, Range{5, 6}, true},
+		{Range{10, 5}, Range{11, 0}, false},
+		{Range{11, 0}, Range{10, 5}, false},
+		{Range{65535, 1}, Range{65534, 2}, true},
+		{Range{65535, 1}, Range{0, 1}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.r.Overlaps(tt.o), "%+v overlaps %+v", tt.r, tt.o)
+		assert.Equal(t, tt.want, tt.o.Overlaps(tt.r), "%+v overlaps %+v", tt.o, tt.r)
+	}
+}
+
+func TestRange_Merge(t *testing.T) {
+	tests := []struct {
+		r, o Range
+		want Range
+		ok   bool
+	}{
+		{Range{10, 5}, Range{15, 5}, Range{10, 10}, true},
+		{Range{15, 5}, Range{10, 5}, Range{10, 10}, true},
+		{Range{10, 5}, Range{12, 10}, Range{10, 12}, true},
+		{Range{10, 5}, Range{11, 1}, Range{10, 5}, true},
+		{Range{10, 5}, Range{16, 5}, Range{}, false},
+		{Range{10, 5}, Range{15, 0}, Range{10, 5}, true},
+		{Range{65534, 1}, Range{65535, 1}, Range{65534, 2}, true},
+		{Range{65535, 1}, Range{0, 1}, Range{}, false},
+		{Range{0, 65535}, Range{65535, 1}, Range{}, false},
+		{Range{1, 65535}, Range{0, 1}, Range{0, 65535}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.r.Merge(tt.o)
+		assert.Equal(t, tt.ok, ok, "%+v merge %+v", tt.r, tt.o)
+		if tt.ok {
+			assert.Equal(t, tt.want, got, "%+v merge %+v", tt.r, tt.o)
+		}
+	}
+}
+
+func TestRange_SplitAt(t *testing.T) {
+	tests := []struct {
+		r           Range
+		register    uint16
+		left, right Range
+	}{
+		{Range{10, 5}, 12, Range{10, 2}, Range{12, 3}},
+		{Range{10, 5}, 10, Range{10, 0}, Range{10, 5}},
+		{Range{10, 5}, 5, Range{5, 0}, Range{10, 5}},
+		{Range{10, 5}, 15, Range{10, 5}, Range{15, 0}},
+		{Range{10, 5}, 20, Range{10, 5}, Range{20, 0}},
+		{Range{65530, 6}, 65535, Range{65530, 5}, Range{65535, 1}},
+		{Range{10, 0}, 10, Range{10, 0}, Range{10, 0}},
+	}
+	for _, tt := range tests {
+		left, right := tt.r.SplitAt(tt.register)
+		assert.Equal(t, tt.left, left, "%+v split at %d", tt.r, tt.register)
+		assert.Equal(t, tt.right, right, "%+v split at %d", tt.r, tt.register)
+		assert.Equal(t, int(tt.r.Quantity), int(left.Quantity)+int(right.Quantity))
+	}
+}
+
+func TestRange_Clamp(t *testing.T) {
+	assert.Equal(t, Range{10, 5}, Range{10, 5}.Clamp())
+	assert.Equal(t, Range{65535, 1}, Range{65535, 1}.Clamp())
+	assert.Equal(t, Range{65535, 1}, Range{65535, 100}.Clamp())
+	assert.Equal(t, Range{65000, 536}, Range{65000, 65535}.Clamp())
+	assert.Equal(t, Range{0, 65535}, Range{0, 65535}.Clamp())
+}
+
+func TestRange_String(t *testing.T) {
+	assert.Equal(t, "10-14", Range{10, 5}.String())
+	assert.Equal(t, "65535-65535", Range{65535, 1}.String())
+	assert.Equal(t, "10 (empty)", Range{10, 0}.String())
+}
@@ -0,0 +1,22 @@
+//go:build !opmodbus_check
+// +build !opmodbus_check
+
+package modbus
+
+import "github.com/goburrow/modbus"
+
+// invariants checks Guarantees in builds with the opmodbus_check tag,
+// and does nothing otherwise.
+type invariants struct{}
+
+func (*invariants) enter() {}
+
+func (*invariants) exit() {}
+
+func (*invariants) transporter(h modbus.ClientHandler) modbus.Transporter {
+	return h
+}
+
+func (*invariants) report() []string {
+	return nil
+}
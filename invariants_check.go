@@ -0,0 +1,146 @@
+//go:build opmodbus_check
+// +build opmodbus_check
+
+package modbus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/goburrow/modbus"
+)
+
+// invariants checks Guarantees on every wire request and records the
+// violations.
+type invariants struct {
+	mtx sync.Mutex
+	// id of the operation holding the client mutex, 0 if none
+	active uint64
+	last   uint64
+	// id of the operation that made the last wire request
+	lastWire uint64
+	// id of the operation that last wrote a register or a coil
+	writes     map[address]uint64
+	violations []string
+}
+
+func (v *invariants) enter() {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if v.active != 0 {
+		v.violate("operation %d entered while operation %d holds the client mutex", v.last+1, v.active)
+	}
+	v.last++
+	v.active = v.last
+}
+
+func (v *invariants) exit() {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.active = 0
+}
+
+func (v *invariants) violate(format string, args ...interface{}) {
+	v.violations = append(v.violations, fmt.Sprintf(format, args...))
+}
+
+func (v *invariants) transporter(h modbus.ClientHandler) modbus.Transporter {
+	if h == nil {
+		return nil
+	}
+	return checkedTransporter{h, v}
+}
+
+// check checks an encoded request against the guarantees.
+func (v *invariants) check(packager modbus.Packager, adu []byte) {
+	pdu, err := packager.Decode(adu)
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if err != nil {
+		v.violate("undecodable request % X: %v", adu, err)
+		return
+	}
+	if v.active == 0 {
+		v.violate("function %d request outside of an operation", pdu.FunctionCode)
+		return
+	}
+	if v.active < v.lastWire {
+		v.violate("function %d request of operation %d after a request of operation %d", pdu.FunctionCode, v.active, v.lastWire)
+	}
+	v.lastWire = v.active
+	w, coils, ok := written(pdu)
+	if !ok {
+		return
+	}
+	if v.writes == nil {
+		v.writes = make(map[address]uint64)
+	}
+	for i := uint16(0); i < w.Quantity; i++ {
+		a := address{w.Register + i, coils}
+		if prev := v.writes[a]; prev > v.active {
+			v.violate("write of %v by operation %d after a write by operation %d", a, v.active, prev)
+		}
+		v.writes[a] = v.active
+	}
+}
+
+// address is a register or a coil written by a request.
+type address struct {
+	address uint16
+	coil    bool
+}
+
+func (a address) String() string {
+	if a.coil {
+		return fmt.Sprintf("coil %d", a.address)
+	}
+	return fmt.Sprintf("register %d", a.address)
+}
+
+// written returns the registers or coils written by pdu.
+func written(pdu *modbus.ProtocolDataUnit) (_ RegisterRange, coils, ok bool) {
+	d := pdu.Data
+	at := func(i int) uint16 {
+		return uint16(d[i])<<8 | uint16(d[i+1])
+	}
+	switch pdu.FunctionCode {
+	case modbus.FuncCodeWriteSingleRegister, modbus.FuncCodeMaskWriteRegister:
+		if len(d) >= 2 {
+			return RegisterRange{at(0), 1}, false, true
+		}
+	case modbus.FuncCodeWriteSingleCoil:
+		if len(d) >= 2 {
+			return RegisterRange{at(0), 1}, true, true
+		}
+	case modbus.FuncCodeWriteMultipleRegisters:
+		if len(d) >= 4 {
+			return RegisterRange{at(0), at(2)}, false, true
+		}
+	case modbus.FuncCodeWriteMultipleCoils:
+		if len(d) >= 4 {
+			return RegisterRange{at(0), at(2)}, true, true
+		}
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		if len(d) >= 8 {
+			return RegisterRange{at(4), at(6)}, false, true
+		}
+	}
+	return RegisterRange{}, false, false
+}
+
+func (v *invariants) report() []string {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return append([]string(nil), v.violations...)
+}
+
+// checkedTransporter checks requests before sending them.
+type checkedTransporter struct {
+	handler    modbus.ClientHandler
+	invariants *invariants
+}
+
+func (t checkedTransporter) Send(adu []byte) ([]byte, error) {
+	t.invariants.check(t.handler, adu)
+	return t.handler.Send(adu)
+}
@@ -0,0 +1,62 @@
+package modbus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ErrNoType is returned by Registers.UnmarshalJSONWithTypes for
+// registers without a type.
+var ErrNoType = errors.New("no type for register")
+
+// MarshalJSON encodes the registers as an object keyed by decimal
+// register numbers, with values in their JSON representation, see
+// types.UnmarshalValue. Unavailable values are encoded as null.
+func (r Registers) MarshalJSON() ([]byte, error) {
+	res := make(map[string]interface{}, len(r))
+	for register, value := range r {
+		res[strconv.Itoa(int(register))] = value
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSONWithTypes decodes registers encoded by MarshalJSON, the
+// value of every register being of the type in t. Null values are
+// skipped. Decoded registers are added to r.
+func (r *Registers) UnmarshalJSONWithTypes(b []byte, t map[uint16]types.Type) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if *r == nil {
+		*r = make(Registers, len(raw))
+	}
+	for key, value := range raw {
+		register, err := strconv.ParseUint(key, 10, 16)
+		if err != nil {
+			return fmt.Errorf("register %q: %w", key, err)
+		}
+		if string(value) == "null" {
+			continue
+		}
+		typ, ok := t[uint16(register)]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrNoType, register)
+		}
+		v, err := types.UnmarshalValue(typ, value)
+		if err != nil {
+			return fmt.Errorf("register %d: %w", register, err)
+		}
+		(*r)[uint16(register)] = v
+	}
+	return nil
+}
+
+// MarshalJSON encodes u as null.
+func (u Unavailable) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
@@ -0,0 +1,45 @@
+package modbus_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestRegisters_JSON(t *testing.T) {
+	level := types.NewScaled(types.Uint16Type, 0.5, -10)
+	hints := map[uint16]types.Type{
+		10: types.Uint16Type,
+		11: types.Float32Type,
+		13: types.Float32CDABType,
+		15: level,
+		16: types.BoolType,
+	}
+	res := modbus.Registers{
+		10: types.Uint16(42),
+		11: types.Float32(1.5),
+		13: types.Float32CDAB(-2),
+		15: level.With(240),
+		16: types.Bool(true),
+		20: modbus.Unavailable{Op: testRead{20, types.Uint16Type}},
+	}
+
+	b, err := json.Marshal(res)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"10": 42, "11": 1.5, "13": -2, "15": 240, "16": true, "20": null}`, string(b))
+
+	var got modbus.Registers
+	assert.NoError(t, got.UnmarshalJSONWithTypes(b, hints))
+	delete(res, 20)
+	assert.Equal(t, res, got)
+
+	delete(hints, 16)
+	err = got.UnmarshalJSONWithTypes(b, hints)
+	assert.True(t, errors.Is(err, modbus.ErrNoType), "%v", err)
+	err = got.UnmarshalJSONWithTypes([]byte(`{"x": 1}`), hints)
+	assert.Error(t, err)
+}
@@ -0,0 +1,177 @@
+package modbus
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyStats holds response time statistics of the wire requests
+// attributed to a register range. Percentiles are estimated from a
+// histogram and are accurate to about 10%.
+type LatencyStats struct {
+	Range RegisterRange
+	// Other is set for the bucket that collects requests not
+	// attributed to any other range.
+	Other bool
+
+	Count uint64
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+const (
+	// maxLatencyBuckets limits the number of register ranges tracked
+	// at once; requests beyond the limit go to the Other bucket
+	maxLatencyBuckets = 64
+
+	latencyBins   = 128
+	latencyMin    = 100 * time.Microsecond
+	latencyGrowth = 1.15
+)
+
+// latencyHistogram is a fixed-size histogram with exponentially
+// growing bins.
+type latencyHistogram struct {
+	bins  [latencyBins]uint64
+	count uint64
+	max   time.Duration
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	bin := 0
+	if d > latencyMin {
+		bin = int(math.Log(float64(d)/float64(latencyMin)) / math.Log(latencyGrowth))
+		if bin >= latencyBins {
+			bin = latencyBins - 1
+		}
+	}
+	h.bins[bin]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// add merges the observations of other into h.
+func (h *latencyHistogram) add(other *latencyHistogram) {
+	for bin, n := range other.bins {
+		h.bins[bin] += n
+	}
+	h.count += other.count
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// percentile returns an estimate of p-th (0 < p <= 1) percentile.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p * float64(h.count)))
+	var seen uint64
+	for bin, n := range h.bins {
+		seen += n
+		if seen < rank {
+			continue
+		}
+		// geometric middle of the bin; the first bin also holds
+		// everything below latencyMin, so it is reported as latencyMin
+		d := time.Duration(float64(latencyMin) * math.Pow(latencyGrowth, float64(bin)+0.5))
+		if bin == 0 {
+			d = latencyMin
+		}
+		if d > h.max {
+			d = h.max
+		}
+		return d
+	}
+	return h.max
+}
+
+// latencyTracker attributes wire request latencies to register ranges.
+// If no ranges are configured, every distinct wire request range gets
+// its own bucket.
+// WithLatencyRanges adds register ranges to collect response time
+// statistics for. A wire request is attributed to the first range
+// containing its start register. Without latency ranges, every
+// distinct wire request range is tracked on its own. See Latencies.
+func WithLatencyRanges(ranges ...RegisterRange) Option {
+	return func(c *Config) {
+		c.LatencyRanges = append(c.LatencyRanges[:len(c.LatencyRanges):len(c.LatencyRanges)], ranges...)
+	}
+}
+
+type latencyTracker struct {
+	mtx     sync.Mutex
+	buckets map[RegisterRange]*latencyHistogram
+	other   latencyHistogram
+}
+
+func (t *latencyTracker) observe(ranges []RegisterRange, register, quantity uint16, d time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	key, ok := RegisterRange{register, quantity}, true
+	if ranges != nil {
+		ok = false
+		for _, r := range ranges {
+			if r.Contains(register) {
+				key, ok = r, true
+				break
+			}
+		}
+	}
+	if !ok {
+		t.other.observe(d)
+		return
+	}
+
+	if t.buckets == nil {
+		t.buckets = make(map[RegisterRange]*latencyHistogram)
+	}
+	h, ok := t.buckets[key]
+	if !ok {
+		if len(t.buckets) >= maxLatencyBuckets {
+			t.other.observe(d)
+			return
+		}
+		h = new(latencyHistogram)
+		t.buckets[key] = h
+	}
+	h.observe(d)
+}
+
+func (t *latencyTracker) stats() []LatencyStats {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	res := make([]LatencyStats, 0, len(t.buckets)+1)
+	for r, h := range t.buckets {
+		res = append(res, h.stats(r))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i].Range, res[j].Range
+		return a.Register < b.Register || a.Register == b.Register && a.Quantity < b.Quantity
+	})
+	if t.other.count > 0 {
+		other := t.other.stats(RegisterRange{})
+		other.Other = true
+		res = append(res, other)
+	}
+
+	return res
+}
+
+func (h *latencyHistogram) stats(r RegisterRange) LatencyStats {
+	return LatencyStats{
+		Range: r,
+		Count: h.count,
+		P50:   h.percentile(0.5),
+		P95:   h.percentile(0.95),
+		Max:   h.max,
+	}
+}
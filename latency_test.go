@@ -0,0 +1,76 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_latencyTracker(t *testing.T) {
+	ranges := []RegisterRange{{100, 50}, {1000, 10}}
+	var tracker latencyTracker
+	// fast block: 1..100 ms, slow block: 800 ms with a spike
+	for i := 1; i <= 100; i++ {
+		tracker.observe(ranges, 110, 4, time.Duration(i)*time.Millisecond)
+	}
+	for i := 0; i < 19; i++ {
+		tracker.observe(ranges, 1002, 2, 800*time.Millisecond)
+	}
+	tracker.observe(ranges, 1000, 10, 3*time.Second)
+	tracker.observe(ranges, 5000, 1, time.Millisecond)
+
+	stats := tracker.stats()
+	if !assert.Len(t, stats, 3) {
+		return
+	}
+	within := func(want, got time.Duration) {
+		assert.InEpsilon(t, float64(want), float64(got), 0.1, "want %v, got %v", want, got)
+	}
+
+	fast := stats[0]
+	assert.Equal(t, RegisterRange{100, 50}, fast.Range)
+	assert.EqualValues(t, 100, fast.Count)
+	within(50*time.Millisecond, fast.P50)
+	within(95*time.Millisecond, fast.P95)
+	assert.Equal(t, 100*time.Millisecond, fast.Max)
+
+	slow := stats[1]
+	assert.Equal(t, RegisterRange{1000, 10}, slow.Range)
+	assert.EqualValues(t, 20, slow.Count)
+	within(800*time.Millisecond, slow.P50)
+	within(800*time.Millisecond, slow.P95)
+	assert.Equal(t, 3*time.Second, slow.Max)
+
+	assert.True(t, stats[2].Other)
+	assert.EqualValues(t, 1, stats[2].Count)
+}
+
+func Test_latencyTracker_bounded(t *testing.T) {
+	var tracker latencyTracker
+	for i := 0; i < maxLatencyBuckets*2; i++ {
+		tracker.observe(nil, uint16(i*10), 2, time.Millisecond)
+	}
+
+	stats := tracker.stats()
+	assert.Len(t, stats, maxLatencyBuckets+1)
+	assert.Equal(t, RegisterRange{0, 2}, stats[0].Range)
+	assert.True(t, stats[maxLatencyBuckets].Other)
+	assert.EqualValues(t, maxLatencyBuckets, stats[maxLatencyBuckets].Count)
+}
+
+func Test_latencyHistogram_fastSamples(t *testing.T) {
+	var h latencyHistogram
+	for i := 0; i < 99; i++ {
+		h.observe(50 * time.Microsecond)
+	}
+	h.observe(5 * time.Second)
+
+	assert.Equal(t, latencyMin, h.percentile(0.5), "fast samples are reported as latencyMin, not the outlier")
+	assert.Equal(t, latencyMin, h.percentile(0.95))
+	assert.Equal(t, 5*time.Second, h.percentile(1))
+
+	var fast latencyHistogram
+	fast.observe(20 * time.Microsecond)
+	assert.Equal(t, 20*time.Microsecond, fast.percentile(0.5), "capped at the max")
+}
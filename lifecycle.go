@@ -0,0 +1,230 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClientClosed is returned by operations on a client that was shut
+// down.
+var ErrClientClosed = errors.New("client is shut down")
+
+// ShutdownError lists everything that failed or didn't finish in time
+// during Shutdown.
+type ShutdownError struct {
+	Errors []error
+}
+
+func (e *ShutdownError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "shutdown: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches target.
+func (e *ShutdownError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShutdownPolicy decides on the batches in flight during Shutdown, see
+// WithShutdownPolicy.
+type ShutdownPolicy int
+
+const (
+	// ShutdownComplete lets the batch in flight complete, and write
+	// queues write their pending windows. This is the default.
+	ShutdownComplete ShutdownPolicy = iota
+	// ShutdownCancel cancels the context of the batch in flight, and of
+	// the batches the components run while they are stopped. They stop
+	// before their next wire request with an error wrapping
+	// ErrClientClosed; a request in flight is not interrupted. Pending
+	// windows of write queues fail the same way.
+	ShutdownCancel
+)
+
+// WithShutdownPolicy sets what Shutdown does with the batches in
+// flight. Operations other than batches, such as Read and Write, are a
+// single wire request and always complete.
+func WithShutdownPolicy(p ShutdownPolicy) Option {
+	return func(c *Config) {
+		c.ShutdownPolicy = p
+	}
+}
+
+type shutdownHook struct {
+	name string
+	stop func(context.Context) error
+}
+
+// OnShutdown registers a component of the client to be stopped by
+// Shutdown. Components are stopped in reverse order of registration,
+// after in-flight operations of the client have finished. Pollers and
+// write queues register themselves.
+func (c *Client) OnShutdown(name string, stop func(context.Context) error) {
+	c.hooksMtx.Lock()
+	defer c.hooksMtx.Unlock()
+	c.hooks = append(c.hooks, shutdownHook{name, stop})
+}
+
+// Shutdown stops the client. All new operations fail with
+// ErrClientClosed right away. Shutdown then waits for the operations in
+// flight to complete, stops the components registered with OnShutdown,
+// and closes the handler if it implements io.Closer.
+//
+// With ShutdownCancel, the batch in flight is canceled first, see
+// WithShutdownPolicy. If ctx is done before an in-flight operation
+// completes, Shutdown proceeds without waiting for it. Everything that failed or didn't
+// finish in time is reported with a *ShutdownError.
+func (c *Client) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return ErrClientClosed
+	}
+
+	if c.config.ShutdownPolicy == ShutdownCancel {
+		c.aborts.abort()
+	}
+	var errs []error
+	idle := make(chan struct{})
+	go func() {
+		c.mtx.Lock()
+		close(idle)
+		c.mtx.Unlock()
+	}()
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("waiting for in-flight operations: %w", ctx.Err()))
+	}
+
+	c.hooksMtx.Lock()
+	hooks := c.hooks
+	c.hooks = nil
+	c.hooksMtx.Unlock()
+	atomic.StoreInt32(&c.stopping, 1)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := stopHook(ctx, hooks[i]); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", hooks[i].name, err))
+		}
+	}
+	atomic.StoreInt32(&c.stopping, 0)
+
+	if closer, ok := c.ClientHandler.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing handler: %w", err))
+		}
+	}
+
+	if errs != nil {
+		return &ShutdownError{errs}
+	}
+	return nil
+}
+
+// abortTracker cancels the contexts of batches once Shutdown aborts
+// them, see ShutdownCancel.
+type abortTracker struct {
+	mtx     sync.Mutex
+	aborted bool
+	cancel  context.CancelFunc
+}
+
+// track returns ctx canceled once batches are aborted, and a function
+// releasing it. As batches hold the client mutex, at most one is in
+// flight.
+func (t *abortTracker) track(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.aborted {
+		cancel()
+	} else {
+		t.cancel = cancel
+	}
+	return ctx, func() {
+		t.mtx.Lock()
+		t.cancel = nil
+		t.mtx.Unlock()
+		cancel()
+	}
+}
+
+// abort cancels the batch in flight and those started afterwards.
+func (t *abortTracker) abort() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.aborted = true
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// err converts err caused by an abort into an error wrapping
+// ErrClientClosed.
+func (t *abortTracker) err(err error) error {
+	t.mtx.Lock()
+	aborted := t.aborted
+	t.mtx.Unlock()
+	if !aborted || !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: batch canceled: %v", ErrClientClosed, err)
+}
+
+// stopHook runs the stop function of a component, converting a panic
+// into an error.
+func stopHook(ctx context.Context, hook shutdownHook) (err error) {
+	defer recoverPanic(&err)
+	return hook.stop(ctx)
+}
+
+// lock acquires the client mutex unless the client is shut down, and
+// connects the handler if needed. Every operation making wire requests
+// holds the mutex from lock to unlock, see Guarantees.
+func (c *Client) lock() error {
+	return c.lockFor(batchConfig{})
+}
+
+// lockFor is lock for a batch of cfg, which is admitted while Shutdown
+// stops the components if cfg.drain is set.
+func (c *Client) lockFor(cfg batchConfig) error {
+	if c.rejects(cfg) {
+		return ErrClientClosed
+	}
+	c.mtx.Lock()
+	c.invariants.enter()
+	if c.rejects(cfg) {
+		c.unlock()
+		return ErrClientClosed
+	}
+	if err := c.connect(); err != nil {
+		c.unlock()
+		return err
+	}
+	return nil
+}
+
+// rejects reports whether a batch of cfg fails with ErrClientClosed.
+func (c *Client) rejects(cfg batchConfig) bool {
+	if atomic.LoadInt32(&c.closed) == 0 {
+		return false
+	}
+	return !cfg.drain || atomic.LoadInt32(&c.stopping) == 0
+}
+
+// unlock releases the client mutex acquired with lock.
+func (c *Client) unlock() {
+	c.invariants.exit()
+	c.mtx.Unlock()
+}
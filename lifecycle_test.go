@@ -0,0 +1,175 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// longBatch observes the requests of a batch reading registers from
+// first on.
+type longBatch struct {
+	first    uint16
+	once     sync.Once
+	started  chan struct{}
+	requests int32
+}
+
+func (b *longBatch) BeforeRequest(_ context.Context, op modbus.OpInfo) {
+	if op.Register >= b.first {
+		b.once.Do(func() { close(b.started) })
+		atomic.AddInt32(&b.requests, 1)
+	}
+}
+
+func (b *longBatch) AfterRequest(context.Context, modbus.OpInfo, []byte, error, time.Duration) {}
+
+func TestClient_Shutdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy modbus.ShutdownPolicy
+	}{
+		{"complete", modbus.ShutdownComplete},
+		{"cancel", modbus.ShutdownCancel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+			slave := newTestSlave()
+			slave.delay = time.Millisecond
+			long := &longBatch{first: 1000, started: make(chan struct{})}
+			client := modbus.NewClient(slave, modbus.WithShutdownPolicy(tt.policy), modbus.WithInterceptors(long))
+
+			// a background component stopped by the client
+			ticks := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				for {
+					select {
+					case <-ticks:
+						return
+					default:
+						_ = client.Write(20, types.Uint16(1))
+					}
+				}
+			}()
+			var order []string
+			client.OnShutdown("ticker", func(ctx context.Context) error {
+				close(ticks)
+				<-stopped
+				order = append(order, "ticker")
+				return nil
+			})
+			client.OnShutdown("second", func(ctx context.Context) error {
+				order = append(order, "second")
+				return nil
+			})
+			poller, err := modbus.NewPoller(client, []modbus.Read{testRead{30, types.Uint16Type}}, time.Millisecond)
+			assert.NoError(t, err)
+			assert.NoError(t, poller.Start(context.Background()))
+			polled := make(chan struct{})
+			go func() {
+				defer close(polled)
+				for range poller.Results() {
+				}
+			}()
+			queue := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: time.Hour})
+			queued := queue.Submit(context.Background(), testWrite{40, types.Uint16(7)})
+
+			var wg sync.WaitGroup
+			errs := make([]error, 4)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for {
+						_, err := client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}})
+						if err != nil {
+							errs[i] = err
+							return
+						}
+					}
+				}(i)
+			}
+			// a batch of 10 requests in flight during Shutdown
+			var longErr error
+			longOps := make([]modbus.Read, 10)
+			for i := range longOps {
+				longOps[i] = testRead{uint16(1000 + 2*i), types.Uint16Type}
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, longErr = client.BatchRead(longOps)
+			}()
+
+			<-long.started
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			assert.NoError(t, client.Shutdown(ctx))
+			wg.Wait()
+			<-polled
+			for _, err := range errs {
+				assert.ErrorIs(t, err, modbus.ErrClientClosed)
+			}
+			assert.Equal(t, []string{"second", "ticker"}, order)
+			assert.ErrorIs(t, client.Shutdown(ctx), modbus.ErrClientClosed)
+			_, err = client.Read(10, types.Uint16Type)
+			assert.ErrorIs(t, err, modbus.ErrClientClosed)
+
+			if tt.policy == modbus.ShutdownCancel {
+				assert.ErrorIs(t, longErr, modbus.ErrClientClosed, "the batch in flight is canceled")
+				assert.Less(t, int(atomic.LoadInt32(&long.requests)), len(longOps))
+				assert.ErrorIs(t, <-queued, modbus.ErrClientClosed, "so is the pending window")
+				assert.Equal(t, []byte{0, 0}, slave.get(40, 1))
+			} else {
+				assert.NoError(t, longErr, "the batch in flight completes")
+				assert.Equal(t, len(longOps), int(atomic.LoadInt32(&long.requests)))
+				assert.NoError(t, <-queued, "so does the pending window")
+				assert.Equal(t, []byte{0, 7}, slave.get(40, 1))
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "goroutines left behind")
+		})
+	}
+}
+
+func TestClient_Shutdown_timeout(t *testing.T) {
+	slave := newTestSlave()
+	slave.delay = 200 * time.Millisecond
+	client := modbus.NewClient(slave)
+	failure := errors.New("stuck")
+	client.OnShutdown("stuck", func(ctx context.Context) error {
+		return failure
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = client.Read(10, types.Uint16Type)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := client.Shutdown(ctx)
+	var shutdownErr *modbus.ShutdownError
+	if assert.True(t, errors.As(err, &shutdownErr)) {
+		assert.Len(t, shutdownErr.Errors, 2)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, failure)
+	<-done
+}
@@ -0,0 +1,118 @@
+package modbus
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+)
+
+// max bytes of payload logged
+const maxLoggedPayload = 16
+
+// LogLevel is the severity of a LogEntry.
+type LogLevel int
+
+const (
+	// LogDebug entries report completed requests.
+	LogDebug LogLevel = iota
+	// LogWarn entries report failed and retried requests.
+	LogWarn
+)
+
+func (l LogLevel) String() string {
+	if l == LogWarn {
+		return "warn"
+	}
+	return "debug"
+}
+
+// LogEntry describes a register request, as intercepted by an
+// Interceptor.
+type LogEntry struct {
+	Level    LogLevel
+	Message  string
+	Function byte
+	RegisterRange
+	// Payload is the hex data of the request for writes and of the
+	// response otherwise, truncated to 16 bytes.
+	Payload  string
+	Duration time.Duration
+	// Attempt counts the attempts of the request from 1.
+	Attempt int
+	Err     error
+}
+
+// Logger receives the entries logged by a client, see WithLogger. ctx
+// is the context of the operation, as passed to an Interceptor.
+type Logger interface {
+	Log(ctx context.Context, e LogEntry)
+}
+
+// LoggerFunc adapts a function to Logger.
+type LoggerFunc func(ctx context.Context, e LogEntry)
+
+func (f LoggerFunc) Log(ctx context.Context, e LogEntry) {
+	f(ctx, e)
+}
+
+// WithLogger makes the client log its register requests to l: a debug
+// entry per completed request, and warn entries for failed attempts
+// and before retries. Logging is off by default.
+//
+// Loggers are called with the client mutex held and must not use the
+// client.
+func WithLogger(l Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+// attempts performs f with retry under the context of the requests,
+// counting the attempts for the log. The caller must hold the client
+// mutex.
+func (c *Client) attempts(retry Retry, f func() error) error {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.config.Logger == nil {
+		return retry.do(ctx, f)
+	}
+	defer func() { c.attempt = 0 }()
+	return retry.do(ctx, func() error {
+		c.attempt++
+		return f()
+	})
+}
+
+// logRequest logs a request of info once it completed, or before it is
+// sent if done is not set. payload is the written data for writes and
+// the response otherwise.
+func (c *Client) logRequest(ctx context.Context, info OpInfo, payload []byte, err error, d time.Duration, done bool) {
+	e := LogEntry{
+		Level:         LogDebug,
+		Message:       "request",
+		Function:      info.Function,
+		RegisterRange: info.RegisterRange,
+		Attempt:       c.attempt,
+	}
+	if e.Attempt == 0 {
+		e.Attempt = 1
+	}
+	switch {
+	case !done:
+		e.Level, e.Message = LogWarn, "retrying request"
+	case err != nil:
+		e.Level, e.Message, e.Err, e.Duration = LogWarn, "request failed", err, d
+	default:
+		e.Payload, e.Duration = loggedPayload(payload), d
+	}
+	c.config.Logger.Log(ctx, e)
+}
+
+func loggedPayload(b []byte) string {
+	if len(b) <= maxLoggedPayload {
+		return hex.EncodeToString(b)
+	}
+	return hex.EncodeToString(b[:maxLoggedPayload]) + "..."
+}
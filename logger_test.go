@@ -0,0 +1,72 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// capturingLogger records the entries it gets.
+type capturingLogger struct {
+	entries []modbus.LogEntry
+}
+
+func (l *capturingLogger) Log(_ context.Context, e modbus.LogEntry) {
+	l.entries = append(l.entries, e)
+}
+
+func TestWithLogger(t *testing.T) {
+	slave := newTestSlave()
+	for r := uint16(0); r < 20; r++ {
+		slave.set(10+r, 0, byte(r))
+	}
+	logger := &capturingLogger{}
+	client := modbus.NewClient(slave, modbus.WithLogger(logger))
+
+	_, err := client.BatchRead([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint64Type},
+		testRead{15, types.Uint64Type},
+		testRead{19, types.Uint16Type},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, logger.entries, 1, "one merged read") {
+		e := logger.entries[0]
+		assert.Greater(t, int64(e.Duration), int64(0))
+		e.Duration = 0
+		assert.Equal(t, modbus.LogEntry{
+			Level:         modbus.LogDebug,
+			Message:       "request",
+			Function:      3,
+			RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 10},
+			Payload:       "00000001000200030004000500060007...",
+			Attempt:       1,
+		}, e)
+	}
+}
+
+func TestWithLogger_retry(t *testing.T) {
+	slave := newTestSlave()
+	slave.failures = 1
+	logger := &capturingLogger{}
+	client := modbus.NewClient(slave, modbus.WithLogger(logger), modbus.WithRetry(modbus.Retry{Retries: 1}))
+
+	assert.NoError(t, client.Write(10, types.Uint16(0xABCD)))
+	levels := make([]modbus.LogLevel, len(logger.entries))
+	messages := make([]string, len(logger.entries))
+	for i, e := range logger.entries {
+		levels[i], messages[i] = e.Level, e.Message
+	}
+	assert.Equal(t, []modbus.LogLevel{modbus.LogWarn, modbus.LogWarn, modbus.LogDebug}, levels)
+	assert.Equal(t, []string{"request failed", "retrying request", "request"}, messages)
+	if assert.Len(t, logger.entries, 3) {
+		assert.True(t, errors.Is(logger.entries[0].Err, errTransport), "%v", logger.entries[0].Err)
+		assert.Equal(t, 1, logger.entries[0].Attempt)
+		assert.Equal(t, 2, logger.entries[1].Attempt)
+		assert.Equal(t, "abcd", logger.entries[2].Payload, "written data")
+	}
+}
@@ -0,0 +1,208 @@
+package modbustest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goburrow/modbus"
+	opmodbus "github.com/tdemin/opmodbus"
+)
+
+// WireExpectation declares a single expected wire request.
+type WireExpectation struct {
+	Function byte
+	Register uint16
+	Quantity uint16
+	// Payload is the expected register data of a write request if not
+	// nil. For function 22 it holds the AND and OR masks, for function
+	// 15 the packed coils, for function 23 the write address followed by
+	// the register data.
+	Payload []byte
+	// Match reports whether the register data of a write request is
+	// acceptable if not nil.
+	Match func(payload []byte) bool
+}
+
+// Read expects a function 3 request.
+func Read(register, quantity uint16) WireExpectation {
+	return WireExpectation{Function: modbus.FuncCodeReadHoldingRegisters, Register: register, Quantity: quantity}
+}
+
+// ReadInput expects a function 4 request.
+func ReadInput(register, quantity uint16) WireExpectation {
+	return WireExpectation{Function: modbus.FuncCodeReadInputRegisters, Register: register, Quantity: quantity}
+}
+
+// ReadCoils expects a function 1 request.
+func ReadCoils(coil, quantity uint16) WireExpectation {
+	return WireExpectation{Function: modbus.FuncCodeReadCoils, Register: coil, Quantity: quantity}
+}
+
+// ReadDiscrete expects a function 2 request.
+func ReadDiscrete(input, quantity uint16) WireExpectation {
+	return WireExpectation{Function: modbus.FuncCodeReadDiscreteInputs, Register: input, Quantity: quantity}
+}
+
+// WriteCoils expects a function 15 request writing quantity coils
+// packed in payload.
+func WriteCoils(coil, quantity uint16, payload ...byte) WireExpectation {
+	return WireExpectation{
+		Function: modbus.FuncCodeWriteMultipleCoils,
+		Register: coil,
+		Quantity: quantity,
+		Payload:  payload,
+	}
+}
+
+// Write expects a function 16 request writing payload.
+func Write(register uint16, payload ...byte) WireExpectation {
+	return WireExpectation{
+		Function: modbus.FuncCodeWriteMultipleRegisters,
+		Register: register,
+		Quantity: uint16(len(payload) / 2),
+		Payload:  payload,
+	}
+}
+
+// ReadWrite expects a function 23 request reading quantity registers at
+// register after writing payload at write.
+func ReadWrite(register, quantity, write uint16, payload ...byte) WireExpectation {
+	return WireExpectation{
+		Function: modbus.FuncCodeReadWriteMultipleRegisters,
+		Register: register,
+		Quantity: quantity,
+		Payload:  append([]byte{byte(write >> 8), byte(write)}, payload...),
+	}
+}
+
+func (e WireExpectation) String() string {
+	s := fmt.Sprintf("function %d at %d, %d registers", e.Function, e.Register, e.Quantity)
+	switch {
+	case e.Payload != nil:
+		s += fmt.Sprintf(", payload % x", e.Payload)
+	case e.Match != nil:
+		s += ", payload matching predicate"
+	}
+	return s
+}
+
+func (e WireExpectation) matches(got WireExpectation) bool {
+	if e.Function != got.Function || e.Register != got.Register || e.Quantity != got.Quantity {
+		return false
+	}
+	if e.Payload != nil && !bytes.Equal(e.Payload, got.Payload) {
+		return false
+	}
+	return e.Match == nil || e.Match(got.Payload)
+}
+
+// decode describes a recorded request the same way as expectations.
+func decode(pdu modbus.ProtocolDataUnit) WireExpectation {
+	e := WireExpectation{Function: pdu.FunctionCode}
+	if len(pdu.Data) < 4 {
+		e.Payload = pdu.Data
+		return e
+	}
+	e.Register = binary.BigEndian.Uint16(pdu.Data[0:2])
+	e.Quantity = binary.BigEndian.Uint16(pdu.Data[2:4])
+	switch pdu.FunctionCode {
+	case modbus.FuncCodeWriteMultipleRegisters, modbus.FuncCodeWriteMultipleCoils:
+		if len(pdu.Data) > 5 {
+			e.Payload = pdu.Data[5:]
+		}
+	case modbus.FuncCodeMaskWriteRegister:
+		e.Quantity = 1
+		e.Payload = pdu.Data[2:]
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		if len(pdu.Data) > 9 {
+			e.Payload = append(append([]byte(nil), pdu.Data[4:6]...), pdu.Data[9:]...)
+		}
+	}
+	return e
+}
+
+// ExpectPlan performs BatchRead of ops with client and checks that the
+// requests it made match expected, in order. The client handler must be
+// a Recorder. The read results are returned for further checks.
+func ExpectPlan(t testing.TB, client *opmodbus.Client, ops []opmodbus.Read, expected []WireExpectation) opmodbus.Registers {
+	t.Helper()
+	recorder := recorderOf(t, client)
+	before := len(recorder.Requests())
+	res, err := client.BatchRead(ops)
+	if err != nil {
+		t.Errorf("BatchRead: %v", err)
+	}
+	expectRequests(t, recorder.Requests()[before:], expected)
+	return res
+}
+
+// ExpectWrites performs BatchWrite of ops and oldData with client and
+// checks that the requests it made match expected, in order. The client
+// handler must be a Recorder.
+func ExpectWrites(t testing.TB, client *opmodbus.Client, ops []opmodbus.Write, oldData opmodbus.Registers, expected []WireExpectation) {
+	t.Helper()
+	recorder := recorderOf(t, client)
+	before := len(recorder.Requests())
+	if err := client.BatchWrite(ops, oldData); err != nil {
+		t.Errorf("BatchWrite: %v", err)
+	}
+	expectRequests(t, recorder.Requests()[before:], expected)
+}
+
+// ExpectReadWrite performs BatchReadWrite of reads, writes and oldData
+// with client and checks that the requests it made match expected, in
+// order. The client handler must be a Recorder. The read results are
+// returned for further checks.
+func ExpectReadWrite(t testing.TB, client *opmodbus.Client, reads []opmodbus.Read, writes []opmodbus.Write, oldData opmodbus.Registers, expected []WireExpectation) opmodbus.Registers {
+	t.Helper()
+	recorder := recorderOf(t, client)
+	before := len(recorder.Requests())
+	res, err := client.BatchReadWrite(reads, writes, oldData)
+	if err != nil {
+		t.Errorf("BatchReadWrite: %v", err)
+	}
+	expectRequests(t, recorder.Requests()[before:], expected)
+	return res
+}
+
+func recorderOf(t testing.TB, client *opmodbus.Client) Recorder {
+	t.Helper()
+	recorder, ok := client.ClientHandler.(Recorder)
+	if !ok {
+		t.Fatalf("client handler %T does not record requests", client.ClientHandler)
+	}
+	return recorder
+}
+
+// expectRequests reports the differences between the recorded requests
+// and expected line by line.
+func expectRequests(t testing.TB, requests []modbus.ProtocolDataUnit, expected []WireExpectation) {
+	t.Helper()
+	n := len(requests)
+	if len(expected) > n {
+		n = len(expected)
+	}
+	var diff strings.Builder
+	failed := false
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(requests):
+			failed = true
+			fmt.Fprintf(&diff, "\n%d: missing: %v", i+1, expected[i])
+		case i >= len(expected):
+			failed = true
+			fmt.Fprintf(&diff, "\n%d: unexpected: %v", i+1, decode(requests[i]))
+		case !expected[i].matches(decode(requests[i])):
+			failed = true
+			fmt.Fprintf(&diff, "\n%d: expected: %v\n%d: got:      %v", i+1, expected[i], i+1, decode(requests[i]))
+		default:
+			fmt.Fprintf(&diff, "\n%d: ok: %v", i+1, expected[i])
+		}
+	}
+	if failed {
+		t.Errorf("wire requests don't match:%s", diff.String())
+	}
+}
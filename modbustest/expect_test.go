@@ -0,0 +1,85 @@
+package modbustest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	opmodbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// recordingT collects failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+type read struct {
+	register uint16
+	t        types.Type
+}
+
+func (r read) Register() uint16 { return r.register }
+func (r read) Type() types.Type { return r.t }
+
+type write struct {
+	register uint16
+	v        types.Value
+}
+
+func (w write) Register() uint16   { return w.register }
+func (w write) Value() types.Value { return w.v }
+
+func TestExpectPlan(t *testing.T) {
+	slave := NewSlave()
+	slave.Seed(opmodbus.Registers{10: types.Uint16(1), 11: types.Uint32(2)})
+	client := opmodbus.NewClient(slave)
+	ops := []opmodbus.Read{read{10, types.Uint16Type}, read{11, types.Uint32Type}, read{20, types.Uint16Type}}
+
+	res := ExpectPlan(t, client, ops, []WireExpectation{Read(10, 3), Read(20, 1)})
+	assert.Equal(t, opmodbus.Registers{10: types.Uint16(1), 11: types.Uint32(2), 20: types.Uint16(0)}, res)
+
+	rt := &recordingT{TB: t}
+	ExpectPlan(rt, client, ops, []WireExpectation{Read(10, 2), Read(12, 1), Read(20, 1)})
+	if assert.Len(t, rt.errors, 1) {
+		assert.Equal(t, "wire requests don't match:\n"+
+			"1: expected: function 3 at 10, 2 registers\n"+
+			"1: got:      function 3 at 10, 3 registers\n"+
+			"2: expected: function 3 at 12, 1 registers\n"+
+			"2: got:      function 3 at 20, 1 registers\n"+
+			"3: missing: function 3 at 20, 1 registers", rt.errors[0])
+	}
+}
+
+func TestExpectWrites(t *testing.T) {
+	slave := NewSlave()
+	client := opmodbus.NewClient(slave)
+	ops := []opmodbus.Write{write{10, types.Uint16(1)}, write{11, types.Uint16(2)}}
+
+	ExpectWrites(t, client, ops, nil, []WireExpectation{Write(10, 0, 1, 0, 2)})
+	assert.Equal(t, []byte{0, 1, 0, 2}, slave.Get(10, 2))
+	ExpectWrites(t, client, ops, opmodbus.Registers{10: types.Uint16(1)}, []WireExpectation{{
+		Function: 16,
+		Register: 11,
+		Quantity: 1,
+		Match:    func(payload []byte) bool { return payload[1] == 2 },
+	}})
+
+	rt := &recordingT{TB: t}
+	ExpectWrites(rt, client, ops, nil, []WireExpectation{Write(10, 0, 1, 0, 3)})
+	if assert.Len(t, rt.errors, 1) {
+		assert.Contains(t, rt.errors[0], "1: got:      function 16 at 10, 2 registers, payload 00 01 00 02")
+	}
+	rt = &recordingT{TB: t}
+	ExpectWrites(rt, client, ops, nil, nil)
+	if assert.Len(t, rt.errors, 1) {
+		assert.Contains(t, rt.errors[0], "1: unexpected: function 16 at 10")
+	}
+}
@@ -0,0 +1,214 @@
+// Package modbustest provides an in-memory Modbus slave and helpers
+// asserting the wire requests a client makes, for pinning device
+// interaction contracts in tests.
+package modbustest
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/goburrow/modbus"
+	opmodbus "github.com/tdemin/opmodbus"
+)
+
+// Recorder is a modbus.ClientHandler recording requests it receives.
+type Recorder interface {
+	Requests() []modbus.ProtocolDataUnit
+}
+
+// Slave is an in-memory Modbus slave implementing modbus.ClientHandler
+// on the PDU level. It serves functions 1, 2, 3, 4, 15, 16, 22 and 23
+// and the echo of function 8 sub-function 0, and records every request
+// it receives. Input registers, coils and discrete inputs are kept apart
+// from holding registers.
+type Slave struct {
+	mtx      sync.Mutex
+	mem      []byte
+	input    []byte
+	coils    []bool
+	discrete []bool
+	disabled map[byte]bool
+	requests []modbus.ProtocolDataUnit
+}
+
+// funcCodeDiagnostics is Modbus function 8.
+const funcCodeDiagnostics = 8
+
+// NewSlave returns a slave with all the registers, coils and discrete
+// inputs set to zero.
+func NewSlave() *Slave {
+	return &Slave{
+		mem:      make([]byte, 65536*2),
+		input:    make([]byte, 65536*2),
+		coils:    make([]bool, 65536),
+		discrete: make([]bool, 65536),
+	}
+}
+
+// Seed sets registers to values.
+func (s *Slave) Seed(values opmodbus.Registers) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for register, value := range values {
+		copy(s.mem[int(register)*2:], value.Bytes())
+	}
+}
+
+// SeedInput sets input registers to values.
+func (s *Slave) SeedInput(values opmodbus.Registers) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for register, value := range values {
+		copy(s.input[int(register)*2:], value.Bytes())
+	}
+}
+
+// SeedCoils sets coils to values.
+func (s *Slave) SeedCoils(values map[uint16]bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for coil, value := range values {
+		s.coils[coil] = value
+	}
+}
+
+// SeedDiscrete sets discrete inputs to values.
+func (s *Slave) SeedDiscrete(values map[uint16]bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for input, value := range values {
+		s.discrete[input] = value
+	}
+}
+
+// Coils returns the states of quantity coils starting at coil.
+func (s *Slave) Coils(coil, quantity uint16) []bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]bool(nil), s.coils[coil:int(coil)+int(quantity)]...)
+}
+
+// Get returns the contents of quantity registers starting at register.
+func (s *Slave) Get(register, quantity uint16) []byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]byte(nil), s.mem[int(register)*2:(int(register)+int(quantity))*2]...)
+}
+
+// Disable makes the slave reject functions with an illegal function
+// exception, as slaves not implementing them do.
+func (s *Slave) Disable(functions ...byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.disabled == nil {
+		s.disabled = make(map[byte]bool)
+	}
+	for _, f := range functions {
+		s.disabled[f] = true
+	}
+}
+
+// Requests returns the requests received so far.
+func (s *Slave) Requests() []modbus.ProtocolDataUnit {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]modbus.ProtocolDataUnit(nil), s.requests...)
+}
+
+func (s *Slave) Encode(pdu *modbus.ProtocolDataUnit) ([]byte, error) {
+	return append([]byte{pdu.FunctionCode}, pdu.Data...), nil
+}
+
+func (s *Slave) Decode(adu []byte) (*modbus.ProtocolDataUnit, error) {
+	if len(adu) == 0 {
+		return nil, errors.New("empty adu")
+	}
+	return &modbus.ProtocolDataUnit{FunctionCode: adu[0], Data: adu[1:]}, nil
+}
+
+func (s *Slave) Verify(aduRequest, aduResponse []byte) error {
+	return nil
+}
+
+func (s *Slave) Send(adu []byte) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	pdu := modbus.ProtocolDataUnit{FunctionCode: adu[0], Data: append([]byte(nil), adu[1:]...)}
+	s.requests = append(s.requests, pdu)
+	if s.disabled[pdu.FunctionCode] {
+		return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalFunction), nil
+	}
+	if pdu.FunctionCode == funcCodeDiagnostics {
+		if len(pdu.Data) < 2 || binary.BigEndian.Uint16(pdu.Data) != 0 {
+			return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalFunction), nil
+		}
+		return adu, nil
+	}
+	if len(pdu.Data) < 4 {
+		return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataValue), nil
+	}
+
+	register := int(binary.BigEndian.Uint16(pdu.Data[0:2]))
+	quantity := int(binary.BigEndian.Uint16(pdu.Data[2:4]))
+	if pdu.FunctionCode == modbus.FuncCodeMaskWriteRegister {
+		quantity = 1
+	}
+	if register+quantity > 65536 {
+		return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+	}
+	switch pdu.FunctionCode {
+	case modbus.FuncCodeReadHoldingRegisters:
+		res := []byte{pdu.FunctionCode, byte(quantity * 2)}
+		return append(res, s.mem[register*2:(register+quantity)*2]...), nil
+	case modbus.FuncCodeReadCoils:
+		return packBits(pdu.FunctionCode, s.coils[register:register+quantity]), nil
+	case modbus.FuncCodeReadDiscreteInputs:
+		return packBits(pdu.FunctionCode, s.discrete[register:register+quantity]), nil
+	case modbus.FuncCodeWriteMultipleCoils:
+		for i := 0; i < quantity; i++ {
+			s.coils[register+i] = pdu.Data[5+i/8]&(1<<(i%8)) != 0
+		}
+		return append([]byte{pdu.FunctionCode}, pdu.Data[0:4]...), nil
+	case modbus.FuncCodeReadInputRegisters:
+		res := []byte{pdu.FunctionCode, byte(quantity * 2)}
+		return append(res, s.input[register*2:(register+quantity)*2]...), nil
+	case modbus.FuncCodeMaskWriteRegister:
+		and, or := binary.BigEndian.Uint16(pdu.Data[2:4]), binary.BigEndian.Uint16(pdu.Data[4:6])
+		word := binary.BigEndian.Uint16(s.mem[register*2:])&and | or&^and
+		binary.BigEndian.PutUint16(s.mem[register*2:], word)
+		return adu, nil
+	case modbus.FuncCodeWriteMultipleRegisters:
+		copy(s.mem[register*2:], pdu.Data[5:])
+		return append([]byte{pdu.FunctionCode}, pdu.Data[0:4]...), nil
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		if len(pdu.Data) < 9 {
+			return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataValue), nil
+		}
+		// the write is performed before the read
+		write := int(binary.BigEndian.Uint16(pdu.Data[4:6]))
+		if write*2+len(pdu.Data[9:]) > len(s.mem) {
+			return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+		}
+		copy(s.mem[write*2:], pdu.Data[9:])
+		res := []byte{pdu.FunctionCode, byte(quantity * 2)}
+		return append(res, s.mem[register*2:(register+quantity)*2]...), nil
+	}
+	return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalFunction), nil
+}
+
+// packBits builds a function 1 or 2 response holding states.
+func packBits(function byte, states []bool) []byte {
+	packed := make([]byte, (len(states)+7)/8)
+	for i, on := range states {
+		if on {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append([]byte{function, byte(len(packed))}, packed...)
+}
+
+func exception(function, code byte) []byte {
+	return []byte{function | 0x80, code}
+}
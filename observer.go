@@ -0,0 +1,166 @@
+package modbus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// Observer is a modbus.Client passing every call through to the wrapped
+// client unchanged, while estimating what the optimizer of Client would
+// have saved on the same traffic. It is meant for measuring the benefit
+// before migrating code calling goburrow/modbus directly.
+//
+// Function 3, 4 and 16 requests are grouped into windows: a window starts
+// with a request and includes every request made within the window
+// duration after it. The requests of a window are planned as if they
+// were a single BatchRead and BatchWrite. Other functions are passed
+// through without being observed.
+type Observer struct {
+	modbus.Client
+
+	window time.Duration
+	now    func() time.Time
+
+	mtx     sync.Mutex
+	start   time.Time
+	reads   []readOp
+	writes  []writeOp
+	planned ObserverStats
+}
+
+// ObserverStats holds the observed traffic and the traffic the
+// optimizer would have made instead.
+type ObserverStats struct {
+	Windows int
+	// Reads and Writes are the numbers of observed requests.
+	Reads  int
+	Writes int
+	// PlannedReads and PlannedWrites are the numbers of requests the
+	// optimizer would have made.
+	PlannedReads  int
+	PlannedWrites int
+	// MergedRegisters is the number of registers of observed requests
+	// that would have been merged with other requests.
+	MergedRegisters int
+}
+
+// RequestsAvoided returns the number of requests the optimizer would
+// have saved.
+func (s ObserverStats) RequestsAvoided() int {
+	return s.Reads + s.Writes - s.PlannedReads - s.PlannedWrites
+}
+
+func (s *ObserverStats) add(other ObserverStats) {
+	s.Windows += other.Windows
+	s.Reads += other.Reads
+	s.Writes += other.Writes
+	s.PlannedReads += other.PlannedReads
+	s.PlannedWrites += other.PlannedWrites
+	s.MergedRegisters += other.MergedRegisters
+}
+
+// NewObserver wraps client, grouping requests into windows of the given
+// duration.
+func NewObserver(client modbus.Client, window time.Duration) *Observer {
+	return &Observer{Client: client, window: window, now: time.Now}
+}
+
+func (o *Observer) ReadHoldingRegisters(address, quantity uint16) ([]byte, error) {
+	o.observe(func() { o.reads = append(o.reads, readOp{address, quantity, HoldingRegisters}) })
+	return o.Client.ReadHoldingRegisters(address, quantity)
+}
+
+func (o *Observer) ReadInputRegisters(address, quantity uint16) ([]byte, error) {
+	o.observe(func() { o.reads = append(o.reads, readOp{address, quantity, InputRegisters}) })
+	return o.Client.ReadInputRegisters(address, quantity)
+}
+
+func (o *Observer) WriteMultipleRegisters(address, quantity uint16, value []byte) ([]byte, error) {
+	o.observe(func() { o.writes = append(o.writes, writeOp{address, quantity, nil}) })
+	return o.Client.WriteMultipleRegisters(address, quantity, value)
+}
+
+// observe records a request with record, closing the current window if
+// it has passed.
+func (o *Observer) observe(record func()) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	now := o.now()
+	if len(o.reads)+len(o.writes) > 0 && now.Sub(o.start) > o.window {
+		o.planned.add(o.plan())
+		o.reads, o.writes = nil, nil
+	}
+	if len(o.reads)+len(o.writes) == 0 {
+		o.start = now
+	}
+	record()
+}
+
+// Stats returns the statistics of all the observed windows, including
+// the current one.
+func (o *Observer) Stats() ObserverStats {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	stats := o.planned
+	if len(o.reads)+len(o.writes) > 0 {
+		stats.add(o.plan())
+	}
+	return stats
+}
+
+// plan runs the optimizer over the current window. The caller must hold
+// the mutex.
+func (o *Observer) plan() ObserverStats {
+	stats := ObserverStats{Windows: 1, Reads: len(o.reads), Writes: len(o.writes)}
+
+	for _, r := range optimizeRead(o.reads, nil, maxFunc3Quantity, 0) {
+		stats.PlannedReads++
+		stats.MergedRegisters += merged(o.reads, r)
+	}
+
+	observed := make([]uint16, len(o.writes))
+	for i, w := range o.writes {
+		observed[i] = w.register
+	}
+	for _, w := range optimizeWrite(o.writes, nil, maxFunc16Quantity) {
+		stats.PlannedWrites++
+		stats.MergedRegisters += mergedWrites(observed, RegisterRange{w.register, w.quantity})
+	}
+
+	return stats
+}
+
+// merged returns the number of registers of a planned read request if
+// it merges several of the observed ones.
+func merged(observed []readOp, planned readOp) int {
+	n := 0
+	for _, r := range observed {
+		if r.space == planned.space && planned.rng().Contains(r.register) {
+			n++
+		}
+	}
+	if n > 1 {
+		return int(planned.quantity)
+	}
+	return 0
+}
+
+// mergedWrites returns the number of registers of a planned write
+// request if it merges several of the observed requests starting at
+// registers.
+func mergedWrites(registers []uint16, planned RegisterRange) int {
+	n := 0
+	for _, register := range registers {
+		if planned.Contains(register) {
+			n++
+		}
+	}
+	if n > 1 {
+		return int(planned.Quantity)
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package modbus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	opmodbus "github.com/tdemin/opmodbus"
+)
+
+func TestObserver_passthrough(t *testing.T) {
+	calls := []func(c modbus.Client) ([]byte, error){
+		func(c modbus.Client) ([]byte, error) { return c.ReadHoldingRegisters(10, 3) },
+		func(c modbus.Client) ([]byte, error) { return c.WriteMultipleRegisters(11, 2, []byte{0, 1, 0, 2}) },
+		func(c modbus.Client) ([]byte, error) { return c.ReadHoldingRegisters(10, 3) },
+		// read-only register
+		func(c modbus.Client) ([]byte, error) { return c.WriteMultipleRegisters(20, 1, []byte{0, 1}) },
+		func(c modbus.Client) ([]byte, error) { return c.MaskWriteRegister(12, 0xff00, 0x0011) },
+		func(c modbus.Client) ([]byte, error) { return c.ReadHoldingRegisters(12, 1) },
+		// not served by the slave
+		func(c modbus.Client) ([]byte, error) { return c.ReadCoils(0, 8) },
+		func(c modbus.Client) ([]byte, error) { return c.ReadHoldingRegisters(65535, 2) },
+	}
+	newSlave := func() *testSlave {
+		slave := newTestSlave()
+		slave.set(10, 0xde, 0xad, 0xbe, 0xef, 0x12, 0x34)
+		slave.readOnly[20] = true
+		return slave
+	}
+	direct, observed := newSlave(), newSlave()
+	directClient := modbus.NewClient(direct)
+	observer := opmodbus.NewObserver(modbus.NewClient(observed), time.Hour)
+
+	for i, call := range calls {
+		want, wantErr := call(directClient)
+		got, gotErr := call(observer)
+		assert.Equal(t, want, got, "call %d", i)
+		assert.Equal(t, wantErr, gotErr, "call %d", i)
+	}
+	assert.Equal(t, direct.requests, observed.requests)
+	assert.Equal(t, direct.mem, observed.mem)
+}
+
+func TestObserver_Stats(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		// windows are separated by a pause longer than the window
+		windows [][]func(c modbus.Client)
+		want    opmodbus.ObserverStats
+	}{
+		{
+			name:   "adjacent reads",
+			window: time.Hour,
+			windows: [][]func(c modbus.Client){{
+				func(c modbus.Client) { c.ReadHoldingRegisters(10, 2) },
+				func(c modbus.Client) { c.ReadHoldingRegisters(12, 1) },
+				func(c modbus.Client) { c.ReadHoldingRegisters(20, 1) },
+			}},
+			want: opmodbus.ObserverStats{Windows: 1, Reads: 3, PlannedReads: 2, MergedRegisters: 3},
+		},
+		{
+			name:   "adjacent writes",
+			window: time.Hour,
+			windows: [][]func(c modbus.Client){{
+				func(c modbus.Client) { c.WriteMultipleRegisters(11, 1, []byte{0, 1}) },
+				func(c modbus.Client) { c.ReadHoldingRegisters(10, 1) },
+				func(c modbus.Client) { c.WriteMultipleRegisters(10, 1, []byte{0, 2}) },
+				func(c modbus.Client) { c.WriteMultipleRegisters(12, 2, []byte{0, 3, 0, 4}) },
+			}},
+			want: opmodbus.ObserverStats{Windows: 1, Reads: 1, Writes: 3, PlannedReads: 1, PlannedWrites: 1, MergedRegisters: 4},
+		},
+		{
+			name:   "separate windows",
+			window: 10 * time.Millisecond,
+			windows: [][]func(c modbus.Client){
+				{func(c modbus.Client) { c.ReadHoldingRegisters(10, 1) }},
+				{func(c modbus.Client) { c.ReadHoldingRegisters(11, 1) }},
+			},
+			want: opmodbus.ObserverStats{Windows: 2, Reads: 2, PlannedReads: 2},
+		},
+		{
+			name:   "unobserved functions",
+			window: time.Hour,
+			windows: [][]func(c modbus.Client){{
+				func(c modbus.Client) { c.ReadCoils(0, 8) },
+				func(c modbus.Client) { c.MaskWriteRegister(10, 0, 1) },
+			}},
+			want: opmodbus.ObserverStats{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observer := opmodbus.NewObserver(modbus.NewClient(newTestSlave()), tt.window)
+			for i, window := range tt.windows {
+				if i > 0 {
+					time.Sleep(2 * tt.window)
+				}
+				for _, call := range window {
+					call(observer)
+				}
+			}
+			stats := observer.Stats()
+			assert.Equal(t, tt.want, stats)
+			assert.Equal(t, tt.want.Reads+tt.want.Writes-tt.want.PlannedReads-tt.want.PlannedWrites, stats.RequestsAvoided())
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package modbus
+
+import (
+	"errors"
+
+	"github.com/goburrow/modbus"
+)
+
+// OpError is returned by BatchRead and BatchWrite for a failed wire
+// request, naming the ops of the batch the request covered.
+type OpError struct {
+	// Function is the function code of the request, or zero for fetches
+	// of custom regions.
+	Function byte
+	RegisterRange
+	// Ops are the indices of the ops of the batch whose registers the
+	// request covers, in ascending order.
+	Ops []int
+	// Err is the error of the request.
+	Err error
+
+	space     Space
+	registers []uint16
+}
+
+func (e *OpError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// AffectedRegisters returns the registers of the ops listed in Ops.
+func (e *OpError) AffectedRegisters() []uint16 {
+	return e.registers
+}
+
+// readError describes failed read request r.
+func (c *Client) readError(r readOp, err error) *OpError {
+	return &OpError{Function: c.readFunction(r), RegisterRange: RegisterRange{r.register, r.quantity}, Err: err, space: r.space}
+}
+
+// writeError describes failed write request w.
+func writeError(w writeOp, err error) *OpError {
+	return &OpError{Function: modbus.FuncCodeWriteMultipleRegisters, RegisterRange: RegisterRange{w.register, w.quantity}, Err: err}
+}
+
+// readOps sets the ops of an *OpError in err to those of ops the
+// request covers.
+func readOps(err error, ops []Read) {
+	covered := func(e *OpError) {
+		e.Ops, e.registers = nil, nil
+		for i, op := range ops {
+			if spaceOf(op) == e.space && e.Overlaps(RegisterRange{op.Register(), op.Type().Size()}) {
+				e.Ops, e.registers = append(e.Ops, i), append(e.registers, op.Register())
+			}
+		}
+	}
+	withOps(err, covered)
+}
+
+// writeOps sets the ops of an *OpError in err, or of any failures of
+// *WriteErrors, to those of ops the request writes. Ops skipped by
+// differential optimization are left out.
+func writeOps(err error, ops []Write, image registerImage, diff bool) {
+	covered := func(e *OpError) {
+		e.Ops, e.registers = nil, nil
+		for i, op := range ops {
+			if diff && unchanged(op, image) {
+				continue
+			}
+			if e.Overlaps(RegisterRange{op.Register(), uint16(len(op.Value().Bytes()) / 2)}) {
+				e.Ops, e.registers = append(e.Ops, i), append(e.registers, op.Register())
+			}
+		}
+	}
+	var werr *WriteErrors
+	if errors.As(err, &werr) {
+		for _, f := range werr.Failures {
+			withOps(f.Err, covered)
+		}
+		return
+	}
+	withOps(err, covered)
+}
+
+func withOps(err error, covered func(*OpError)) {
+	var oerr *OpError
+	if errors.As(err, &oerr) {
+		covered(oerr)
+	}
+}
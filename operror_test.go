@@ -0,0 +1,80 @@
+package modbus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestOpError_read(t *testing.T) {
+	ops := []modbus.Read{
+		testRead{100, types.Uint16Type},
+		testRead{101, types.Uint32Type},
+		testRead{103, types.Uint16Type},
+		testRead{200, types.Uint16Type},
+	}
+	tests := []struct {
+		name      string
+		missing   uint16
+		want      modbus.RegisterRange
+		wantOps   []int
+		registers []uint16
+	}{
+		{"merged", 102, modbus.RegisterRange{Register: 100, Quantity: 4}, []int{0, 1, 2}, []uint16{100, 101, 103}},
+		{"single", 200, modbus.RegisterRange{Register: 200, Quantity: 1}, []int{3}, []uint16{200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.missing[tt.missing] = true
+			client := modbus.NewClient(slave)
+
+			_, err := client.BatchRead(ops)
+			var oerr *modbus.OpError
+			if assert.True(t, errors.As(err, &oerr), "%v", err) {
+				assert.Equal(t, byte(3), oerr.Function)
+				assert.Equal(t, tt.want, oerr.RegisterRange)
+				assert.Equal(t, tt.wantOps, oerr.Ops)
+				assert.Equal(t, tt.registers, oerr.AffectedRegisters())
+			}
+
+			_, err = client.BatchReadPartial(ops)
+			var berr *modbus.BatchError
+			if assert.True(t, errors.As(err, &berr), "%v", err) {
+				failed := berr.Errors[tt.registers[0]]
+				assert.True(t, errors.As(failed, &oerr), "%v", failed)
+				assert.Equal(t, tt.registers, oerr.AffectedRegisters())
+			}
+		})
+	}
+}
+
+func TestOpError_write(t *testing.T) {
+	ops := []modbus.Write{
+		testWrite{10, types.Uint16(1)},
+		testWrite{11, types.Uint16(2)},
+		testWrite{12, types.Uint16(3)},
+	}
+	slave := newTestSlave()
+	slave.readOnly[11] = true
+	client := modbus.NewClient(slave)
+
+	err := client.BatchWrite(ops, modbus.Registers{10: types.Uint16(1)})
+	var oerr *modbus.OpError
+	if assert.True(t, errors.As(err, &oerr), "%v", err) {
+		assert.Equal(t, byte(16), oerr.Function)
+		assert.Equal(t, modbus.RegisterRange{Register: 11, Quantity: 2}, oerr.RegisterRange)
+		assert.Equal(t, []int{1, 2}, oerr.Ops, "the skipped op is not affected")
+		assert.Equal(t, []uint16{11, 12}, oerr.AffectedRegisters())
+	}
+
+	err = client.BatchWriteWith(ops, nil, modbus.BatchOptions{ContinueOnError: true})
+	var werr *modbus.WriteErrors
+	if assert.True(t, errors.As(err, &werr), "%v", err) && assert.Len(t, werr.Failures, 1) {
+		assert.True(t, errors.As(werr.Failures[0].Err, &oerr))
+		assert.Equal(t, []uint16{10, 11, 12}, oerr.AffectedRegisters())
+	}
+}
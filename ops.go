@@ -0,0 +1,35 @@
+package modbus
+
+import "github.com/tdemin/opmodbus/types"
+
+// ReadRequest is a Read of a value of DataType at register Address, for
+// batches built from literals. Its fields can't be named after the
+// methods of Read.
+type ReadRequest struct {
+	Address  uint16
+	DataType types.Type
+}
+
+// NewRead returns a ReadRequest of t at register.
+func NewRead(register uint16, t types.Type) ReadRequest {
+	return ReadRequest{register, t}
+}
+
+func (r ReadRequest) Register() uint16 { return r.Address }
+func (r ReadRequest) Type() types.Type { return r.DataType }
+
+// WriteRequest is a Write of Data at register Address, for batches
+// built from literals. Its fields can't be named after the methods of
+// Write.
+type WriteRequest struct {
+	Address uint16
+	Data    types.Value
+}
+
+// NewWrite returns a WriteRequest of v at register.
+func NewWrite(register uint16, v types.Value) WriteRequest {
+	return WriteRequest{register, v}
+}
+
+func (w WriteRequest) Register() uint16   { return w.Address }
+func (w WriteRequest) Value() types.Value { return w.Data }
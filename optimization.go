@@ -3,70 +3,184 @@ package modbus
 import (
 	"fmt"
 	"sort"
+	"time"
+
+	"github.com/tdemin/opmodbus/internal/regrange"
+	"github.com/tdemin/opmodbus/types"
 )
 
 const (
 	// limits to how many registers you can read / write with Modbus at once
 	maxFunc16Quantity = 123
-	maxFunc3Quantity  = 2047
+	maxFunc3Quantity  = 125
 )
 
-func optimizeRead(r []readOp) []readOp {
-	preopt := make([]readOp, len(r))
-	copy(preopt, r)
-	sort.Slice(preopt, func(i, j int) bool {
-		return preopt[i].register < preopt[j].register
+// optimizeRead merges adjacent and overlapping reads into requests of
+// at most max registers, dropping reads of registers another read
+// covers. Reads separated by at most gap unread registers are merged as
+// well, unless the gap crosses into another region of slow.
+func optimizeRead(r []readOp, slow []SlowRange, max, gap uint16) []readOp {
+	// zero-quantity ops read nothing and are dropped
+	preopt := make([]readOp, 0, len(r))
+	for _, op := range r {
+		if op.quantity > 0 {
+			preopt = append(preopt, op)
+		}
+	}
+	// stable, so that requests only depend on the input: of ops at the
+	// same register, the widest goes first to cover the others
+	sort.SliceStable(preopt, func(i, j int) bool {
+		if preopt[i].space != preopt[j].space {
+			return preopt[i].space < preopt[j].space
+		}
+		if preopt[i].register != preopt[j].register {
+			return preopt[i].register < preopt[j].register
+		}
+		return preopt[i].quantity > preopt[j].quantity
 	})
 
+	// every op either extends the run of the last request or starts a
+	// new request
 	opt := make([]readOp, 0, len(preopt))
-	for i := 0; i < len(preopt); i++ {
-		op := preopt[i]
-		for j := i + 1; j < len(preopt); j++ {
-			if preopt[j].register == op.register+op.quantity &&
-				op.quantity+preopt[j].quantity <= maxFunc3Quantity {
-				op.quantity += preopt[j].quantity
-				i++
+	for _, op := range preopt {
+		if n := len(opt); n > 0 && opt[n-1].space == op.space {
+			run := &opt[n-1]
+			if run.rng().ContainsRange(op.rng()) {
+				// duplicates and contained ops are decoded from the
+				// registers read for the run
+				continue
+			}
+			merged, ok := mergeGap(run.rng(), op.rng(), max, gap)
+			if gap > 0 {
+				ok = ok && sameRegion(slow, run.register, merged)
+			} else {
+				ok = ok && slowRegion(slow, op.register) == slowRegion(slow, run.register)
+			}
+			if ok {
+				run.quantity = merged.Quantity
+				continue
 			}
 		}
 		opt = append(opt, op)
 	}
 
+	sort.SliceStable(opt, func(i, j int) bool {
+		return slowCost(slow, opt[i].register) < slowCost(slow, opt[j].register)
+	})
 	return opt
 }
 
-func optimizeWrite(w []writeOp) []writeOp {
-	preopt := make([]writeOp, len(w))
-	copy(preopt, w)
-	sort.Slice(preopt, func(i, j int) bool {
+// mergeGap merges b into a if b starts at most gap registers after the
+// end of a, including overlapping it, and the merged range doesn't
+// exceed max registers.
+func mergeGap(a, b regrange.Range, max, gap uint16) (regrange.Range, bool) {
+	if int(b.Start) > a.End()+int(gap) {
+		return regrange.Range{}, false
+	}
+	merged := regrange.Range{Start: a.Start, Quantity: a.Quantity}
+	if b.End() > a.End() {
+		merged.Quantity = uint16(b.End() - int(a.Start))
+	}
+	return merged, merged.Quantity <= max
+}
+
+// sameRegion reports whether every register of merged belongs to the
+// region of slow register belongs to, see slowRegion.
+func sameRegion(slow []SlowRange, register uint16, merged regrange.Range) bool {
+	own := slowRegion(slow, register)
+	if own >= 0 && !slow[own].rng().ContainsRange(merged) {
+		return false
+	}
+	// regions before own take precedence over it
+	for i, r := range slow {
+		if (own < 0 || i < own) && r.rng().Overlaps(merged) {
+			return false
+		}
+	}
+	return true
+}
+
+func optimizeWrite(w []writeOp, slow []SlowRange, max uint16) []writeOp {
+	// zero-quantity ops write nothing and are dropped
+	preopt := make([]writeOp, 0, len(w))
+	for _, op := range w {
+		if op.quantity > 0 {
+			preopt = append(preopt, op)
+		}
+	}
+	// stable, so that ops at the same register keep their order
+	sort.SliceStable(preopt, func(i, j int) bool {
 		return preopt[i].register < preopt[j].register
 	})
 
 	opt := make([]writeOp, 0, len(preopt))
-	for i := 0; i < len(preopt); i++ {
-		op := preopt[i]
-		for j := i + 1; j < len(preopt); j++ {
-			if preopt[j].register == op.register+op.quantity &&
-				op.quantity+preopt[j].quantity <= maxFunc16Quantity {
-				op.quantity += preopt[j].quantity
-				op.value = append(op.value, preopt[j].value...)
-				i++
+	for _, op := range preopt {
+		if n := len(opt); n > 0 {
+			run := &opt[n-1]
+			if merged, ok := mergeAdjacent(run.rng(), op.rng(), max); ok &&
+				slowRegion(slow, op.register) == slowRegion(slow, run.register) {
+				run.quantity = merged.Quantity
+				run.value = append(run.value, op.value...)
+				continue
 			}
 		}
+		// capped, so that extending the run copies the value instead of
+		// writing to the array of the caller
+		op.value = op.value[:len(op.value):len(op.value)]
 		opt = append(opt, op)
 	}
 
+	sort.SliceStable(opt, func(i, j int) bool {
+		return slowCost(slow, opt[i].register) < slowCost(slow, opt[j].register)
+	})
 	return opt
 }
 
+// mergeAdjacent merges b into a if b follows a and the merged range
+// doesn't exceed max registers.
+func mergeAdjacent(a, b regrange.Range, max uint16) (regrange.Range, bool) {
+	if !a.Adjacent(b) {
+		return regrange.Range{}, false
+	}
+	merged, ok := a.Merge(b)
+	return merged, ok && merged.Quantity <= max
+}
+
+// slowRegion returns the index of the slow range register belongs to,
+// or -1 if it belongs to none. Ops from different regions are never
+// merged.
+func slowRegion(slow []SlowRange, register uint16) int {
+	for i, r := range slow {
+		if r.Contains(register) {
+			return i
+		}
+	}
+	return -1
+}
+
+// slowCost returns the estimated cost of a request starting at
+// register, zero for registers outside of slow ranges. Merged requests
+// are ordered by ascending cost, so that slow requests go last.
+func slowCost(slow []SlowRange, register uint16) time.Duration {
+	if i := slowRegion(slow, register); i >= 0 {
+		return slow[i].Cost
+	}
+	return 0
+}
+
 func convertReadOp(r Read) (readOp, error) {
 	ro := readOp{
 		register: r.Register(),
 		quantity: r.Type().Size(),
+		space:    spaceOf(r),
 	}
 	return ro, ro.validate()
 }
 
 func convertWriteOp(w Write) (writeOp, error) {
+	if err := validateValue(w.Register(), w.Value()); err != nil {
+		return writeOp{}, err
+	}
 	wo := writeOp{
 		register: w.Register(),
 		quantity: uint16(len(w.Value().Bytes()) / 2),
@@ -75,15 +189,57 @@ func convertWriteOp(w Write) (writeOp, error) {
 	return wo, wo.validate()
 }
 
+// validateValue rejects nil values and values out of range of their
+// encoding, see types.Validator.
+func validateValue(register uint16, v types.Value) error {
+	if v == nil {
+		return fmt.Errorf("%w: nil value for register %d (0x%04X)", ErrEmptyOperation, register, register)
+	}
+	if v, ok := v.(types.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("register %d (0x%04X): %w", register, register, err)
+		}
+	}
+	return nil
+}
+
+// checkEmpty rejects write ops of no registers before their values are
+// used.
+func checkEmpty(w Write) error {
+	v := w.Value()
+	if v == nil || len(v.Bytes()) >= 2 {
+		return validateValue(w.Register(), v)
+	}
+	return fmt.Errorf("%w: %d bytes for register %d (0x%04X)", ErrEmptyOperation, len(v.Bytes()), w.Register(), w.Register())
+}
+
 type readOp struct {
 	register uint16
 	quantity uint16
+	space    Space
+}
+
+func (r readOp) rng() regrange.Range {
+	return regrange.Range{Start: r.register, Quantity: r.quantity}
+}
+
+func (r readOp) String() string {
+	if r.space != HoldingRegisters {
+		return fmt.Sprintf("%v %s", r.space, describeRange(r.rng()))
+	}
+	return describeRange(r.rng())
 }
 
 func (r readOp) validate() error {
+	if r.quantity == 0 {
+		return fmt.Errorf("%w: read at %d (0x%04X)", ErrEmptyOperation, r.register, r.register)
+	}
 	if r.quantity > maxFunc3Quantity {
 		return fmt.Errorf("%w: %d: %v", ErrTooManyRegisters, maxFunc3Quantity, r)
 	}
+	if !r.rng().Valid() {
+		return fmt.Errorf("%w: %v", ErrAddressSpace, r)
+	}
 	return nil
 }
 
@@ -93,16 +249,82 @@ type writeOp struct {
 	value    []byte
 }
 
+func (w writeOp) rng() regrange.Range {
+	return regrange.Range{Start: w.register, Quantity: w.quantity}
+}
+
+func (w writeOp) String() string {
+	return fmt.Sprintf("%s, value 0x%X", describeRange(w.rng()), w.value)
+}
+
+// describeRange formats r with its quantity and bounds in decimal and
+// hex, e.g. "2 registers at 10-11 (0x000A-0x000B)".
+func describeRange(r regrange.Range) string {
+	last, ok := r.Last()
+	switch {
+	case !ok:
+		return fmt.Sprintf("0 registers at %d (0x%04X)", r.Start, r.Start)
+	case r.Quantity == 1:
+		return fmt.Sprintf("register %d (0x%04X)", r.Start, r.Start)
+	}
+	return fmt.Sprintf("%d registers at %v (0x%04X-0x%04X)", r.Quantity, r, r.Start, last)
+}
+
 func (w writeOp) validate() error {
+	if w.quantity == 0 {
+		return fmt.Errorf("%w: %d bytes for register %d (0x%04X)", ErrEmptyOperation, len(w.value), w.register, w.register)
+	}
 	if w.quantity > maxFunc16Quantity {
 		// no more than 123 registers are allowed per write operation
 		return fmt.Errorf("%w: %d: %v", ErrTooManyRegisters, maxFunc16Quantity, w)
 	}
+	if !w.rng().Valid() {
+		return fmt.Errorf("%w: %v", ErrAddressSpace, w)
+	}
 	return nil
 }
 
-func newReadOp(r, q uint16) (readOp, error) {
-	ro := readOp{r, q}
+// splitRead splits r into requests of at most max registers, see
+// Limits.StrictReadSize.
+func splitRead(r readOp, max uint16) ([]readOp, error) {
+	if !r.rng().Valid() {
+		return nil, fmt.Errorf("%w: %v", ErrAddressSpace, r)
+	}
+	pieces := make([]readOp, 0, (int(r.quantity)+int(max)-1)/int(max))
+	for rest := r.rng(); !rest.Empty(); {
+		piece := rest
+		if piece.Quantity > max {
+			piece, rest = rest.SplitAt(rest.Start + max)
+		} else {
+			rest.Quantity = 0
+		}
+		pieces = append(pieces, readOp{piece.Start, piece.Quantity, r.space})
+	}
+	return pieces, nil
+}
+
+// splitWrite splits w into requests of at most max registers, see
+// Limits.StrictWriteSize.
+func splitWrite(w writeOp, max uint16) ([]writeOp, error) {
+	if len(w.value) != int(w.quantity)*2 {
+		return nil, fmt.Errorf("%w: %d bytes for register %d (0x%04X)", types.ErrInvalidInput, len(w.value), w.register, w.register)
+	}
+	if !w.rng().Valid() {
+		return nil, fmt.Errorf("%w: %v", ErrAddressSpace, w)
+	}
+	pieces := make([]writeOp, 0, (int(w.quantity)+int(max)-1)/int(max))
+	for offset := 0; offset < int(w.quantity); offset += int(max) {
+		end := offset + int(max)
+		if end > int(w.quantity) {
+			end = int(w.quantity)
+		}
+		pieces = append(pieces, writeOp{w.register + uint16(offset), uint16(end - offset), w.value[offset*2 : end*2]})
+	}
+	return pieces, nil
+}
+
+func newReadOp(r, q uint16, s Space) (readOp, error) {
+	ro := readOp{r, q, s}
 	return ro, ro.validate()
 }
 
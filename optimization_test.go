@@ -1,7 +1,9 @@
 package modbus
 
 import (
+	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -18,43 +20,105 @@ func Test_optimizeRead(t *testing.T) {
 		{
 			"optimizes two requests",
 			args{[]readOp{
-				{2, 2},
-				{4, 2},
-				{7, 1},
+				{2, 2, HoldingRegisters},
+				{4, 2, HoldingRegisters},
+				{7, 1, HoldingRegisters},
 			}},
 			[]readOp{
-				{2, 4},
-				{7, 1},
+				{2, 4, HoldingRegisters},
+				{7, 1, HoldingRegisters},
 			},
 		},
 		{
 			"optimizes multiple requests after each other",
 			args{[]readOp{
-				{2, 2},
-				{4, 2},
-				{6, 1},
-				{7, 1},
-				{9, 3},
+				{2, 2, HoldingRegisters},
+				{4, 2, HoldingRegisters},
+				{6, 1, HoldingRegisters},
+				{7, 1, HoldingRegisters},
+				{9, 3, HoldingRegisters},
 			}},
 			[]readOp{
-				{2, 6},
-				{9, 3},
+				{2, 6, HoldingRegisters},
+				{9, 3, HoldingRegisters},
 			},
 		},
 		{
 			"skips optimization on quantity limit",
 			args{[]readOp{
-				{2, 4},
-				{6, 2045},
+				{2, 4, HoldingRegisters},
+				{6, 2045, HoldingRegisters},
 			}},
 			[]readOp{
-				{2, 4},
-				{6, 2045},
+				{2, 4, HoldingRegisters},
+				{6, 2045, HoldingRegisters},
+			},
+		},
+		{
+			"never merges across register spaces",
+			args{[]readOp{
+				{4, 2, InputRegisters},
+				{2, 2, HoldingRegisters},
+				{6, 1, InputRegisters},
+				{4, 1, HoldingRegisters},
+			}},
+			[]readOp{
+				{2, 3, HoldingRegisters},
+				{4, 3, InputRegisters},
+			},
+		},
+		{
+			"drops duplicates",
+			args{[]readOp{
+				{4, 2, HoldingRegisters},
+				{4, 2, HoldingRegisters},
+				{4, 2, InputRegisters},
+			}},
+			[]readOp{
+				{4, 2, HoldingRegisters},
+				{4, 2, InputRegisters},
+			},
+		},
+		{
+			"drops contained requests",
+			args{[]readOp{
+				{5, 1, HoldingRegisters},
+				{4, 1, HoldingRegisters},
+				{4, 4, HoldingRegisters},
+				{10, 1, HoldingRegisters},
+			}},
+			[]readOp{
+				{4, 4, HoldingRegisters},
+				{10, 1, HoldingRegisters},
+			},
+		},
+		{
+			"merges partial overlaps",
+			args{[]readOp{
+				{4, 2, HoldingRegisters},
+				{5, 1, HoldingRegisters},
+				{5, 2, HoldingRegisters},
+				{7, 1, HoldingRegisters},
+			}},
+			[]readOp{
+				{4, 4, HoldingRegisters},
+			},
+		},
+		{
+			"skips merging partial overlaps on quantity limit",
+			args{[]readOp{
+				{2, 100, HoldingRegisters},
+				{50, 100, HoldingRegisters},
+				{60, 1, HoldingRegisters},
+			}},
+			[]readOp{
+				{2, 100, HoldingRegisters},
+				{50, 100, HoldingRegisters},
 			},
 		},
 	}
 	for _, tt := range tests {
-		assert.Equal(t, tt.want, optimizeRead(tt.args.r), tt.name)
+		assert.Equal(t, tt.want, optimizeRead(tt.args.r, nil, maxFunc3Quantity, 0), tt.name)
 	}
 }
 
@@ -116,6 +180,263 @@ func Test_optimizeWrite(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		assert.Equal(t, tt.want, optimizeWrite(tt.args.w), tt.name)
+		assert.Equal(t, tt.want, optimizeWrite(tt.args.w, nil, maxFunc16Quantity), tt.name)
+	}
+}
+
+func Test_optimizeRead_slowRanges(t *testing.T) {
+	slow := []SlowRange{
+		{RegisterRange{10, 10}, 800 * time.Millisecond},
+		{RegisterRange{20, 10}, 200 * time.Millisecond},
+	}
+	got := optimizeRead([]readOp{
+		{6, 2, HoldingRegisters},
+		{8, 2, HoldingRegisters},
+		{10, 2, HoldingRegisters},
+		{12, 2, HoldingRegisters},
+		{18, 2, HoldingRegisters},
+		{20, 2, HoldingRegisters},
+		{30, 1, HoldingRegisters},
+	}, slow, maxFunc3Quantity, 0)
+	assert.Equal(t, []readOp{
+		{6, 4, HoldingRegisters},
+		{30, 1, HoldingRegisters},
+		{20, 2, HoldingRegisters},
+		{10, 4, HoldingRegisters},
+		{18, 2, HoldingRegisters},
+	}, got)
+	for _, op := range got {
+		first := slowRegion(slow, op.register)
+		last := slowRegion(slow, op.register+op.quantity-1)
+		assert.Equal(t, first, last, "%v straddles a slow range boundary", op)
+	}
+}
+
+func Test_optimizeRead_gaps(t *testing.T) {
+	slow := []SlowRange{{RegisterRange{100, 10}, time.Second}}
+	tests := []struct {
+		name string
+		ops  []readOp
+		gap  uint16
+		max  uint16
+		want []readOp
+	}{
+		{"gap of zero keeps adjacency", []readOp{{10, 1, HoldingRegisters}, {12, 1, HoldingRegisters}}, 0, maxFunc3Quantity,
+			[]readOp{{10, 1, HoldingRegisters}, {12, 1, HoldingRegisters}}},
+		{"within the gap", []readOp{{12, 2, HoldingRegisters}, {10, 1, HoldingRegisters}, {17, 1, HoldingRegisters}}, 3, maxFunc3Quantity,
+			[]readOp{{10, 8, HoldingRegisters}}},
+		{"beyond the gap", []readOp{{10, 1, HoldingRegisters}, {15, 1, HoldingRegisters}}, 3, maxFunc3Quantity,
+			[]readOp{{10, 1, HoldingRegisters}, {15, 1, HoldingRegisters}}},
+		{"overlapping and duplicate ops", []readOp{{10, 4, HoldingRegisters}, {11, 1, HoldingRegisters}, {11, 1, HoldingRegisters}, {14, 2, HoldingRegisters}}, 1, maxFunc3Quantity,
+			[]readOp{{10, 6, HoldingRegisters}}},
+		{"merged at the limit", []readOp{{0, 1, HoldingRegisters}, {99, 1, HoldingRegisters}}, 100, 100,
+			[]readOp{{0, 100, HoldingRegisters}}},
+		{"merged above the limit", []readOp{{0, 1, HoldingRegisters}, {100, 1, HoldingRegisters}, {101, 1, HoldingRegisters}}, 100, 100,
+			[]readOp{{0, 1, HoldingRegisters}, {100, 2, HoldingRegisters}}},
+		{"gap into a slow range", []readOp{{97, 1, HoldingRegisters}, {101, 1, HoldingRegisters}}, 5, maxFunc3Quantity,
+			[]readOp{{97, 1, HoldingRegisters}, {101, 1, HoldingRegisters}}},
+		{"gap across a slow range", []readOp{{98, 1, HoldingRegisters}, {111, 1, HoldingRegisters}}, 20, maxFunc3Quantity,
+			[]readOp{{98, 1, HoldingRegisters}, {111, 1, HoldingRegisters}}},
+		{"gap within a slow range", []readOp{{101, 1, HoldingRegisters}, {105, 1, HoldingRegisters}}, 5, maxFunc3Quantity,
+			[]readOp{{101, 5, HoldingRegisters}}},
+		{"gap across spaces", []readOp{{10, 1, HoldingRegisters}, {11, 1, InputRegisters}}, 5, maxFunc3Quantity,
+			[]readOp{{10, 1, HoldingRegisters}, {11, 1, InputRegisters}}},
+		{"end of the address space", []readOp{{65530, 1, HoldingRegisters}, {65535, 1, HoldingRegisters}}, 10, maxFunc3Quantity,
+			[]readOp{{65530, 6, HoldingRegisters}}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, optimizeRead(tt.ops, slow, tt.max, tt.gap), tt.name)
+	}
+}
+
+func Test_optimizeWrite_slowRanges(t *testing.T) {
+	slow := []SlowRange{{RegisterRange{10, 10}, time.Second}}
+	assert.Equal(t, []writeOp{
+		{8, 2, mb(1, 1, 2, 2)},
+		{10, 1, mb(3, 3)},
+	}, optimizeWrite([]writeOp{
+		{10, 1, mb(3, 3)},
+		{8, 1, mb(1, 1)},
+		{9, 1, mb(2, 2)},
+	}, slow, maxFunc16Quantity))
+}
+
+func Test_validate_addressSpace(t *testing.T) {
+	assert.NoError(t, readOp{65535, 1, HoldingRegisters}.validate())
+	assert.ErrorIs(t, readOp{65535, 2, HoldingRegisters}.validate(), ErrAddressSpace)
+	assert.ErrorIs(t, readOp{65500, 100, InputRegisters}.validate(), ErrAddressSpace)
+	assert.NoError(t, writeOp{65535, 1, []byte{0, 1}}.validate())
+	assert.ErrorIs(t, writeOp{65535, 2, []byte{0, 1, 0, 2}}.validate(), ErrAddressSpace)
+
+	// merges of valid ops end at the last register at most
+	read := optimizeRead([]readOp{{65533, 2, HoldingRegisters}, {65535, 1, HoldingRegisters}}, nil, maxFunc3Quantity, 0)
+	assert.Equal(t, []readOp{{65533, 3, HoldingRegisters}}, read)
+	write := optimizeWrite([]writeOp{{65534, 1, []byte{0, 1}}, {65535, 1, []byte{0, 2}}}, nil, maxFunc16Quantity)
+	assert.Equal(t, []writeOp{{65534, 2, []byte{0, 1, 0, 2}}}, write)
+	assert.NoError(t, read[0].validate())
+	assert.NoError(t, write[0].validate())
+}
+
+func Test_optimize_zeroQuantity(t *testing.T) {
+	read := optimizeRead([]readOp{{10, 1, HoldingRegisters}, {11, 0, HoldingRegisters}, {11, 1, HoldingRegisters}}, nil, maxFunc3Quantity, 0)
+	assert.Equal(t, []readOp{{10, 2, HoldingRegisters}}, read)
+	write := optimizeWrite([]writeOp{{10, 1, []byte{0, 1}}, {11, 0, nil}, {11, 1, []byte{0, 2}}}, nil, maxFunc16Quantity)
+	assert.Equal(t, []writeOp{{10, 2, []byte{0, 1, 0, 2}}}, write)
+}
+
+func Test_optimize_regressions(t *testing.T) {
+	reads := []struct {
+		name string
+		r    []readOp
+		want []readOp
+	}{
+		{
+			"out of order",
+			[]readOp{{9, 1, HoldingRegisters}, {2, 2, HoldingRegisters}, {5, 1, HoldingRegisters}, {4, 1, HoldingRegisters}},
+			[]readOp{{2, 4, HoldingRegisters}, {9, 1, HoldingRegisters}},
+		},
+		{
+			"duplicates between mergeable ops",
+			[]readOp{{3, 1, HoldingRegisters}, {2, 1, HoldingRegisters}, {2, 1, HoldingRegisters}, {4, 1, HoldingRegisters}},
+			[]readOp{{2, 3, HoldingRegisters}},
+		},
+		{
+			"gapped",
+			[]readOp{{2, 2, HoldingRegisters}, {10, 1, HoldingRegisters}, {5, 1, HoldingRegisters}, {4, 1, HoldingRegisters}, {12, 1, HoldingRegisters}},
+			[]readOp{{2, 4, HoldingRegisters}, {10, 1, HoldingRegisters}, {12, 1, HoldingRegisters}},
+		},
+		{
+			"contained before adjacent",
+			[]readOp{{2, 3, HoldingRegisters}, {3, 1, HoldingRegisters}, {5, 1, HoldingRegisters}, {7, 1, HoldingRegisters}},
+			[]readOp{{2, 4, HoldingRegisters}, {7, 1, HoldingRegisters}},
+		},
+	}
+	for _, tt := range reads {
+		assert.Equal(t, tt.want, optimizeRead(tt.r, nil, maxFunc3Quantity, 0), tt.name)
+	}
+
+	writes := []struct {
+		name string
+		w    []writeOp
+		want []writeOp
+	}{
+		{
+			"out of order",
+			[]writeOp{{7, 1, mb(0, 3)}, {2, 1, mb(0, 1)}, {3, 1, mb(0, 2)}},
+			[]writeOp{{2, 2, mb(0, 1, 0, 2)}, {7, 1, mb(0, 3)}},
+		},
+		{
+			"duplicates between mergeable ops",
+			[]writeOp{{2, 1, mb(0, 1)}, {2, 1, mb(0, 1)}, {3, 1, mb(0, 2)}},
+			[]writeOp{{2, 1, mb(0, 1)}, {2, 2, mb(0, 1, 0, 2)}},
+		},
+		{
+			"gapped",
+			[]writeOp{{5, 1, mb(0, 3)}, {2, 1, mb(0, 1)}, {4, 1, mb(0, 2)}},
+			[]writeOp{{2, 1, mb(0, 1)}, {4, 2, mb(0, 2, 0, 3)}},
+		},
+	}
+	for _, tt := range writes {
+		assert.Equal(t, tt.want, optimizeWrite(tt.w, nil, maxFunc16Quantity), tt.name)
+	}
+
+	shared := make([]byte, 2, 4)
+	optimizeWrite([]writeOp{{2, 1, shared}, {3, 1, mb(0, 2)}}, nil, maxFunc16Quantity)
+	assert.Equal(t, mb(0, 0, 0, 0), shared[:4], "the value of the caller is not written to")
+}
+
+func Test_optimizeRead_properties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 500; round++ {
+		max := uint16(8 + rng.Intn(32))
+		var ops []readOp
+		want := make(map[Space]map[uint16]bool)
+		var total int
+		for i := 0; i < 1+rng.Intn(30); i++ {
+			op := readOp{uint16(rng.Intn(200)), uint16(1 + rng.Intn(8)), Space(rng.Intn(2))}
+			ops = append(ops, op)
+			total += int(op.quantity)
+			if want[op.space] == nil {
+				want[op.space] = make(map[uint16]bool)
+			}
+			for r := op.register; r < op.register+op.quantity; r++ {
+				want[op.space][r] = true
+			}
+		}
+
+		got := make(map[Space]map[uint16]bool)
+		var quantity int
+		for _, req := range optimizeRead(ops, nil, max, 0) {
+			assert.LessOrEqual(t, req.quantity, max, "round %d", round)
+			quantity += int(req.quantity)
+			if got[req.space] == nil {
+				got[req.space] = make(map[uint16]bool)
+			}
+			for r := req.register; r < req.register+req.quantity; r++ {
+				got[req.space][r] = true
+			}
+		}
+		assert.Equal(t, want, got, "round %d: registers read", round)
+		assert.LessOrEqual(t, quantity, total, "round %d", round)
+	}
+}
+
+func Test_optimizeWrite_properties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 500; round++ {
+		max := uint16(8 + rng.Intn(32))
+		var ops []writeOp
+		want := make(map[uint16][2]byte)
+		var total int
+		for _, start := range rng.Perm(50)[:1+rng.Intn(30)] {
+			op := writeOp{register: uint16(start * 4), quantity: uint16(1 + rng.Intn(4))}
+			for r := op.register; r < op.register+op.quantity; r++ {
+				b := [2]byte{byte(round), byte(r)}
+				op.value = append(op.value, b[:]...)
+				want[r] = b
+			}
+			ops = append(ops, op)
+			total += int(op.quantity)
+		}
+
+		got := make(map[uint16][2]byte)
+		var quantity int
+		for _, req := range optimizeWrite(ops, nil, max) {
+			assert.LessOrEqual(t, req.quantity, max, "round %d", round)
+			assert.Len(t, req.value, int(req.quantity)*2, "round %d", round)
+			quantity += int(req.quantity)
+			for i := 0; i+1 < len(req.value); i += 2 {
+				got[req.register+uint16(i/2)] = [2]byte{req.value[i], req.value[i+1]}
+			}
+		}
+		assert.Equal(t, want, got, "round %d: registers written", round)
+		assert.Equal(t, total, quantity, "round %d", round)
+	}
+}
+
+func Test_optimize_stable(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var reads []readOp
+	for i := 0; i < 40; i++ {
+		reads = append(reads, readOp{uint16(10 * rng.Intn(4)), uint16(1 + rng.Intn(4)), Space(rng.Intn(2))})
+	}
+	want := optimizeRead(reads, nil, maxFunc3Quantity, 0)
+	for round := 0; round < 20; round++ {
+		shuffled := append([]readOp(nil), reads...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		assert.Equal(t, want, optimizeRead(shuffled, nil, maxFunc3Quantity, 0), "round %d", round)
+	}
+
+	// ops of the same register are never merged and keep their order
+	var writes []writeOp
+	for i := 0; i < 40; i++ {
+		writes = append(writes, writeOp{uint16(10 * (i % 2)), 1, mb(0, byte(i))})
+	}
+	var wantWrites []writeOp
+	for parity := 0; parity < 2; parity++ {
+		for i := parity; i < len(writes); i += 2 {
+			wantWrites = append(wantWrites, writes[i])
+		}
 	}
+	assert.Equal(t, wantWrites, optimizeWrite(writes, nil, maxFunc16Quantity))
 }
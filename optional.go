@@ -0,0 +1,198 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrOptionalUnavailable is wrapped by Unavailable values of optional
+// ops the slave rejected.
+var ErrOptionalUnavailable = errors.New("optional registers unavailable")
+
+// Optional may be implemented by Read ops for registers that may be
+// missing on a slave, e.g. registers of certain firmware revisions
+// only. If a request covering an optional op is rejected with a Modbus
+// exception, BatchRead retries the required ops of the request without
+// the optional ones, and reads each optional op on its own. Optional ops
+// rejected again get an Unavailable value in the results instead of
+// failing the batch. Other failures, such as timeouts, are not
+// downgraded.
+type Optional interface {
+	Optional() bool
+}
+
+// OptionalRead marks r as optional, see Optional.
+func OptionalRead(r Read) Read {
+	return optionalRead{r}
+}
+
+type optionalRead struct {
+	Read
+}
+
+func (optionalRead) Optional() bool {
+	return true
+}
+
+func (r optionalRead) String() string {
+	return fmt.Sprintf("optional %v", r.Read)
+}
+
+func (r optionalRead) unwrap() Read {
+	return r.Read
+}
+
+// Unavailable is the result of an optional op rejected by the slave. It
+// is both a types.Value and an error wrapping ErrOptionalUnavailable.
+// It can't be written back: its Bytes() are empty and its Validate
+// returns itself.
+type Unavailable struct {
+	Op  Read
+	Err error
+}
+
+func (u Unavailable) Error() string {
+	return fmt.Sprintf("%v: %v: %v", ErrOptionalUnavailable, u.Op, u.Err)
+}
+
+func (u Unavailable) Unwrap() error {
+	return ErrOptionalUnavailable
+}
+
+func (u Unavailable) Bytes() []byte {
+	return []byte{}
+}
+
+func (u Unavailable) Validate() error {
+	return u
+}
+
+func isOptional(op interface{}) bool {
+	for {
+		if o, ok := op.(Optional); ok {
+			return o.Optional()
+		}
+		w, ok := op.(interface{ unwrap() Read })
+		if !ok {
+			return false
+		}
+		op = w.unwrap()
+	}
+}
+
+// optionalPlan splits the converted ops of a batch into required and
+// optional ones.
+type optionalPlan struct {
+	required []readOp
+	optional []readOp
+}
+
+func (p *optionalPlan) add(op Read, rop readOp) {
+	if isOptional(op) {
+		p.optional = append(p.optional, rop)
+	} else {
+		p.required = append(p.required, rop)
+	}
+}
+
+// readChunksOptional is readChunks falling back to reading the required
+// and the optional ops of a rejected request separately, each request
+// performed with read. Rejections of optional ops are returned keyed by
+// their register. Once ctx is done,
+// the results read so far are returned with its error. The caller must
+// hold the client mutex.
+func (c *Client) readChunksOptional(ctx context.Context, ops []readOp, opt optionalPlan, read func(readOp, Retry) ([]byte, error), retry Retry, failed map[readOp]error) (map[readOp][]byte, map[uint16]error, error) {
+	results := make(map[readOp][]byte)
+	unavailable := make(map[uint16]error)
+	completed := 0
+	// fail stops the batch with err, or records it if failed is not nil
+	fail := func(r readOp, err error) error {
+		if failed == nil {
+			return err
+		}
+		failed[r] = err
+		return nil
+	}
+	for i, v := range ops {
+		if err := c.pace(ctx, completed); err != nil {
+			return results, unavailable, err
+		}
+		b, err := read(v, retry)
+		completed++
+		if err == nil {
+			results[v] = b
+			continue
+		}
+		optional := within(opt.optional, v)
+		if !isException(err) || len(optional) == 0 {
+			if err := fail(v, c.readError(v, fmt.Errorf("read request %d of %v: %w", i+1, v, err))); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		for _, r := range optimizeRead(within(opt.required, v), c.readRegions(), v.quantity, 0) {
+			if err := c.pace(ctx, completed); err != nil {
+				return results, unavailable, err
+			}
+			b, err := read(r, retry)
+			completed++
+			if err != nil {
+				if err := fail(r, c.readError(r, fmt.Errorf("read request %d of %v without optional ops: %w", i+1, r, err))); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			results[r] = b
+		}
+		for _, r := range optional {
+			if err := c.pace(ctx, completed); err != nil {
+				return results, unavailable, err
+			}
+			b, err := read(r, retry)
+			completed++
+			switch {
+			case err == nil:
+				results[r] = b
+			case isException(err):
+				unavailable[r.register] = err
+			default:
+				if err := fail(r, c.readError(r, fmt.Errorf("optional read of %v: %w", r, err))); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	return results, unavailable, nil
+}
+
+// within returns ops of the space of r starting within r.
+func within(ops []readOp, r readOp) []readOp {
+	var res []readOp
+	for _, op := range ops {
+		if op.space == r.space && r.rng().Contains(op.register) {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+// splitUnavailable separates the optional ops rejected by the slave from
+// the ops of a batch.
+func splitUnavailable(ops []Read, unavailable map[uint16]error) ([]Read, []Unavailable) {
+	if len(unavailable) == 0 {
+		return ops, nil
+	}
+	available := make([]Read, 0, len(ops))
+	var absent []Unavailable
+	for _, op := range ops {
+		if err, ok := unavailable[op.Register()]; ok && isOptional(op) {
+			absent = append(absent, Unavailable{op, err})
+			continue
+		}
+		available = append(available, op)
+	}
+	return available, absent
+}
@@ -0,0 +1,119 @@
+package modbus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchRead_optional(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing []uint16
+		ops     []modbus.Read
+		want    modbus.Registers
+		absent  []uint16
+		// requests holds the data of the expected function 3 requests
+		requests [][]byte
+	}{
+		{
+			name:    "mixed chunk",
+			missing: []uint16{12},
+			ops: []modbus.Read{
+				testRead{10, types.Uint16Type},
+				testRead{11, types.Uint16Type},
+				modbus.OptionalRead(testRead{12, types.Uint16Type}),
+				testRead{13, types.Uint16Type},
+			},
+			want:     modbus.Registers{10: types.Uint16(10), 11: types.Uint16(11), 13: types.Uint16(13)},
+			absent:   []uint16{12},
+			requests: [][]byte{{0, 10, 0, 4}, {0, 10, 0, 2}, {0, 13, 0, 1}, {0, 12, 0, 1}},
+		},
+		{
+			name:    "optional chunk",
+			missing: []uint16{21},
+			ops: []modbus.Read{
+				modbus.OptionalRead(testRead{20, types.Uint16Type}),
+				modbus.OptionalRead(testRead{21, types.Uint16Type}),
+			},
+			want:     modbus.Registers{20: types.Uint16(20)},
+			absent:   []uint16{21},
+			requests: [][]byte{{0, 20, 0, 2}, {0, 20, 0, 1}, {0, 21, 0, 1}},
+		},
+		{
+			name: "optional present",
+			ops: []modbus.Read{
+				testRead{10, types.Uint16Type},
+				modbus.OptionalRead(testRead{11, types.Uint16Type}),
+			},
+			want:     modbus.Registers{10: types.Uint16(10), 11: types.Uint16(11)},
+			requests: [][]byte{{0, 10, 0, 2}},
+		},
+		{
+			name:    "separate chunks",
+			missing: []uint16{30},
+			ops: []modbus.Read{
+				testRead{10, types.Uint16Type},
+				modbus.OptionalRead(testRead{30, types.Uint16Type}),
+			},
+			want:     modbus.Registers{10: types.Uint16(10)},
+			absent:   []uint16{30},
+			requests: [][]byte{{0, 10, 0, 1}, {0, 30, 0, 1}, {0, 30, 0, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			for r := uint16(0); r < 40; r++ {
+				slave.set(r, 0, byte(r))
+			}
+			for _, r := range tt.missing {
+				slave.missing[r] = true
+			}
+			client := modbus.NewClient(slave)
+
+			res, err := client.BatchRead(tt.ops)
+			assert.NoError(t, err)
+			for _, r := range tt.absent {
+				u, ok := res[r].(modbus.Unavailable)
+				if assert.True(t, ok, "register %d: %v", r, res[r]) {
+					assert.True(t, errors.Is(u, modbus.ErrOptionalUnavailable))
+					assert.Equal(t, r, u.Op.Register())
+				}
+				delete(res, r)
+			}
+			assert.Equal(t, tt.want, res)
+			if assert.Len(t, slave.requests, len(tt.requests)) {
+				for i, r := range slave.requests {
+					assert.Equal(t, tt.requests[i], r.Data, "request %d", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_BatchRead_optionalFailures(t *testing.T) {
+	ops := []modbus.Read{
+		testRead{10, types.Uint16Type},
+		modbus.OptionalRead(testRead{11, types.Uint16Type}),
+	}
+
+	slave := newTestSlave()
+	slave.missing[10] = true
+	_, err := modbus.NewClient(slave).BatchRead(ops)
+	assert.Error(t, err, "missing required registers fail the batch")
+	assert.False(t, errors.Is(err, modbus.ErrOptionalUnavailable), "%v", err)
+
+	slave = newTestSlave()
+	slave.failures = 1
+	_, err = modbus.NewClient(slave).BatchRead(ops)
+	assert.True(t, errors.Is(err, errTransport), "transport failures are not downgraded: %v", err)
+	assert.Equal(t, 1, slave.calls())
+
+	client := modbus.NewClient(newTestSlave())
+	err = client.Write(11, modbus.Unavailable{Op: ops[1], Err: errTransport})
+	assert.True(t, errors.Is(err, modbus.ErrOptionalUnavailable), "unavailable values are not written: %v", err)
+}
@@ -0,0 +1,517 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/tdemin/opmodbus/store"
+)
+
+// Config holds the settings a client was built with. It is resolved
+// once in NewClient from the options and never changes afterwards; see
+// Client.Config.
+//
+// Batch operations accepting BatchOptions can override parts of it for
+// a single call. Settings take precedence in this order: BatchOptions,
+// options passed to NewClient, defaults.
+//
+// All operations of a client are serialized: a batch holds the client
+// mutex while it performs its wire requests, and is never interleaved
+// with requests of other operations.
+type Config struct {
+	Limits Limits
+	Retry  Retry
+	// Verify enables verification of writes if not nil.
+	Verify *Verify
+	// Store persists state of the client that should survive restarts,
+	// currently write budget history, if not nil. StoreErrors receives
+	// failures to load or save that state; they never fail operations.
+	Store       store.Store
+	StoreErrors func(error)
+	// StrictSpec makes batch operations fail with ErrSpecViolation
+	// before sending anything if a planned request or a setting would
+	// violate the Modbus specification: requests above the protocol
+	// maximums and vendor functions of custom regions. See CheckSpec.
+	// Ops of zero registers are rejected with ErrEmptyOperation in any
+	// mode.
+	StrictSpec bool
+	// Probe is the register Open reads to check that the slave responds
+	// if not nil, see WithProbe.
+	Probe *uint16
+	// RequestSpacing is the bus silence kept after every response, see
+	// WithRequestSpacing.
+	RequestSpacing time.Duration
+	// BatchTimeout bounds the duration of batch operations if positive,
+	// see WithBatchTimeout.
+	BatchTimeout time.Duration
+	// Interceptors observe the register requests of the client, see
+	// WithInterceptors.
+	Interceptors []Interceptor
+	// Logger receives the log of register requests if not nil, see
+	// WithLogger.
+	Logger Logger
+	// ReadCacheTTL enables the read cache if positive, see
+	// WithReadCache.
+	ReadCacheTTL time.Duration
+	// WriteConflicts decides on write ops of overlapping registers, see
+	// WithWriteConflicts.
+	WriteConflicts ConflictPolicy
+	// ShutdownPolicy decides on the batches in flight during Shutdown,
+	// see WithShutdownPolicy.
+	ShutdownPolicy ShutdownPolicy
+	// CheckTypes enables type checks of write batches, see
+	// WithTypeChecks.
+	CheckTypes bool
+	// LatencyRanges are the ranges response times are collected for,
+	// see WithLatencyRanges.
+	LatencyRanges []RegisterRange
+	// ChunkCacheTTL enables the chunk cache if positive, see
+	// WithChunkCache.
+	ChunkCacheTTL time.Duration
+	// SlowRanges are the ranges the slave serves slower than the rest,
+	// see WithSlowRanges.
+	SlowRanges []SlowRange
+	// WriteBudgets limit how often registers may be written, see
+	// WithWriteBudgets. OverrideWriteBudgets only accounts for writes
+	// without enforcing the budgets, see WithWriteBudgetOverride.
+	WriteBudgets         []WriteBudget
+	OverrideWriteBudgets bool
+	// ApplyRules declare registers written after writes of guarded
+	// ranges, see WithApplyRules.
+	ApplyRules []ApplyRule
+	// EmulateMaskWrite makes mask writes read and write registers
+	// instead of using function 22, see WithMaskWriteEmulation.
+	EmulateMaskWrite bool
+	// AdaptiveTimeout tunes the handler timeout if not nil, see
+	// WithAdaptiveTimeout.
+	AdaptiveTimeout *AdaptiveTimeout
+	// Identity names the device the client talks to, see WithIdentity.
+	Identity string
+	// ValidationRules are checked against the values read, see
+	// WithValidationRules.
+	ValidationRules []ValidationRule
+	// CustomRegions route reads of register ranges to user-supplied
+	// functions, see WithCustomRegions.
+	CustomRegions []CustomRegion
+
+	// err is the first error of the options, see ErrInvalidOption
+	err error
+}
+
+// Limits bounds the size of batches and of their wire requests.
+//
+// Ops are only merged while the merged request fits the register
+// limits. Read ops exceeding the read limit on their own are split into
+// several requests whose data is reassembled before conversion, unless
+// StrictReadSize is set; optional ops are never split. Likewise, write
+// ops exceeding the write limit are split into several requests of
+// consecutive registers unless StrictWriteSize is set. Other ops
+// exceeding the limits fail with ErrTooManyRegisters. Zero register
+// limits mean the protocol maximums of 125 registers for reads and 123
+// for writes; limits above them are rejected with ErrInvalidOption,
+// see WithLimits and BatchOptions.Limits.
+//
+// Batches of more ops or planned requests than MaxOps and MaxRequests
+// fail with a *BatchSizeError before any request is sent. Zero or
+// negative caps mean the defaults of 100000 ops and 10000 requests.
+//
+// MaxReadGap lets reads separated by up to that many unread registers
+// be merged into one request, trading throwaway registers for round
+// trips. The values of the gap registers are not reported. Gaps never
+// cross slow ranges or custom regions, and writes are never merged
+// across gaps. Only use it with slaves serving every register of the
+// gaps: a request covering a missing register fails as a whole. Zero,
+// the default, merges exactly adjacent reads only.
+type Limits struct {
+	MaxReadQuantity  uint16
+	MaxWriteQuantity uint16
+	MaxOps           int
+	MaxRequests      int
+	MaxReadGap       uint16
+	StrictReadSize   bool
+	StrictWriteSize  bool
+}
+
+const (
+	defaultMaxOps      = 100000
+	defaultMaxRequests = 10000
+)
+
+func (l Limits) read() uint16 {
+	if l.MaxReadQuantity == 0 {
+		return maxFunc3Quantity
+	}
+	return l.MaxReadQuantity
+}
+
+func (l Limits) write() uint16 {
+	if l.MaxWriteQuantity == 0 {
+		return maxFunc16Quantity
+	}
+	return l.MaxWriteQuantity
+}
+
+// outOfRange describes the register limit of l above the protocol
+// maximums, if any.
+func (l Limits) outOfRange() string {
+	switch {
+	case l.MaxReadQuantity > maxFunc3Quantity:
+		return fmt.Sprintf("max read quantity of %d exceeds %d", l.MaxReadQuantity, maxFunc3Quantity)
+	case l.MaxWriteQuantity > maxFunc16Quantity:
+		return fmt.Sprintf("max write quantity of %d exceeds %d", l.MaxWriteQuantity, maxFunc16Quantity)
+	}
+	return ""
+}
+
+// splits reports whether r is split into several requests.
+func (l Limits) splits(r readOp) bool {
+	return !l.StrictReadSize && r.quantity > l.read()
+}
+
+// splitsWrite reports whether w is split into several requests.
+func (l Limits) splitsWrite(w writeOp) bool {
+	return !l.StrictWriteSize && w.quantity > l.write()
+}
+
+func (l Limits) checkRead(r readOp) error {
+	if max := l.read(); r.quantity > max {
+		return fmt.Errorf("%w: %d: %v", ErrTooManyRegisters, max, r)
+	}
+	return nil
+}
+
+func (l Limits) checkWrite(w writeOp) error {
+	if max := l.write(); w.quantity > max {
+		return fmt.Errorf("%w: %d: %v", ErrTooManyRegisters, max, w)
+	}
+	return nil
+}
+
+// checkOps fails batches of more than MaxOps ops.
+func (l Limits) checkOps(n int) error {
+	max := l.MaxOps
+	if max <= 0 {
+		max = defaultMaxOps
+	}
+	if n > max {
+		return &BatchSizeError{"ops", "MaxOps", max, n}
+	}
+	return nil
+}
+
+// checkRequests fails batches planned to more than MaxRequests wire
+// requests.
+func (l Limits) checkRequests(n int) error {
+	max := l.MaxRequests
+	if max <= 0 {
+		max = defaultMaxRequests
+	}
+	if n > max {
+		return &BatchSizeError{"requests", "MaxRequests", max, n}
+	}
+	return nil
+}
+
+// ErrBatchTooLarge is wrapped by BatchSizeError.
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// BatchSizeError is returned when a batch exceeds a cap of Limits.
+type BatchSizeError struct {
+	// What is either "ops" or "requests".
+	What string
+	// Limit names the field of Limits setting the cap.
+	Limit string
+	Cap   int
+	Size  int
+}
+
+func (e *BatchSizeError) Error() string {
+	return fmt.Sprintf("%v: %d %s exceed the cap of %d; split the batch or raise Limits.%s",
+		ErrBatchTooLarge, e.Size, e.What, e.Cap, e.Limit)
+}
+
+func (e *BatchSizeError) Unwrap() error {
+	return ErrBatchTooLarge
+}
+
+// Retry configures retries of wire requests that failed with a
+// transport error, such as a timeout. Modbus exceptions returned by the
+// slave are never retried. Retries are disabled by default.
+type Retry struct {
+	// Retries is the number of attempts made after the first one.
+	Retries int
+	// Delay is waited before every retry, unless the context of the
+	// operation is done first.
+	Delay time.Duration
+	// Policy decides on retries instead of Retries and Delay if not
+	// nil, see WithRetryPolicy.
+	Policy RetryPolicy
+}
+
+// do runs f until it succeeds, fails with a Modbus exception, or
+// retries are exhausted, or as long as the policy says so. Waiting for
+// a retry ends early with the error of ctx once it is done.
+func (r Retry) do(ctx context.Context, f func() error) error {
+	err := f()
+	if r.Policy != nil {
+		for attempt := 1; err != nil; attempt++ {
+			delay, ok := r.Policy.ShouldRetry(attempt, err)
+			if !ok {
+				break
+			}
+			if err := wait(ctx, delay); err != nil {
+				return err
+			}
+			err = f()
+		}
+		return err
+	}
+	for i := 0; i < r.Retries && err != nil && !isException(err); i++ {
+		if err := wait(ctx, r.Delay); err != nil {
+			return err
+		}
+		err = f()
+	}
+	return err
+}
+
+// wait sleeps for delay unless ctx is done first.
+func wait(ctx context.Context, delay time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+func isException(err error) bool {
+	var e *modbus.ModbusError
+	return errors.As(err, &e)
+}
+
+func isIllegalFunction(err error) bool {
+	var e *modbus.ModbusError
+	return errors.As(err, &e) && e.ExceptionCode == modbus.ExceptionCodeIllegalFunction
+}
+
+// Option configures a client in NewClient and Open. Options are
+// validated eagerly, and Open fails for invalid ones.
+type Option func(*Config)
+
+// ErrInvalidOption is returned by Open and operations of a client built
+// with an option whose setting is out of range, or with options
+// conflicting with each other.
+var ErrInvalidOption = errors.New("invalid option")
+
+// invalid records the first error of the options.
+func (c *Config) invalid(format string, args ...interface{}) {
+	if c.err == nil {
+		c.err = fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidOption}, args...)...)
+	}
+}
+
+// check records settings out of range or conflicting with each other
+// once all the options are applied.
+func (c *Config) check() {
+	switch {
+	case c.Retry.Retries < 0 || c.Retry.Delay < 0:
+		c.invalid("negative retries or retry delay")
+	case c.Retry.Policy != nil && (c.Retry.Retries != 0 || c.Retry.Delay != 0):
+		c.invalid("retry policy combined with retries or a retry delay")
+	case c.Verify != nil && (c.Verify.Retries < 0 || c.Verify.Settle < 0 || c.Verify.Interval < 0):
+		c.invalid("negative verification retries or delays")
+	case c.RequestSpacing < 0:
+		c.invalid("negative request spacing of %v", c.RequestSpacing)
+	case c.BatchTimeout < 0:
+		c.invalid("negative batch timeout of %v", c.BatchTimeout)
+	case c.ReadCacheTTL < 0:
+		c.invalid("negative read cache TTL of %v", c.ReadCacheTTL)
+	case c.ChunkCacheTTL < 0:
+		c.invalid("negative chunk cache TTL of %v", c.ChunkCacheTTL)
+	case c.AdaptiveTimeout != nil && (c.AdaptiveTimeout.Min < 0 || c.AdaptiveTimeout.Max < 0 || c.AdaptiveTimeout.Factor < 0):
+		c.invalid("negative adaptive timeout bounds or factor")
+	case c.WriteConflicts != RejectConflicts && c.WriteConflicts != LastWins:
+		c.invalid("unknown write conflict policy %d", c.WriteConflicts)
+	}
+}
+
+// WithLimits sets the limits of wire request size. Register limits
+// above the protocol maximums are invalid.
+func WithLimits(l Limits) Option {
+	return func(c *Config) {
+		if s := l.outOfRange(); s != "" {
+			c.invalid("%s", s)
+			return
+		}
+		c.Limits = l
+	}
+}
+
+// WithMaxReadQuantity sets Limits.MaxReadQuantity for slaves failing on
+// reads below the protocol maximum: reads are merged up to n registers,
+// and longer read ops are split. n must be from 1 to 125.
+func WithMaxReadQuantity(n uint16) Option {
+	return func(c *Config) {
+		if n == 0 || n > maxFunc3Quantity {
+			c.invalid("max read quantity of %d is out of 1-%d", n, maxFunc3Quantity)
+			return
+		}
+		c.Limits.MaxReadQuantity = n
+	}
+}
+
+// WithMaxWriteQuantity is WithMaxReadQuantity for writes, setting
+// Limits.MaxWriteQuantity. n must be from 1 to 123.
+func WithMaxWriteQuantity(n uint16) Option {
+	return func(c *Config) {
+		if n == 0 || n > maxFunc16Quantity {
+			c.invalid("max write quantity of %d is out of 1-%d", n, maxFunc16Quantity)
+			return
+		}
+		c.Limits.MaxWriteQuantity = n
+	}
+}
+
+// WithRetry sets retries of failed wire requests.
+func WithRetry(r Retry) Option {
+	return func(c *Config) {
+		c.Retry = r
+	}
+}
+
+// WithStore persists state of the client in s. Errors of s are
+// reported to onError if not nil. Use store.WithPrefix to share a store
+// between several clients.
+func WithStore(s store.Store, onError func(error)) Option {
+	return func(c *Config) {
+		c.Store = s
+		c.StoreErrors = onError
+	}
+}
+
+// Config returns a copy of the settings the client was built with.
+// Changing it doesn't affect the client.
+func (c *Client) Config() Config {
+	cfg := c.config
+	if cfg.Verify != nil {
+		v := *cfg.Verify
+		cfg.Verify = &v
+	}
+	if cfg.Probe != nil {
+		p := *cfg.Probe
+		cfg.Probe = &p
+	}
+	if cfg.AdaptiveTimeout != nil {
+		t := *cfg.AdaptiveTimeout
+		cfg.AdaptiveTimeout = &t
+	}
+	cfg.Interceptors = append([]Interceptor(nil), cfg.Interceptors...)
+	cfg.LatencyRanges = append([]RegisterRange(nil), cfg.LatencyRanges...)
+	cfg.SlowRanges = append([]SlowRange(nil), cfg.SlowRanges...)
+	cfg.WriteBudgets = append([]WriteBudget(nil), cfg.WriteBudgets...)
+	cfg.ApplyRules = append([]ApplyRule(nil), cfg.ApplyRules...)
+	cfg.ValidationRules = append([]ValidationRule(nil), cfg.ValidationRules...)
+	cfg.CustomRegions = append([]CustomRegion(nil), cfg.CustomRegions...)
+	return cfg
+}
+
+// BatchOptions overrides settings of Config for a single batch
+// operation. Nil fields keep the settings of the client.
+type BatchOptions struct {
+	Limits *Limits
+	Retry  *Retry
+	// DisableDiff turns off differential optimization in BatchWriteWith
+	// even if oldData is given. oldData is still used for type checks.
+	DisableDiff bool
+	// Partial makes BatchReadWith go on after failed requests, see
+	// BatchReadPartial.
+	Partial bool
+	// ContinueOnError makes BatchWriteWith attempt all the write
+	// requests even if some of them fail, see WriteErrors.
+	ContinueOnError bool
+	// Tolerance makes differential optimization in BatchWriteWith skip
+	// floating-point ops within it of their value in oldData if not
+	// nil. Other ops of the batch, and registers read with ReadBack,
+	// are compared exactly.
+	Tolerance *Tolerance
+	// ReadBack makes BatchWriteWith read the registers of the ops from
+	// the slave before writing, under the same lock, and skip ops whose
+	// registers already hold their values, instead of diffing against
+	// oldData. Verification reuses the read requests, and skips the
+	// skipped ops. DisableDiff turns ReadBack off.
+	ReadBack bool
+	// Context stops the batch between wire requests once it is done,
+	// and is passed to the extension points called during the batch,
+	// such as ValidationRule.CheckContext. Nil means
+	// context.Background().
+	Context context.Context
+	// Timeout overrides Config.BatchTimeout if not nil. Zero disables
+	// the timeout.
+	Timeout *time.Duration
+	// Unit addresses the batch to a unit other than the one of the
+	// handler if not nil, see Unit.
+	Unit *byte
+	// Stats receives the accounting of the batch if not nil.
+	Stats *BatchStats
+
+	// drain sets batchConfig.drain
+	drain bool
+}
+
+func (o BatchOptions) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// batchConfig is the Config of a single batch, resolved from its
+// BatchOptions, along with the state of the batch.
+type batchConfig struct {
+	Config
+	// unit is the unit a batch is addressed to, see batchUnit
+	unit *byte
+	// batch are the ops of a batch before merging, see OpInfo.Ops
+	batch []readOp
+	// stats accounts for the requests of a batch, see BatchStats
+	stats *BatchStats
+	// sent receives the attempted write requests of a batch if not
+	// nil, see BatchWriteTracked
+	sent *[]sentWrite
+	// unverified are the registers of Unverified ops of a batch
+	unverified []RegisterRange
+	// tolerance is the BatchOptions.Tolerance of a batch
+	tolerance *Tolerance
+	// cacheable are the read ops of a batch batchRead caches the data
+	// of, see WithReadCache
+	cacheable []Read
+	// drain admits the writes of a batch while Shutdown stops the
+	// components, so that a WriteQueue flushes its pending ops
+	drain bool
+	// expiry receives the deadline a batch was bounded by if not nil,
+	// see BatchTimeout
+	expiry *time.Time
+}
+
+// resolve applies opts on top of the client settings.
+func (c *Client) resolve(opts BatchOptions) (batchConfig, error) {
+	cfg := batchConfig{Config: c.config}
+	if opts.Limits != nil {
+		if s := opts.Limits.outOfRange(); s != "" {
+			return batchConfig{}, fmt.Errorf("%w: BatchOptions.Limits: %s", ErrInvalidOption, s)
+		}
+		cfg.Limits = *opts.Limits
+	}
+	if opts.Retry != nil {
+		cfg.Retry = *opts.Retry
+	}
+	if opts.Timeout != nil {
+		cfg.BatchTimeout = *opts.Timeout
+	}
+	cfg.tolerance, cfg.drain = opts.Tolerance, opts.drain
+	return cfg, nil
+}
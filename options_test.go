@@ -0,0 +1,398 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestNewClient_options(t *testing.T) {
+	slave := newTestSlave()
+	assert.Equal(t, modbus.Config{}, modbus.NewClient(slave).Config())
+
+	client := modbus.NewClient(slave,
+		modbus.WithLimits(modbus.Limits{MaxReadQuantity: 10}),
+		modbus.WithRetry(modbus.Retry{Retries: 2}),
+		modbus.WithLimits(modbus.Limits{MaxReadQuantity: 20, MaxWriteQuantity: 5}),
+	)
+	assert.Equal(t, modbus.Config{
+		Limits: modbus.Limits{MaxReadQuantity: 20, MaxWriteQuantity: 5},
+		Retry:  modbus.Retry{Retries: 2},
+	}, client.Config(), "later options override earlier ones")
+}
+
+func TestClient_Config_copy(t *testing.T) {
+	slow := modbus.SlowRange{RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 2}}
+	client := modbus.NewClient(newTestSlave(),
+		modbus.WithSlowRanges(slow),
+		modbus.WithAdaptiveTimeout(modbus.AdaptiveTimeout{Factor: 2}),
+		modbus.WithIdentity("meter"),
+		modbus.WithTypeChecks(),
+	)
+	want := modbus.Config{
+		SlowRanges:      []modbus.SlowRange{slow},
+		AdaptiveTimeout: &modbus.AdaptiveTimeout{Factor: 2},
+		Identity:        "meter",
+		CheckTypes:      true,
+	}
+	cfg := client.Config()
+	assert.Equal(t, want, cfg)
+
+	cfg.SlowRanges[0].Register = 20
+	cfg.AdaptiveTimeout.Factor = 3
+	assert.Equal(t, want, client.Config(), "changing a copy doesn't affect the client")
+}
+
+func TestClient_BatchReadWith_limits(t *testing.T) {
+	ops := []modbus.Read{
+		testRead{0, types.Uint64Type},
+		testRead{4, types.Uint64Type},
+		testRead{8, types.Uint64Type},
+	}
+	tests := []struct {
+		name     string
+		options  []modbus.Option
+		batch    modbus.BatchOptions
+		requests int
+		err      error
+	}{
+		{"defaults", nil, modbus.BatchOptions{}, 1, nil},
+		{
+			name:     "client limits",
+			options:  []modbus.Option{modbus.WithLimits(modbus.Limits{MaxReadQuantity: 8})},
+			requests: 2,
+		},
+		{
+			name:     "batch overrides client",
+			options:  []modbus.Option{modbus.WithLimits(modbus.Limits{MaxReadQuantity: 8})},
+			batch:    modbus.BatchOptions{Limits: &modbus.Limits{}},
+			requests: 1,
+		},
+		{
+			name:     "batch overrides defaults",
+			batch:    modbus.BatchOptions{Limits: &modbus.Limits{MaxReadQuantity: 4}},
+			requests: 3,
+		},
+		{
+			name:     "op split at the limit",
+			batch:    modbus.BatchOptions{Limits: &modbus.Limits{MaxReadQuantity: 3}},
+			requests: 6,
+		},
+		{
+			name:  "strict op exceeding the limit",
+			batch: modbus.BatchOptions{Limits: &modbus.Limits{MaxReadQuantity: 3, StrictReadSize: true}},
+			err:   modbus.ErrTooManyRegisters,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			client := modbus.NewClient(slave, tt.options...)
+
+			_, err := client.BatchReadWith(ops, tt.batch)
+			assert.True(t, errors.Is(err, tt.err), "%v", err)
+			assert.Equal(t, tt.requests, slave.calls())
+		})
+	}
+}
+
+func TestClient_BatchWriteWith_limits(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithLimits(modbus.Limits{MaxWriteQuantity: 4}))
+	ops := []modbus.Write{
+		testWrite{0, types.Uint64(1)},
+		testWrite{4, types.Uint64(2)},
+	}
+
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.Equal(t, 2, slave.calls())
+	err := client.Write(0, types.Float64(1))
+	assert.NoError(t, err)
+	err = client.BatchWrite([]modbus.Write{testWrite{0, rawWrite(make([]byte, 10))}}, nil)
+	assert.NoError(t, err, "ops are split at the limit")
+	assert.Equal(t, 5, slave.calls())
+	err = client.BatchWriteWith([]modbus.Write{testWrite{0, rawWrite(make([]byte, 10))}}, nil, modbus.BatchOptions{
+		Limits: &modbus.Limits{MaxWriteQuantity: 4, StrictWriteSize: true},
+	})
+	assert.True(t, errors.Is(err, modbus.ErrTooManyRegisters))
+	assert.Equal(t, 5, slave.calls())
+	err = client.BatchWriteWith(ops, nil, modbus.BatchOptions{Limits: &modbus.Limits{MaxWriteQuantity: 124}})
+	assert.ErrorIs(t, err, modbus.ErrInvalidOption, "limits above the protocol maximums are rejected")
+	assert.Equal(t, 5, slave.calls())
+}
+
+func TestWithMaxQuantity(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  modbus.Option
+		// requests of two adjacent 60-register reads and writes
+		reads, writes int
+		err           error
+	}{
+		{"protocol maximums by default", func(*modbus.Config) {}, 1, 1, nil},
+		{"read cap", modbus.WithMaxReadQuantity(100), 2, 1, nil},
+		{"write cap", modbus.WithMaxWriteQuantity(100), 1, 2, nil},
+		{"read cap above the protocol maximum", modbus.WithMaxReadQuantity(150), 0, 0, modbus.ErrInvalidOption},
+		{"write cap above the protocol maximum", modbus.WithMaxWriteQuantity(124), 0, 0, modbus.ErrInvalidOption},
+		{"zero cap", modbus.WithMaxReadQuantity(0), 0, 0, modbus.ErrInvalidOption},
+		{"read limit above the protocol maximum", modbus.WithLimits(modbus.Limits{MaxReadQuantity: 2000}), 0, 0, modbus.ErrInvalidOption},
+		{"write limit above the protocol maximum", modbus.WithLimits(modbus.Limits{MaxWriteQuantity: 124}), 0, 0, modbus.ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			client, err := modbus.Open(slave, tt.opt)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				_, err := modbus.NewClient(slave, tt.opt).BatchRead([]modbus.Read{testRead{0, types.NewRaw(60)}})
+				assert.ErrorIs(t, err, tt.err, "operations fail as well")
+				assert.Zero(t, slave.calls())
+				return
+			}
+
+			_, err = client.BatchRead([]modbus.Read{testRead{0, types.NewRaw(60)}, testRead{60, types.NewRaw(60)}})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.reads, slave.calls())
+			err = client.BatchWrite([]modbus.Write{testWrite{0, rawWrite(make([]byte, 120))}, testWrite{60, rawWrite(make([]byte, 120))}}, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.writes, slave.writes())
+		})
+	}
+}
+
+func TestOpen_invalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []modbus.Option
+	}{
+		{"negative retries", []modbus.Option{modbus.WithRetry(modbus.Retry{Retries: -1})}},
+		{"retry policy with retries", []modbus.Option{
+			modbus.WithRetry(modbus.Retry{Retries: 2}),
+			modbus.WithRetryPolicy(modbus.ExponentialBackoff{Retries: 2}),
+		}},
+		{"negative verification interval", []modbus.Option{modbus.WithVerify(modbus.Verify{Interval: -time.Second})}},
+		{"negative request spacing", []modbus.Option{modbus.WithRequestSpacing(-time.Second)}},
+		{"negative batch timeout", []modbus.Option{modbus.WithBatchTimeout(-time.Second)}},
+		{"negative read cache TTL", []modbus.Option{modbus.WithReadCache(-time.Second)}},
+		{"negative chunk cache TTL", []modbus.Option{modbus.WithChunkCache(-time.Second)}},
+		{"negative adaptive timeout factor", []modbus.Option{modbus.WithAdaptiveTimeout(modbus.AdaptiveTimeout{Factor: -1})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := modbus.Open(newTestSlave(), tt.opts...)
+			assert.ErrorIs(t, err, modbus.ErrInvalidOption)
+		})
+	}
+
+	_, err := modbus.Open(newTestSlave(), modbus.WithRetry(modbus.Retry{}), modbus.WithRetryPolicy(modbus.ExponentialBackoff{Retries: 2}))
+	assert.NoError(t, err)
+}
+
+func TestClient_maxReadGap(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2), 12: types.Uint16(3), 13: types.Uint16(4)})
+	client := modbus.NewClient(slave, modbus.WithLimits(modbus.Limits{MaxReadGap: 2}))
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		testRead{13, types.Uint16Type},
+		testRead{10, types.Uint16Type},
+		testRead{20, types.Uint16Type},
+	}, []modbustest.WireExpectation{modbustest.Read(10, 4), modbustest.Read(20, 1)})
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 13: types.Uint16(4), 20: types.Uint16(0)}, res,
+		"registers of the gap are not reported")
+
+	modbustest.ExpectWrites(t, client, []modbus.Write{
+		testWrite{13, types.Uint16(5)},
+		testWrite{10, types.Uint16(6)},
+	}, nil, []modbustest.WireExpectation{modbustest.Write(10, 0, 6), modbustest.Write(13, 0, 5)})
+	assert.Equal(t, []byte{0, 6, 0, 2, 0, 3, 0, 5}, slave.Get(10, 4), "registers of the gap are not written")
+}
+
+func TestClient_retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []modbus.Option
+		batch    modbus.BatchOptions
+		failures int
+		requests int
+		err      error
+	}{
+		{"disabled", nil, modbus.BatchOptions{}, 1, 1, errTransport},
+		{
+			name:     "recovers",
+			options:  []modbus.Option{modbus.WithRetry(modbus.Retry{Retries: 2})},
+			failures: 2,
+			requests: 3,
+		},
+		{
+			name:     "exhausted",
+			options:  []modbus.Option{modbus.WithRetry(modbus.Retry{Retries: 2})},
+			failures: 3,
+			requests: 3,
+			err:      errTransport,
+		},
+		{
+			name:     "batch overrides client",
+			options:  []modbus.Option{modbus.WithRetry(modbus.Retry{Retries: 2})},
+			batch:    modbus.BatchOptions{Retry: &modbus.Retry{}},
+			failures: 1,
+			requests: 1,
+			err:      errTransport,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.failures = tt.failures
+			client := modbus.NewClient(slave, tt.options...)
+
+			_, err := client.BatchReadWith([]modbus.Read{testRead{10, types.Uint16Type}}, tt.batch)
+			assert.True(t, errors.Is(err, tt.err), "%v", err)
+			assert.Equal(t, tt.requests, slave.calls())
+		})
+	}
+}
+
+func TestClient_retry_exception(t *testing.T) {
+	slave := newTestSlave()
+	slave.readOnly[10] = true
+	client := modbus.NewClient(slave, modbus.WithRetry(modbus.Retry{Retries: 3}))
+
+	err := client.Write(10, types.Uint16(1))
+	var exception *goburrow.ModbusError
+	assert.True(t, errors.As(err, &exception))
+	assert.Equal(t, 1, slave.calls(), "exceptions are not retried")
+}
+
+func TestClient_retry_context(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry modbus.Retry
+	}{
+		{"delay", modbus.Retry{Retries: 1, Delay: time.Hour}},
+		{"policy", modbus.Retry{Policy: modbus.ExponentialBackoff{Retries: 1, Initial: time.Hour}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.failures = 1
+			client := modbus.NewClient(slave, modbus.WithRetry(tt.retry))
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := client.BatchReadContext(ctx, []modbus.Read{testRead{10, types.Uint16Type}})
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, int64(time.Since(start)), int64(time.Minute), "waiting for a retry is cancelled")
+			assert.Equal(t, 1, slave.calls())
+		})
+	}
+}
+
+func TestClient_BatchWriteWith_disableDiff(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	ops := []modbus.Write{testWrite{10, types.Uint16(1)}}
+	old := modbus.Registers{10: types.Uint16(1)}
+
+	assert.NoError(t, client.BatchWrite(ops, old))
+	assert.Equal(t, 0, slave.calls())
+	assert.NoError(t, client.BatchWriteWith(ops, old, modbus.BatchOptions{DisableDiff: true}))
+	assert.Equal(t, 1, slave.calls())
+}
+
+// rawWrite is a value of arbitrary bytes.
+type rawWrite []byte
+
+func (w rawWrite) Bytes() []byte { return w }
+
+func TestClient_batchSizeCaps(t *testing.T) {
+	reads := func(registers ...uint16) []modbus.Read {
+		ops := make([]modbus.Read, len(registers))
+		for i, r := range registers {
+			ops[i] = testRead{r, types.Uint16Type}
+		}
+		return ops
+	}
+	writes := func(registers ...uint16) []modbus.Write {
+		ops := make([]modbus.Write, len(registers))
+		for i, r := range registers {
+			ops[i] = testWrite{r, types.Uint16(1)}
+		}
+		return ops
+	}
+	caps := modbus.Limits{MaxOps: 3, MaxRequests: 2}
+	tests := []struct {
+		name string
+		run  func(c *modbus.Client) error
+		// err is the exceeded cap if any
+		err string
+	}{
+		{"reads at ops cap", func(c *modbus.Client) error {
+			_, err := c.BatchRead(reads(10, 11, 20))
+			return err
+		}, ""},
+		{"reads over ops cap", func(c *modbus.Client) error {
+			_, err := c.BatchRead(reads(10, 11, 12, 13))
+			return err
+		}, "MaxOps"},
+		{"reads over requests cap", func(c *modbus.Client) error {
+			_, err := c.BatchRead(reads(10, 20, 30))
+			return err
+		}, "MaxRequests"},
+		{"writes at ops cap", func(c *modbus.Client) error {
+			return c.BatchWrite(writes(10, 11, 20), nil)
+		}, ""},
+		{"writes over ops cap", func(c *modbus.Client) error {
+			return c.BatchWrite(writes(10, 11, 12, 13), nil)
+		}, "MaxOps"},
+		{"writes over requests cap", func(c *modbus.Client) error {
+			return c.BatchWrite(writes(10, 20, 30), nil)
+		}, "MaxRequests"},
+		{"default caps", func(c *modbus.Client) error {
+			registers := make([]uint16, 100001)
+			_, err := c.BatchReadWith(reads(registers...), modbus.BatchOptions{Limits: &modbus.Limits{}})
+			return err
+		}, "MaxOps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			err := tt.run(modbus.NewClient(slave, modbus.WithLimits(caps)))
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var serr *modbus.BatchSizeError
+			if assert.True(t, errors.As(err, &serr), "%v", err) {
+				assert.Equal(t, tt.err, serr.Limit)
+				assert.Greater(t, serr.Size, serr.Cap)
+			}
+			assert.True(t, errors.Is(err, modbus.ErrBatchTooLarge))
+			assert.Contains(t, err.Error(), "Limits."+tt.err)
+			assert.Equal(t, 0, slave.calls(), "nothing is sent")
+		})
+	}
+}
+
+func BenchmarkClient_BatchRead_tooLarge(b *testing.B) {
+	ops := make([]modbus.Read, 2000000)
+	for i := range ops {
+		ops[i] = testRead{uint16(i), types.Uint16Type}
+	}
+	client := modbus.NewClient(newTestSlave())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.BatchRead(ops); !errors.Is(err, modbus.ErrBatchTooLarge) {
+			b.Fatal(err)
+		}
+	}
+}
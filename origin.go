@@ -0,0 +1,43 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrWrongDevice is returned when an op built for one device is
+// submitted to a client talking to another one.
+var ErrWrongDevice = errors.New("op belongs to another device")
+
+// Origin may be implemented by Read and Write ops that know the device
+// they were built for, e.g. ops derived from a device register map. If
+// the client has an identity, see WithIdentity, batch operations fail with ErrWrongDevice on
+// ops of different origin. Ops not implementing Origin, or returning an
+// empty string, are never rejected.
+type Origin interface {
+	Origin() string
+}
+
+// WithIdentity names the device the client talks to. Batch operations
+// then reject ops whose Origin names another device.
+func WithIdentity(name string) Option {
+	return func(c *Config) {
+		c.Identity = name
+	}
+}
+
+// checkOrigin verifies that op, if labeled with an origin, matches
+// identity.
+func checkOrigin(identity string, op interface{}) error {
+	if identity == "" {
+		return nil
+	}
+	o, ok := op.(Origin)
+	if !ok {
+		return nil
+	}
+	if origin := o.Origin(); origin != "" && origin != identity {
+		return fmt.Errorf("%w: op for %q submitted to %q: %v", ErrWrongDevice, origin, identity, op)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPanic is returned when code supplied to the client, such as a
+// Converter, a Value, a handler or a callback, panics during an
+// operation. The client stays usable afterwards.
+var ErrPanic = errors.New("panic in user code")
+
+// recoverPanic converts a panic into an error stored in err. It must be
+// deferred by an exported operation before anything else, so that it
+// runs after the deferred unlocks of the operation.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", ErrPanic, r)
+	}
+}
@@ -0,0 +1,223 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// panickingHandler panics on the next request if armed.
+type panickingHandler struct {
+	*testSlave
+	armed bool
+}
+
+func (h *panickingHandler) Send(adu []byte) ([]byte, error) {
+	if h.armed {
+		h.armed = false
+		panic("handler")
+	}
+	return h.testSlave.Send(adu)
+}
+
+// panickingType panics in its Converter.
+type panickingType struct{}
+
+func (panickingType) Size() uint16  { return 1 }
+func (panickingType) Bytes() []byte { return []byte{0, 0} }
+func (panickingType) Converter() types.Converter {
+	return func([]byte) (types.Value, error) { panic("converter") }
+}
+
+// panickingValue panics in Bytes.
+type panickingValue struct{}
+
+func (panickingValue) Bytes() []byte { panic("value") }
+
+// panickingRead panics in Type.
+type panickingRead struct{}
+
+func (panickingRead) Register() uint16 { return 0 }
+func (panickingRead) Type() types.Type { panic("read") }
+
+// panickingOrigin panics in Origin.
+type panickingOrigin struct{ testRead }
+
+func (panickingOrigin) Origin() string { panic("origin") }
+
+func TestClient_panics(t *testing.T) {
+	healthy := []modbus.Read{testRead{10, types.Uint16Type}}
+	tests := []struct {
+		name string
+		// identity is set on the client so that origins are checked
+		identity string
+		rules    []modbus.ApplyRule
+		run      func(c *modbus.Client, h *panickingHandler) error
+	}{
+		{
+			name: "BatchRead converter",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{10, panickingType{}}})
+				return err
+			},
+		},
+		{
+			name: "BatchRead op",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.BatchRead([]modbus.Read{panickingRead{}})
+				return err
+			},
+		},
+		{
+			name:     "BatchRead origin",
+			identity: "meter",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.BatchRead([]modbus.Read{panickingOrigin{testRead{10, types.Uint16Type}}})
+				return err
+			},
+		},
+		{
+			name: "BatchRead handler",
+			run: func(c *modbus.Client, h *panickingHandler) error {
+				h.armed = true
+				_, err := c.BatchRead(healthy)
+				return err
+			},
+		},
+		{
+			name: "Read converter",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.Read(10, panickingType{})
+				return err
+			},
+		},
+		{
+			name: "Read handler",
+			run: func(c *modbus.Client, h *panickingHandler) error {
+				h.armed = true
+				_, err := c.Read(10, types.Uint16Type)
+				return err
+			},
+		},
+		{
+			name: "BatchWrite value",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				return c.BatchWrite([]modbus.Write{testWrite{10, panickingValue{}}}, nil)
+			},
+		},
+		{
+			name: "BatchWrite old data",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				return c.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, modbus.Registers{10: panickingValue{}})
+			},
+		},
+		{
+			name: "BatchWrite apply rule",
+			rules: []modbus.ApplyRule{{
+				Guarded:  modbus.RegisterRange{Register: 10, Quantity: 1},
+				Register: 20,
+				Value:    panickingValue{},
+			}},
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				return c.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil)
+			},
+		},
+		{
+			name: "BatchWrite handler",
+			run: func(c *modbus.Client, h *panickingHandler) error {
+				h.armed = true
+				return c.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil)
+			},
+		},
+		{
+			name: "Write value",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				return c.Write(10, panickingValue{})
+			},
+		},
+		{
+			name: "Write handler",
+			run: func(c *modbus.Client, h *panickingHandler) error {
+				h.armed = true
+				return c.Write(10, types.Uint16(1))
+			},
+		},
+		{
+			name: "Swap converter",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.Swap([]modbus.Write{testWrite{10, panickingType{}}})
+				return err
+			},
+		},
+		{
+			name: "BatchWriteBits handler",
+			run: func(c *modbus.Client, h *panickingHandler) error {
+				h.armed = true
+				return c.BatchWriteBits([]modbus.BitWrite{{Bit: modbus.Bit{Register: 10}, Value: true}})
+			},
+		},
+		{
+			name: "BatchReadStable flag",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.BatchReadStable(healthy, modbus.StableOpts{
+					Flag: func(modbus.Read) bool { panic("flag") },
+				})
+				return err
+			},
+		},
+		{
+			name: "BatchReadCrossCheck predicate",
+			run: func(c *modbus.Client, _ *panickingHandler) error {
+				_, err := c.BatchReadCrossCheck([]modbus.Read{testRead{13, types.Uint16Type}}, modbus.CrossCheckOptions{
+					Stable: func(modbus.Read) bool { panic("stable") },
+				})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.volatile[13] = true
+			handler := &panickingHandler{testSlave: slave}
+			client := modbus.NewClient(handler, modbus.WithIdentity(tt.identity), modbus.WithApplyRules(tt.rules...))
+
+			err := tt.run(client, handler)
+			assert.True(t, errors.Is(err, modbus.ErrPanic), "%v", err)
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := client.BatchRead(healthy)
+				if err == nil {
+					err = client.Write(11, types.Uint16(1))
+				}
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				assert.NoError(t, err, "client is usable after a panic")
+			case <-time.After(time.Second):
+				t.Fatal("client is deadlocked after a panic")
+			}
+		})
+	}
+}
+
+func TestClient_Shutdown_panickingHook(t *testing.T) {
+	client := modbus.NewClient(newTestSlave())
+	stopped := false
+	client.OnShutdown("first", func(context.Context) error {
+		stopped = true
+		return nil
+	})
+	client.OnShutdown("second", func(context.Context) error { panic("hook") })
+
+	err := client.Shutdown(context.Background())
+	assert.True(t, errors.Is(err, modbus.ErrPanic), "%v", err)
+	assert.True(t, stopped, "other components are still stopped")
+}
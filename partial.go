@@ -0,0 +1,210 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrPartialRead is wrapped by BatchError.
+var ErrPartialRead = errors.New("batch read partially failed")
+
+// BatchError is returned by BatchReadPartial along with the values of
+// the ops that were read if some of the requests failed.
+type BatchError struct {
+	// Errors holds the errors of the failed requests keyed by the
+	// registers of the ops they covered.
+	Errors map[uint16]error
+	// Validation holds the violations of ValidationRules by the values
+	// that were read, if any. Strict violations are returned on their
+	// own instead.
+	Validation *ValidationError
+}
+
+func (e *BatchError) Error() string {
+	registers := make([]int, 0, len(e.Errors))
+	for register := range e.Errors {
+		registers = append(registers, int(register))
+	}
+	sort.Ints(registers)
+	msgs := make([]string, len(registers))
+	for i, register := range registers {
+		msgs[i] = fmt.Sprintf("register %d: %v", register, e.Errors[uint16(register)])
+	}
+	msg := fmt.Sprintf("%v: %s", ErrPartialRead, strings.Join(msgs, "; "))
+	if e.Validation != nil {
+		msg += "; " + e.Validation.Error()
+	}
+	return msg
+}
+
+func (e *BatchError) Unwrap() error {
+	return ErrPartialRead
+}
+
+// BatchReadPartial is BatchRead going on after failed requests instead
+// of discarding the whole batch. The values of the ops covered by
+// successful requests are returned along with a *BatchError listing
+// the ops of the failed ones.
+//
+// Errors other than failed requests, such as invalid ops or a done
+// context, still fail the whole batch.
+func (c *Client) BatchReadPartial(ops []Read) (Registers, error) {
+	return c.BatchReadWith(ops, BatchOptions{Partial: true})
+}
+
+// splitFailed separates ops covered by failed requests from the rest.
+func splitFailed(ops []Read, failed map[readOp]error) ([]Read, *BatchError) {
+	if len(failed) == 0 {
+		return ops, nil
+	}
+	succeeded := make([]Read, 0, len(ops))
+	berr := &BatchError{Errors: make(map[uint16]error)}
+	for _, op := range ops {
+		if err := failedRequest(failed, readOp{op.Register(), op.Type().Size(), spaceOf(op)}); err != nil {
+			berr.Errors[op.Register()] = err
+			continue
+		}
+		succeeded = append(succeeded, op)
+	}
+	return succeeded, berr
+}
+
+// failedRequest returns the error of a failed request covering
+// registers of op, or nil. Ops split into several requests fail with
+// any of them.
+func failedRequest(failed map[readOp]error, op readOp) error {
+	for r, err := range failed {
+		if r.space == op.space && r.rng().Overlaps(op.rng()) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrWritesFailed is wrapped by WriteErrors.
+var ErrWritesFailed = errors.New("batch write partially failed")
+
+// FailedWrite is a failed wire write request.
+type FailedWrite struct {
+	Range RegisterRange
+	Err   error
+}
+
+// WriteErrors is returned by BatchWriteWith with ContinueOnError if
+// some of the write requests failed.
+//
+// Write ops skipped by differential optimization are in neither
+// Written nor Failed: their registers already held the values.
+type WriteErrors struct {
+	// Failures lists the failed requests in the order they were made.
+	Failures []FailedWrite
+	// Written and Failed hold the registers of the write ops that were
+	// and were not applied, in ascending order. Ops split into several
+	// requests, see Limits, are reported once per request, at the first
+	// register of the request.
+	Written []uint16
+	Failed  []uint16
+	// Verification holds the error of verifying the written ops, if
+	// verification is enabled and failed.
+	Verification error
+}
+
+func (e *WriteErrors) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.Err.Error()
+	}
+	msg := fmt.Sprintf("%v: %s", ErrWritesFailed, strings.Join(msgs, "; "))
+	if e.Verification != nil {
+		msg += "; " + e.Verification.Error()
+	}
+	return msg
+}
+
+func (e *WriteErrors) Unwrap() error {
+	return ErrWritesFailed
+}
+
+// Retry returns the ops of a batch that were not applied, for retrying
+// them. Ops split into several requests are retried as a whole if any
+// of their requests failed.
+func (e *WriteErrors) Retry(ops []Write) []Write {
+	var res []Write
+	for _, op := range ops {
+		i := sort.Search(len(e.Failed), func(i int) bool { return e.Failed[i] >= op.Register() })
+		end := int(op.Register()) + len(op.Value().Bytes())/2
+		if i < len(e.Failed) && (e.Failed[i] == op.Register() || int(e.Failed[i]) < end) {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+// batchWriteAll is batchWrite going on after failed requests. ops are
+// the write ops before merging. Apply writes are only performed for
+// rules triggered by successful writes, and only successful writes are
+// verified.
+func (c *Client) batchWriteAll(ctx context.Context, ops, optimized, applies []writeOp, cfg batchConfig) error {
+	if err := c.lockFor(cfg); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(ctx, cfg.BatchTimeout)
+	return done(c.writeAll(ctx, ops, optimized, applies, nil, cfg))
+}
+
+// writeAll is the body of batchWriteAll verifying with plan, see
+// verify. The caller must hold the client mutex.
+func (c *Client) writeAll(ctx context.Context, ops, optimized, applies []writeOp, plan []readOp, cfg batchConfig) error {
+	all := append(optimized[:len(optimized):len(optimized)], applies...)
+	if err := c.budgets.check(c.config.WriteBudgets, all, c.config.OverrideWriteBudgets); err != nil {
+		return err
+	}
+
+	werr := &WriteErrors{}
+	var written, failed []writeOp
+	for i, v := range optimized {
+		if err := c.pace(ctx, i); err != nil {
+			return err
+		}
+		if err := c.write(v, cfg.Retry); err != nil {
+			werr.Failures = append(werr.Failures, FailedWrite{RegisterRange{v.register, v.quantity}, writeError(v, fmt.Errorf("write request %d of %v: %w", i+1, v, err))})
+			failed = append(failed, v)
+			continue
+		}
+		written = append(written, v)
+	}
+	triggered, err := applyWrites(c.config.ApplyRules, written)
+	if err != nil {
+		return err
+	}
+	for i, v := range triggered {
+		if err := c.pace(ctx, len(optimized)+i); err != nil {
+			return err
+		}
+		if err := c.write(v, cfg.Retry); err != nil {
+			werr.Failures = append(werr.Failures, FailedWrite{RegisterRange{v.register, v.quantity}, writeError(v, fmt.Errorf("write request %d of %v: %w", len(optimized)+i+1, v, err))})
+		}
+	}
+	if len(written) > 0 {
+		werr.Verification = c.verify(ctx, cfg, written, plan, time.Now())
+	}
+
+	if len(werr.Failures) == 0 {
+		return werr.Verification
+	}
+	werr.Written = writtenRegisters(ops, written)
+	werr.Failed = writtenRegisters(ops, failed)
+	return werr
+}
@@ -0,0 +1,228 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// flakySlave is a testSlave failing reads within broken with
+// errTransport.
+type flakySlave struct {
+	*testSlave
+	broken modbus.RegisterRange
+}
+
+func (s flakySlave) Send(adu []byte) ([]byte, error) {
+	register := binary.BigEndian.Uint16(adu[1:3])
+	quantity := binary.BigEndian.Uint16(adu[3:5])
+	if adu[0] == 3 && s.broken.Contains(register) && s.broken.Contains(register+quantity-1) {
+		return nil, errTransport
+	}
+	return s.testSlave.Send(adu)
+}
+
+func TestClient_BatchReadPartial(t *testing.T) {
+	slave := newTestSlave()
+	for _, r := range []uint16{10, 11, 100, 200, 201} {
+		slave.set(r, types.Uint16(r).Bytes()...)
+	}
+	client := modbus.NewClient(flakySlave{slave, modbus.RegisterRange{Register: 100, Quantity: 2}})
+	ops := []modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+		testRead{100, types.Uint16Type},
+		testRead{101, types.Uint16Type},
+		testRead{200, types.Uint16Type},
+		testRead{201, types.Uint16Type},
+	}
+
+	_, err := client.BatchRead(ops)
+	assert.ErrorIs(t, err, errTransport, "fail-fast by default")
+	assert.False(t, errors.Is(err, modbus.ErrPartialRead))
+
+	res, err := client.BatchReadPartial(ops)
+	assert.Equal(t, modbus.Registers{
+		10:  types.Uint16(10),
+		11:  types.Uint16(11),
+		200: types.Uint16(200),
+		201: types.Uint16(201),
+	}, res)
+	assert.ErrorIs(t, err, modbus.ErrPartialRead)
+	var berr *modbus.BatchError
+	if assert.True(t, errors.As(err, &berr)) {
+		assert.Len(t, berr.Errors, 2)
+		for _, r := range []uint16{100, 101} {
+			assert.ErrorIs(t, berr.Errors[r], errTransport)
+			assert.Contains(t, berr.Errors[r].Error(), "2 registers at 100-101")
+		}
+		assert.Nil(t, berr.Validation)
+	}
+	assert.EqualError(t, err, "batch read partially failed: "+
+		"register 100: read request 2 of 2 registers at 100-101 (0x0064-0x0065): transport failure; "+
+		"register 101: read request 2 of 2 registers at 100-101 (0x0064-0x0065): transport failure")
+
+	res, err = client.BatchReadPartial(ops[:2])
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+}
+
+func TestClient_BatchReadPartial_split(t *testing.T) {
+	slave := newTestSlave()
+	// the second of three pieces fails
+	client := modbus.NewClient(flakySlave{slave, modbus.RegisterRange{Register: 125, Quantity: 125}})
+
+	res, err := client.BatchReadPartial([]modbus.Read{
+		testRead{0, types.NewRaw(300)},
+		testRead{400, types.Uint16Type},
+	})
+	assert.Equal(t, modbus.Registers{400: types.Uint16(0)}, res)
+	var berr *modbus.BatchError
+	if assert.True(t, errors.As(err, &berr)) {
+		assert.Len(t, berr.Errors, 1)
+		assert.ErrorIs(t, berr.Errors[0], errTransport)
+	}
+	assert.Equal(t, 3, slave.calls(), "the other pieces are read")
+}
+
+func TestClient_BatchReadPartial_optional(t *testing.T) {
+	slave := newTestSlave()
+	slave.missing[12] = true
+	client := modbus.NewClient(flakySlave{slave, modbus.RegisterRange{Register: 10, Quantity: 2}})
+
+	res, err := client.BatchReadPartial([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+		modbus.OptionalRead(testRead{12, types.Uint16Type}),
+		testRead{20, types.Uint16Type},
+	})
+	var berr *modbus.BatchError
+	if assert.True(t, errors.As(err, &berr)) {
+		assert.Len(t, berr.Errors, 2, "required ops of the failed fallback request")
+		assert.ErrorIs(t, berr.Errors[10], errTransport)
+		assert.ErrorIs(t, berr.Errors[11], errTransport)
+	}
+	assert.Len(t, res, 2)
+	assert.Equal(t, types.Uint16(0), res[20])
+	assert.IsType(t, modbus.Unavailable{}, res[12])
+}
+
+func TestClient_BatchReadPartial_validation(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(flakySlave{slave, modbus.RegisterRange{Register: 100, Quantity: 1}}, modbus.WithValidationRules(modbus.ValidationRule{
+		Name:  "never valid",
+		Range: modbus.RegisterRange{Register: 10, Quantity: 1},
+		Check: func(modbus.Registers) error { return errors.New("invalid") },
+	}))
+
+	res, err := client.BatchReadPartial([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{20, types.Uint16Type},
+		testRead{100, types.Uint16Type},
+	})
+	assert.Equal(t, modbus.Registers{20: types.Uint16(0)}, res)
+	assert.ErrorIs(t, err, modbus.ErrPartialRead)
+	var berr *modbus.BatchError
+	if assert.True(t, errors.As(err, &berr)) && assert.NotNil(t, berr.Validation) {
+		assert.Len(t, berr.Validation.Violations, 1)
+	}
+}
+
+func TestClient_BatchWriteWith_continueOnError(t *testing.T) {
+	ops := []modbus.Write{
+		testWrite{10, types.Uint16(1)},
+		testWrite{11, types.Uint16(2)},
+		testWrite{20, types.Uint16(3)},
+		testWrite{30, types.Uint16(4)},
+		testWrite{40, types.Uint16(5)},
+	}
+	// 30 is skipped by differential optimization
+	oldData := modbus.Registers{30: types.Uint16(4), 40: types.Uint16(0)}
+	tests := []struct {
+		name     string
+		readOnly uint16
+		request  modbus.RegisterRange
+		written  []uint16
+		failed   []uint16
+		applied  uint16
+		skipped  uint16
+	}{
+		{
+			name:     "single request fails",
+			readOnly: 20,
+			request:  modbus.RegisterRange{Register: 20, Quantity: 1},
+			written:  []uint16{10, 11, 40},
+			failed:   []uint16{20},
+			applied:  98,
+			skipped:  99,
+		},
+		{
+			name:     "merged request fails",
+			readOnly: 11,
+			request:  modbus.RegisterRange{Register: 10, Quantity: 2},
+			written:  []uint16{20, 40},
+			failed:   []uint16{10, 11},
+			applied:  99,
+			skipped:  98,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.readOnly[tt.readOnly] = true
+			client := modbus.NewClient(slave, modbus.WithApplyRules(
+				modbus.ApplyRule{Guarded: modbus.RegisterRange{Register: 10, Quantity: 10}, Register: 98, Value: types.Uint16(1)},
+				modbus.ApplyRule{Guarded: modbus.RegisterRange{Register: 20, Quantity: 10}, Register: 99, Value: types.Uint16(1)},
+			))
+
+			err := client.BatchWriteWith(ops, oldData, modbus.BatchOptions{ContinueOnError: true})
+			assert.ErrorIs(t, err, modbus.ErrWritesFailed)
+			var werr *modbus.WriteErrors
+			if !assert.True(t, errors.As(err, &werr)) {
+				return
+			}
+			if assert.Len(t, werr.Failures, 1) {
+				assert.Equal(t, tt.request, werr.Failures[0].Range)
+			}
+			assert.Equal(t, tt.written, werr.Written)
+			assert.Equal(t, tt.failed, werr.Failed)
+			var retried []uint16
+			for _, op := range werr.Retry(ops) {
+				retried = append(retried, op.Register())
+			}
+			assert.Equal(t, tt.failed, retried)
+
+			for _, r := range append(tt.written, tt.applied) {
+				assert.NotEqual(t, []byte{0, 0}, slave.get(r, 1), "register %d", r)
+			}
+			for _, r := range append(tt.failed, 30, tt.skipped) {
+				assert.Equal(t, []byte{0, 0}, slave.get(r, 1), "register %d", r)
+			}
+		})
+	}
+
+	slave := newTestSlave()
+	slave.readOnly[20] = true
+	err := modbus.NewClient(slave).BatchWrite(ops, oldData)
+	assert.False(t, errors.Is(err, modbus.ErrWritesFailed), "fail-fast by default")
+	assert.Equal(t, []byte{0, 0}, slave.get(40, 1))
+}
+
+func TestClient_BatchWriteWith_continueOnError_split(t *testing.T) {
+	slave := newTestSlave()
+	slave.readOnly[250] = true
+	ops := []modbus.Write{testWrite{0, rawWrite(make([]byte, 600))}, testWrite{400, types.Uint16(1)}}
+
+	err := modbus.NewClient(slave).BatchWriteWith(ops, nil, modbus.BatchOptions{ContinueOnError: true})
+	var werr *modbus.WriteErrors
+	if !assert.True(t, errors.As(err, &werr), "%v", err) {
+		return
+	}
+	assert.Equal(t, []uint16{0, 123, 400}, werr.Written)
+	assert.Equal(t, []uint16{246}, werr.Failed)
+	assert.Equal(t, ops[:1], werr.Retry(ops), "split ops are retried as a whole")
+}
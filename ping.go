@@ -0,0 +1,97 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// ErrUnhealthy is matched by errors of Ping, see UnhealthyError.
+var ErrUnhealthy = errors.New("slave unhealthy")
+
+// UnhealthyError is returned by Ping when the slave doesn't respond. It
+// matches ErrUnhealthy and unwraps to the cause.
+type UnhealthyError struct {
+	Err error
+}
+
+func (e *UnhealthyError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrUnhealthy, e.Err)
+}
+
+func (e *UnhealthyError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnhealthy.
+func (e *UnhealthyError) Is(target error) bool {
+	return target == ErrUnhealthy
+}
+
+// funcCodeDiagnostics is Modbus function 8, and pingData the query data
+// echoed with its sub-function 0.
+const funcCodeDiagnostics = 8
+
+var pingData = []byte{0xa5, 0x5a}
+
+// Ping checks that the slave responds without touching process values,
+// with the echo request of function 8 sub-function 0. Once the slave
+// rejects function 8 with an illegal function exception, Ping reads the
+// register set with WithProbe instead; without a probe register, the
+// rejection is the response. Modbus exceptions count as responses.
+//
+// Ping is a single request made under the client mutex. It fails with
+// an *UnhealthyError if ctx is done before the request.
+func (c *Client) Ping(ctx context.Context) (err error) {
+	defer recoverPanic(&err)
+	if err := c.lock(); err != nil {
+		return &UnhealthyError{err}
+	}
+	defer c.unlock()
+
+	if err := cancelled(ctx, 0); err != nil {
+		return &UnhealthyError{err}
+	}
+	if err := c.ping(); err != nil {
+		return &UnhealthyError{err}
+	}
+	return nil
+}
+
+// ping performs the request of Ping. The caller must hold the client
+// mutex.
+func (c *Client) ping() error {
+	if !c.noDiagnostics {
+		echo := append([]byte{0, 0}, pingData...)
+		b, err := c.send(modbus.ProtocolDataUnit{FunctionCode: funcCodeDiagnostics, Data: echo})
+		switch {
+		case err == nil && !bytes.Equal(b, echo):
+			return fmt.Errorf("diagnostics echo of %x returned %x", echo, b)
+		case err == nil, isException(err) && !isIllegalFunction(err):
+			return nil
+		case !isIllegalFunction(err):
+			return err
+		}
+		c.noDiagnostics = true
+		if c.config.Probe == nil {
+			return nil
+		}
+	}
+	if p := c.config.Probe; p != nil {
+		if _, err := c.read(readOp{*p, 1, HoldingRegisters}, Retry{}); err != nil && !isException(err) {
+			return fmt.Errorf("probing register %d: %w", *p, err)
+		}
+	}
+	return nil
+}
+
+// LastContact returns when the slave last responded to a request of the
+// client, including with a Modbus exception. It is zero until then.
+func (c *Client) LastContact() time.Time {
+	t, _ := c.lastContact.Load().(time.Time)
+	return t
+}
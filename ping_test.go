@@ -0,0 +1,69 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+)
+
+func TestClient_Ping(t *testing.T) {
+	tests := []struct {
+		name      string
+		disable   bool
+		opts      []modbus.Option
+		functions []byte
+	}{
+		{"echo", false, nil, []byte{8, 8}},
+		{"probe fallback", true, []modbus.Option{modbus.WithProbe(100)}, []byte{8, 3, 3}},
+		{"rejection", true, nil, []byte{8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			if tt.disable {
+				slave.Disable(8)
+			}
+			client := modbus.NewClient(slave, tt.opts...)
+			assert.Zero(t, client.LastContact())
+
+			assert.NoError(t, client.Ping(context.Background()))
+			contact := client.LastContact()
+			assert.False(t, contact.IsZero())
+			assert.NoError(t, client.Ping(context.Background()))
+			assert.False(t, client.LastContact().Before(contact))
+
+			var functions []byte
+			for _, r := range slave.Requests() {
+				functions = append(functions, r.FunctionCode)
+			}
+			assert.Equal(t, tt.functions, functions)
+			if len(tt.functions) > 0 && tt.functions[0] == 8 {
+				assert.Equal(t, []byte{0, 0, 0xa5, 0x5a}, slave.Requests()[0].Data)
+			}
+		})
+	}
+}
+
+func TestClient_Ping_unhealthy(t *testing.T) {
+	slave := newTestSlave()
+	slave.failures = 1
+	client := modbus.NewClient(slave)
+
+	err := client.Ping(context.Background())
+	assert.ErrorIs(t, err, modbus.ErrUnhealthy)
+	assert.ErrorIs(t, err, errTransport)
+	var uerr *modbus.UnhealthyError
+	assert.True(t, errors.As(err, &uerr))
+	assert.Zero(t, client.LastContact())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = client.Ping(ctx)
+	assert.ErrorIs(t, err, modbus.ErrUnhealthy)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, slave.calls())
+}
@@ -0,0 +1,217 @@
+package modbus
+
+import (
+	"github.com/goburrow/modbus"
+)
+
+// PlannedRequest is a wire request a batch would send, see PlanRead and
+// PlanWrite.
+type PlannedRequest struct {
+	// Function is the function code of the request, or zero for fetches
+	// of custom regions.
+	Function byte
+	RegisterRange
+	// Payload is the written data of write requests.
+	Payload []byte
+	// Ops are the indices of the ops whose registers the request
+	// covers.
+	Ops []int
+	// Wasted is the number of registers a read request of PlanRead
+	// transfers that none of the ops cover, such as the gap registers
+	// of MaxReadGap.
+	Wasted int
+}
+
+// PlanRead returns the requests BatchRead would send for ops, in order,
+// without sending anything. It fails where BatchRead would fail before
+// sending a request.
+//
+// Ops served from the read cache and responses served from the chunk
+// cache are planned like any other, and optional ops of failed requests
+// are planned as read once.
+//
+// Plans are deterministic: the same ops, in the same order, are always
+// planned as the same requests in the same order, ops at the same
+// register included.
+func (c *Client) PlanRead(ops []Read) (_ []PlannedRequest, err error) {
+	defer recoverPanic(&err)
+
+	p, err := c.planRead(ops, batchConfig{Config: c.config})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PlannedRequest, len(p.requests))
+	for i, r := range p.requests {
+		res[i] = c.plannedRead(r, func(rng RegisterRange) []int {
+			var indices []int
+			for j, op := range ops {
+				if spaceOf(op) == r.space && rng.Overlaps(RegisterRange{op.Register(), op.Type().Size()}) {
+					indices = append(indices, j)
+				}
+			}
+			return indices
+		})
+		res[i].Wasted = wasted(r, p.ops)
+	}
+	return res, nil
+}
+
+// PlanWrite returns the requests BatchWrite would send for ops and
+// oldData, in order, without sending anything: the merged writes,
+// writes of ApplyRules and, if enabled, the first verification reads.
+// It fails where BatchWrite would fail before sending a request.
+//
+// Plans are deterministic as with PlanRead.
+func (c *Client) PlanWrite(ops []Write, oldData Registers) (_ []PlannedRequest, err error) {
+	defer recoverPanic(&err)
+
+	cfg := batchConfig{Config: c.config, unverified: unverifiedRanges(ops)}
+	p, err := c.planWrite(ops, oldData, BatchOptions{}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	image := imageOf(oldData, nil)
+	indices := func(rng RegisterRange) []int {
+		var res []int
+		for i, op := range ops {
+			r := RegisterRange{op.Register(), uint16(len(op.Value().Bytes()) / 2)}
+			if !unchanged(op, image) && rng.Overlaps(r) {
+				res = append(res, i)
+			}
+		}
+		return res
+	}
+	var res []PlannedRequest
+	for _, w := range append(p.requests[:len(p.requests):len(p.requests)], p.applies...) {
+		r := RegisterRange{w.register, w.quantity}
+		res = append(res, PlannedRequest{Function: modbus.FuncCodeWriteMultipleRegisters, RegisterRange: r, Payload: w.value, Ops: indices(r)})
+	}
+	if cfg.Verify != nil {
+		chunks, err := verifyPlan(verifiable(p.requests, cfg.unverified), nil, c.readRegions(), cfg.Limits.read())
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range chunks {
+			res = append(res, c.plannedRead(chunk.read, indices))
+		}
+	}
+	return res, nil
+}
+
+// plannedRead describes read request r, with the indices of the ops it
+// covers.
+func (c *Client) plannedRead(r readOp, indices func(RegisterRange) []int) PlannedRequest {
+	rng := RegisterRange{r.register, r.quantity}
+	return PlannedRequest{Function: c.readFunction(r), RegisterRange: rng, Ops: indices(rng)}
+}
+
+// readFunction returns the function code of read request r, or zero for
+// custom regions.
+func (c *Client) readFunction(r readOp) byte {
+	if _, ok := c.customRegion(r); ok {
+		return 0
+	} else if r.space == InputRegisters {
+		return modbus.FuncCodeReadInputRegisters
+	}
+	return modbus.FuncCodeReadHoldingRegisters
+}
+
+// readPlan is the plan of a read batch.
+type readPlan struct {
+	// ops are the converted ops before merging
+	ops      []readOp
+	opt      optionalPlan
+	requests []readOp
+}
+
+// amplification returns the number of registers the ops of p cover and
+// the number of registers its requests read.
+func (p readPlan) amplification() (requested, read int) {
+	for _, r := range p.requests {
+		read += int(r.quantity)
+		requested += int(r.quantity) - wasted(r, p.ops)
+	}
+	return requested, read
+}
+
+// wasted returns the number of registers of request r none of ops
+// cover.
+func wasted(r readOp, ops []readOp) int {
+	covered := make([]bool, r.quantity)
+	n := len(covered)
+	for _, op := range ops {
+		if op.space != r.space {
+			continue
+		}
+		for k := int(op.register) - int(r.register); k < int(op.register)-int(r.register)+int(op.quantity); k++ {
+			if k >= 0 && k < len(covered) && !covered[k] {
+				covered[k] = true
+				n--
+			}
+		}
+	}
+	return n
+}
+
+// planRead converts and merges the read ops of a batch, and checks the
+// planned requests.
+func (c *Client) planRead(ops []Read, cfg batchConfig) (readPlan, error) {
+	if err := cfg.Limits.checkOps(len(ops)); err != nil {
+		return readPlan{}, err
+	}
+	preopt, opt, err := c.convertReads(ops, cfg)
+	if err != nil {
+		return readPlan{}, err
+	}
+	optimized := optimizeRead(preopt, c.readRegions(), cfg.Limits.read(), cfg.Limits.MaxReadGap)
+	if err := cfg.Limits.checkRequests(len(optimized)); err != nil {
+		return readPlan{}, err
+	}
+	if err := c.checkSpecPlan(cfg.Config, optimized, nil); err != nil {
+		return readPlan{}, err
+	}
+	return readPlan{preopt, opt, optimized}, nil
+}
+
+// writePlan is the plan of a write batch.
+type writePlan struct {
+	// ops are the converted ops left by differential optimization,
+	// before merging
+	ops      []writeOp
+	skipped  int
+	requests []writeOp
+	applies  []writeOp
+	// readBack is set for batches reading back the registers of ops
+	// with the requests of backPlan, see BatchOptions.ReadBack
+	readBack bool
+	backPlan []readOp
+}
+
+// planWrite converts and merges the write ops of a batch as set by
+// opts, and checks the planned requests.
+func (c *Client) planWrite(ops []Write, oldData Registers, opts BatchOptions, cfg batchConfig) (writePlan, error) {
+	if err := cfg.Limits.checkOps(len(ops)); err != nil {
+		return writePlan{}, err
+	}
+	readBack := opts.ReadBack && !opts.DisableDiff
+	diffOpt, skipped, err := c.convertWrites(ops, oldData, !opts.DisableDiff && !readBack, cfg)
+	if err != nil {
+		return writePlan{}, err
+	}
+	optimized := optimizeWrite(diffOpt, c.config.SlowRanges, cfg.Limits.write())
+	applies, err := applyWrites(c.config.ApplyRules, diffOpt)
+	if err != nil {
+		return writePlan{}, err
+	}
+	var plan []readOp
+	if readBack {
+		plan = readBackPlan(diffOpt, c.readRegions(), cfg.Limits.read(), cfg.Limits.MaxReadGap)
+	}
+	if err := cfg.Limits.checkRequests(len(plan) + len(optimized) + len(applies)); err != nil {
+		return writePlan{}, err
+	}
+	if err := c.checkSpecPlan(cfg.Config, plan, append(optimized[:len(optimized):len(optimized)], applies...)); err != nil {
+		return writePlan{}, err
+	}
+	return writePlan{diffOpt, skipped, optimized, applies, readBack, plan}, nil
+}
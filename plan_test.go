@@ -0,0 +1,177 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// planned converts requests received by a slave to planned requests
+// without op annotations.
+func planned(pdus []goburrow.ProtocolDataUnit) []modbus.PlannedRequest {
+	res := make([]modbus.PlannedRequest, len(pdus))
+	for i, pdu := range pdus {
+		res[i] = modbus.PlannedRequest{
+			Function: pdu.FunctionCode,
+			RegisterRange: modbus.RegisterRange{
+				Register: binary.BigEndian.Uint16(pdu.Data[0:2]),
+				Quantity: binary.BigEndian.Uint16(pdu.Data[2:4]),
+			},
+		}
+		if pdu.FunctionCode == goburrow.FuncCodeWriteMultipleRegisters {
+			res[i].Payload = pdu.Data[5:]
+		}
+	}
+	return res
+}
+
+func withoutOps(plan []modbus.PlannedRequest) []modbus.PlannedRequest {
+	res := make([]modbus.PlannedRequest, len(plan))
+	for i, r := range plan {
+		r.Ops, r.Wasted = nil, 0
+		res[i] = r
+	}
+	return res
+}
+
+func TestClient_PlanRead(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []modbus.Option
+		ops  []modbus.Read
+		want []modbus.PlannedRequest
+	}{
+		{"merged", nil, []modbus.Read{
+			testRead{10, types.Uint16Type},
+			testRead{11, types.Uint32Type},
+			testRead{20, types.Uint16Type},
+		}, []modbus.PlannedRequest{
+			{Function: 3, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 3}, Ops: []int{0, 1}},
+			{Function: 3, RegisterRange: modbus.RegisterRange{Register: 20, Quantity: 1}, Ops: []int{2}},
+		}},
+		{"gap and spaces", []modbus.Option{modbus.WithLimits(modbus.Limits{MaxReadGap: 5})}, []modbus.Read{
+			testRead{10, types.Uint16Type},
+			testRead{14, types.Uint16Type},
+			modbus.InputRead(testRead{11, types.Uint16Type}),
+		}, []modbus.PlannedRequest{
+			{Function: 3, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 5}, Ops: []int{0, 1}, Wasted: 3},
+			{Function: 4, RegisterRange: modbus.RegisterRange{Register: 11, Quantity: 1}, Ops: []int{2}},
+		}},
+		{"split", []modbus.Option{modbus.WithLimits(modbus.Limits{MaxReadQuantity: 3})}, []modbus.Read{
+			testRead{10, types.Uint64Type},
+			testRead{14, types.Uint16Type},
+		}, []modbus.PlannedRequest{
+			{Function: 3, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 3}, Ops: []int{0}},
+			{Function: 3, RegisterRange: modbus.RegisterRange{Register: 13, Quantity: 2}, Ops: []int{0, 1}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			client := modbus.NewClient(slave, tt.opts...)
+
+			plan, err := client.PlanRead(tt.ops)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+			assert.Empty(t, slave.Requests(), "nothing is sent")
+
+			_, err = client.BatchRead(tt.ops)
+			assert.NoError(t, err)
+			assert.Equal(t, withoutOps(plan), planned(slave.Requests()), "the plan is what BatchRead sends")
+		})
+	}
+}
+
+func TestClient_PlanWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []modbus.Option
+		ops     []modbus.Write
+		oldData modbus.Registers
+		want    []modbus.PlannedRequest
+	}{
+		{"merged", nil, []modbus.Write{
+			testWrite{10, types.Uint16(1)},
+			testWrite{11, types.Uint32(2)},
+			testWrite{20, types.Uint16(3)},
+		}, nil, []modbus.PlannedRequest{
+			{Function: 16, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 3}, Payload: []byte{0, 1, 0, 0, 0, 2}, Ops: []int{0, 1}},
+			{Function: 16, RegisterRange: modbus.RegisterRange{Register: 20, Quantity: 1}, Payload: []byte{0, 3}, Ops: []int{2}},
+		}},
+		{"differential", nil, []modbus.Write{
+			testWrite{10, types.Uint16(1)},
+			testWrite{11, types.Uint16(2)},
+			testWrite{12, types.Uint16(3)},
+		}, modbus.Registers{11: types.Uint16(2)}, []modbus.PlannedRequest{
+			{Function: 16, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 1}, Payload: []byte{0, 1}, Ops: []int{0}},
+			{Function: 16, RegisterRange: modbus.RegisterRange{Register: 12, Quantity: 1}, Payload: []byte{0, 3}, Ops: []int{2}},
+		}},
+		{"verified", []modbus.Option{modbus.WithVerify(modbus.Verify{})}, []modbus.Write{
+			testWrite{10, types.Uint16(1)},
+			testWrite{11, types.Uint16(2)},
+		}, nil, []modbus.PlannedRequest{
+			{Function: 16, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 2}, Payload: []byte{0, 1, 0, 2}, Ops: []int{0, 1}},
+			{Function: 3, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 2}, Ops: []int{0, 1}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			client := modbus.NewClient(slave, tt.opts...)
+
+			plan, err := client.PlanWrite(tt.ops, tt.oldData)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+			assert.Empty(t, slave.Requests(), "nothing is sent")
+
+			assert.NoError(t, client.BatchWrite(tt.ops, tt.oldData))
+			assert.Equal(t, withoutOps(plan), planned(slave.Requests()), "the plan is what BatchWrite sends")
+		})
+	}
+}
+
+func TestClient_PlanRead_invalid(t *testing.T) {
+	client := modbus.NewClient(modbustest.NewSlave(), modbus.WithLimits(modbus.Limits{MaxRequests: 1}))
+
+	_, err := client.PlanRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{20, types.Uint16Type}})
+	var serr *modbus.BatchSizeError
+	assert.ErrorAs(t, err, &serr)
+}
+
+func TestClient_Plan_deterministic(t *testing.T) {
+	var reads []modbus.Read
+	var writes []modbus.Write
+	for i := uint16(0); i < 30; i++ {
+		reads = append(reads, testRead{10 + i%3, types.Uint16Type}, testRead{10 + i%3, types.Uint32Type})
+		writes = append(writes, testWrite{10 + i%3, types.Uint16(i)})
+	}
+	client := modbus.NewClient(modbustest.NewSlave(), modbus.WithWriteConflicts(modbus.LastWins), modbus.WithSlowRanges(modbus.SlowRange{RegisterRange: modbus.RegisterRange{Register: 11, Quantity: 1}}))
+
+	wantReads, err := client.PlanRead(reads)
+	assert.NoError(t, err)
+	wantWrites, err := client.PlanWrite(writes, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []modbus.PlannedRequest{
+		{Function: 3, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 2}},
+		{Function: 3, RegisterRange: modbus.RegisterRange{Register: 11, Quantity: 2}},
+		{Function: 3, RegisterRange: modbus.RegisterRange{Register: 12, Quantity: 2}},
+	}, withoutOps(wantReads))
+	assert.Equal(t, []modbus.PlannedRequest{
+		{Function: 16, RegisterRange: modbus.RegisterRange{Register: 10, Quantity: 1}, Payload: []byte{0, 27}},
+		{Function: 16, RegisterRange: modbus.RegisterRange{Register: 11, Quantity: 1}, Payload: []byte{0, 28}},
+		{Function: 16, RegisterRange: modbus.RegisterRange{Register: 12, Quantity: 1}, Payload: []byte{0, 29}},
+	}, withoutOps(wantWrites))
+	for i := 0; i < 20; i++ {
+		plan, err := client.PlanRead(reads)
+		assert.NoError(t, err)
+		assert.Equal(t, wantReads, plan)
+		plan, err = client.PlanWrite(writes, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, wantWrites, plan)
+	}
+}
@@ -0,0 +1,210 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPollerStarted is returned when starting a poller twice.
+var ErrPollerStarted = errors.New("poller already started")
+
+// ErrPollInterval is returned by NewPoller for intervals of zero or
+// less.
+var ErrPollInterval = errors.New("poll interval must be positive")
+
+// PollResult is a cycle of a Poller.
+type PollResult struct {
+	Time time.Time
+	// Changes holds the values which changed since the previous cycle.
+	Changes Registers
+	// Err is the error of the cycle's BatchRead, if any. Values read
+	// despite the error are in Changes.
+	Err error
+}
+
+// Poller reads a set of ops every interval, delivering the values which
+// changed since the previous cycle. A value has changed when its bytes
+// differ from those last read for its register; every value is a change
+// of the first cycle reading it.
+//
+// Cycles are batches of the client, so they are serialized with other
+// operations of the client. On consecutive failed cycles, the interval
+// is doubled up to MaxBackoff. The values of every cycle can be kept in
+// a History, see SetHistory.
+type Poller struct {
+	// MaxBackoff bounds the interval after failed cycles. Zero means 16
+	// times the interval. It must be set before Start.
+	MaxBackoff time.Duration
+
+	client   *Client
+	interval time.Duration
+	results  chan PollResult
+
+	mtx     sync.Mutex
+	ops     []Read
+	history *History
+	cancel  func()
+	done    chan struct{}
+}
+
+// NewPoller returns a poller reading ops with c every interval. It
+// fails with ErrPollInterval if interval is not positive.
+func NewPoller(c *Client, ops []Read, interval time.Duration) (*Poller, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrPollInterval, interval)
+	}
+	return &Poller{
+		client:   c,
+		interval: interval,
+		results:  make(chan PollResult),
+		ops:      ops,
+	}, nil
+}
+
+// Results returns the channel delivering cycles which changed values or
+// failed. Cycles without either are not delivered. Cycles wait for the
+// previous result to be received, and the channel is closed once the
+// poller stops.
+func (p *Poller) Results() <-chan PollResult {
+	return p.results
+}
+
+// SetOps replaces the ops read, starting with the next cycle. A cycle in
+// progress completes with the previous ops.
+func (p *Poller) SetOps(ops []Read) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.ops = ops
+}
+
+// SetHistory makes every cycle record the values it read in h, starting
+// with the next cycle. Cycles reading nothing, such as failed ones, are
+// not recorded. A nil h stops recording.
+func (p *Poller) SetHistory(h *History) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.history = h
+}
+
+// History returns the history cycles are recorded in, or nil, see
+// SetHistory.
+func (p *Poller) History() *History {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.history
+}
+
+// Start starts polling until ctx is done, Stop is called or the client
+// is shut down. The first cycle is run right away. A poller can only be
+// started once.
+func (p *Poller) Start(ctx context.Context) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.done != nil {
+		return ErrPollerStarted
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go p.run(ctx)
+	p.client.OnShutdown("poller", func(context.Context) error {
+		p.Stop()
+		return nil
+	})
+	return nil
+}
+
+// Stop stops polling and waits for the cycle in progress to be
+// abandoned.
+func (p *Poller) Stop() {
+	p.mtx.Lock()
+	cancel, done := p.cancel, p.done
+	p.mtx.Unlock()
+	if done == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (p *Poller) run(ctx context.Context) {
+	defer close(p.done)
+	defer close(p.results)
+
+	last := Registers{}
+	failures := 0
+	for {
+		ops := p.current()
+		at := time.Now()
+		res, err := p.client.BatchReadContext(ctx, ops)
+		if ctx.Err() != nil {
+			return
+		}
+		if h := p.History(); h != nil && len(res) > 0 {
+			h.Record(Snapshot{at, res})
+		}
+		changes := make(Registers)
+		next := make(Registers, len(ops))
+		for _, op := range ops {
+			r := op.Register()
+			v, ok := res[r]
+			if !ok {
+				if v, ok := last[r]; ok {
+					next[r] = v
+				}
+				continue
+			}
+			if prev, ok := last[r]; !ok || !bytes.Equal(prev.Bytes(), v.Bytes()) {
+				changes[r] = v
+			}
+			next[r] = v
+		}
+		last = next
+
+		if err != nil {
+			failures++
+		} else {
+			failures = 0
+		}
+		if len(changes) > 0 || err != nil {
+			select {
+			case p.results <- PollResult{time.Now(), changes, err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		timer := time.NewTimer(p.backoff(failures))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (p *Poller) current() []Read {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.ops
+}
+
+// backoff returns the interval after failures consecutive failed cycles.
+func (p *Poller) backoff(failures int) time.Duration {
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = 16 * p.interval
+	}
+	d := p.interval
+	for i := 0; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
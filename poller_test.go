@@ -0,0 +1,156 @@
+package modbus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// receive returns the next result of p, failing t after a second.
+func receive(t *testing.T, p *modbus.Poller) modbus.PollResult {
+	t.Helper()
+	select {
+	case res, ok := <-p.Results():
+		if !ok {
+			t.Fatal("results are closed")
+		}
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("no result")
+	}
+	return modbus.PollResult{}
+}
+
+func TestPoller(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 1, 0, 2)
+	client := modbus.NewClient(slave)
+	p, err := modbus.NewPoller(client, []modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+	}, time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Start(context.Background()))
+	defer p.Stop()
+
+	res := receive(t, p)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res.Changes, "first cycle")
+
+	// serialized with the cycles through the client
+	assert.NoError(t, client.Write(11, types.Uint16(3)))
+	res = receive(t, p)
+	assert.Equal(t, modbus.Registers{11: types.Uint16(3)}, res.Changes, "only the changed value")
+
+	p.SetOps([]modbus.Read{testRead{10, types.Uint16Type}, testRead{12, types.Uint16Type}})
+	res = receive(t, p)
+	assert.Equal(t, modbus.Registers{12: types.Uint16(0)}, res.Changes, "the added op")
+
+	slave.set(10, 0, 4)
+	res = receive(t, p)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(4)}, res.Changes)
+
+	p.Stop()
+	_, ok := <-p.Results()
+	assert.False(t, ok, "results are closed")
+	assert.ErrorIs(t, p.Start(context.Background()), modbus.ErrPollerStarted)
+}
+
+func TestPoller_backoff(t *testing.T) {
+	slave := newTestSlave()
+	slave.failures = 4
+	client := modbus.NewClient(slave)
+	p, err := modbus.NewPoller(client, []modbus.Read{testRead{10, types.Uint16Type}}, 10*time.Millisecond)
+	assert.NoError(t, err)
+	p.MaxBackoff = 40 * time.Millisecond
+	assert.NoError(t, p.Start(context.Background()))
+	defer p.Stop()
+
+	var times []time.Time
+	for i := 0; i < 4; i++ {
+		res := receive(t, p)
+		assert.ErrorIs(t, res.Err, errTransport)
+		times = append(times, res.Time)
+	}
+	res := receive(t, p)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(0)}, res.Changes)
+	times = append(times, res.Time)
+
+	for i, want := range []time.Duration{20, 40, 40, 40} {
+		assert.GreaterOrEqual(t, int64(times[i+1].Sub(times[i])), int64(want*time.Millisecond), "after %d failures", i+1)
+	}
+}
+
+func TestPoller_context(t *testing.T) {
+	client := modbus.NewClient(newTestSlave())
+	p, err := modbus.NewPoller(client, []modbus.Read{testRead{10, types.Uint16Type}}, time.Millisecond)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, p.Start(ctx))
+
+	receive(t, p)
+	cancel()
+	for range p.Results() {
+	}
+	p.Stop()
+}
+
+func TestPoller_Shutdown(t *testing.T) {
+	client := modbus.NewClient(newTestSlave())
+	p, err := modbus.NewPoller(client, []modbus.Read{testRead{10, types.Uint16Type}}, time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Start(context.Background()))
+	receive(t, p)
+
+	go func() {
+		for range p.Results() {
+		}
+	}()
+	assert.NoError(t, client.Shutdown(context.Background()))
+	select {
+	case _, ok := <-p.Results():
+		assert.False(t, ok, "results are closed once the poller stops")
+	case <-time.After(time.Second):
+		t.Fatal("poller not stopped")
+	}
+}
+
+func TestPoller_history(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 1)
+	client := modbus.NewClient(slave)
+	p, err := modbus.NewPoller(client, []modbus.Read{testRead{10, types.Uint16Type}}, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Nil(t, p.History())
+	h := modbus.NewHistory(modbus.HistoryOptions{MaxSnapshots: 100})
+	p.SetHistory(h)
+	assert.Equal(t, h, p.History())
+	start := time.Now()
+	assert.NoError(t, p.Start(context.Background()))
+	defer p.Stop()
+
+	receive(t, p)
+	slave.set(10, 0, 2)
+	receive(t, p)
+	p.Stop()
+	assert.GreaterOrEqual(t, h.Len(), 2, "every cycle is recorded, not only changes")
+	series := h.Series(10, start, time.Now())
+	if assert.NotEmpty(t, series) {
+		assert.Equal(t, types.Uint16(1), series[0].Value)
+		assert.Equal(t, types.Uint16(2), series[len(series)-1].Value)
+	}
+}
+
+func TestNewPoller_interval(t *testing.T) {
+	client := modbus.NewClient(newTestSlave())
+	for _, interval := range []time.Duration{0, -time.Second} {
+		p, err := modbus.NewPoller(client, nil, interval)
+		assert.ErrorIs(t, err, modbus.ErrPollInterval)
+		assert.Nil(t, p)
+	}
+}
@@ -0,0 +1,238 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/goburrow/modbus"
+)
+
+// PooledClient performs batches over several connections to the same
+// slave, such as a TCP gateway accepting concurrent connections. Batch
+// reads are planned like those of Client, and their wire requests are
+// spread over the connections, so that latency-bound batches complete
+// faster.
+//
+// The first client of the pool plans batches and validates their
+// results, so ValidationRules, Identity and the like are taken from it;
+// settings of wire requests, such as ChunkCacheTTL or CustomRegions,
+// must be set on every client, see Clients. It is safe for concurrent
+// use.
+type PooledClient struct {
+	// Workers bounds the number of wire requests in flight. Zero
+	// means one per handler.
+	Workers int
+	// ParallelWrites makes BatchWrite spread its wire requests over the
+	// connections as well. The requests are then no longer sent in
+	// register order, and write budgets are accounted by the clients
+	// sending them. Writes are sequential by default.
+	ParallelWrites bool
+
+	clients []*Client
+}
+
+// NewPooledClient builds a pool of clients from handlers, which should
+// all talk to the same slave. Every client is built with opts, see
+// NewClient. Operations of a pool without handlers fail with
+// ErrNilHandler.
+func NewPooledClient(handlers []modbus.ClientHandler, opts ...Option) *PooledClient {
+	p := &PooledClient{clients: make([]*Client, len(handlers))}
+	for i, h := range handlers {
+		p.clients[i] = NewClient(h, opts...)
+	}
+	return p
+}
+
+// Clients returns the clients of the pool, one per handler.
+func (p *PooledClient) Clients() []*Client {
+	return p.clients
+}
+
+// Shutdown shuts down every client of the pool concurrently, see
+// Client.Shutdown, reporting the clients failing to shut down with a
+// *ShutdownError. Components registered with a client, such as a
+// Poller of the first client, are stopped by its Shutdown.
+func (p *PooledClient) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(p.clients))
+	var wg sync.WaitGroup
+	for i, c := range p.clients {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			if err := c.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("client %d: %w", i, err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if failed != nil {
+		return &ShutdownError{failed}
+	}
+	return nil
+}
+
+// BatchRead is Client.BatchRead performing the wire requests of the
+// batch over the pool. The first failed request stops the others; the
+// batch fails with its error.
+func (p *PooledClient) BatchRead(ops []Read) (Registers, error) {
+	return p.BatchReadContext(context.Background(), ops)
+}
+
+// BatchReadContext is BatchRead stopping the outstanding wire requests
+// once ctx is done.
+func (p *PooledClient) BatchReadContext(ctx context.Context, ops []Read) (_ Registers, err error) {
+	defer recoverPanic(&err)
+	if len(p.clients) == 0 {
+		return nil, fmt.Errorf("%w: empty pool", ErrNilHandler)
+	}
+	primary := p.clients[0]
+	cfg, err := primary.resolve(BatchOptions{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	plan, err := primary.planRead(ops, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.batch = plan.ops
+
+	var mtx sync.Mutex
+	results := make(map[readOp][]byte, len(plan.requests))
+	unavailable := make(map[uint16]error)
+	err = p.spread(ctx, len(plan.requests), func(ctx context.Context, c *Client, i int) error {
+		res, absent, err := c.batchRead(ctx, plan.requests[i:i+1], plan.opt, cfg, nil)
+		if err != nil {
+			readOps(err, ops)
+			return err
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		for k, v := range res {
+			results[k] = v
+		}
+		for k, v := range absent {
+			unavailable[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	available, absent := splitUnavailable(ops, unavailable)
+	res, err := decodeRead(available, results)
+	if err != nil {
+		return nil, err
+	}
+	verr := validate(ctx, primary.config.ValidationRules, res)
+	if verr != nil && verr.Strict() {
+		return nil, verr
+	}
+	for _, u := range absent {
+		res[u.Op.Register()] = u
+	}
+	if verr != nil {
+		return res, verr
+	}
+	return res, nil
+}
+
+// BatchWrite is Client.BatchWrite. It is performed by the first client
+// of the pool, unless ParallelWrites is set.
+func (p *PooledClient) BatchWrite(ops []Write, oldData Registers) error {
+	return p.BatchWriteContext(context.Background(), ops, oldData)
+}
+
+// BatchWriteContext is BatchWrite stopping the outstanding wire requests
+// once ctx is done.
+func (p *PooledClient) BatchWriteContext(ctx context.Context, ops []Write, oldData Registers) (err error) {
+	defer recoverPanic(&err)
+	if len(p.clients) == 0 {
+		return fmt.Errorf("%w: empty pool", ErrNilHandler)
+	}
+	primary := p.clients[0]
+	if !p.ParallelWrites {
+		return primary.BatchWriteContext(ctx, ops, oldData)
+	}
+	opts := BatchOptions{Context: ctx}
+	cfg, err := primary.resolve(opts)
+	if err != nil {
+		return err
+	}
+	plan, err := primary.planWrite(ops, oldData, opts, cfg)
+	if err != nil {
+		return err
+	}
+	cfg.batch = interceptedOps(plan.ops)
+
+	image := imageOf(oldData, opts.Tolerance)
+	err = p.spread(ctx, len(plan.requests), func(ctx context.Context, c *Client, i int) error {
+		err := c.batchWrite(ctx, plan.requests[i:i+1], nil, cfg)
+		writeOps(err, ops, image, true)
+		return err
+	})
+	if err != nil || len(plan.applies) == 0 {
+		return err
+	}
+	return primary.batchWrite(ctx, nil, plan.applies, cfg)
+}
+
+// spread calls do for n requests with up to p.Workers clients at once.
+// The first error cancels the context of the outstanding calls and is
+// returned.
+func (p *PooledClient) spread(ctx context.Context, n int, do func(ctx context.Context, c *Client, i int) error) error {
+	workers := len(p.clients)
+	if p.Workers > 0 && p.Workers < workers {
+		workers = p.Workers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	next := make(chan int)
+	var completed int32
+	var once sync.Once
+	var first error
+	var wg sync.WaitGroup
+	for _, c := range p.clients[:workers] {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			for i := range next {
+				if err := do(ctx, c, i); err != nil {
+					once.Do(func() { first = err })
+					cancel()
+					continue
+				}
+				atomic.AddInt32(&completed, 1)
+			}
+		}(c)
+	}
+	sent := 0
+feed:
+	for ; sent < n; sent++ {
+		select {
+		case next <- sent:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if first != nil {
+		return first
+	}
+	if sent < n {
+		return cancelled(ctx, int(completed))
+	}
+	return nil
+}
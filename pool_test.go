@@ -0,0 +1,136 @@
+package modbus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// pooled returns n handlers of slave. testSlave is safe for concurrent
+// use, and sleeps its delay outside of its mutex.
+func pooled(slave *testSlave, n int) []goburrow.ClientHandler {
+	handlers := make([]goburrow.ClientHandler, n)
+	for i := range handlers {
+		handlers[i] = slave
+	}
+	return handlers
+}
+
+// sparseReads returns n reads too far apart to be merged.
+func sparseReads(n int) []modbus.Read {
+	ops := make([]modbus.Read, n)
+	for i := range ops {
+		ops[i] = testRead{uint16(i * 1000), types.Uint16Type}
+	}
+	return ops
+}
+
+func TestPooledClient_BatchRead(t *testing.T) {
+	slave := newTestSlave()
+	slave.delay = 5 * time.Millisecond
+	ops := sparseReads(12)
+	want := make(modbus.Registers)
+	for i, op := range ops {
+		slave.set(op.Register(), 0, byte(i+1))
+		want[op.Register()] = types.Uint16(i + 1)
+	}
+	ops = append(ops, modbus.OptionalRead(testRead{20001, types.Uint16Type}))
+	slave.missing[20001] = true
+
+	pool := modbus.NewPooledClient(pooled(slave, 4))
+	res, err := pool.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, res[20001].(error), modbus.ErrOptionalUnavailable)
+	delete(res, 20001)
+	assert.Equal(t, want, res)
+	assert.Equal(t, 14, slave.calls(), "the rejected optional op is retried alone")
+}
+
+func TestPooledClient_BatchRead_failed(t *testing.T) {
+	slave := newTestSlave()
+	slave.delay = 5 * time.Millisecond
+	slave.failures = 1
+	pool := modbus.NewPooledClient(pooled(slave, 2))
+
+	_, err := pool.BatchRead(sparseReads(20))
+	assert.ErrorIs(t, err, errTransport)
+	assert.Less(t, slave.calls(), 20, "outstanding requests are cancelled")
+}
+
+func TestPooledClient_BatchReadContext(t *testing.T) {
+	slave := newTestSlave()
+	pool := modbus.NewPooledClient(pooled(slave, 2))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := pool.BatchReadContext(ctx, sparseReads(4))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, slave.calls())
+}
+
+func TestPooledClient_BatchWrite(t *testing.T) {
+	ops := []modbus.Write{
+		testWrite{10, types.Uint16(1)},
+		testWrite{1000, types.Uint16(2)},
+		testWrite{2000, types.Uint16(3)},
+	}
+	for _, parallel := range []bool{false, true} {
+		slave := newTestSlave()
+		pool := modbus.NewPooledClient(pooled(slave, 3))
+		pool.ParallelWrites = parallel
+
+		assert.NoError(t, pool.BatchWrite(ops, nil))
+		assert.Equal(t, 3, slave.writes())
+		for _, op := range ops {
+			assert.Equal(t, op.Value().Bytes(), slave.get(op.Register(), 1))
+		}
+	}
+}
+
+func TestPooledClient_empty(t *testing.T) {
+	pool := modbus.NewPooledClient(nil)
+	_, err := pool.BatchRead(sparseReads(1))
+	assert.ErrorIs(t, err, modbus.ErrNilHandler)
+	assert.ErrorIs(t, pool.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil), modbus.ErrNilHandler)
+}
+
+func TestPooledClient_Shutdown(t *testing.T) {
+	slave := newTestSlave()
+	pool := modbus.NewPooledClient(pooled(slave, 3))
+	p, err := modbus.NewPoller(pool.Clients()[1], sparseReads(1), time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Start(context.Background()))
+
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	for range p.Results() {
+	}
+	for _, c := range pool.Clients() {
+		_, err := c.Read(10, types.Uint16Type)
+		assert.ErrorIs(t, err, modbus.ErrClientClosed)
+	}
+	_, err = pool.BatchRead(sparseReads(2))
+	assert.ErrorIs(t, err, modbus.ErrClientClosed)
+	var serr *modbus.ShutdownError
+	assert.ErrorAs(t, pool.Shutdown(context.Background()), &serr)
+}
+
+func BenchmarkPooledClient_BatchRead(b *testing.B) {
+	ops := sparseReads(16)
+	for _, n := range []int{1, 4} {
+		slave := newTestSlave()
+		slave.delay = time.Millisecond
+		pool := modbus.NewPooledClient(pooled(slave, n))
+		b.Run(map[int]string{1: "sequential", 4: "pooled"}[n], func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := pool.BatchRead(ops); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
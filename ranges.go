@@ -0,0 +1,47 @@
+package modbus
+
+import (
+	"time"
+
+	"github.com/tdemin/opmodbus/internal/regrange"
+)
+
+// RegisterRange is a contiguous range of Modbus registers.
+type RegisterRange struct {
+	Register uint16
+	Quantity uint16
+}
+
+// Contains reports whether register belongs to the range.
+func (r RegisterRange) Contains(register uint16) bool {
+	return r.rng().Contains(register)
+}
+
+// Overlaps reports whether two ranges have at least one register in
+// common.
+func (r RegisterRange) Overlaps(o RegisterRange) bool {
+	return r.rng().Overlaps(o.rng())
+}
+
+func (r RegisterRange) rng() regrange.Range {
+	return regrange.Range{Start: r.Register, Quantity: r.Quantity}
+}
+
+// SlowRange is a range of registers the slave is known to serve slowly,
+// e.g. an EEPROM-backed configuration block. See WithSlowRanges.
+type SlowRange struct {
+	RegisterRange
+	// Cost is an estimated duration of a single request to the range.
+	Cost time.Duration
+}
+
+// WithSlowRanges declares register ranges the slave serves slower than
+// the rest. Batch operations never merge ops from a slow range with ops
+// from outside of it or from another slow range, and perform requests
+// to slow ranges after all the other requests of a batch, in ascending
+// order of their Cost.
+func WithSlowRanges(ranges ...SlowRange) Option {
+	return func(c *Config) {
+		c.SlowRanges = append(c.SlowRanges[:len(c.SlowRanges):len(c.SlowRanges)], ranges...)
+	}
+}
@@ -0,0 +1,67 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// readBackPlan returns the read requests covering registers of ops.
+func readBackPlan(ops []writeOp, regions []SlowRange, max, gap uint16) []readOp {
+	reads := make([]readOp, len(ops))
+	for i, op := range ops {
+		reads[i] = readOp{op.register, op.quantity, HoldingRegisters}
+	}
+	return optimizeRead(reads, regions, max, gap)
+}
+
+// batchWriteReadBack reads plan, then writes and verifies the ops whose
+// registers don't hold their values yet, see BatchOptions.ReadBack.
+func (c *Client) batchWriteReadBack(ctx context.Context, ops []writeOp, plan []readOp, cfg batchConfig, continueOnError bool) error {
+	if err := c.lockFor(cfg); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	restore, err := c.address(cfg.unit)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer c.track(cfg)()
+	ctx, done := c.deadline(ctx, cfg.BatchTimeout)
+	results, err := c.readChunks(ctx, plan, cfg.Retry, nil)
+	if err != nil {
+		return done(fmt.Errorf("read-back: %w", err))
+	}
+	changed := ops[:0:0]
+	for _, op := range ops {
+		if !bytes.Equal(currentValue(plan, results, op), op.value) {
+			changed = append(changed, op)
+		}
+	}
+
+	optimized := optimizeWrite(changed, c.config.SlowRanges, cfg.Limits.write())
+	applies, err := applyWrites(c.config.ApplyRules, changed)
+	if err != nil {
+		return err
+	}
+	if continueOnError {
+		return done(c.writeAll(ctx, changed, optimized, applies, plan, cfg))
+	}
+	return done(c.writeVerified(ctx, optimized, applies, changed, plan, cfg))
+}
+
+// currentValue returns the registers of op as read by plan.
+func currentValue(plan []readOp, results map[readOp][]byte, op writeOp) []byte {
+	i := containing(plan, op)
+	if i < 0 {
+		return nil
+	}
+	offset := int(op.register-plan[i].register) * 2
+	b := results[plan[i]]
+	if offset+len(op.value) > len(b) {
+		return nil
+	}
+	return b[offset : offset+len(op.value)]
+}
@@ -0,0 +1,153 @@
+package modbus
+
+import (
+	"sync"
+	"time"
+)
+
+// CacheStats holds counters of the read cache, see WithReadCache.
+// Evictions counts entries dropped before being served again, because
+// they expired or were overwritten by writes of the client.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// WithReadCache makes BatchRead, Read and the operations built on them
+// serve the values of ops read within ttl from memory. Cached ops are
+// left out of the batch, so that only the other ops are read from the
+// slave, and merged without the cached ones.
+//
+// Writes made through the client drop the cached values of the written
+// registers; writes by other masters or through the embedded
+// modbus.Client are not seen, so only cache registers that don't change
+// within ttl otherwise. Batches addressed to another unit are never
+// cached.
+func WithReadCache(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ReadCacheTTL = ttl
+	}
+}
+
+// CacheStats returns counters of the read cache.
+func (c *Client) CacheStats() CacheStats {
+	return c.cache.counters()
+}
+
+// FlushCache drops all the values of the read cache.
+func (c *Client) FlushCache() {
+	c.cache.flush()
+}
+
+// readCache holds the raw data of read ops keyed by op.
+type readCache struct {
+	mtx     sync.Mutex
+	now     func() time.Time
+	entries map[readOp]chunkEntry
+	stats   CacheStats
+}
+
+func (c *readCache) get(op readOp) ([]byte, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[op]
+	if ok && !c.clock().Before(e.expires) {
+		delete(c.entries, op)
+		c.stats.Evictions++
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	return append([]byte(nil), e.data...), true
+}
+
+func (c *readCache) put(op readOp, data []byte, ttl time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.clock()
+	if c.entries == nil {
+		c.entries = make(map[readOp]chunkEntry)
+	}
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+			c.stats.Evictions++
+		}
+	}
+	c.entries[op] = chunkEntry{append([]byte(nil), data...), now.Add(ttl)}
+}
+
+// invalidate drops every holding register entry overlapping r.
+func (c *readCache) invalidate(r RegisterRange) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for k := range c.entries {
+		if k.space == HoldingRegisters && r.Overlaps(RegisterRange{k.register, k.quantity}) {
+			delete(c.entries, k)
+			c.stats.Evictions++
+		}
+	}
+}
+
+func (c *readCache) flush() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.entries = nil
+}
+
+func (c *readCache) counters() CacheStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.stats
+}
+
+func (c *readCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// cachedReads splits ops into the cached ones, returned with their data,
+// and the others.
+func (c *Client) cachedReads(ops []Read, cfg batchConfig) (map[readOp][]byte, []Read) {
+	if cfg.ReadCacheTTL <= 0 || cfg.unit != nil {
+		return nil, ops
+	}
+	hits := make(map[readOp][]byte)
+	misses := ops[:0:0]
+	for _, op := range ops {
+		rop := readOp{op.Register(), op.Type().Size(), spaceOf(op)}
+		if b, ok := c.cache.get(rop); ok {
+			hits[rop] = b
+			continue
+		}
+		misses = append(misses, op)
+	}
+	return hits, misses
+}
+
+// cacheReads caches the data of ops read with a single request of
+// results.
+func (c *Client) cacheReads(ops []Read, results map[readOp][]byte, cfg batchConfig) {
+	if cfg.ReadCacheTTL <= 0 || cfg.unit != nil {
+		return
+	}
+	for _, op := range ops {
+		rop := readOp{op.Register(), op.Type().Size(), spaceOf(op)}
+		r := requestOf(results, rop)
+		b, ok := results[r]
+		if !ok || !r.rng().ContainsRange(rop.rng()) {
+			continue
+		}
+		offset := int(rop.register-r.register) * 2
+		c.cache.put(rop, b[offset:offset+int(rop.quantity)*2], cfg.ReadCacheTTL)
+	}
+}
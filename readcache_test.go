@@ -0,0 +1,110 @@
+package modbus_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestWithReadCache(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 1, 0, 2)
+	client := modbus.NewClient(slave, modbus.WithReadCache(time.Hour))
+
+	_, err := client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}})
+	assert.NoError(t, err)
+
+	// the cached op would have been merged with its neighbour
+	res, err := client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint16Type}})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res)
+	if assert.Len(t, slave.requests, 2) {
+		assert.Equal(t, []byte{0, 11, 0, 1}, slave.requests[1].Data, "only the uncached op is read")
+	}
+
+	res, err = client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint16Type}})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res)
+	v, err := client.Read(11, types.Uint16Type)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Uint16(2), v)
+	assert.Equal(t, 2, slave.calls(), "served from the cache")
+	assert.Equal(t, modbus.CacheStats{Hits: 4, Misses: 2}, client.CacheStats())
+}
+
+func TestWithReadCache_invalidate(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithReadCache(time.Hour))
+	ops := []modbus.Read{testRead{10, types.Uint32Type}, testRead{12, types.Uint16Type}}
+
+	_, err := client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Write(11, types.Uint16(5)))
+
+	res, err := client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint32(5), 12: types.Uint16(0)}, res)
+	if assert.Len(t, slave.requests, 3) {
+		assert.Equal(t, []byte{0, 10, 0, 2}, slave.requests[2].Data, "only the overwritten op is read")
+	}
+	assert.Equal(t, uint64(1), client.CacheStats().Evictions)
+
+	client.FlushCache()
+	_, err = client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, slave.calls())
+	if assert.Len(t, slave.requests, 4) {
+		assert.Equal(t, []byte{0, 10, 0, 3}, slave.requests[3].Data, "flushed ops are merged again")
+	}
+}
+
+func TestWithReadCache_expired(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithReadCache(10*time.Millisecond))
+
+	_, err := client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, slave.calls())
+	assert.Equal(t, modbus.CacheStats{Misses: 2, Evictions: 1}, client.CacheStats())
+}
+
+func TestWithReadCache_concurrentWrites(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithReadCache(time.Hour))
+	ops := []modbus.Read{testRead{10, types.Uint16Type}}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_, err := client.BatchRead(ops)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	for i := uint16(1); i <= 2000; i++ {
+		assert.NoError(t, client.Write(10, types.Uint16(i)))
+		res, err := client.BatchRead(ops)
+		assert.NoError(t, err)
+		if !assert.Equal(t, modbus.Registers{10: types.Uint16(i)}, res, "no value read before the write is cached") {
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
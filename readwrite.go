@@ -0,0 +1,244 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// limit to how many registers function 23 may write at once
+const maxFunc23WriteQuantity = 121
+
+// readWriteOp is a function 23 request writing write, then reading
+// read.
+type readWriteOp struct {
+	read  readOp
+	write writeOp
+}
+
+func (p readWriteOp) String() string {
+	return fmt.Sprintf("write of %v and read of %v", p.write, p.read)
+}
+
+// BatchReadWrite performs a batch of writes followed by a batch of
+// reads and returns the results of the reads as BatchRead does. Writes
+// are optimized as in BatchWrite, including differential optimization
+// against oldData, and reads as in BatchRead.
+//
+// To save round trips, a merged write request is paired with a merged
+// read request into a single function 23 request where possible, in
+// the order of both plans. Writes of more than 121 registers, reads of
+// input registers, custom regions or optional ops, and reads of
+// registers written by the batch, including by ApplyRules, are never
+// paired: they are performed on their own, the reads after all the
+// writes, so that reads always see the written values.
+//
+// If the slave rejects function 23 as an illegal function, the request
+// is repeated as separate writes and reads, and the client stops
+// pairing requests.
+func (c *Client) BatchReadWrite(reads []Read, writes []Write, oldData Registers) (_ Registers, err error) {
+	defer recoverPanic(&err)
+	stats := &BatchStats{Batches: 1, Ops: len(reads) + len(writes)}
+	defer c.account(stats, time.Now(), nil)
+
+	cfg, err := c.resolve(BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	cfg.stats, cfg.unverified = stats, unverifiedRanges(writes)
+	if err := cfg.Limits.checkOps(len(reads) + len(writes)); err != nil {
+		return nil, err
+	}
+	preopt, opt, err := c.convertReads(reads, cfg)
+	if err != nil {
+		return nil, err
+	}
+	diffOpt, skipped, err := c.convertWrites(writes, oldData, true, cfg)
+	if err != nil {
+		return nil, err
+	}
+	stats.SkippedOps = skipped
+	cfg.batch = append(preopt[:len(preopt):len(preopt)], interceptedOps(diffOpt)...)
+
+	optimizedReads := optimizeRead(preopt, c.readRegions(), cfg.Limits.read(), cfg.Limits.MaxReadGap)
+	optimizedWrites := optimizeWrite(diffOpt, c.config.SlowRanges, cfg.Limits.write())
+	applies, err := applyWrites(c.config.ApplyRules, diffOpt)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []readWriteOp
+	restReads, restWrites := optimizedReads, optimizedWrites
+	if atomic.LoadInt32(&c.noReadWrite) == 0 {
+		pairs, restReads, restWrites = c.pairReadWrite(optimizedReads, optimizedWrites, applies, opt)
+	}
+	if err := cfg.Limits.checkRequests(len(pairs) + len(restReads) + len(restWrites) + len(applies)); err != nil {
+		return nil, err
+	}
+	if err := c.checkSpecPlan(cfg.Config, optimizedReads, append(optimizedWrites[:len(optimizedWrites):len(optimizedWrites)], applies...)); err != nil {
+		return nil, err
+	}
+
+	results, unavailable, err := c.batchReadWrite(pairs, restReads, restWrites, applies, diffOpt, opt, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	available, absent := splitUnavailable(reads, unavailable)
+	res, err := decodeRead(available, results)
+	if err != nil {
+		return nil, err
+	}
+	verr := validate(context.Background(), c.config.ValidationRules, res)
+	if verr != nil && verr.Strict() {
+		return nil, verr
+	}
+	for _, u := range absent {
+		res[u.Op.Register()] = u
+	}
+	if verr != nil {
+		return res, verr
+	}
+	return res, nil
+}
+
+// pairReadWrite pairs writes with reads eligible for function 23 in the
+// order of both plans, and returns the pairs with the reads and writes
+// left over.
+func (c *Client) pairReadWrite(reads []readOp, writes, applies []writeOp, opt optionalPlan) (pairs []readWriteOp, restReads []readOp, restWrites []writeOp) {
+	written := append(writes[:len(writes):len(writes)], applies...)
+	paired := make(map[readOp]bool)
+	next := 0
+	for _, w := range writes {
+		for next < len(reads) && !c.pairable(reads[next], written, opt) {
+			next++
+		}
+		if w.quantity > maxFunc23WriteQuantity || next == len(reads) {
+			restWrites = append(restWrites, w)
+			continue
+		}
+		pairs = append(pairs, readWriteOp{reads[next], w})
+		paired[reads[next]] = true
+		next++
+	}
+	for _, r := range reads {
+		if !paired[r] {
+			restReads = append(restReads, r)
+		}
+	}
+	return pairs, restReads, restWrites
+}
+
+// pairable reports whether r may be read by function 23 before all the
+// writes of the batch are done.
+func (c *Client) pairable(r readOp, written []writeOp, opt optionalPlan) bool {
+	if r.space != HoldingRegisters || len(within(opt.optional, r)) > 0 {
+		return false
+	}
+	if _, ok := c.customRegion(r); ok {
+		return false
+	}
+	for _, w := range written {
+		if r.rng().Overlaps(w.rng()) {
+			return false
+		}
+	}
+	return true
+}
+
+// batchReadWrite performs pairs, then writes followed by applies, and
+// verifies ops if enabled, then performs reads.
+func (c *Client) batchReadWrite(pairs []readWriteOp, reads []readOp, writes, applies, ops []writeOp, opt optionalPlan, cfg batchConfig) (map[readOp][]byte, map[uint16]error, error) {
+	if err := c.lock(); err != nil {
+		return nil, nil, err
+	}
+	defer c.unlock()
+	defer c.track(cfg)()
+
+	all := append(writes[:len(writes):len(writes)], applies...)
+	for _, p := range pairs {
+		all = append(all, p.write)
+	}
+	if err := c.budgets.check(c.config.WriteBudgets, all, c.config.OverrideWriteBudgets); err != nil {
+		return nil, nil, err
+	}
+
+	results := make(map[readOp][]byte)
+	for i, p := range pairs {
+		b, err := c.readWriteOrFallback(p, cfg.Retry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read-write request %d of %v: %w", i+1, p, err)
+		}
+		results[p.read] = b
+	}
+	ctx := context.Background()
+	if _, err := c.writeChunks(ctx, append(writes[:len(writes):len(writes)], applies...), cfg.Retry); err != nil {
+		return nil, nil, err
+	}
+	if len(ops) > 0 {
+		if err := c.verify(ctx, cfg, ops, nil, time.Now()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var rest map[readOp][]byte
+	var unavailable map[uint16]error
+	var err error
+	if len(opt.optional) > 0 {
+		rest, unavailable, err = c.readChunksOptional(ctx, reads, opt, c.readChunk, cfg.Retry, nil)
+	} else {
+		rest, err = c.readChunks(ctx, reads, cfg.Retry, nil)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for r, b := range rest {
+		results[r] = b
+	}
+	return results, unavailable, nil
+}
+
+// readWriteOrFallback performs p with function 23, or as a separate
+// write and read if the slave doesn't implement it. The caller must
+// hold the client mutex.
+func (c *Client) readWriteOrFallback(p readWriteOp, retry Retry) ([]byte, error) {
+	if atomic.LoadInt32(&c.noReadWrite) == 0 {
+		b, err := c.readWrite(p, retry)
+		if !isIllegalFunction(err) {
+			return b, err
+		}
+		atomic.StoreInt32(&c.noReadWrite, 1)
+	}
+	if err := c.write(p.write, retry); err != nil {
+		return nil, err
+	}
+	return c.readChunk(p.read, retry)
+}
+
+func (c *Client) readWrite(p readWriteOp, retry Retry) (b []byte, err error) {
+	if c.sched != nil {
+		c.sched.yield()
+	}
+	c.chunks.invalidate(RegisterRange{p.write.register, p.write.quantity})
+	c.cache.invalidate(RegisterRange{p.write.register, p.write.quantity})
+	c.swr.invalidate(RegisterRange{p.write.register, p.write.quantity})
+	err = c.attempts(retry, func() error {
+		start := time.Now()
+		info := OpInfo{Function: modbus.FuncCodeReadWriteMultipleRegisters, RegisterRange: RegisterRange{p.read.register, p.read.quantity}, Written: RegisterRange{p.write.register, p.write.quantity}}
+		b, err = c.intercept(info, nil, func() ([]byte, error) {
+			return c.ReadWriteMultipleRegisters(p.read.register, p.read.quantity, p.write.register, p.write.quantity, p.write.value)
+		})
+		c.observe(p.read.register, p.read.quantity, time.Since(start), err)
+		if err == nil && len(b) != int(p.read.quantity)*2 {
+			err = fmt.Errorf("%w: %d bytes for %v", types.ErrInvalidInput, len(b), p.read)
+		}
+		return err
+	})
+	if err == nil {
+		c.budgets.charge(c.config.WriteBudgets, RegisterRange{p.write.register, p.write.quantity})
+	}
+	return b, err
+}
@@ -0,0 +1,126 @@
+package modbus_test
+
+import (
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchReadWrite(t *testing.T) {
+	writes121, payload121 := consecutiveWrites(0, 121)
+	writes122, payload122 := consecutiveWrites(0, 122)
+	tests := []struct {
+		name    string
+		reads   []modbus.Read
+		writes  []modbus.Write
+		oldData modbus.Registers
+		want    []modbustest.WireExpectation
+		// values read in addition to the seeded ones
+		values modbus.Registers
+	}{
+		{"pairs in plan order", []modbus.Read{
+			testRead{300, types.Uint16Type}, testRead{100, types.Uint32Type},
+		}, []modbus.Write{
+			testWrite{20, types.Uint16(2)}, testWrite{10, types.Uint16(1)},
+		}, nil, []modbustest.WireExpectation{
+			modbustest.ReadWrite(100, 2, 10, 0, 1),
+			modbustest.ReadWrite(300, 1, 20, 0, 2),
+		}, nil},
+		{"more reads than writes", []modbus.Read{
+			testRead{100, types.Uint16Type}, testRead{200, types.Uint16Type},
+		}, []modbus.Write{testWrite{10, types.Uint16(1)}}, nil, []modbustest.WireExpectation{
+			modbustest.ReadWrite(100, 1, 10, 0, 1),
+			modbustest.Read(200, 1),
+		}, nil},
+		{"more writes than reads", []modbus.Read{testRead{100, types.Uint16Type}}, []modbus.Write{
+			testWrite{10, types.Uint16(1)}, testWrite{20, types.Uint16(2)},
+		}, nil, []modbustest.WireExpectation{
+			modbustest.ReadWrite(100, 1, 10, 0, 1),
+			modbustest.Write(20, 0, 2),
+		}, nil},
+		{"read of written registers goes last", []modbus.Read{
+			testRead{11, types.Uint16Type}, testRead{100, types.Uint16Type},
+		}, []modbus.Write{
+			testWrite{10, types.Uint32(3)}, testWrite{20, types.Uint16(2)},
+		}, nil, []modbustest.WireExpectation{
+			modbustest.ReadWrite(100, 1, 10, 0, 0, 0, 3),
+			modbustest.Write(20, 0, 2),
+			modbustest.Read(11, 1),
+		}, modbus.Registers{11: types.Uint16(3)}},
+		{"input registers are not paired", []modbus.Read{
+			modbus.InputRead(testRead{100, types.Uint16Type}),
+		}, []modbus.Write{testWrite{10, types.Uint16(1)}}, nil, []modbustest.WireExpectation{
+			modbustest.Write(10, 0, 1),
+			modbustest.ReadInput(100, 1),
+		}, nil},
+		{"optional ops are not paired", []modbus.Read{
+			modbus.OptionalRead(testRead{100, types.Uint16Type}),
+		}, []modbus.Write{testWrite{10, types.Uint16(1)}}, nil, []modbustest.WireExpectation{
+			modbustest.Write(10, 0, 1),
+			modbustest.Read(100, 1),
+		}, nil},
+		{"write at the function 23 limit", []modbus.Read{testRead{500, types.Uint16Type}}, writes121, nil, []modbustest.WireExpectation{
+			modbustest.ReadWrite(500, 1, 0, payload121...),
+		}, nil},
+		{"write above the function 23 limit", []modbus.Read{testRead{500, types.Uint16Type}}, writes122, nil, []modbustest.WireExpectation{
+			modbustest.Write(0, payload122...),
+			modbustest.Read(500, 1),
+		}, nil},
+		{"unchanged writes are skipped", []modbus.Read{testRead{100, types.Uint16Type}}, []modbus.Write{
+			testWrite{10, types.Uint16(1)}, testWrite{20, types.Uint16(2)},
+		}, modbus.Registers{10: types.Uint16(1)}, []modbustest.WireExpectation{
+			modbustest.ReadWrite(100, 1, 20, 0, 2),
+		}, nil},
+		{"reads only", []modbus.Read{testRead{100, types.Uint16Type}}, nil, nil, []modbustest.WireExpectation{
+			modbustest.Read(100, 1),
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			slave.Seed(modbus.Registers{100: types.Uint32(0x10002), 300: types.Uint16(3), 500: types.Uint16(5)})
+			client := modbus.NewClient(slave)
+
+			res := modbustest.ExpectReadWrite(t, client, tt.reads, tt.writes, tt.oldData, tt.want)
+			for _, op := range tt.writes {
+				if _, ok := tt.oldData[op.Register()]; ok {
+					continue
+				}
+				b := op.Value().Bytes()
+				assert.Equal(t, b, slave.Get(op.Register(), uint16(len(b)/2)), "register %d", op.Register())
+			}
+			assert.Len(t, res, len(tt.reads))
+			for register, want := range tt.values {
+				assert.Equal(t, want, res[register], "register %d", register)
+			}
+		})
+	}
+}
+
+func TestClient_BatchReadWrite_fallback(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Disable(goburrow.FuncCodeReadWriteMultipleRegisters)
+	slave.Seed(modbus.Registers{100: types.Uint16(7)})
+	client := modbus.NewClient(slave)
+	reads := []modbus.Read{testRead{100, types.Uint16Type}}
+	writes := []modbus.Write{testWrite{10, types.Uint16(1)}}
+
+	res := modbustest.ExpectReadWrite(t, client, reads, writes, nil, []modbustest.WireExpectation{
+		modbustest.ReadWrite(100, 1, 10, 0, 1),
+		modbustest.Write(10, 0, 1),
+		modbustest.Read(100, 1),
+	})
+	assert.Equal(t, types.Uint16(7), res[100])
+	assert.Equal(t, []byte{0, 1}, slave.Get(10, 1))
+
+	// once rejected, function 23 is not tried again
+	writes = []modbus.Write{testWrite{10, types.Uint16(2)}}
+	modbustest.ExpectReadWrite(t, client, reads, writes, nil, []modbustest.WireExpectation{
+		modbustest.Write(10, 0, 2),
+		modbustest.Read(100, 1),
+	})
+}
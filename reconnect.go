@@ -0,0 +1,128 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// ConnectionHandler is a handler holding a connection, such as goburrow
+// TCP and RTU handlers.
+type ConnectionHandler interface {
+	modbus.ClientHandler
+	Connect() error
+	Close() error
+}
+
+// ReconnectingHandler re-establishes the connection of a handler once
+// it is lost, e.g. because the slave or gateway restarted. A request
+// failing with a connection error, such as io.EOF, a refused connection
+// or a broken pipe, closes the connection and connects it again, and
+// is sent once more on the new connection. Other errors, including
+// Modbus exceptions, are returned as is.
+//
+// It is safe for concurrent use if the wrapped handler is; concurrent
+// requests failing on the same connection reconnect it once.
+type ReconnectingHandler struct {
+	ConnectionHandler
+
+	// Attempts is the number of connection attempts of a reconnect.
+	// Zero means 3.
+	Attempts int
+	// Backoff is the delay after the first failed connection attempt,
+	// doubled after every next one up to MaxBackoff. Zero means no
+	// delay.
+	Backoff time.Duration
+	// MaxBackoff bounds the delay between connection attempts. Zero
+	// means no bound.
+	MaxBackoff time.Duration
+
+	mtx sync.Mutex
+	// connection counts the connections, so that a request failed on a
+	// replaced connection doesn't reconnect again
+	connection int
+	reconnects int
+}
+
+// NewReconnectingHandler wraps h with the default settings.
+func NewReconnectingHandler(h ConnectionHandler) *ReconnectingHandler {
+	return &ReconnectingHandler{ConnectionHandler: h}
+}
+
+// Send sends aduRequest, reconnecting and sending it again once if the
+// connection is lost.
+func (h *ReconnectingHandler) Send(aduRequest []byte) ([]byte, error) {
+	h.mtx.Lock()
+	connection := h.connection
+	h.mtx.Unlock()
+
+	res, err := h.ConnectionHandler.Send(aduRequest)
+	if err == nil || !isConnectionError(err) {
+		return res, err
+	}
+	if rerr := h.reconnect(connection); rerr != nil {
+		return nil, fmt.Errorf("%v, reconnecting: %w", err, rerr)
+	}
+	return h.ConnectionHandler.Send(aduRequest)
+}
+
+// Reconnects returns the number of connections re-established.
+func (h *ReconnectingHandler) Reconnects() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.reconnects
+}
+
+// reconnect closes and connects the handler unless the connection
+// changed since connection.
+func (h *ReconnectingHandler) reconnect(connection int) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.connection != connection {
+		return nil
+	}
+
+	_ = h.ConnectionHandler.Close()
+	attempts := h.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	delay := h.Backoff
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			if delay *= 2; h.MaxBackoff > 0 && delay > h.MaxBackoff {
+				delay = h.MaxBackoff
+			}
+		}
+		if err = h.ConnectionHandler.Connect(); err == nil {
+			h.connection++
+			h.reconnects++
+			return nil
+		}
+	}
+	return err
+}
+
+// isConnectionError reports whether err means that the connection of a
+// handler is lost. Modbus exceptions never do.
+func isConnectionError(err error) bool {
+	if isException(err) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	// errors of some transports only carry the message
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
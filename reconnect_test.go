@@ -0,0 +1,141 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// droppingServer is a Modbus TCP slave answering function 3 with zeros,
+// or with an exception for register 99. It drops every connection on
+// the request following the answered ones.
+type droppingServer struct {
+	ln       net.Listener
+	answered int
+	wg       sync.WaitGroup
+	mtx      sync.Mutex
+	conns    []net.Conn
+}
+
+func newDroppingServer(t *testing.T, answered int) *droppingServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &droppingServer{ln: ln, answered: answered}
+	s.wg.Add(1)
+	go s.accept()
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *droppingServer) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mtx.Lock()
+		s.conns = append(s.conns, conn)
+		s.mtx.Unlock()
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *droppingServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	for n := 0; ; n++ {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint16(header[4:6])-1)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		if n == s.answered {
+			return
+		}
+		pdu := []byte{req[0] | 0x80, goburrow.ExceptionCodeIllegalDataAddress}
+		if register := binary.BigEndian.Uint16(req[1:3]); req[0] == goburrow.FuncCodeReadHoldingRegisters && register != 99 {
+			quantity := binary.BigEndian.Uint16(req[3:5])
+			pdu = append([]byte{req[0], byte(quantity * 2)}, make([]byte, quantity*2)...)
+		}
+		binary.BigEndian.PutUint16(header[4:6], uint16(len(pdu)+1))
+		if _, err := conn.Write(append(header, pdu...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *droppingServer) close() {
+	s.ln.Close()
+	s.mtx.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mtx.Unlock()
+	s.wg.Wait()
+}
+
+func tcpHandler(addr string) *goburrow.TCPClientHandler {
+	h := goburrow.NewTCPClientHandler(addr)
+	h.Timeout = time.Second
+	return h
+}
+
+func TestReconnectingHandler(t *testing.T) {
+	server := newDroppingServer(t, 2)
+	h := modbus.NewReconnectingHandler(tcpHandler(server.ln.Addr().String()))
+	defer h.Close()
+	client := modbus.NewClient(h)
+
+	for i := 0; i < 7; i++ {
+		v, err := client.Read(10, types.Uint16Type)
+		assert.NoError(t, err, "read %d", i)
+		assert.Equal(t, types.Uint16(0), v)
+	}
+	assert.Equal(t, 3, h.Reconnects())
+}
+
+func TestReconnectingHandler_exception(t *testing.T) {
+	server := newDroppingServer(t, 10)
+	h := modbus.NewReconnectingHandler(tcpHandler(server.ln.Addr().String()))
+	defer h.Close()
+	client := modbus.NewClient(h)
+
+	_, err := client.Read(99, types.Uint16Type)
+	var merr *goburrow.ModbusError
+	assert.True(t, errors.As(err, &merr), "%v", err)
+	_, err = client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+	assert.Zero(t, h.Reconnects())
+}
+
+func TestReconnectingHandler_refused(t *testing.T) {
+	server := newDroppingServer(t, 1)
+	h := modbus.NewReconnectingHandler(tcpHandler(server.ln.Addr().String()))
+	h.Attempts, h.Backoff = 2, time.Millisecond
+	defer h.Close()
+	client := modbus.NewClient(h)
+
+	_, err := client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+	server.close()
+	_, err = client.Read(10, types.Uint16Type)
+	assert.True(t, errors.Is(err, syscall.ECONNREFUSED), "%v", err)
+	assert.Zero(t, h.Reconnects())
+}
@@ -0,0 +1,88 @@
+package modbus
+
+import (
+	"errors"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// RetryPolicy decides whether a failed wire request is repeated, and
+// after which delay. attempt is the number of the attempt that failed
+// with err, starting from 1. Policies classify errors themselves, see
+// Retryable.
+type RetryPolicy interface {
+	ShouldRetry(attempt int, err error) (time.Duration, bool)
+}
+
+// RetryPolicyFunc adapts a function to RetryPolicy.
+type RetryPolicyFunc func(attempt int, err error) (time.Duration, bool)
+
+func (f RetryPolicyFunc) ShouldRetry(attempt int, err error) (time.Duration, bool) {
+	return f(attempt, err)
+}
+
+// WithRetryPolicy sets the policy deciding on retries of failed wire
+// requests, overriding the number of retries and the delay of
+// WithRetry.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Config) {
+		c.Retry.Policy = p
+	}
+}
+
+// Retryable reports whether err is worth retrying: transport errors,
+// such as timeouts and CRC mismatches, are, while Modbus exceptions are
+// not, except for the slave or the gateway target being busy or not
+// responding.
+func Retryable(err error) bool {
+	var e *modbus.ModbusError
+	if !errors.As(err, &e) {
+		return true
+	}
+	switch e.ExceptionCode {
+	case modbus.ExceptionCodeServerDeviceBusy, modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond:
+		return true
+	}
+	return false
+}
+
+// ExponentialBackoff is a RetryPolicy retrying errors classified as
+// retryable up to Retries times, with delays growing from Initial by
+// Multiplier up to Max.
+type ExponentialBackoff struct {
+	Retries int
+	Initial time.Duration
+	// Max caps the delay if positive.
+	Max time.Duration
+	// Multiplier defaults to 2 if below 1.
+	Multiplier float64
+	// Retryable classifies errors, defaulting to the package level
+	// Retryable if nil.
+	Retryable func(error) bool
+}
+
+func (b ExponentialBackoff) ShouldRetry(attempt int, err error) (time.Duration, bool) {
+	retryable := b.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	if attempt > b.Retries || !retryable(err) {
+		return 0, false
+	}
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if b.Max > 0 && delay >= float64(b.Max) {
+			return b.Max, true
+		}
+	}
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max, true
+	}
+	return time.Duration(delay), true
+}
@@ -0,0 +1,131 @@
+package modbus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// erringSlave is a testSlave failing the next requests with errs, in
+// order, and counting all requests.
+type erringSlave struct {
+	*testSlave
+	errs     []error
+	attempts int
+}
+
+func (s *erringSlave) Send(adu []byte) ([]byte, error) {
+	s.attempts++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+	return s.testSlave.Send(adu)
+}
+
+func modbusError(code byte) error {
+	return &goburrow.ModbusError{FunctionCode: goburrow.FuncCodeReadHoldingRegisters, ExceptionCode: code}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := modbus.ExponentialBackoff{Retries: 5, Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		delay, ok := b.ShouldRetry(attempt+1, errTransport)
+		assert.True(t, ok, "attempt %d", attempt+1)
+		assert.Equal(t, want*time.Millisecond, delay, "attempt %d", attempt+1)
+	}
+	_, ok := b.ShouldRetry(6, errTransport)
+	assert.False(t, ok, "retries are exhausted")
+	_, ok = b.ShouldRetry(1, modbusError(goburrow.ExceptionCodeIllegalDataAddress))
+	assert.False(t, ok, "exceptions are not retried")
+
+	b = modbus.ExponentialBackoff{Retries: 2, Initial: time.Millisecond, Multiplier: 3}
+	delay, _ := b.ShouldRetry(2, errTransport)
+	assert.Equal(t, 3*time.Millisecond, delay)
+}
+
+func TestClient_retryPolicy(t *testing.T) {
+	backoff := modbus.ExponentialBackoff{Retries: 2, Initial: time.Microsecond}
+	tests := []struct {
+		name     string
+		policy   modbus.RetryPolicy
+		batch    modbus.BatchOptions
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{"timeouts are retried", backoff, modbus.BatchOptions{}, []error{errTransport, errTransport}, 3, nil},
+		{"retries are exhausted", backoff, modbus.BatchOptions{}, []error{errTransport, errTransport, errTransport}, 3, errTransport},
+		{
+			name:     "illegal data address is not retried",
+			policy:   backoff,
+			errs:     []error{modbusError(goburrow.ExceptionCodeIllegalDataAddress)},
+			attempts: 1,
+			err:      modbusError(goburrow.ExceptionCodeIllegalDataAddress),
+		},
+		{
+			name:     "busy slave is retried",
+			policy:   backoff,
+			errs:     []error{modbusError(goburrow.ExceptionCodeServerDeviceBusy)},
+			attempts: 2,
+		},
+		{
+			name: "custom classification",
+			policy: modbus.ExponentialBackoff{Retries: 2, Retryable: func(err error) bool {
+				return !errors.Is(err, errTransport)
+			}},
+			errs:     []error{errTransport},
+			attempts: 1,
+			err:      errTransport,
+		},
+		{
+			name:     "batch overrides client",
+			policy:   backoff,
+			batch:    modbus.BatchOptions{Retry: &modbus.Retry{}},
+			errs:     []error{errTransport},
+			attempts: 1,
+			err:      errTransport,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, op := range []string{"read", "write"} {
+				slave := &erringSlave{testSlave: newTestSlave(), errs: tt.errs}
+				client := modbus.NewClient(slave, modbus.WithRetryPolicy(tt.policy))
+
+				var err error
+				if op == "read" {
+					_, err = client.BatchReadWith([]modbus.Read{testRead{10, types.Uint16Type}}, tt.batch)
+				} else {
+					err = client.BatchWriteWith([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil, tt.batch)
+				}
+				var want, got *goburrow.ModbusError
+				if errors.As(tt.err, &want) && assert.True(t, errors.As(err, &got), "%s: %v", op, err) {
+					assert.Equal(t, want.ExceptionCode, got.ExceptionCode, op)
+				} else {
+					assert.ErrorIs(t, err, tt.err, op)
+				}
+				assert.Equal(t, tt.attempts, slave.attempts, op)
+			}
+		})
+	}
+}
+
+func TestClient_retryPolicy_attempts(t *testing.T) {
+	slave := &erringSlave{testSlave: newTestSlave(), errs: []error{errTransport, errTransport}}
+	var attempts []int
+	client := modbus.NewClient(slave, modbus.WithRetryPolicy(modbus.RetryPolicyFunc(func(attempt int, err error) (time.Duration, bool) {
+		attempts = append(attempts, attempt)
+		return 0, true
+	})))
+
+	_, err := client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, attempts)
+}
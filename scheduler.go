@@ -0,0 +1,14 @@
+package modbus
+
+// scheduler perturbs the order and timing of wire requests. It is only
+// set by tests exploring interleavings of concurrent operations; the
+// client keeps the planned order without one.
+type scheduler interface {
+	// shuffle reorders the independent read requests of a batch in
+	// place.
+	shuffle(ops []readOp)
+	// yield is called before every wire request.
+	yield()
+	// duplicate reports whether to send a read request twice.
+	duplicate() bool
+}
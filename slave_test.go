@@ -0,0 +1,203 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// testSlave is an in-memory Modbus slave implementing
+// modbus.ClientHandler on the PDU level. It records every request it
+// receives.
+type testSlave struct {
+	mtx      sync.Mutex
+	mem      []byte
+	requests []modbus.ProtocolDataUnit
+	// writes to readOnly registers are answered with an illegal data
+	// address exception
+	readOnly map[uint16]bool
+	// reads covering missing registers are answered with an illegal
+	// data address exception
+	missing map[uint16]bool
+	// noMaskWrite makes the slave reject function 22
+	noMaskWrite bool
+	// volatile registers are incremented after every read request
+	volatile map[uint16]bool
+	// scripted registers take the next scripted value before every read
+	// request covering them, and keep the last one
+	script map[uint16][][]byte
+	// delay is slept before answering every request
+	delay time.Duration
+	// failures is the number of next requests failing with errTransport
+	failures int
+	// lag delays applying function 16 writes after they are
+	// acknowledged
+	lag     time.Duration
+	pending []lagged
+}
+
+type lagged struct {
+	at       time.Time
+	register int
+	data     []byte
+}
+
+var errTransport = errors.New("transport failure")
+
+func newTestSlave() *testSlave {
+	return &testSlave{
+		mem:      make([]byte, 65536*2),
+		readOnly: make(map[uint16]bool),
+		missing:  make(map[uint16]bool),
+		volatile: make(map[uint16]bool),
+		script:   make(map[uint16][][]byte),
+	}
+}
+
+func (s *testSlave) Encode(pdu *modbus.ProtocolDataUnit) ([]byte, error) {
+	return append([]byte{pdu.FunctionCode}, pdu.Data...), nil
+}
+
+func (s *testSlave) Decode(adu []byte) (*modbus.ProtocolDataUnit, error) {
+	if len(adu) == 0 {
+		return nil, errors.New("empty adu")
+	}
+	return &modbus.ProtocolDataUnit{FunctionCode: adu[0], Data: adu[1:]}, nil
+}
+
+func (s *testSlave) Verify(aduRequest, aduResponse []byte) error {
+	return nil
+}
+
+func (s *testSlave) Send(adu []byte) ([]byte, error) {
+	time.Sleep(s.delay)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	pending := s.pending[:0]
+	for _, w := range s.pending {
+		if time.Now().Before(w.at) {
+			pending = append(pending, w)
+			continue
+		}
+		copy(s.mem[w.register*2:], w.data)
+	}
+	s.pending = pending
+
+	pdu := modbus.ProtocolDataUnit{FunctionCode: adu[0], Data: append([]byte(nil), adu[1:]...)}
+	s.requests = append(s.requests, pdu)
+	if s.failures > 0 {
+		s.failures--
+		return nil, errTransport
+	}
+
+	register := int(binary.BigEndian.Uint16(pdu.Data[0:2]))
+	quantity := int(binary.BigEndian.Uint16(pdu.Data[2:4]))
+	if pdu.FunctionCode == modbus.FuncCodeMaskWriteRegister {
+		quantity = 1
+	}
+	if register+quantity > 65536 {
+		return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+	}
+	switch pdu.FunctionCode {
+	case modbus.FuncCodeReadHoldingRegisters:
+		for r := register; r < register+quantity; r++ {
+			if s.missing[uint16(r)] {
+				return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+			}
+		}
+		for r, values := range s.script {
+			if int(r) >= register && int(r) < register+quantity && len(values) > 0 {
+				copy(s.mem[int(r)*2:], values[0])
+				s.script[r] = values[1:]
+			}
+		}
+		res := []byte{pdu.FunctionCode, byte(quantity * 2)}
+		res = append(res, s.mem[register*2:(register+quantity)*2]...)
+		for r := range s.volatile {
+			word := binary.BigEndian.Uint16(s.mem[int(r)*2:])
+			binary.BigEndian.PutUint16(s.mem[int(r)*2:], word+1)
+		}
+		return res, nil
+	case modbus.FuncCodeMaskWriteRegister:
+		if s.noMaskWrite {
+			break
+		}
+		if s.readOnly[uint16(register)] {
+			return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+		}
+		and, or := binary.BigEndian.Uint16(pdu.Data[2:4]), binary.BigEndian.Uint16(pdu.Data[4:6])
+		word := binary.BigEndian.Uint16(s.mem[register*2:])&and | or&^and
+		binary.BigEndian.PutUint16(s.mem[register*2:], word)
+		return adu, nil
+	case modbus.FuncCodeWriteMultipleRegisters:
+		for r := register; r < register+quantity; r++ {
+			if s.readOnly[uint16(r)] {
+				return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+			}
+		}
+		if s.lag > 0 {
+			s.pending = append(s.pending, lagged{time.Now().Add(s.lag), register, pdu.Data[5:]})
+		} else {
+			copy(s.mem[register*2:], pdu.Data[5:])
+		}
+		return append([]byte{pdu.FunctionCode}, pdu.Data[0:4]...), nil
+	case vendorRead:
+		for r := register; r < register+quantity; r++ {
+			if s.missing[uint16(r)] {
+				return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalDataAddress), nil
+			}
+		}
+		res := []byte{pdu.FunctionCode, byte(quantity * 2)}
+		for _, b := range s.mem[register*2 : (register+quantity)*2] {
+			res = append(res, b^0xFF)
+		}
+		return res, nil
+	}
+	return exception(pdu.FunctionCode, modbus.ExceptionCodeIllegalFunction), nil
+}
+
+// vendorRead is a vendor-specific function returning register data
+// with every bit inverted.
+const vendorRead = 0x41
+
+func exception(function, code byte) []byte {
+	return []byte{function | 0x80, code}
+}
+
+// set fills slave memory starting at register.
+func (s *testSlave) set(register uint16, data ...byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	copy(s.mem[int(register)*2:], data)
+}
+
+// get returns slave memory of quantity registers starting at register.
+func (s *testSlave) get(register, quantity uint16) []byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]byte(nil), s.mem[int(register)*2:(int(register)+int(quantity))*2]...)
+}
+
+// calls returns the number of requests received.
+func (s *testSlave) calls() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.requests)
+}
+
+// writes returns the number of function 16 requests received.
+func (s *testSlave) writes() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.FunctionCode == modbus.FuncCodeWriteMultipleRegisters {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,90 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Space is a register space of a slave.
+type Space int
+
+const (
+	// HoldingRegisters are read with function 3 and written with
+	// function 16. Ops are in this space unless they implement
+	// SpaceRead.
+	HoldingRegisters Space = iota
+	// InputRegisters are read-only and read with function 4.
+	InputRegisters
+)
+
+func (s Space) String() string {
+	switch s {
+	case HoldingRegisters:
+		return "holding"
+	case InputRegisters:
+		return "input"
+	}
+	return fmt.Sprintf("Space(%d)", int(s))
+}
+
+// ErrSpaceConflict is returned when a batch reads the same register
+// number from several register spaces, which would collide in the
+// results.
+var ErrSpaceConflict = errors.New("register read from several spaces")
+
+// SpaceRead may be implemented by Read ops to select the register space
+// they are read from. Batch operations group ops by space and never
+// merge ops of different spaces, even with adjacent registers.
+type SpaceRead interface {
+	Read
+	Space() Space
+}
+
+// InputRead makes r read from input registers, see SpaceRead.
+func InputRead(r Read) Read {
+	return inputRead{r}
+}
+
+type inputRead struct {
+	Read
+}
+
+func (inputRead) Space() Space {
+	return InputRegisters
+}
+
+func (r inputRead) String() string {
+	return fmt.Sprintf("input %v", r.Read)
+}
+
+func (r inputRead) unwrap() Read {
+	return r.Read
+}
+
+// spaceOf returns the register space of r, looking through the
+// wrappers of this package.
+func spaceOf(r Read) Space {
+	for {
+		if s, ok := r.(SpaceRead); ok {
+			return s.Space()
+		}
+		w, ok := r.(interface{ unwrap() Read })
+		if !ok {
+			return HoldingRegisters
+		}
+		r = w.unwrap()
+	}
+}
+
+// checkSpaces rejects ops reading the same register from different
+// spaces.
+func checkSpaces(ops []readOp) error {
+	spaces := make(map[uint16]Space, len(ops))
+	for _, op := range ops {
+		if s, ok := spaces[op.register]; ok && s != op.space {
+			return fmt.Errorf("%w: %v and %v", ErrSpaceConflict, readOp{op.register, op.quantity, s}, op)
+		}
+		spaces[op.register] = op.space
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package modbus_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchRead_inputRegisters(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)})
+	slave.SeedInput(modbus.Registers{12: types.Uint16(3), 13: types.Uint16(4), 30: types.Float32(49.98)})
+	client := modbus.NewClient(slave)
+
+	res := modbustest.ExpectPlan(t, client, []modbus.Read{
+		modbus.InputRead(testRead{13, types.Uint16Type}),
+		testRead{10, types.Uint16Type},
+		modbus.InputRead(testRead{30, types.Float32Type}),
+		modbus.InputRead(testRead{12, types.Uint16Type}),
+		testRead{11, types.Uint16Type},
+	}, []modbustest.WireExpectation{
+		modbustest.Read(10, 2),
+		// adjacent to the holding registers, but never merged with them
+		modbustest.ReadInput(12, 2),
+		modbustest.ReadInput(30, 2),
+	})
+	assert.Equal(t, modbus.Registers{
+		10: types.Uint16(1),
+		11: types.Uint16(2),
+		12: types.Uint16(3),
+		13: types.Uint16(4),
+		30: types.Float32(49.98),
+	}, res)
+}
+
+func TestClient_BatchRead_inputRegistersOptional(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.SeedInput(modbus.Registers{12: types.Uint16(3)})
+	client := modbus.NewClient(slave)
+
+	// the wrappers keep both properties in either order
+	res, err := client.BatchRead([]modbus.Read{
+		modbus.OptionalRead(modbus.InputRead(testRead{12, types.Uint16Type})),
+		modbus.InputRead(modbus.OptionalRead(testRead{65535, types.Uint16Type})),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, types.Uint16(3), res[12])
+	for _, pdu := range slave.Requests() {
+		assert.Equal(t, byte(4), pdu.FunctionCode)
+	}
+}
+
+func TestClient_BatchRead_spaceConflict(t *testing.T) {
+	slave := modbustest.NewSlave()
+	client := modbus.NewClient(slave)
+
+	_, err := client.BatchRead([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		modbus.InputRead(testRead{10, types.Uint16Type}),
+	})
+	assert.ErrorIs(t, err, modbus.ErrSpaceConflict)
+	assert.Empty(t, slave.Requests(), "nothing is sent")
+}
+
+func TestClient_ReadInput(t *testing.T) {
+	slave := modbustest.NewSlave()
+	slave.Seed(modbus.Registers{20: types.Int16(-1)})
+	slave.SeedInput(modbus.Registers{20: types.Int16(-12)})
+	client := modbus.NewClient(slave)
+
+	v, err := client.ReadInput(20, types.Int16Type)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Int16(-12), v)
+	v, err = client.Read(20, types.Int16Type)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Int16(-1), v)
+}
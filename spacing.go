@@ -0,0 +1,68 @@
+package modbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// WithRequestSpacing makes the client keep the bus silent for d after
+// every response before sending the next request, such as for RTU
+// slaves missing requests that follow responses too closely. The
+// silence is kept between the requests of a batch, between retries and
+// between separate operations. Operations accepting a context stop
+// waiting once it is done.
+func WithRequestSpacing(d time.Duration) Option {
+	return func(c *Config) {
+		c.RequestSpacing = d
+	}
+}
+
+// spacing returns the silence left to keep before the next request.
+// The caller must hold the client mutex.
+func (c *Client) spacing() time.Duration {
+	if c.config.RequestSpacing <= 0 || c.lastResponse.IsZero() {
+		return 0
+	}
+	return c.config.RequestSpacing - time.Since(c.lastResponse)
+}
+
+// pace is cancelled waiting for the request spacing to elapse. The
+// caller must hold the client mutex.
+func (c *Client) pace(ctx context.Context, completed int) error {
+	if err := cancelled(ctx, completed); err != nil {
+		return err
+	}
+	if wait := c.spacing(); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return cancelled(ctx, completed)
+		}
+	}
+	return nil
+}
+
+// trackedTransporter keeps the request spacing of requests sent without
+// a context, and records when and how many requests complete, and
+// when the slave last responded.
+type trackedTransporter struct {
+	modbus.Transporter
+	client *Client
+}
+
+func (t trackedTransporter) Send(adu []byte) ([]byte, error) {
+	if wait := t.client.spacing(); wait > 0 {
+		time.Sleep(wait)
+	}
+	b, err := t.Transporter.Send(adu)
+	t.client.lastResponse = time.Now()
+	t.client.completed++
+	if err == nil {
+		t.client.lastContact.Store(t.client.lastResponse)
+	}
+	return b, err
+}
@@ -0,0 +1,72 @@
+package modbus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// clockedSlave is a testSlave recording when every request starts and
+// completes.
+type clockedSlave struct {
+	*testSlave
+	starts, ends []time.Time
+}
+
+func (s *clockedSlave) Send(adu []byte) ([]byte, error) {
+	s.starts = append(s.starts, time.Now())
+	defer func() { s.ends = append(s.ends, time.Now()) }()
+	return s.testSlave.Send(adu)
+}
+
+// silences returns the bus silence before every request but the first.
+func (s *clockedSlave) silences() []time.Duration {
+	var res []time.Duration
+	for i := 1; i < len(s.starts); i++ {
+		res = append(res, s.starts[i].Sub(s.ends[i-1]))
+	}
+	return res
+}
+
+func TestWithRequestSpacing(t *testing.T) {
+	const spacing = 20 * time.Millisecond
+	slave := &clockedSlave{testSlave: newTestSlave()}
+	slave.delay = 10 * time.Millisecond
+	client := modbus.NewClient(slave, modbus.WithRequestSpacing(spacing), modbus.WithRetry(modbus.Retry{Retries: 1}))
+
+	_, err := client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{20, types.Uint16Type}})
+	assert.NoError(t, err)
+	assert.NoError(t, client.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{20, types.Uint16(2)}}, nil))
+	assert.NoError(t, client.Write(30, types.Uint16(3)))
+	_, err = client.Read(30, types.Uint16Type)
+	assert.NoError(t, err)
+	slave.failures = 1
+	_, err = client.Read(30, types.Uint16Type)
+	assert.NoError(t, err, "retried")
+
+	assert.Len(t, slave.starts, 8)
+	for i, silence := range slave.silences() {
+		assert.GreaterOrEqual(t, int64(silence), int64(spacing), "before request %d", i+2)
+	}
+}
+
+func TestWithRequestSpacing_cancel(t *testing.T) {
+	slave := &clockedSlave{testSlave: newTestSlave()}
+	client := modbus.NewClient(slave, modbus.WithRequestSpacing(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.BatchReadContext(ctx, []modbus.Read{testRead{10, types.Uint16Type}, testRead{20, types.Uint16Type}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), "the wait is cancelled")
+	assert.Len(t, slave.starts, 1)
+
+	_, err = client.ReadContext(ctx, 10, types.Uint16Type)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, slave.starts, 1)
+}
@@ -0,0 +1,58 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSpecViolation is returned in strict spec mode for settings and
+// planned requests that would violate the Modbus specification.
+var ErrSpecViolation = errors.New("modbus specification violated")
+
+// WithStrictSpec enables strict spec mode, see Config.StrictSpec.
+func WithStrictSpec() Option {
+	return func(c *Config) {
+		c.StrictSpec = true
+	}
+}
+
+// CheckSpec returns an error wrapping ErrSpecViolation if strict spec
+// mode is enabled and settings of the client conflict with it, such as
+// custom regions. It is meant to
+// be called once the client is configured; batch operations perform
+// the same check before planning.
+func (c *Client) CheckSpec() error {
+	return c.checkSpec(c.config)
+}
+
+func (c *Client) checkSpec(cfg Config) error {
+	if !cfg.StrictSpec {
+		return nil
+	}
+	if len(c.config.CustomRegions) > 0 {
+		return fmt.Errorf("%w: custom region %v uses vendor functions", ErrSpecViolation, c.config.CustomRegions[0].RegisterRange)
+	}
+	return nil
+}
+
+// checkSpecPlan fails planned requests violating the specification in
+// strict spec mode, before any of them is sent.
+func (c *Client) checkSpecPlan(cfg Config, reads []readOp, writes []writeOp) error {
+	if !cfg.StrictSpec {
+		return nil
+	}
+	if err := c.checkSpec(cfg); err != nil {
+		return err
+	}
+	for _, r := range reads {
+		if r.quantity > maxFunc3Quantity || !r.rng().Valid() {
+			return fmt.Errorf("%w: read request of %v", ErrSpecViolation, r)
+		}
+	}
+	for _, w := range writes {
+		if w.quantity > maxFunc16Quantity || !w.rng().Valid() || len(w.value) != int(w.quantity)*2 {
+			return fmt.Errorf("%w: write request of %v", ErrSpecViolation, w)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package modbus_test
+
+import (
+	"testing"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_strictSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []modbus.Option
+		call func(*modbus.Client) error
+	}{
+		{
+			name: "vendor function",
+			opts: []modbus.Option{modbus.WithCustomRegions(modbus.CustomRegion{
+				RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10},
+				Fetch: func(send func(goburrow.ProtocolDataUnit) ([]byte, error), register, quantity uint16) ([]byte, error) {
+					return send(goburrow.ProtocolDataUnit{FunctionCode: 0x41})
+				},
+			})},
+			call: func(c *modbus.Client) error {
+				_, err := c.BatchRead([]modbus.Read{testRead{100, types.Uint16Type}})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			client := modbus.NewClient(slave, append(tt.opts, modbus.WithStrictSpec())...)
+			assert.ErrorIs(t, tt.call(client), modbus.ErrSpecViolation)
+			assert.Empty(t, slave.Requests(), "caught before the wire")
+		})
+	}
+}
+
+func TestClient_CheckSpec(t *testing.T) {
+	slave := modbustest.NewSlave()
+	assert.NoError(t, modbus.NewClient(slave, modbus.WithStrictSpec()).CheckSpec())
+	regions := []modbus.CustomRegion{{RegisterRange: modbus.RegisterRange{Register: 100, Quantity: 10}}}
+	client := modbus.NewClient(slave, modbus.WithCustomRegions(regions...))
+	assert.NoError(t, client.CheckSpec(), "only checked in strict mode")
+
+	client = modbus.NewClient(slave, modbus.WithStrictSpec(), modbus.WithCustomRegions(regions...))
+	assert.ErrorIs(t, client.CheckSpec(), modbus.ErrSpecViolation)
+
+	// compliant batches go through unchanged
+	client = modbus.NewClient(slave, modbus.WithStrictSpec())
+	modbustest.ExpectPlan(t, client, consecutiveReads(0, 126), []modbustest.WireExpectation{
+		modbustest.Read(0, 125), modbustest.Read(125, 1),
+	})
+}
@@ -0,0 +1,188 @@
+package modbus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ErrUnstable is returned when a value does not stabilize within the
+// allowed number of reads. See UnstableError.
+var ErrUnstable = errors.New("value did not stabilize")
+
+// UnstableError holds every value observed for a register that did not
+// stabilize.
+type UnstableError struct {
+	Register uint16
+	Values   []types.Value
+}
+
+func (e *UnstableError) Error() string {
+	return fmt.Sprintf("%v: register %d: observed %v", ErrUnstable, e.Register, e.Values)
+}
+
+func (e *UnstableError) Unwrap() error {
+	return ErrUnstable
+}
+
+// StableOpts configures stable reads.
+type StableOpts struct {
+	// Attempts is the maximum number of reads of a value. Defaults to
+	// twice the Agree.
+	Attempts int
+	// Agree is the number of consecutive reads that must return the
+	// same value. Defaults to 2.
+	Agree int
+	// Epsilon is the maximum difference between numeric values still
+	// considered the same. Values of other kinds, and all values if
+	// Epsilon is zero, must be byte-identical. NaN never matches.
+	Epsilon float64
+	// Delay is slept between consecutive reads.
+	Delay time.Duration
+	// Flag selects ops of BatchReadStable that are read until stable.
+	// Other ops are read once. All ops are selected if Flag is nil.
+	Flag func(op Read) bool
+}
+
+func (o StableOpts) withDefaults() StableOpts {
+	if o.Agree < 1 {
+		o.Agree = 2
+	}
+	if o.Attempts < 1 {
+		o.Attempts = o.Agree * 2
+	}
+	return o
+}
+
+// ReadStable reads a single value repeatedly until opts.Agree
+// consecutive reads return the same value, and returns that value. Each
+// read is a separate client operation, so other operations of the
+// client may go in between. If the value doesn't stabilize within
+// opts.Attempts reads, an *UnstableError is returned.
+func (c *Client) ReadStable(register uint16, t types.Type, opts StableOpts) (types.Value, error) {
+	opts.Flag = nil
+	res, err := c.BatchReadStable([]Read{stableRead{register, t}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res[register], nil
+}
+
+// BatchReadStable performs BatchRead, then re-reads the ops selected by
+// opts.Flag until each of them returns opts.Agree consecutive matching
+// values. Re-reads only cover the ops that haven't stabilized yet.
+//
+// If some values don't stabilize within opts.Attempts reads, the last
+// observed values are returned along with an *UnstableError for the
+// first of them.
+func (c *Client) BatchReadStable(ops []Read, opts StableOpts) (_ Registers, err error) {
+	defer recoverPanic(&err)
+
+	opts = opts.withDefaults()
+
+	res, err := c.BatchRead(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	type run struct {
+		op       Read
+		observed []types.Value
+		agreed   int
+	}
+	var pending []*run
+	for _, op := range ops {
+		if opts.Flag == nil || opts.Flag(op) {
+			pending = append(pending, &run{op, []types.Value{res[op.Register()]}, 1})
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		// drop stable values
+		unstable := pending[:0]
+		for _, r := range pending {
+			if r.agreed < opts.Agree {
+				unstable = append(unstable, r)
+			}
+		}
+		pending = unstable
+		if len(pending) == 0 {
+			return res, nil
+		}
+		if attempt >= opts.Attempts {
+			break
+		}
+
+		time.Sleep(opts.Delay)
+		reads := make([]Read, len(pending))
+		for i, r := range pending {
+			reads[i] = r.op
+		}
+		values, err := c.BatchRead(reads)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range pending {
+			value := values[r.op.Register()]
+			first := r.observed[len(r.observed)-r.agreed]
+			if sameValues(first, value, opts.Epsilon) {
+				r.agreed++
+			} else {
+				r.agreed = 1
+			}
+			r.observed = append(r.observed, value)
+			res[r.op.Register()] = value
+		}
+	}
+
+	return res, &UnstableError{pending[0].op.Register(), pending[0].observed}
+}
+
+// sameValues compares two values either numerically within epsilon or
+// byte by byte.
+func sameValues(a, b types.Value, epsilon float64) bool {
+	if epsilon > 0 {
+		x, okA := numeric(a)
+		y, okB := numeric(b)
+		if okA && okB {
+			return math.Abs(x-y) <= epsilon
+		}
+	}
+	return bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+// numeric returns value as float64 if it is backed by a numeric kind or
+// provides a Float64 method, like types.Scaled.
+func numeric(v types.Value) (float64, bool) {
+	if f, ok := v.(interface{ Float64() float64 }); ok {
+		return f.Float64(), true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+type stableRead struct {
+	register uint16
+	t        types.Type
+}
+
+func (r stableRead) Register() uint16 {
+	return r.register
+}
+
+func (r stableRead) Type() types.Type {
+	return r.t
+}
@@ -0,0 +1,122 @@
+package modbus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_ReadStable(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      types.Type
+		script []types.Value
+		opts   modbus.StableOpts
+		want   types.Value
+		calls  int
+	}{
+		{
+			name:   "transient",
+			t:      types.Uint16Type,
+			script: []types.Value{types.Uint16(5), types.Uint16(999), types.Uint16(5), types.Uint16(5)},
+			opts:   modbus.StableOpts{Attempts: 5, Agree: 2},
+			want:   types.Uint16(5),
+			calls:  4,
+		},
+		{
+			name:   "agree 3",
+			t:      types.Uint16Type,
+			script: []types.Value{types.Uint16(5), types.Uint16(5), types.Uint16(6), types.Uint16(6), types.Uint16(6)},
+			opts:   modbus.StableOpts{Attempts: 5, Agree: 3},
+			want:   types.Uint16(6),
+			calls:  5,
+		},
+		{
+			name:   "epsilon",
+			t:      types.Float32Type,
+			script: []types.Value{types.Float32(1), types.Float32(1.01)},
+			opts:   modbus.StableOpts{Epsilon: 0.02},
+			want:   types.Float32(1.01),
+			calls:  2,
+		},
+		{
+			name:   "epsilon relative to first of run",
+			t:      types.Float32Type,
+			script: []types.Value{types.Float32(1), types.Float32(1.015), types.Float32(1.03), types.Float32(1.03)},
+			opts:   modbus.StableOpts{Agree: 3, Epsilon: 0.02},
+			want:   types.Float32(1.03),
+			calls:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			for _, v := range tt.script {
+				slave.script[10] = append(slave.script[10], v.Bytes())
+			}
+			client := modbus.NewClient(slave)
+
+			got, err := client.ReadStable(10, tt.t, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.calls, slave.calls())
+		})
+	}
+}
+
+func TestClient_ReadStable_unstable(t *testing.T) {
+	slave := newTestSlave()
+	slave.volatile[10] = true
+	client := modbus.NewClient(slave)
+
+	_, err := client.ReadStable(10, types.Uint16Type, modbus.StableOpts{Attempts: 4})
+	assert.True(t, errors.Is(err, modbus.ErrUnstable))
+	var unstable *modbus.UnstableError
+	if assert.True(t, errors.As(err, &unstable)) {
+		assert.Equal(t, uint16(10), unstable.Register)
+		assert.Equal(t, []types.Value{
+			types.Uint16(0), types.Uint16(1), types.Uint16(2), types.Uint16(3),
+		}, unstable.Values)
+	}
+	assert.Equal(t, 4, slave.calls())
+}
+
+func TestClient_BatchReadStable(t *testing.T) {
+	slave := newTestSlave()
+	slave.script[10] = [][]byte{{0, 1}, {0xFF, 0xFF}, {0, 1}}
+	slave.set(11, 0, 2)
+	slave.set(20, 0, 3)
+	client := modbus.NewClient(slave)
+	ops := []modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+		testRead{20, types.Uint16Type},
+	}
+
+	res, err := client.BatchReadStable(ops, modbus.StableOpts{
+		Flag: func(op modbus.Read) bool { return op.Register() < 20 },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{
+		10: types.Uint16(1),
+		11: types.Uint16(2),
+		20: types.Uint16(3),
+	}, res)
+	// 11 is stable after the second read, 10 needs two more, 20 is read
+	// once
+	want := [][]byte{
+		{0, 10, 0, 2},
+		{0, 20, 0, 1},
+		{0, 10, 0, 2},
+		{0, 10, 0, 1},
+		{0, 10, 0, 1},
+	}
+	if assert.Len(t, slave.requests, len(want)) {
+		for i, r := range slave.requests {
+			assert.Equal(t, want[i], r.Data)
+		}
+	}
+}
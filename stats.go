@@ -0,0 +1,110 @@
+package modbus
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchStats holds the accounting of register batch operations:
+// BatchReadWith, BatchWriteWith and the operations built on them,
+// BatchReadWrite and Swap.
+type BatchStats struct {
+	Batches int
+	// Ops is the number of ops passed to the batches.
+	Ops int
+	// Requests is the number of register requests sent by the batches,
+	// including retries and verification reads. Responses served from
+	// the chunk cache aren't requests.
+	Requests int
+	// Registers is the number of registers read and written by the
+	// requests, including the gap registers of MaxReadGap.
+	Registers int
+	// SkippedOps is the number of write ops skipped by differential
+	// optimization against oldData.
+	SkippedOps int
+	// RequestedRegisters is the number of registers the read ops of
+	// BatchReadWith cover, and ReadRegisters the number of registers
+	// the planned read requests transfer, once per request. Ops served
+	// from the read cache are not planned.
+	RequestedRegisters int
+	ReadRegisters      int
+	// Duration is the wall-clock time spent in the batches.
+	Duration time.Duration
+}
+
+// RequestsAvoided returns the number of requests saved compared to
+// sending a request per op.
+func (s BatchStats) RequestsAvoided() int {
+	return s.Ops - s.Requests
+}
+
+// WastedRegisters returns the number of registers read without any op
+// covering them, such as the gap registers of MaxReadGap.
+func (s BatchStats) WastedRegisters() int {
+	return s.ReadRegisters - s.RequestedRegisters
+}
+
+// Amplification returns the ratio of ReadRegisters to
+// RequestedRegisters, or 0 if nothing was read.
+func (s BatchStats) Amplification() float64 {
+	if s.RequestedRegisters == 0 {
+		return 0
+	}
+	return float64(s.ReadRegisters) / float64(s.RequestedRegisters)
+}
+
+func (s *BatchStats) add(other BatchStats) {
+	s.Batches += other.Batches
+	s.Ops += other.Ops
+	s.Requests += other.Requests
+	s.Registers += other.Registers
+	s.SkippedOps += other.SkippedOps
+	s.RequestedRegisters += other.RequestedRegisters
+	s.ReadRegisters += other.ReadRegisters
+	s.Duration += other.Duration
+}
+
+// statsCounter accumulates the BatchStats of a client.
+type statsCounter struct {
+	mtx   sync.Mutex
+	total BatchStats
+}
+
+func (c *statsCounter) add(s BatchStats) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.total.add(s)
+}
+
+func (c *statsCounter) get() BatchStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.total
+}
+
+func (c *statsCounter) reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.total = BatchStats{}
+}
+
+// Stats returns the accounting of all the batches of the client since
+// it was built or since the last ResetStats.
+func (c *Client) Stats() BatchStats {
+	return c.stats.get()
+}
+
+// ResetStats zeroes the counters returned by Stats.
+func (c *Client) ResetStats() {
+	c.stats.reset()
+}
+
+// account completes the stats of a batch of ops started at start, adds
+// them to the client counters and stores them in out if not nil.
+func (c *Client) account(stats *BatchStats, start time.Time, out *BatchStats) {
+	stats.Duration = time.Since(start)
+	c.stats.add(*stats)
+	if out != nil {
+		*out = *stats
+	}
+}
@@ -0,0 +1,106 @@
+package modbus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestBatchStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		do       func(c *modbus.Client, stats *modbus.BatchStats) error
+		want     modbus.BatchStats
+	}{
+		{"merged reads", 0, func(c *modbus.Client, stats *modbus.BatchStats) error {
+			_, err := c.BatchReadWith([]modbus.Read{
+				testRead{10, types.Uint16Type},
+				testRead{11, types.Uint32Type},
+				testRead{20, types.Uint16Type},
+			}, modbus.BatchOptions{Stats: stats})
+			return err
+		}, modbus.BatchStats{Batches: 1, Ops: 3, Requests: 2, Registers: 4, RequestedRegisters: 4, ReadRegisters: 4}},
+		{"gap merged reads", 0, func(c *modbus.Client, stats *modbus.BatchStats) error {
+			_, err := c.BatchReadWith([]modbus.Read{
+				testRead{10, types.Uint16Type},
+				testRead{14, types.Uint16Type},
+				testRead{20, types.Uint16Type},
+				testRead{20, types.Uint16Type},
+			}, modbus.BatchOptions{Limits: &modbus.Limits{MaxReadGap: 3}, Stats: stats})
+			return err
+		}, modbus.BatchStats{Batches: 1, Ops: 4, Requests: 2, Registers: 6, RequestedRegisters: 3, ReadRegisters: 6}},
+		{"reads over the limit", 0, func(c *modbus.Client, stats *modbus.BatchStats) error {
+			_, err := c.BatchReadWith([]modbus.Read{
+				testRead{10, types.Uint16Type},
+				testRead{11, types.Uint16Type},
+				testRead{12, types.Uint16Type},
+			}, modbus.BatchOptions{Limits: &modbus.Limits{MaxReadQuantity: 2}, Stats: stats})
+			return err
+		}, modbus.BatchStats{Batches: 1, Ops: 3, Requests: 2, Registers: 3, RequestedRegisters: 3, ReadRegisters: 3}},
+		{"retried read", 1, func(c *modbus.Client, stats *modbus.BatchStats) error {
+			_, err := c.BatchReadWith([]modbus.Read{testRead{10, types.Uint16Type}}, modbus.BatchOptions{Stats: stats})
+			return err
+		}, modbus.BatchStats{Batches: 1, Ops: 1, Requests: 2, Registers: 2, RequestedRegisters: 1, ReadRegisters: 1}},
+		{"merged writes", 0, func(c *modbus.Client, stats *modbus.BatchStats) error {
+			return c.BatchWriteWith([]modbus.Write{
+				testWrite{10, types.Uint16(1)},
+				testWrite{11, types.Uint16(2)},
+				testWrite{12, types.Uint32(3)},
+			}, nil, modbus.BatchOptions{Stats: stats})
+		}, modbus.BatchStats{Batches: 1, Ops: 3, Requests: 1, Registers: 4}},
+		{"differential writes", 0, func(c *modbus.Client, stats *modbus.BatchStats) error {
+			return c.BatchWriteWith([]modbus.Write{
+				testWrite{10, types.Uint16(1)},
+				testWrite{11, types.Uint16(2)},
+				testWrite{12, types.Uint16(3)},
+			}, modbus.Registers{11: types.Uint16(2), 12: types.Uint16(4)}, modbus.BatchOptions{Stats: stats})
+		}, modbus.BatchStats{Batches: 1, Ops: 3, Requests: 2, Registers: 2, SkippedOps: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.failures = tt.failures
+			client := modbus.NewClient(slave, modbus.WithRetry(modbus.Retry{Retries: 1}))
+
+			var stats modbus.BatchStats
+			assert.NoError(t, tt.do(client, &stats))
+			assert.Greater(t, int64(stats.Duration), int64(0))
+			stats.Duration = 0
+			assert.Equal(t, tt.want, stats)
+			assert.Equal(t, tt.want.Ops-tt.want.Requests, stats.RequestsAvoided())
+			assert.Equal(t, tt.want.ReadRegisters-tt.want.RequestedRegisters, stats.WastedRegisters())
+		})
+	}
+}
+
+func TestClient_Stats(t *testing.T) {
+	slave := newTestSlave()
+	slave.delay = time.Millisecond
+	client := modbus.NewClient(slave)
+
+	_, err := client.BatchRead([]modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint16Type}})
+	assert.NoError(t, err)
+	assert.NoError(t, client.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{20, types.Uint16(2)}}, nil))
+	_, err = client.Read(10, types.Uint16Type)
+	assert.NoError(t, err)
+
+	stats := client.Stats()
+	assert.GreaterOrEqual(t, int64(stats.Duration), int64(3*time.Millisecond))
+	stats.Duration = 0
+	assert.Equal(t, modbus.BatchStats{Batches: 2, Ops: 4, Requests: 3, Registers: 4, RequestedRegisters: 2, ReadRegisters: 2}, stats, "single reads aren't batches")
+	assert.Equal(t, 1, stats.RequestsAvoided())
+	assert.Equal(t, 1.0, stats.Amplification())
+
+	_, err = client.BatchReadWith([]modbus.Read{testRead{10, types.Uint16Type}, testRead{13, types.Uint16Type}},
+		modbus.BatchOptions{Limits: &modbus.Limits{MaxReadGap: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, client.Stats().WastedRegisters())
+	assert.Equal(t, 1.5, client.Stats().Amplification())
+
+	client.ResetStats()
+	assert.Equal(t, modbus.BatchStats{}, client.Stats())
+}
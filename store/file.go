@@ -0,0 +1,173 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CorruptionError lists entries of a file store that couldn't be read
+// and were skipped.
+type CorruptionError struct {
+	Path  string
+	Lines []int
+}
+
+func (e *CorruptionError) Error() string {
+	lines := make([]string, len(e.Lines))
+	for i, l := range e.Lines {
+		lines[i] = fmt.Sprint(l)
+	}
+	return fmt.Sprintf("%s: skipped corrupt entries on lines %s", e.Path, strings.Join(lines, ", "))
+}
+
+// File is a Store keeping entries in a file, one JSON object per line.
+// Every change rewrites the file through a temporary file renamed over
+// it, so the file always holds either the old or the new contents.
+type File struct {
+	path    string
+	mtx     sync.Mutex
+	entries entries
+}
+
+type fileEntry struct {
+	Namespace string `json:"ns"`
+	Key       string `json:"key"`
+	Value     []byte `json:"value"`
+}
+
+// OpenFile opens a file store, creating it on the first change if path
+// doesn't exist. Entries that can't be read, e.g. when the file was
+// truncated, are skipped: the store is returned along with a
+// *CorruptionError listing them, and they are dropped from the file on
+// the next change.
+func OpenFile(path string) (*File, error) {
+	f := &File{path: path, entries: make(entries)}
+	b, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var corrupt []int
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e fileEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Namespace == "" {
+			corrupt = append(corrupt, line)
+			continue
+		}
+		f.entries.put(e.Namespace, map[string][]byte{e.Key: e.Value})
+	}
+	if corrupt != nil {
+		return f, &CorruptionError{path, corrupt}
+	}
+	return f, nil
+}
+
+func (f *File) Get(namespace, key string) ([]byte, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.entries.get(namespace, key)
+}
+
+func (f *File) Put(namespace string, batch map[string][]byte) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	previous := make(map[string][]byte, len(batch))
+	for k := range batch {
+		if v, ok := f.entries[namespace][k]; ok {
+			previous[k] = v
+		}
+	}
+	f.entries.put(namespace, batch)
+	if err := f.save(); err != nil {
+		// roll back to keep memory consistent with the file
+		for k := range batch {
+			if v, ok := previous[k]; ok {
+				f.entries[namespace][k] = v
+			} else {
+				f.entries.delete(namespace, k)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (f *File) Delete(namespace, key string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	v, ok := f.entries[namespace][key]
+	if !ok {
+		return nil
+	}
+	f.entries.delete(namespace, key)
+	if err := f.save(); err != nil {
+		f.entries.put(namespace, map[string][]byte{key: v})
+		return err
+	}
+	return nil
+}
+
+func (f *File) List(namespace string) ([]string, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.entries.list(namespace), nil
+}
+
+// save writes all the entries to the file. The caller must hold the
+// mutex.
+func (f *File) save() error {
+	namespaces := make([]string, 0, len(f.entries))
+	for ns := range f.entries {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	var buf bytes.Buffer
+	for _, ns := range namespaces {
+		for _, k := range f.entries.list(ns) {
+			line, err := json.Marshal(fileEntry{ns, k, f.entries[ns][k]})
+			if err != nil {
+				return err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
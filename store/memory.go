@@ -0,0 +1,40 @@
+package store
+
+import "sync"
+
+// Memory is a Store keeping entries in memory.
+type Memory struct {
+	mtx     sync.Mutex
+	entries entries
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{entries: make(entries)}
+}
+
+func (m *Memory) Get(namespace, key string) ([]byte, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.entries.get(namespace, key)
+}
+
+func (m *Memory) Put(namespace string, batch map[string][]byte) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.entries.put(namespace, batch)
+	return nil
+}
+
+func (m *Memory) Delete(namespace, key string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.entries.delete(namespace, key)
+	return nil
+}
+
+func (m *Memory) List(namespace string) ([]string, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.entries.list(namespace), nil
+}
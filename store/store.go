@@ -0,0 +1,128 @@
+// Package store defines the persistence interface used by components
+// of opmodbus that keep state across restarts, along with in-memory and
+// file-backed implementations.
+//
+// Any key-value database can back a Store by implementing its four
+// methods.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNotFound is returned by Get for keys missing from a namespace.
+var ErrNotFound = errors.New("key not found")
+
+// ErrVersion is returned by Decode for payloads of another version.
+var ErrVersion = errors.New("unsupported payload version")
+
+// Store is a namespaced key-value store. Every component uses its own
+// namespace. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value of key in namespace, or ErrNotFound.
+	Get(namespace, key string) ([]byte, error)
+	// Put sets all the entries of namespace atomically: either all of
+	// them are stored or none.
+	Put(namespace string, entries map[string][]byte) error
+	// Delete removes key from namespace. Deleting a missing key is not
+	// an error.
+	Delete(namespace, key string) error
+	// List returns the keys of namespace in ascending order.
+	List(namespace string) ([]string, error)
+}
+
+type envelope struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Encode marshals v to JSON tagged with version, so that payloads
+// written by a different version of a component can be told apart.
+func Encode(version int, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{version, data})
+}
+
+// Decode unmarshals a payload made by Encode into v. It fails with
+// ErrVersion if the payload has a different version.
+func Decode(b []byte, version int, v interface{}) error {
+	var e envelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	if e.Version != version {
+		return fmt.Errorf("%w: %d, expected %d", ErrVersion, e.Version, version)
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// WithPrefix returns a view of s that prefixes namespaces with prefix,
+// e.g. to share a single store between clients of several devices.
+func WithPrefix(s Store, prefix string) Store {
+	return prefixed{s, prefix}
+}
+
+type prefixed struct {
+	s      Store
+	prefix string
+}
+
+func (p prefixed) Get(namespace, key string) ([]byte, error) {
+	return p.s.Get(p.prefix+namespace, key)
+}
+
+func (p prefixed) Put(namespace string, entries map[string][]byte) error {
+	return p.s.Put(p.prefix+namespace, entries)
+}
+
+func (p prefixed) Delete(namespace, key string) error {
+	return p.s.Delete(p.prefix+namespace, key)
+}
+
+func (p prefixed) List(namespace string) ([]string, error) {
+	return p.s.List(p.prefix + namespace)
+}
+
+// entries is the contents of a store, keyed by namespace and key.
+type entries map[string]map[string][]byte
+
+func (e entries) get(namespace, key string) ([]byte, error) {
+	v, ok := e[namespace][key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, namespace, key)
+	}
+	return append([]byte(nil), v...), nil
+}
+
+func (e entries) put(namespace string, batch map[string][]byte) {
+	ns, ok := e[namespace]
+	if !ok {
+		ns = make(map[string][]byte, len(batch))
+		e[namespace] = ns
+	}
+	for k, v := range batch {
+		ns[k] = append([]byte(nil), v...)
+	}
+}
+
+func (e entries) delete(namespace, key string) {
+	delete(e[namespace], key)
+	if len(e[namespace]) == 0 {
+		delete(e, namespace)
+	}
+}
+
+func (e entries) list(namespace string) []string {
+	keys := make([]string, 0, len(e[namespace]))
+	for k := range e[namespace] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
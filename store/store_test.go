@@ -0,0 +1,186 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStores(t *testing.T) map[string]func() Store {
+	dir := t.TempDir()
+	n := 0
+	return map[string]func() Store{
+		"memory": func() Store { return NewMemory() },
+		"file": func() Store {
+			n++
+			f, err := OpenFile(filepath.Join(dir, fmt.Sprintf("store%d", n)))
+			assert.NoError(t, err)
+			return f
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := open()
+			_, err := s.Get("a", "x")
+			assert.True(t, errors.Is(err, ErrNotFound))
+
+			assert.NoError(t, s.Put("a", map[string][]byte{"x": {1}, "y": {2}}))
+			assert.NoError(t, s.Put("b", map[string][]byte{"x": {3}}))
+			v, err := s.Get("a", "x")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{1}, v)
+			v, err = s.Get("b", "x")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{3}, v, "namespaces are separate")
+
+			keys, err := s.List("a")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"x", "y"}, keys)
+
+			assert.NoError(t, s.Delete("a", "x"))
+			assert.NoError(t, s.Delete("a", "missing"))
+			keys, err = s.List("a")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"y"}, keys)
+			keys, err = s.List("c")
+			assert.NoError(t, err)
+			assert.Empty(t, keys)
+		})
+	}
+}
+
+func TestStore_concurrent(t *testing.T) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := open()
+			var wg sync.WaitGroup
+			for c := 0; c < 4; c++ {
+				wg.Add(1)
+				go func(component int) {
+					defer wg.Done()
+					ns := fmt.Sprintf("component%d", component)
+					for i := 0; i < 50; i++ {
+						assert.NoError(t, s.Put(ns, map[string][]byte{fmt.Sprint(i % 5): {byte(i)}}))
+						_, err := s.List(ns)
+						assert.NoError(t, err)
+					}
+				}(c)
+			}
+			wg.Wait()
+			for c := 0; c < 4; c++ {
+				v, err := s.Get(fmt.Sprintf("component%d", c), "4")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte{49}, v)
+			}
+		})
+	}
+}
+
+func TestFile_reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	f, err := OpenFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Put("a", map[string][]byte{"x": {1}, "y": {2}}))
+	assert.NoError(t, f.Put("b", map[string][]byte{"z": {3}}))
+
+	f, err = OpenFile(path)
+	assert.NoError(t, err)
+	v, err := f.Get("b", "z")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{3}, v)
+}
+
+func TestFile_truncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	f, err := OpenFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Put("a", map[string][]byte{"x": {1}, "y": {2}, "z": {3}}))
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path, b[:len(b)-10], 0o644))
+
+	f, err = OpenFile(path)
+	var corrupt *CorruptionError
+	if assert.True(t, errors.As(err, &corrupt)) {
+		assert.Equal(t, []int{3}, corrupt.Lines)
+	}
+	keys, err := f.List("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, keys, "valid entries are kept")
+
+	// the next change drops the corrupt entry from the file
+	assert.NoError(t, f.Put("a", map[string][]byte{"z": {4}}))
+	f, err = OpenFile(path)
+	assert.NoError(t, err)
+	v, err := f.Get("a", "z")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{4}, v)
+}
+
+func TestFile_garbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("{\"ns\":\"a\",\"key\":\"x\",\"value\":\"AQ==\"}\nnot json\n{}\n"), 0o644))
+
+	f, err := OpenFile(path)
+	var corrupt *CorruptionError
+	if assert.True(t, errors.As(err, &corrupt)) {
+		assert.Equal(t, []int{2, 3}, corrupt.Lines)
+	}
+	v, err := f.Get("a", "x")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, v)
+}
+
+func TestFile_putFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dir")
+	assert.NoError(t, os.Mkdir(dir, 0o755))
+	f, err := OpenFile(filepath.Join(dir, "store"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Put("a", map[string][]byte{"x": {1}}))
+	assert.NoError(t, os.RemoveAll(dir))
+
+	assert.Error(t, f.Put("a", map[string][]byte{"x": {2}, "y": {3}}))
+	v, err := f.Get("a", "x")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, v, "failed batches are rolled back")
+	_, err = f.Get("a", "y")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestEncode(t *testing.T) {
+	type payload struct{ N int }
+	b, err := Encode(2, payload{7})
+	assert.NoError(t, err)
+
+	var p payload
+	assert.NoError(t, Decode(b, 2, &p))
+	assert.Equal(t, payload{7}, p)
+	assert.True(t, errors.Is(Decode(b, 1, &p), ErrVersion))
+	assert.Error(t, Decode([]byte("{"), 2, &p))
+}
+
+func TestWithPrefix(t *testing.T) {
+	m := NewMemory()
+	s := WithPrefix(m, "meter/")
+	assert.NoError(t, s.Put("budgets", map[string][]byte{"1": {1}}))
+	keys, err := m.List("meter/budgets")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+	v, err := s.Get("budgets", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, v)
+	assert.NoError(t, s.Delete("budgets", "1"))
+	keys, err = s.List("budgets")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
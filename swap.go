@@ -0,0 +1,156 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// SwapOp is a Write that also declares the Type to decode the previous
+// contents of its registers with in Swap.
+type SwapOp interface {
+	Write
+	Type() types.Type
+}
+
+// PartialWriteError is returned when a batch of writes fails after
+// some of the writes were already performed.
+type PartialWriteError struct {
+	// Written holds registers of the write ops that were applied
+	// before the failure, in ascending order.
+	Written []uint16
+	Err     error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("written registers %v: %v", e.Written, e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// Swap writes a batch of values and returns the values the registers
+// held before the write. The read and the writes are performed under a
+// single client lock, so no other operation of this client can come in
+// between.
+//
+// The previous value of an op is decoded with the Type of a SwapOp. For
+// other ops, the Type is inferred from the op Value if it implements
+// Type, otherwise the previous value holds raw bytes of the same size as
+// the written Value.
+//
+// Reads and writes are planned the same way as in BatchRead and
+// BatchWrite, limits included, except that writes are never skipped by
+// differential optimization. If a write fails after the read, the
+// previous values are returned along with a *PartialWriteError.
+func (c *Client) Swap(ops []Write) (_ Registers, err error) {
+	defer recoverPanic(&err)
+	stats := &BatchStats{Batches: 1, Ops: len(ops)}
+	defer c.account(stats, time.Now(), nil)
+
+	cfg, err := c.resolve(BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	cfg.stats = stats
+	ops, err = resolveConflicts(ops, cfg.WriteConflicts)
+	if err != nil {
+		return nil, err
+	}
+	reads := make([]Read, len(ops))
+	starts := make([]writeOp, len(ops))
+	for i, op := range ops {
+		reads[i], starts[i] = swapRead{op}, writeOp{register: op.Register()}
+	}
+	wp, err := c.planWrite(ops, nil, BatchOptions{DisableDiff: true}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := c.planRead(reads, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.batch = rp.ops
+	optimized, planned := append(wp.requests[:len(wp.requests):len(wp.requests)], wp.applies...), rp.requests
+
+	if err := c.lock(); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+	defer c.track(cfg)()
+
+	if err := c.budgets.check(c.config.WriteBudgets, optimized, c.config.OverrideWriteBudgets); err != nil {
+		return nil, err
+	}
+	results, err := c.readChunks(context.Background(), planned, cfg.Retry, nil)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := decodeRead(reads, results)
+	if err != nil {
+		return nil, err
+	}
+
+	if n, err := c.writeChunks(context.Background(), optimized, cfg.Retry); err != nil {
+		return previous, &PartialWriteError{writtenRegisters(starts, optimized[:n]), err}
+	}
+
+	return previous, nil
+}
+
+// writtenRegisters returns registers of ops covered by completed
+// merged writes.
+func writtenRegisters(ops []writeOp, completed []writeOp) []uint16 {
+	written := make([]uint16, 0, len(ops))
+	for _, op := range ops {
+		for _, done := range completed {
+			if (RegisterRange{done.register, done.quantity}).Contains(op.register) {
+				written = append(written, op.register)
+				break
+			}
+		}
+	}
+	sort.Slice(written, func(i, j int) bool { return written[i] < written[j] })
+	return written
+}
+
+// swapRead is the Read covering registers of a Write in Swap.
+type swapRead struct {
+	Write
+}
+
+func (r swapRead) Type() types.Type {
+	if op, ok := r.Write.(SwapOp); ok {
+		return op.Type()
+	}
+	value := r.Value()
+	if t, ok := value.(types.Type); ok {
+		return t
+	}
+	return rawValue(make([]byte, len(value.Bytes())))
+}
+
+// rawValue is a Value of arbitrary size holding bytes as is. As Type, it
+// converts byte slices of its own length.
+type rawValue []byte
+
+func (r rawValue) Bytes() []byte {
+	return r
+}
+
+func (r rawValue) Size() uint16 {
+	return uint16(len(r) / 2)
+}
+
+func (r rawValue) Converter() types.Converter {
+	return func(b []byte) (types.Value, error) {
+		if l := len(b); l != len(r) {
+			return nil, fmt.Errorf("%w: bytes of size %v", types.ErrInvalidInput, l)
+		}
+		return rawValue(append([]byte(nil), b...)), nil
+	}
+}
@@ -0,0 +1,107 @@
+package modbus_test
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_Swap(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, types.Float32CDAB(1.5).Bytes()...)
+	slave.set(12, 0, 7)
+	client := modbus.NewClient(slave)
+
+	previous, err := client.Swap([]modbus.Write{
+		testWrite{10, types.Float32CDAB(2.5)},
+		testWrite{12, types.Uint16(8)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Float32CDAB(1.5), 12: types.Uint16(7)}, previous)
+	assert.Equal(t, append(types.Float32CDAB(2.5).Bytes(), 0, 8), slave.get(10, 3))
+	assert.Equal(t, 2, slave.calls(), "one merged read and one merged write")
+}
+
+func TestClient_Swap_limits(t *testing.T) {
+	slave := newTestSlave()
+	old := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	slave.set(0, old...)
+	client := modbus.NewClient(slave, modbus.WithMaxReadQuantity(4), modbus.WithMaxWriteQuantity(4))
+
+	previous, err := client.Swap([]modbus.Write{testWrite{0, rawWrite(make([]byte, 16))}})
+	assert.NoError(t, err)
+	if assert.Contains(t, previous, uint16(0)) {
+		assert.Equal(t, old, previous[0].Bytes())
+	}
+	assert.Equal(t, make([]byte, 16), slave.get(0, 8))
+	assert.Equal(t, 4, slave.calls(), "two reads and two writes of 4 registers")
+	for _, pdu := range slave.requests {
+		assert.Equal(t, []byte{0, 4}, pdu.Data[2:4])
+	}
+}
+
+func TestClient_Swap_partialWrite(t *testing.T) {
+	slave := newTestSlave()
+	slave.readOnly[20] = true
+	client := modbus.NewClient(slave)
+
+	// the second merged write is rejected by the slave
+	_, err := client.Swap([]modbus.Write{
+		testWrite{10, types.Uint16(1)},
+		testWrite{11, types.Uint16(2)},
+		testWrite{20, types.Uint16(3)},
+	})
+	var partial *modbus.PartialWriteError
+	if assert.True(t, errors.As(err, &partial)) {
+		assert.Equal(t, []uint16{10, 11}, partial.Written)
+	}
+}
+
+func TestClient_Swap_atomic(t *testing.T) {
+	const rounds = 200
+	modbus.SweepChaos(t, modbus.ChaosOptions{Duplicate: true}, func(t *testing.T, chaos func(*modbus.Client)) {
+		slave := newTestSlave()
+		client := modbus.NewClient(slave)
+		chaos(client)
+
+		// every value written by either goroutine must be taken out by
+		// exactly one Swap, otherwise a write has come in between
+		var (
+			mtx  sync.Mutex
+			seen []int
+			wg   sync.WaitGroup
+		)
+		swapper := func(base int) {
+			defer wg.Done()
+			for i := 1; i <= rounds; i++ {
+				previous, err := client.Swap([]modbus.Write{testWrite{10, types.Uint16(base + i)}})
+				if !assert.NoError(t, err) {
+					return
+				}
+				mtx.Lock()
+				seen = append(seen, int(previous[10].(types.Uint16)))
+				mtx.Unlock()
+			}
+		}
+		wg.Add(2)
+		go swapper(0)
+		go swapper(1000)
+		wg.Wait()
+
+		last, err := client.Read(10, types.Uint16Type)
+		assert.NoError(t, err)
+		seen = append(seen, int(last.(types.Uint16)))
+		want := []int{0}
+		for i := 1; i <= rounds; i++ {
+			want = append(want, i, 1000+i)
+		}
+		sort.Ints(seen)
+		sort.Ints(want)
+		assert.Equal(t, want, seen)
+	})
+}
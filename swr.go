@@ -0,0 +1,219 @@
+package modbus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// SWROptions configures BatchReadSWR.
+type SWROptions struct {
+	// MaxStale is the age up to which cached values are fresh. Older
+	// values are refreshed in the background.
+	MaxStale time.Duration
+	// Block makes BatchReadSWR wait for the refresh if any op has no
+	// cached value at all. Otherwise such ops are absent from the
+	// values returned until the refresh lands.
+	Block bool
+}
+
+// SWRResult holds the values returned by BatchReadSWR.
+type SWRResult struct {
+	Values Registers
+	// Ages holds the age of every value of Values.
+	Ages map[uint16]time.Duration
+	// Refresh receives the result of the background refresh of stale and
+	// missing values and is closed afterwards. It is nil if there is no
+	// refresh to wait for.
+	Refresh <-chan SWRUpdate
+}
+
+// SWRUpdate is the result of a background refresh of BatchReadSWR.
+type SWRUpdate struct {
+	Values Registers
+	Err    error
+}
+
+// BatchReadSWR returns cached values of ops without waiting for the
+// slave, e.g. for user interfaces preferring slightly outdated values to
+// blocking. Cached values older than opts.MaxStale are still returned,
+// with their age, and are refreshed with a background BatchRead along
+// with ops that have no cached value. Callers reading the same ops while
+// a refresh of them is in flight share that refresh instead of starting
+// another one.
+//
+// The cache only holds values read by BatchReadSWR refreshes, those of
+// other operations don't populate it. Writes of the client drop the
+// cached values of the registers they touch.
+func (c *Client) BatchReadSWR(ops []Read, opts SWROptions) (*SWRResult, error) {
+	res, stale, missing := c.swr.lookup(ops, opts.MaxStale)
+	if len(stale) == 0 {
+		return res, nil
+	}
+
+	key := swrKey(stale)
+	updates, first := c.swr.join(key)
+	if first {
+		writes := c.swr.written()
+		go func() {
+			values, err := c.BatchRead(stale)
+			c.swr.finish(key, stale, values, err, writes)
+		}()
+	}
+	res.Refresh = updates
+	if !opts.Block || !missing {
+		return res, nil
+	}
+
+	update := <-updates
+	res.Refresh = nil
+	if update.Err != nil && update.Values == nil {
+		return nil, update.Err
+	}
+	for register, value := range update.Values {
+		res.Values[register] = value
+		res.Ages[register] = 0
+	}
+	return res, update.Err
+}
+
+// swrCache holds the values read by BatchReadSWR and its refreshes in
+// flight.
+type swrCache struct {
+	mtx      sync.Mutex
+	entries  map[uint16]swrEntry
+	inflight map[string][]chan SWRUpdate
+	// writes counts the invalidations, so that refreshes overlapping a
+	// write don't cache what they read before it
+	writes uint64
+	now    func() time.Time
+}
+
+type swrEntry struct {
+	// kind tells the type the value was read as
+	kind  string
+	space Space
+	size  uint16
+	value types.Value
+	at    time.Time
+}
+
+func (c *swrCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func swrKind(op Read) string {
+	return fmt.Sprintf("%T/%d", op.Type(), op.Type().Size())
+}
+
+// swrKey identifies a set of ops.
+func swrKey(ops []Read) string {
+	keys := make([]string, len(ops))
+	for i, op := range ops {
+		keys[i] = fmt.Sprintf("%d:%s", op.Register(), swrKind(op))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// lookup returns the cached values of ops and the ops to refresh, and
+// whether any of them has no cached value.
+func (c *swrCache) lookup(ops []Read, maxStale time.Duration) (*SWRResult, []Read, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.clock()
+	res := &SWRResult{Values: make(Registers), Ages: make(map[uint16]time.Duration)}
+	var stale []Read
+	missing := false
+	for _, op := range ops {
+		e, ok := c.entries[op.Register()]
+		if !ok || e.kind != swrKind(op) {
+			stale = append(stale, op)
+			missing = true
+			continue
+		}
+		age := now.Sub(e.at)
+		res.Values[op.Register()] = e.value
+		res.Ages[op.Register()] = age
+		if age > maxStale {
+			stale = append(stale, op)
+		}
+	}
+	return res, stale, missing
+}
+
+// join returns a channel receiving the result of the refresh of the ops
+// identified by key, and whether the caller must start that refresh.
+func (c *swrCache) join(key string) (<-chan SWRUpdate, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.inflight == nil {
+		c.inflight = make(map[string][]chan SWRUpdate)
+	}
+	updates := make(chan SWRUpdate, 1)
+	waiters, ok := c.inflight[key]
+	c.inflight[key] = append(waiters, updates)
+	return updates, !ok
+}
+
+// written returns the number of invalidations so far.
+func (c *swrCache) written() uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.writes
+}
+
+// finish caches the results of the refresh of ops and delivers them to
+// the callers waiting for it. The results are only cached if nothing was
+// invalidated since the refresh started, when writes were counted.
+func (c *swrCache) finish(key string, ops []Read, values Registers, err error, writes uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[uint16]swrEntry)
+	}
+	now := c.clock()
+	for _, op := range ops {
+		if writes != c.writes {
+			// a write may have changed the values read
+			break
+		}
+		if value, ok := values[op.Register()]; ok {
+			if _, unavailable := value.(Unavailable); !unavailable {
+				c.entries[op.Register()] = swrEntry{swrKind(op), spaceOf(op), op.Type().Size(), value, now}
+			}
+		}
+	}
+	for _, updates := range c.inflight[key] {
+		res := make(Registers, len(values))
+		for register, value := range values {
+			res[register] = value
+		}
+		updates <- SWRUpdate{res, err}
+		close(updates)
+	}
+	delete(c.inflight, key)
+}
+
+// invalidate drops the cached values of holding registers overlapping r.
+func (c *swrCache) invalidate(r RegisterRange) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.writes++
+	for register, e := range c.entries {
+		if e.space == HoldingRegisters && r.Overlaps(RegisterRange{register, e.size}) {
+			delete(c.entries, register)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tdemin/opmodbus/types"
+)
+
+type swrRead struct {
+	register uint16
+	t        types.Type
+}
+
+func (r swrRead) Register() uint16 { return r.register }
+func (r swrRead) Type() types.Type { return r.t }
+
+func Test_swrCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := swrCache{now: func() time.Time { return now }}
+	ops := []Read{swrRead{10, types.Uint16Type}, swrRead{11, types.Uint16Type}}
+
+	res, stale, missing := cache.lookup(ops, time.Second)
+	assert.Empty(t, res.Values)
+	assert.Equal(t, ops, stale)
+	assert.True(t, missing)
+
+	updates, first := cache.join(swrKey(stale))
+	assert.True(t, first)
+	shared, first := cache.join(swrKey([]Read{ops[1], ops[0]}))
+	assert.False(t, first, "a refresh of the same ops is in flight")
+	cache.finish(swrKey(stale), stale, Registers{10: types.Uint16(1), 11: types.Uint16(2)}, nil, 0)
+	assert.Equal(t, SWRUpdate{Values: Registers{10: types.Uint16(1), 11: types.Uint16(2)}}, <-updates)
+	assert.Equal(t, SWRUpdate{Values: Registers{10: types.Uint16(1), 11: types.Uint16(2)}}, <-shared)
+
+	now = now.Add(time.Second)
+	res, stale, _ = cache.lookup(ops, time.Second)
+	assert.Equal(t, Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res.Values)
+	assert.Equal(t, map[uint16]time.Duration{10: time.Second, 11: time.Second}, res.Ages)
+	assert.Empty(t, stale, "fresh up to MaxStale")
+
+	now = now.Add(time.Millisecond)
+	res, stale, missing = cache.lookup(ops, time.Second)
+	assert.Len(t, res.Values, 2, "stale values are returned")
+	assert.Equal(t, ops, stale)
+	assert.False(t, missing)
+
+	_, stale, missing = cache.lookup([]Read{swrRead{10, types.Int16Type}}, time.Hour)
+	assert.Len(t, stale, 1, "values read as another type are not reused")
+	assert.True(t, missing)
+
+	_, first = cache.join(swrKey(stale))
+	assert.True(t, first, "refreshes of other ops are not shared")
+}
+
+func Test_swrCache_invalidate(t *testing.T) {
+	cache := swrCache{now: func() time.Time { return time.Unix(1000, 0) }}
+	ops := []Read{swrRead{10, types.Uint32Type}, swrRead{20, types.Uint16Type}}
+	cache.finish(swrKey(ops), ops, Registers{10: types.Uint32(1), 20: types.Uint16(2)}, nil, cache.written())
+
+	cache.invalidate(RegisterRange{11, 1})
+	res, stale, _ := cache.lookup(ops, time.Hour)
+	assert.Equal(t, Registers{20: types.Uint16(2)}, res.Values, "values overlapping the range are dropped, whatever their start")
+	assert.Equal(t, ops[:1], stale)
+
+	writes := cache.written()
+	cache.invalidate(RegisterRange{100, 1})
+	cache.finish(swrKey(ops), ops, Registers{10: types.Uint32(3), 20: types.Uint16(4)}, nil, writes)
+	res, _, _ = cache.lookup(ops, time.Hour)
+	assert.Equal(t, Registers{20: types.Uint16(2)}, res.Values, "refreshes overlapping a write are not cached")
+}
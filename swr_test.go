@@ -0,0 +1,118 @@
+package modbus_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchReadSWR(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 1, 0, 2)
+	client := modbus.NewClient(slave)
+	now := time.Unix(1000, 0)
+	modbus.SetSWRClock(client, func() time.Time { return now })
+	ops := []modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint16Type}}
+
+	res, err := client.BatchReadSWR(ops, modbus.SWROptions{MaxStale: time.Second})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Values, "nothing is cached yet")
+	if assert.NotNil(t, res.Refresh) {
+		update := <-res.Refresh
+		assert.NoError(t, update.Err)
+		assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, update.Values)
+		_, ok := <-res.Refresh
+		assert.False(t, ok, "closed after the update")
+	}
+
+	slave.set(10, 0, 3)
+	now = now.Add(time.Second)
+	res, err = client.BatchReadSWR(ops, modbus.SWROptions{MaxStale: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res.Values)
+	assert.Nil(t, res.Refresh, "fresh values are not refreshed")
+	assert.Equal(t, 1, slave.calls())
+
+	now = now.Add(time.Millisecond)
+	res, err = client.BatchReadSWR(ops, modbus.SWROptions{MaxStale: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res.Values, "stale values are returned")
+	assert.Equal(t, time.Second+time.Millisecond, res.Ages[10])
+	if assert.NotNil(t, res.Refresh) {
+		assert.Equal(t, types.Uint16(3), (<-res.Refresh).Values[10])
+	}
+}
+
+func TestClient_BatchReadSWR_write(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	modbus.SetSWRClock(client, func() time.Time { return time.Unix(1000, 0) })
+	ops := []modbus.Read{testRead{10, types.Uint32Type}, testRead{20, types.Uint16Type}}
+
+	res, err := client.BatchReadSWR(ops, modbus.SWROptions{MaxStale: time.Hour, Block: true})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint32(0), 20: types.Uint16(0)}, res.Values)
+
+	assert.NoError(t, client.Write(11, types.Uint16(7)))
+	res, err = client.BatchReadSWR(ops, modbus.SWROptions{MaxStale: time.Hour, Block: true})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint32(7), 20: types.Uint16(0)}, res.Values,
+		"writes drop cached values they overlap")
+	assert.Equal(t, 4, slave.calls(), "only the overwritten value is read again")
+
+	assert.NoError(t, client.BatchWriteBits([]modbus.BitWrite{{modbus.Bit{Register: 20, Index: 0}, true}}))
+	res, err = client.BatchReadSWR(ops[1:], modbus.SWROptions{MaxStale: time.Hour, Block: true})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{20: types.Uint16(1)}, res.Values, "so do mask writes")
+}
+
+func TestClient_BatchReadSWR_block(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 1, 0, 2)
+	client := modbus.NewClient(slave)
+
+	res, err := client.BatchReadSWR([]modbus.Read{testRead{10, types.Uint16Type}}, modbus.SWROptions{MaxStale: time.Hour})
+	assert.NoError(t, err)
+	<-res.Refresh
+
+	res, err = client.BatchReadSWR([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+	}, modbus.SWROptions{MaxStale: time.Hour, Block: true})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(2)}, res.Values)
+	assert.Equal(t, time.Duration(0), res.Ages[11])
+	assert.Nil(t, res.Refresh, "the refresh was waited for")
+	assert.Equal(t, 2, slave.calls(), "only missing values are read")
+
+	slave.failures = 1
+	_, err = client.BatchReadSWR([]modbus.Read{testRead{12, types.Uint16Type}}, modbus.SWROptions{Block: true})
+	assert.ErrorIs(t, err, errTransport)
+}
+
+func TestClient_BatchReadSWR_dedup(t *testing.T) {
+	modbus.SweepChaos(t, modbus.ChaosOptions{}, func(t *testing.T, chaos func(*modbus.Client)) {
+		slave := newTestSlave()
+		slave.delay = 50 * time.Millisecond
+		client := modbus.NewClient(slave)
+		chaos(client)
+		ops := []modbus.Read{testRead{10, types.Uint16Type}}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := client.BatchReadSWR(ops, modbus.SWROptions{Block: true})
+				assert.NoError(t, err)
+				assert.Equal(t, modbus.Registers{10: types.Uint16(0)}, res.Values)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, slave.calls(), "concurrent refreshes of the same ops are shared")
+	})
+}
@@ -0,0 +1,154 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateClient is returned by SyncBatchRead when a client takes
+// part in it more than once.
+var ErrDuplicateClient = errors.New("client takes part in a synchronized read more than once")
+
+// SyncRead is a batch of reads of a single client taking part in
+// SyncBatchRead.
+type SyncRead struct {
+	Client *Client
+	Ops    []Read
+}
+
+// SyncSnapshot holds the results of a SyncRead. Snapshot.Time is the
+// start of its first request.
+type SyncSnapshot struct {
+	Snapshot
+	// Times holds the start of the request that read each register.
+	Times map[uint16]time.Time
+}
+
+// SyncReport holds the results of SyncBatchRead in the order of its
+// reads.
+type SyncReport struct {
+	Snapshots []SyncSnapshot
+	// Skew is the largest difference between the starts of the first
+	// requests of the clients.
+	Skew time.Duration
+}
+
+// SyncBatchRead performs batches of reads of several clients, starting
+// the first request of every client at the same time. The remaining
+// requests of each client follow as BatchRead would perform them. Use
+// it to sample devices on independent links as close to simultaneously
+// as possible: the registers read by the first request of every batch
+// are sampled within SyncReport.Skew of each other.
+//
+// Every batch is planned as BatchRead would plan it before any request
+// is made, though a rejected request fails the batch even if it covers
+// optional ops, see Optional. Requests bypass
+// the chunk cache, as cached chunks were not sampled at the same time,
+// and a violation of any ValidationRule fails the batch.
+// If any batch fails, SyncBatchRead returns the error of the first
+// failed one.
+func SyncBatchRead(reads []SyncRead) (_ *SyncReport, err error) {
+	defer recoverPanic(&err)
+
+	seen := make(map[*Client]bool, len(reads))
+	plans := make([][]readOp, len(reads))
+	retries := make([]Retry, len(reads))
+	for i, r := range reads {
+		if seen[r.Client] {
+			return nil, fmt.Errorf("%w: read %d", ErrDuplicateClient, i+1)
+		}
+		seen[r.Client] = true
+
+		cfg, err := r.Client.resolve(BatchOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("read %d: %w", i+1, err)
+		}
+		plan, err := r.Client.planRead(r.Ops, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("read %d: %w", i+1, err)
+		}
+		plans[i] = plan.requests
+		retries[i] = cfg.Retry
+	}
+
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+	snapshots := make([]SyncSnapshot, len(reads))
+	errs := make([]error, len(reads))
+	ready.Add(len(reads))
+	done.Add(len(reads))
+	for i := range reads {
+		go func(i int) {
+			defer done.Done()
+			defer recoverPanic(&errs[i])
+			snapshots[i], errs[i] = reads[i].Client.syncRead(reads[i].Ops, plans[i], retries[i], &ready, start)
+		}(i)
+	}
+	ready.Wait()
+	close(start)
+	done.Wait()
+
+	report := &SyncReport{Snapshots: snapshots}
+	var first, last time.Time
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("read %d: %w", i+1, err)
+		}
+		t := snapshots[i].Time
+		if t.IsZero() {
+			// no requests
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	report.Skew = last.Sub(first)
+	return report, nil
+}
+
+// syncRead performs a planned batch of SyncBatchRead. It marks ready
+// once the client is locked, and makes the first request once start is
+// closed.
+func (c *Client) syncRead(ops []Read, plan []readOp, retry Retry, ready *sync.WaitGroup, start <-chan struct{}) (SyncSnapshot, error) {
+	if err := c.lock(); err != nil {
+		ready.Done()
+		return SyncSnapshot{}, err
+	}
+	defer c.unlock()
+	ready.Done()
+	<-start
+
+	snapshot := SyncSnapshot{Times: make(map[uint16]time.Time)}
+	results := make(map[readOp][]byte, len(plan))
+	for i, v := range plan {
+		at := time.Now()
+		if i == 0 {
+			snapshot.Time = at
+		}
+		b, err := c.read(v, retry)
+		if err != nil {
+			return SyncSnapshot{}, fmt.Errorf("read request %d at %d: %w", i+1, v.register, err)
+		}
+		results[v] = b
+		for r := 0; r < int(v.quantity); r++ {
+			snapshot.Times[v.register+uint16(r)] = at
+		}
+	}
+
+	res, err := decodeRead(ops, results)
+	if err != nil {
+		return SyncSnapshot{}, err
+	}
+	if verr := validate(context.Background(), c.config.ValidationRules, res); verr != nil {
+		return SyncSnapshot{}, verr
+	}
+	snapshot.Values = res
+	return snapshot, nil
+}
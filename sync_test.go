@@ -0,0 +1,118 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// timedSlave records when the first request starts being sent.
+type timedSlave struct {
+	*testSlave
+	once  sync.Once
+	start time.Time
+}
+
+func (s *timedSlave) Send(adu []byte) ([]byte, error) {
+	s.once.Do(func() { s.start = time.Now() })
+	return s.testSlave.Send(adu)
+}
+
+func TestSyncBatchRead(t *testing.T) {
+	delays := []time.Duration{40 * time.Millisecond, 10 * time.Millisecond, 0, 25 * time.Millisecond}
+	slaves := make([]*timedSlave, len(delays))
+	reads := make([]modbus.SyncRead, len(delays))
+	for i, delay := range delays {
+		slave := newTestSlave()
+		slave.delay = delay
+		slave.set(10, 0, byte(i), 0, 1)
+		slave.set(100, 0, byte(i+10))
+		slaves[i] = &timedSlave{testSlave: slave}
+		reads[i] = modbus.SyncRead{
+			Client: modbus.NewClient(slaves[i]),
+			Ops: []modbus.Read{
+				testRead{100, types.Uint16Type},
+				testRead{10, types.Uint16Type},
+				testRead{11, types.Uint16Type},
+			},
+		}
+	}
+
+	report, err := modbus.SyncBatchRead(reads)
+	assert.NoError(t, err)
+
+	first, last := slaves[0].start, slaves[0].start
+	for _, s := range slaves {
+		if s.start.Before(first) {
+			first = s.start
+		}
+		if s.start.After(last) {
+			last = s.start
+		}
+	}
+	assert.Less(t, int64(last.Sub(first)), int64(5*time.Millisecond), "first requests are sent concurrently")
+	assert.Less(t, int64(report.Skew), int64(5*time.Millisecond))
+
+	assert.Len(t, report.Snapshots, len(delays))
+	for i, s := range report.Snapshots {
+		assert.Equal(t, modbus.Registers{
+			10:  types.Uint16(i),
+			11:  types.Uint16(1),
+			100: types.Uint16(i + 10),
+		}, s.Values, "client %d", i)
+		assert.Equal(t, s.Time, s.Times[10], "client %d: first request", i)
+		assert.Equal(t, s.Time, s.Times[11], "client %d: first request", i)
+		assert.False(t, s.Times[100].Before(s.Time.Add(delays[i])), "client %d: second request", i)
+		assert.Equal(t, 2, slaves[i].calls(), "client %d", i)
+	}
+}
+
+func TestSyncBatchRead_errors(t *testing.T) {
+	client := modbus.NewClient(newTestSlave())
+	ops := []modbus.Read{testRead{10, types.Uint16Type}}
+
+	_, err := modbus.SyncBatchRead([]modbus.SyncRead{{client, ops}, {client, ops}})
+	assert.True(t, errors.Is(err, modbus.ErrDuplicateClient), "%v", err)
+
+	failing := newTestSlave()
+	failing.failures = 1
+	closed := modbus.NewClient(newTestSlave())
+	assert.NoError(t, closed.Shutdown(context.Background()))
+	_, err = modbus.SyncBatchRead([]modbus.SyncRead{
+		{client, ops},
+		{modbus.NewClient(failing), ops},
+		{closed, ops},
+	})
+	assert.True(t, errors.Is(err, errTransport), "%v", err)
+
+	_, err = modbus.SyncBatchRead([]modbus.SyncRead{{client, ops}, {closed, ops}})
+	assert.True(t, errors.Is(err, modbus.ErrClientClosed), "%v", err)
+
+	_, err = client.BatchRead(ops)
+	assert.NoError(t, err, "clients are unlocked after a failure")
+}
+
+func TestSyncBatchRead_limits(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 1, 2, 3, 4, 5, 6, 7, 8)
+	ops := []modbus.Read{testRead{10, types.Uint64Type}}
+
+	report, err := modbus.SyncBatchRead([]modbus.SyncRead{{modbus.NewClient(slave, modbus.WithMaxReadQuantity(2)), ops}})
+	assert.NoError(t, err)
+	if assert.Len(t, report.Snapshots, 1) {
+		assert.Equal(t, types.Uint64(0x0102030405060708), report.Snapshots[0].Values[10])
+	}
+	assert.Equal(t, 2, slave.calls(), "ops are split as in BatchRead")
+
+	client := modbus.NewClient(slave, modbus.WithLimits(modbus.Limits{MaxReadQuantity: 2, MaxRequests: 1}))
+	_, err = modbus.SyncBatchRead([]modbus.SyncRead{{client, ops}})
+	var serr *modbus.BatchSizeError
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, 2, slave.calls(), "nothing is sent")
+}
@@ -0,0 +1,150 @@
+package modbus
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// AdaptiveTimeout configures automatic tuning of the handler timeout
+// from observed response times. The recommended timeout is the 99th
+// percentile of recent response times, estimated like the percentiles
+// of LatencyStats, multiplied by Factor and bounded by Min and Max. It never exceeds the timeout the handler was
+// originally configured with.
+//
+// Tuning is damped: the timeout moves towards the recommended value by
+// a fraction of the difference on every successful request and only
+// changes when the difference is significant, so that it doesn't
+// oscillate. A single slow response does not affect the 99th
+// percentile.
+//
+// Timeouts can only be applied to the handlers of goburrow/modbus
+// (TCP, RTU and ASCII).
+type AdaptiveTimeout struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// WithAdaptiveTimeout enables tuning of the handler timeout from
+// observed response times as configured by t. See EffectiveTimeout.
+func WithAdaptiveTimeout(t AdaptiveTimeout) Option {
+	return func(c *Config) {
+		c.AdaptiveTimeout = &t
+	}
+}
+
+const (
+	// number of response times after which older ones are forgotten;
+	// the percentile is taken over the last 1 to 2 times as many
+	timeoutWindow = 128
+	// share of the difference between the current and the recommended
+	// timeout applied at once
+	timeoutDamping = 0.25
+	// relative difference below which the timeout is left as is
+	timeoutHysteresis = 0.1
+)
+
+// timeoutTuner computes the effective timeout from histograms of recent
+// response times: recent collects the latest ones and replaces
+// previous once it holds timeoutWindow of them.
+type timeoutTuner struct {
+	mtx      sync.Mutex
+	recent   latencyHistogram
+	previous latencyHistogram
+	current  time.Duration
+}
+
+// observe records a response time and returns the effective timeout.
+// The outer bound is applied on top of the configured maximum if
+// positive.
+func (t *timeoutTuner) observe(cfg AdaptiveTimeout, outer, d time.Duration) time.Duration {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.recent.observe(d)
+	if t.recent.count >= timeoutWindow {
+		t.previous, t.recent = t.recent, latencyHistogram{}
+	}
+
+	max := cfg.Max
+	if outer > 0 && (max <= 0 || outer < max) {
+		max = outer
+	}
+	clamp := func(d time.Duration) time.Duration {
+		if d < cfg.Min {
+			d = cfg.Min
+		}
+		if max > 0 && d > max {
+			d = max
+		}
+		return d
+	}
+
+	window := t.previous
+	window.add(&t.recent)
+	p99 := window.percentile(0.99)
+	target := clamp(time.Duration(float64(p99) * cfg.Factor))
+
+	if t.current == 0 {
+		t.current = target
+		return t.current
+	}
+	diff := float64(target - t.current)
+	if math.Abs(diff) < timeoutHysteresis*float64(t.current) {
+		return t.current
+	}
+	next := t.current + time.Duration(diff*timeoutDamping)
+	if math.Abs(float64(target-next)) < timeoutHysteresis*float64(next) {
+		// close enough to settle
+		next = target
+	}
+	t.current = next
+	return t.current
+}
+
+func (t *timeoutTuner) effective() time.Duration {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.current
+}
+
+// handlerTimeout returns a pointer to the timeout of a goburrow handler,
+// or nil if the handler is not known.
+func handlerTimeout(h modbus.ClientHandler) *time.Duration {
+	switch h := h.(type) {
+	case *modbus.TCPClientHandler:
+		return &h.Timeout
+	case *modbus.RTUClientHandler:
+		return &h.Timeout
+	case *modbus.ASCIIClientHandler:
+		return &h.Timeout
+	}
+	return nil
+}
+
+// tuneTimeout updates the handler timeout after a successful request
+// that took d. The caller must hold the client mutex.
+func (c *Client) tuneTimeout(d time.Duration) {
+	if c.config.AdaptiveTimeout == nil {
+		return
+	}
+	timeout := handlerTimeout(c.ClientHandler)
+	if c.outerTimeout == 0 && timeout != nil {
+		c.outerTimeout = *timeout
+	}
+	effective := c.timeouts.observe(*c.config.AdaptiveTimeout, c.outerTimeout, d)
+	if timeout != nil {
+		*timeout = effective
+	}
+}
+
+// EffectiveTimeout returns the request timeout currently recommended by
+// AdaptiveTimeout, or zero if nothing was observed yet. It is reported
+// on its own rather than in Stats, which sums up batches, or in Config,
+// which never changes after NewClient.
+func (c *Client) EffectiveTimeout() time.Duration {
+	return c.timeouts.effective()
+}
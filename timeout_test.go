@@ -0,0 +1,64 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_timeoutTuner(t *testing.T) {
+	cfg := AdaptiveTimeout{Min: 100 * time.Millisecond, Max: 5 * time.Second, Factor: 3}
+	var tuner timeoutTuner
+	feed := func(n int, d time.Duration) []time.Duration {
+		res := make([]time.Duration, n)
+		for i := range res {
+			res[i] = tuner.observe(cfg, 0, d)
+		}
+		return res
+	}
+
+	steady := feed(timeoutWindow, 50*time.Millisecond)
+	assert.Equal(t, 150*time.Millisecond, steady[len(steady)-1])
+
+	// a transient spike doesn't affect the 99th percentile
+	spike := feed(1, 2*time.Second)
+	following := feed(50, 50*time.Millisecond)
+	assert.Equal(t, 150*time.Millisecond, spike[0])
+	assert.Equal(t, 150*time.Millisecond, following[len(following)-1])
+
+	// a step change moves the timeout monotonically to the new level
+	step := feed(3*timeoutWindow, 200*time.Millisecond)
+	for i := 1; i < len(step); i++ {
+		assert.GreaterOrEqual(t, step[i], step[i-1], "oscillation at sample %d", i)
+	}
+	assert.InEpsilon(t, float64(600*time.Millisecond), float64(step[len(step)-1]), timeoutHysteresis)
+
+	// back down after the device recovers, no lower than Min
+	recovery := feed(3*timeoutWindow, 10*time.Millisecond)
+	for i := 1; i < len(recovery); i++ {
+		assert.LessOrEqual(t, recovery[i], recovery[i-1], "oscillation at sample %d", i)
+	}
+	assert.Equal(t, cfg.Min, recovery[len(recovery)-1])
+}
+
+func Test_timeoutTuner_outerBound(t *testing.T) {
+	cfg := AdaptiveTimeout{Factor: 3}
+	var tuner timeoutTuner
+	assert.Equal(t, time.Second, tuner.observe(cfg, time.Second, 2*time.Second))
+}
+
+func TestClient_tuneTimeout(t *testing.T) {
+	handler := modbus.NewTCPClientHandler("localhost:502")
+	handler.Timeout = time.Second
+	client := NewClient(handler, WithAdaptiveTimeout(AdaptiveTimeout{Min: 10 * time.Millisecond, Factor: 2}))
+
+	client.tuneTimeout(100 * time.Millisecond)
+	assert.Equal(t, 200*time.Millisecond, handler.Timeout)
+	assert.Equal(t, 200*time.Millisecond, client.EffectiveTimeout())
+	for i := 0; i < 3*timeoutWindow; i++ {
+		client.tuneTimeout(5 * time.Second)
+	}
+	assert.Equal(t, time.Second, handler.Timeout, "handler timeout stays the outer bound")
+}
@@ -0,0 +1,61 @@
+package modbus
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// Tolerance makes differential optimization treat floating-point values
+// as unchanged if they differ from their value in oldData by at most
+// Epsilon, see BatchOptions.Tolerance.
+//
+// It only applies to ops whose Value is of a floating-point kind, such
+// as types.Float32CDAB, and of the same type as the value of oldData at
+// the op register. Other ops are compared by bytes. NaN values never
+// equal any value, so ops of NaN or replacing NaN are always written.
+type Tolerance struct {
+	// Epsilon is the largest difference of values considered equal.
+	Epsilon float64
+	// Relative makes Epsilon relative to the larger magnitude of the
+	// two values, so that 0.01 allows a difference of 1%.
+	Relative bool
+}
+
+// equal reports whether x and y are within the tolerance.
+func (t Tolerance) equal(x, y float64) bool {
+	d := math.Abs(x - y)
+	if t.Relative {
+		return d <= t.Epsilon*math.Max(math.Abs(x), math.Abs(y))
+	}
+	return d <= t.Epsilon
+}
+
+// tolerated reports whether op writes a floating-point value within t
+// of its value in oldData. ok is false if the tolerance doesn't apply
+// to op.
+func (t Tolerance) tolerated(op Write, oldData Registers) (equal, ok bool) {
+	x, ok := floatOf(op.Value())
+	if !ok {
+		return false, false
+	}
+	old, ok := oldData[op.Register()]
+	if !ok || reflect.TypeOf(old) != reflect.TypeOf(op.Value()) {
+		return false, false
+	}
+	y, _ := floatOf(old)
+	return t.equal(x, y), true
+}
+
+// floatOf returns v if it is of a floating-point kind.
+func floatOf(v types.Value) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	if kind := rv.Kind(); kind != reflect.Float32 && kind != reflect.Float64 {
+		return 0, false
+	}
+	return rv.Float(), true
+}
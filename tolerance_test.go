@@ -0,0 +1,68 @@
+package modbus_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchWriteWith_tolerance(t *testing.T) {
+	absolute := &modbus.Tolerance{Epsilon: 1e-5}
+	relative := &modbus.Tolerance{Epsilon: 0.01, Relative: true}
+	nan := types.Float32CDAB(math.NaN())
+	tests := []struct {
+		name      string
+		tolerance *modbus.Tolerance
+		old       types.Value
+		value     types.Value
+		written   bool
+	}{
+		{"within", absolute, types.Float32CDAB(25), types.Float32CDAB(24.999998), false},
+		{"outside", absolute, types.Float32CDAB(25), types.Float32CDAB(24.99), true},
+		{"strict", nil, types.Float32CDAB(25), types.Float32CDAB(24.999998), true},
+		{"float64", absolute, types.Float64(25), types.Float64(25.000001), false},
+		{"relative within", relative, types.Float32CDAB(1000), types.Float32CDAB(1009), false},
+		{"relative outside", relative, types.Float32CDAB(1000), types.Float32CDAB(1011), true},
+		{"NaN", absolute, types.Float32CDAB(25), nan, true},
+		{"replacing NaN", absolute, nan, types.Float32CDAB(25), true},
+		{"NaN replacing NaN", absolute, nan, nan, true},
+		{"other byte order", absolute, types.Float32(25), types.Float32CDAB(25), true},
+		{"integers", &modbus.Tolerance{Epsilon: 5}, types.Uint16(1), types.Uint16(2), true},
+		{"equal integers", &modbus.Tolerance{Epsilon: 5}, types.Uint16(1), types.Uint16(1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			client := modbus.NewClient(slave)
+
+			err := client.BatchWriteWith([]modbus.Write{testWrite{10, tt.value}}, modbus.Registers{10: tt.old},
+				modbus.BatchOptions{Tolerance: tt.tolerance})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.written, slave.writes() > 0)
+		})
+	}
+}
+
+func TestClient_BatchWriteWith_toleranceMixed(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	old := modbus.Registers{10: types.Float32CDAB(25), 20: types.Uint16(1), 30: types.Uint16(1)}
+	ops := []modbus.Write{
+		testWrite{10, types.Float32CDAB(24.999998)},
+		testWrite{20, types.Uint16(1)},
+		testWrite{30, types.Uint16(2)},
+	}
+
+	var stats modbus.BatchStats
+	assert.NoError(t, client.BatchWriteWith(ops, old, modbus.BatchOptions{Tolerance: &modbus.Tolerance{Epsilon: 1e-3}, Stats: &stats}))
+	assert.Equal(t, 2, stats.SkippedOps)
+	assert.Equal(t, []byte{0, 2}, slave.get(30, 1))
+	assert.Equal(t, []byte{0, 0, 0, 0}, slave.get(10, 2), "the tolerated op is skipped")
+
+	assert.NoError(t, client.BatchWriteWith(ops, old, modbus.BatchOptions{Stats: &stats}))
+	assert.Equal(t, 1, stats.SkippedOps, "the next batch is strict")
+	assert.Equal(t, types.Float32CDAB(24.999998).Bytes(), slave.get(10, 2))
+}
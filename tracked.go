@@ -0,0 +1,73 @@
+package modbus
+
+// sentWrite is an attempted write request of a batch.
+type sentWrite struct {
+	op writeOp
+	ok bool
+}
+
+// BatchWriteTracked is BatchWrite returning a snapshot of the registers
+// after the batch, to be passed as oldData to the next one. The
+// snapshot is oldData updated with the values of the ops written by the
+// batch, including on failure: ops skipped by differential optimization
+// keep their values, ops whose requests failed or were not sent are
+// left out, and so are values of oldData sharing registers with any of
+// them or with other writes, such as those of ApplyRules.
+func (c *Client) BatchWriteTracked(ops []Write, oldData Registers) (_ Registers, err error) {
+	defer recoverPanic(&err)
+
+	var sent []sentWrite
+	err = c.batchWriteWith(ops, oldData, BatchOptions{}, &sent)
+	return snapshot(ops, oldData, sent), err
+}
+
+// snapshot returns oldData updated with the ops written by sent.
+func snapshot(ops []Write, oldData Registers, sent []sentWrite) Registers {
+	written := completedRegisters(sent)
+	res := make(Registers, len(oldData)+len(ops))
+	for register, value := range oldData {
+		if !touched(written, register, len(value.Bytes())/2) {
+			res[register] = value
+		}
+	}
+	for _, op := range ops {
+		if value := op.Value(); applied(written, op.Register(), len(value.Bytes())/2) {
+			res[op.Register()] = value
+		}
+	}
+	return res
+}
+
+// completedRegisters maps the registers written by sent to whether the
+// last request writing them completed.
+func completedRegisters(sent []sentWrite) map[uint16]bool {
+	written := make(map[uint16]bool)
+	for _, s := range sent {
+		for r := int(s.op.register); r < int(s.op.register)+int(s.op.quantity); r++ {
+			written[uint16(r)] = s.ok
+		}
+	}
+	return written
+}
+
+// touched reports whether a request of written covers any of quantity
+// registers from register.
+func touched(written map[uint16]bool, register uint16, quantity int) bool {
+	for r := int(register); r < int(register)+quantity; r++ {
+		if _, ok := written[uint16(r)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applied reports whether the last requests of written covering
+// quantity registers from register all completed.
+func applied(written map[uint16]bool, register uint16, quantity int) bool {
+	for r := int(register); r < int(register)+quantity; r++ {
+		if !written[uint16(r)] {
+			return false
+		}
+	}
+	return quantity > 0
+}
@@ -0,0 +1,77 @@
+package modbus_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchWriteTracked(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly []uint16
+		ops      []modbus.Write
+		oldData  modbus.Registers
+		want     modbus.Registers
+		wantErr  bool
+	}{
+		{"written and skipped", nil, []modbus.Write{
+			testWrite{10, types.Uint16(1)},
+			testWrite{11, types.Uint32(2)},
+		}, modbus.Registers{10: types.Uint16(1), 20: types.Uint16(5)}, modbus.Registers{
+			10: types.Uint16(1),
+			11: types.Uint32(2),
+			20: types.Uint16(5),
+		}, false},
+		{"no old data", nil, []modbus.Write{testWrite{10, types.Uint16(1)}}, nil, modbus.Registers{
+			10: types.Uint16(1),
+		}, false},
+		{"overwritten multi-register value", nil, []modbus.Write{
+			testWrite{11, types.Uint16(3)},
+		}, modbus.Registers{10: types.Uint32(0), 12: types.Uint16(4)}, modbus.Registers{
+			11: types.Uint16(3),
+			12: types.Uint16(4),
+		}, false},
+		{"failed request", []uint16{20}, []modbus.Write{
+			testWrite{10, types.Uint16(1)},
+			testWrite{20, types.Uint16(2)},
+			testWrite{30, types.Uint16(3)},
+		}, modbus.Registers{20: types.Uint16(7), 30: types.Uint16(8), 40: types.Uint16(9)}, modbus.Registers{
+			10: types.Uint16(1),
+			30: types.Uint16(8),
+			40: types.Uint16(9),
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			for _, r := range tt.readOnly {
+				slave.readOnly[r] = true
+			}
+			client := modbus.NewClient(slave)
+
+			snapshot, err := client.BatchWriteTracked(tt.ops, tt.oldData)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, snapshot)
+		})
+	}
+}
+
+func TestClient_BatchWriteTracked_next(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	ops := []modbus.Write{testWrite{10, types.Uint16(1)}, testWrite{20, types.Uint32(2)}}
+
+	snapshot, err := client.BatchWriteTracked(ops, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, slave.writes())
+	_, err = client.BatchWriteTracked(ops, snapshot)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, slave.writes(), "nothing left to write")
+}
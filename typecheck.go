@@ -0,0 +1,82 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/tdemin/opmodbus/types"
+)
+
+// ErrTypeMismatch is returned by BatchWrite with type checks enabled
+// when a write op does not line up with a value already known for its
+// registers.
+var ErrTypeMismatch = errors.New("write does not match known register type")
+
+// ErrInvalidValue is returned by BatchWrite with type checks enabled
+// for nil values in oldData, whose type can't be checked against.
+var ErrInvalidValue = errors.New("invalid register value")
+
+// WithTypeChecks enables type checks in BatchWrite. It verifies each
+// write op against the values known from oldData and fails with
+// ErrTypeMismatch if the op would only partially overwrite a known
+// value or replace it with a value of a different size or type.
+// Registers missing from oldData are not checked.
+func WithTypeChecks() Option {
+	return func(c *Config) {
+		c.CheckTypes = true
+	}
+}
+
+// checkTypes verifies that every op either touches only unknown
+// registers or exactly replaces a known value of the same size and
+// type. Known values are taken from oldData.
+func checkTypes(ops []Write, known Registers) error {
+	// maps every known register to the start register of the value it
+	// belongs to
+	owners := make(map[uint16]uint16)
+	for register, value := range known {
+		size, err := valueSize(value)
+		if err != nil {
+			return fmt.Errorf("%w: register %d", err, register)
+		}
+		for r := int(register); r < int(register)+int(size) && r < maxUint16; r++ {
+			owners[uint16(r)] = register
+		}
+	}
+
+	for _, op := range ops {
+		value := op.Value()
+		start, size := op.Register(), uint16(len(value.Bytes())/2)
+		for r := int(start); r < int(start)+int(size); r++ {
+			owner, ok := owners[uint16(r)]
+			if !ok {
+				continue
+			}
+			expected := known[owner]
+			if owner != start {
+				return fmt.Errorf("%w: register %d: expected %T at %d, got %T at %d",
+					ErrTypeMismatch, r, expected, owner, value, start)
+			}
+			// known values are not nil, see above
+			if expectedSize, _ := valueSize(expected); expectedSize != size || reflect.TypeOf(expected) != reflect.TypeOf(value) {
+				return fmt.Errorf("%w: register %d: expected %T, got %T",
+					ErrTypeMismatch, start, expected, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// valueSize returns value size in Modbus registers, preferring the
+// size declared by Type if value implements it.
+func valueSize(v types.Value) (uint16, error) {
+	if v == nil {
+		return 0, fmt.Errorf("%w: nil value", ErrInvalidValue)
+	}
+	if t, ok := v.(types.Type); ok {
+		return t.Size(), nil
+	}
+	return uint16(len(v.Bytes()) / 2), nil
+}
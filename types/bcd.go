@@ -0,0 +1,124 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBCDRange is returned by Validate of BCD types if the number has
+// more digits than fit the registers.
+var ErrBCDRange = errors.New("number out of BCD range")
+
+// BCD16 is a decimal number from 0 to 9999 stored in a single register
+// as packed BCD, one digit per nibble, most significant digit first.
+// Bytes() clamps values out of range; use Validate to reject them
+// instead.
+type BCD16 uint16
+
+func (v BCD16) String() string {
+	return fmt.Sprint(uint16(v))
+}
+
+func (v BCD16) Bytes() []byte {
+	return encodeBCD(uint64(v), 2)
+}
+
+func (v BCD16) Size() uint16 {
+	return 1
+}
+
+func (BCD16) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		v, err := decodeBCD(b, 2)
+		if err != nil {
+			return nil, err
+		}
+		return BCD16(v), nil
+	}
+}
+
+// Validate returns ErrBCDRange if v exceeds 9999.
+func (v BCD16) Validate() error {
+	return validateBCD(uint64(v), 2)
+}
+
+// BCD16Type is provided for use as Type.
+const BCD16Type = BCD16(0)
+
+// BCD32 is a decimal number from 0 to 99999999 stored in two registers
+// as packed BCD, most significant digit first. Bytes() clamps values
+// out of range; use Validate to reject them instead.
+type BCD32 uint32
+
+func (v BCD32) String() string {
+	return fmt.Sprint(uint32(v))
+}
+
+func (v BCD32) Bytes() []byte {
+	return encodeBCD(uint64(v), 4)
+}
+
+func (v BCD32) Size() uint16 {
+	return 2
+}
+
+func (BCD32) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		v, err := decodeBCD(b, 4)
+		if err != nil {
+			return nil, err
+		}
+		return BCD32(v), nil
+	}
+}
+
+// Validate returns ErrBCDRange if v exceeds 99999999.
+func (v BCD32) Validate() error {
+	return validateBCD(uint64(v), 4)
+}
+
+// BCD32Type is provided for use as Type.
+const BCD32Type = BCD32(0)
+
+// maxBCD returns the largest number fitting size bytes of BCD.
+func maxBCD(size int) uint64 {
+	max := uint64(1)
+	for i := 0; i < size*2; i++ {
+		max *= 10
+	}
+	return max - 1
+}
+
+func encodeBCD(v uint64, size int) []byte {
+	if max := maxBCD(size); v > max {
+		v = max
+	}
+	r := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		r[i] = byte(v%10) | byte(v/10%10)<<4
+		v /= 100
+	}
+	return r
+}
+
+func decodeBCD(b []byte, size int) (uint64, error) {
+	if l := len(b); l != size {
+		return 0, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+	}
+	var v uint64
+	for i, c := range b {
+		hi, lo := c>>4, c&0x0F
+		if hi > 9 || lo > 9 {
+			return 0, fmt.Errorf("%w: invalid BCD byte %#02x at %d", ErrInvalidInput, c, i)
+		}
+		v = v*100 + uint64(hi)*10 + uint64(lo)
+	}
+	return v, nil
+}
+
+func validateBCD(v uint64, size int) error {
+	if max := maxBCD(size); v > max {
+		return fmt.Errorf("%w: %d exceeds %d", ErrBCDRange, v, max)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBCD(t *testing.T) {
+	tests := []struct {
+		value Value
+		t     Type
+		bytes []byte
+	}{
+		{BCD16(0), BCD16Type, []byte{0x00, 0x00}},
+		{BCD16(1234), BCD16Type, []byte{0x12, 0x34}},
+		{BCD16(9999), BCD16Type, []byte{0x99, 0x99}},
+		{BCD32(0), BCD32Type, []byte{0x00, 0x00, 0x00, 0x00}},
+		{BCD32(12345678), BCD32Type, []byte{0x12, 0x34, 0x56, 0x78}},
+		{BCD32(907), BCD32Type, []byte{0x00, 0x00, 0x09, 0x07}},
+		{BCD32(99999999), BCD32Type, []byte{0x99, 0x99, 0x99, 0x99}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bytes, tt.value.Bytes(), "%v", tt.value)
+		v, err := tt.t.Converter()(tt.bytes)
+		assert.NoError(t, err, "%v", tt.value)
+		assert.Equal(t, tt.value, v)
+	}
+}
+
+func TestBCD_Converter_invalidNibble(t *testing.T) {
+	tests := []struct {
+		t     Type
+		input []byte
+	}{
+		{BCD16Type, []byte{0x1A, 0x00}},
+		{BCD16Type, []byte{0x00, 0xF0}},
+		{BCD16Type, []byte{0xFF, 0xFF}},
+		{BCD32Type, []byte{0x00, 0x00, 0x00, 0x0B}},
+		{BCD32Type, []byte{0xC0, 0x00, 0x00, 0x00}},
+	}
+	for _, tt := range tests {
+		v, err := tt.t.Converter()(tt.input)
+		assert.ErrorIs(t, err, ErrInvalidInput, "% x", tt.input)
+		assert.Nil(t, v)
+	}
+}
+
+func TestBCD_Converter_invalidSize(t *testing.T) {
+	for _, input := range [][]byte{nil, {0x12}, {0x12, 0x34, 0x56}} {
+		_, err := BCD16Type.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		_, err = BCD32Type.Converter()(append(input, 0, 0))
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
+
+func TestBCD_range(t *testing.T) {
+	assert.NoError(t, BCD16(9999).Validate())
+	assert.ErrorIs(t, BCD16(10000).Validate(), ErrBCDRange)
+	assert.Equal(t, []byte{0x99, 0x99}, BCD16(10000).Bytes(), "clamped")
+	assert.NoError(t, BCD32(99999999).Validate())
+	assert.ErrorIs(t, BCD32(100000000).Validate(), ErrBCDRange)
+	assert.Equal(t, []byte{0x99, 0x99, 0x99, 0x99}, BCD32(4000000000).Bytes(), "clamped")
+}
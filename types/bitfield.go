@@ -0,0 +1,70 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Bitfield16 is a set of 16 flags packed into a single Modbus register.
+// Bit 0 is the least significant bit of the register, i.e. the lowest
+// bit of its second byte on the wire; bit 15 is the most significant
+// one.
+type Bitfield16 uint16
+
+// NewBitfield16 builds a Bitfield16 with the given bits set. Indices
+// outside of 0-15 are ignored.
+func NewBitfield16(bits map[int]bool) Bitfield16 {
+	var b Bitfield16
+	for n, v := range bits {
+		b.SetBit(n, v)
+	}
+	return b
+}
+
+// Bit reports whether bit n is set. It returns false for indices
+// outside of 0-15.
+func (b Bitfield16) Bit(n int) bool {
+	if n < 0 || n > 15 {
+		return false
+	}
+	return b&(1<<uint(n)) != 0
+}
+
+// SetBit sets or clears bit n. Indices outside of 0-15 are ignored.
+func (b *Bitfield16) SetBit(n int, v bool) {
+	if n < 0 || n > 15 {
+		return
+	}
+	if v {
+		*b |= 1 << uint(n)
+	} else {
+		*b &^= 1 << uint(n)
+	}
+}
+
+func (b Bitfield16) String() string {
+	return fmt.Sprintf("0b%016b", uint16(b))
+}
+
+func (b Bitfield16) Bytes() []byte {
+	r := make([]byte, 2)
+	binary.BigEndian.PutUint16(r, uint16(b))
+	return r
+}
+
+func (b Bitfield16) Size() uint16 {
+	return 1
+}
+
+func (Bitfield16) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Bitfield16(binary.BigEndian.Uint16(b)), nil
+	}
+}
+
+// Bitfield16Type is provided for use as Type.
+const Bitfield16Type = Bitfield16(0)
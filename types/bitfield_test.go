@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitfield16_ordering(t *testing.T) {
+	tests := []struct {
+		name  string
+		bit   int
+		bytes []byte
+	}{
+		{"LSB", 0, []byte{0x00, 0x01}},
+		{"bit 7", 7, []byte{0x00, 0x80}},
+		{"bit 8", 8, []byte{0x01, 0x00}},
+		{"MSB", 15, []byte{0x80, 0x00}},
+	}
+	for _, tt := range tests {
+		b := NewBitfield16(map[int]bool{tt.bit: true})
+		assert.Equal(t, tt.bytes, b.Bytes(), tt.name)
+
+		v, err := Bitfield16Type.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		decoded := v.(Bitfield16)
+		for n := 0; n < 16; n++ {
+			assert.Equal(t, n == tt.bit, decoded.Bit(n), "%s: bit %d", tt.name, n)
+		}
+	}
+}
+
+func TestBitfield16_SetBit(t *testing.T) {
+	b := NewBitfield16(map[int]bool{0: true, 3: true, 4: false, 16: true, -1: true})
+	assert.Equal(t, Bitfield16(0x0009), b)
+
+	b.SetBit(3, false)
+	b.SetBit(15, true)
+	b.SetBit(20, true)
+	assert.Equal(t, Bitfield16(0x8001), b)
+	assert.False(t, b.Bit(16))
+	assert.False(t, b.Bit(-1))
+}
+
+func TestBitfield16_Converter_invalidInput(t *testing.T) {
+	for _, input := range [][]byte{nil, {1}, {1, 2, 3}} {
+		_, err := Bitfield16Type.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
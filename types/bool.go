@@ -0,0 +1,72 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Bool is a flag stored in a single Modbus register as 0 or 1. Any
+// nonzero register value is decoded as true.
+type Bool bool
+
+func (v Bool) String() string {
+	return fmt.Sprint(bool(v))
+}
+
+func (v Bool) Bytes() []byte {
+	if v {
+		return []byte{0, 1}
+	}
+	return []byte{0, 0}
+}
+
+func (v Bool) Size() uint16 {
+	return 1
+}
+
+func (Bool) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Bool(binary.BigEndian.Uint16(b) != 0), nil
+	}
+}
+
+// BoolType is provided for use as Type.
+const BoolType = Bool(false)
+
+// BoolStrict is a Bool whose Converter only accepts register values 0
+// and 1. It decodes to Bool values.
+type BoolStrict bool
+
+func (v BoolStrict) String() string {
+	return fmt.Sprint(bool(v))
+}
+
+func (v BoolStrict) Bytes() []byte {
+	return Bool(v).Bytes()
+}
+
+func (v BoolStrict) Size() uint16 {
+	return 1
+}
+
+func (BoolStrict) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		switch word := binary.BigEndian.Uint16(b); word {
+		case 0, 1:
+			return Bool(word == 1), nil
+		default:
+			return nil, fmt.Errorf("%w: boolean value %d", ErrInvalidInput, word)
+		}
+	}
+}
+
+// BoolStrictType is provided for use as Type.
+const BoolStrictType = BoolStrict(false)
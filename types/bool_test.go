@@ -0,0 +1,46 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBool(t *testing.T) {
+	assert.Equal(t, []byte{0, 1}, Bool(true).Bytes())
+	assert.Equal(t, []byte{0, 0}, Bool(false).Bytes())
+	assert.Equal(t, []byte{0, 1}, BoolStrict(true).Bytes())
+
+	tests := []struct {
+		input  []byte
+		want   Value
+		strict Value
+	}{
+		{[]byte{0, 0}, Bool(false), Bool(false)},
+		{[]byte{0, 1}, Bool(true), Bool(true)},
+		{[]byte{0, 2}, Bool(true), nil},
+		{[]byte{0x80, 0}, Bool(true), nil},
+	}
+	for _, tt := range tests {
+		v, err := BoolType.Converter()(tt.input)
+		assert.NoError(t, err, "%v", tt.input)
+		assert.Equal(t, tt.want, v, "%v", tt.input)
+
+		v, err = BoolStrictType.Converter()(tt.input)
+		if tt.strict == nil {
+			assert.ErrorIs(t, err, ErrInvalidInput, "%v", tt.input)
+		} else {
+			assert.NoError(t, err, "%v", tt.input)
+		}
+		assert.Equal(t, tt.strict, v, "%v", tt.input)
+	}
+}
+
+func TestBool_Converter_invalidInput(t *testing.T) {
+	for _, input := range [][]byte{nil, {1}, {0, 0, 0}} {
+		_, err := BoolType.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		_, err = BoolStrictType.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
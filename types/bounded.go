@@ -0,0 +1,110 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ErrOutOfBounds is returned by Validate of Bounded if the value is
+// outside of its bounds.
+var ErrOutOfBounds = errors.New("value out of bounds")
+
+// Bounded is a value of Inner constrained to Min..Max inclusive, e.g. a
+// setpoint a device only accepts in a certain range. Its encoding is the
+// one of Inner; Validate rejects values out of bounds, so that writes of
+// them fail before any request is sent.
+//
+// Inner must be a numeric type: an integer or float type of this
+// package, or a type whose values have a Float64() method, such as
+// Scaled. Bounds are compared as float64.
+//
+// Bounded is both a Type, as returned by NewBounded, and the Value
+// produced by its Converter, so that read values can be written back
+// with the same bounds.
+type Bounded struct {
+	Inner Type
+	Min   float64
+	Max   float64
+	// Value is the value of Inner, nil for the zero value.
+	Value Value
+}
+
+// NewBounded returns a Bounded type of t values from min to max.
+func NewBounded(t Type, min, max float64) Bounded {
+	return Bounded{Inner: t, Min: min, Max: max}
+}
+
+// With returns a copy of b holding v, a value of Inner, for use in
+// writes.
+func (b Bounded) With(v Value) Bounded {
+	b.Value = v
+	return b
+}
+
+func (b Bounded) String() string {
+	return fmt.Sprint(b.Value)
+}
+
+func (b Bounded) Size() uint16 {
+	return b.Inner.Size()
+}
+
+func (b Bounded) Bytes() []byte {
+	if b.Value == nil {
+		return make([]byte, int(b.Size())*2)
+	}
+	return b.Value.Bytes()
+}
+
+// Validate returns ErrOutOfBounds if the value is out of bounds, or
+// ErrInvalidInput if it is not numeric.
+func (b Bounded) Validate() error {
+	if v, ok := b.Value.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	x, ok := Float64Of(b.Value)
+	if !ok {
+		return fmt.Errorf("%w: %T is not numeric", ErrInvalidInput, b.Value)
+	}
+	if x < b.Min || x > b.Max || math.IsNaN(x) {
+		return fmt.Errorf("%w: %v out of %v..%v", ErrOutOfBounds, b.Value, b.Min, b.Max)
+	}
+	return nil
+}
+
+func (b Bounded) Converter() Converter {
+	convert := b.Inner.Converter()
+	return func(data []byte) (Value, error) {
+		v, err := convert(data)
+		if err != nil {
+			return nil, err
+		}
+		return b.With(v), nil
+	}
+}
+
+// Float64Of returns the numeric value of v: the result of its Float64
+// method if it has one, or v converted to float64 if it is of an
+// integer or floating-point kind.
+func Float64Of(v Value) (float64, bool) {
+	if f, ok := v.(interface{ Float64() float64 }); ok {
+		return f.Float64(), true
+	}
+	if v == nil {
+		return 0, true
+	}
+	rv := reflect.ValueOf(v)
+	switch kind := rv.Kind(); {
+	case isSigned(kind):
+		return float64(rv.Int()), true
+	case isUnsigned(kind):
+		return float64(rv.Uint()), true
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
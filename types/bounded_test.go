@@ -0,0 +1,51 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBounded(t *testing.T) {
+	setpoint := NewBounded(Uint16Type, 0, 500)
+	assert.Equal(t, uint16(1), setpoint.Size())
+	assert.Equal(t, []byte{0, 0}, setpoint.Bytes())
+
+	v, err := setpoint.Converter()([]byte{0x02, 0x58})
+	assert.NoError(t, err)
+	assert.Equal(t, Uint16(600), v.(Bounded).Value)
+	assert.Equal(t, []byte{0x02, 0x58}, v.Bytes(), "encoding is unchanged")
+	assert.ErrorIs(t, v.(Bounded).Validate(), ErrOutOfBounds, "read values are not rejected until written")
+
+	_, err = setpoint.Converter()([]byte{1})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestBounded_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Bounded
+		err   error
+	}{
+		{"min", NewBounded(Uint16Type, 10, 500).With(Uint16(10)), nil},
+		{"max", NewBounded(Uint16Type, 10, 500).With(Uint16(500)), nil},
+		{"below", NewBounded(Uint16Type, 10, 500).With(Uint16(9)), ErrOutOfBounds},
+		{"above", NewBounded(Uint16Type, 10, 500).With(Uint16(501)), ErrOutOfBounds},
+		{"zero value", NewBounded(Uint16Type, 10, 500), ErrOutOfBounds},
+		{"negative", NewBounded(Int32Type, -40, 40).With(Int32(-41)), ErrOutOfBounds},
+		{"float", NewBounded(Float32Type, 0, 1).With(Float32(0.5)), nil},
+		{"NaN", NewBounded(Float32Type, 0, 1).With(Float32(math.NaN())), ErrOutOfBounds},
+		{"scaled", NewBounded(NewScaled(Int16Type, 0.1, 0), 5, 30).With(NewScaled(Int16Type, 0.1, 0).With(30.5)), ErrOutOfBounds},
+		{"scaled range", NewBounded(NewScaled(Int16Type, 0.1, 0), 0, 1e6).With(NewScaled(Int16Type, 0.1, 0).With(1e5)), ErrScaledRange},
+		{"not numeric", NewBounded(Bitfield16Type, 0, 1).With(NewRaw(1)), ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		err := tt.value.Validate()
+		if tt.err == nil {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.ErrorIs(t, err, tt.err, tt.name)
+		}
+	}
+}
@@ -23,6 +23,13 @@ type Value interface {
 	Bytes() []byte
 }
 
+// Validator is implemented by values whose encoding only covers a
+// part of their range. Writes of values whose Validate returns an
+// error are rejected by the client instead of being transmitted clamped.
+type Validator interface {
+	Validate() error
+}
+
 // Converter is a function that converts a byte representation into
 // Value. If a byte representation is invalid for this data type, an
 // error of type ErrInvalidInput is returned, and Value will be nil.
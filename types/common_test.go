@@ -0,0 +1,35 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue_String(t *testing.T) {
+	tests := []struct {
+		v    Value
+		want string
+	}{
+		{Uint16(300), "300"},
+		{Int16(-3), "-3"},
+		{Uint32CDAB(70000), "70000"},
+		{Int64(-1), "-1"},
+		{Float32(1.5), "1.5"},
+		{Float64Swapped(0.1), "0.1"},
+		{Bool(true), "true"},
+		{BCD16(1234), "1234"},
+		{Bitfield16(0x8001), "0b1000000000000001"},
+		{DurationMinutes(90 * time.Minute), "1h30m0s"},
+		{NewUint8Pair(1, 255), "1:255"},
+		{NewRaw(2).With([]byte{0xDE, 0xAD, 0xBE, 0xEF}), "0xDEADBEEF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Implements(t, (*fmt.Stringer)(nil), tt.v)
+			assert.Equal(t, tt.want, fmt.Sprint(tt.v))
+		})
+	}
+}
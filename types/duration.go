@@ -0,0 +1,335 @@
+package types
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrDurationRange is returned by Validate of duration types if the
+// duration is negative or doesn't fit the registers.
+var ErrDurationRange = errors.New("duration out of range")
+
+// DurationSeconds is a time interval stored in a single register as an unsigned
+// number of seconds. Bytes() rounds to the nearest unit and clamps
+// values out of range; use Validate to reject them instead.
+type DurationSeconds time.Duration
+
+func (d DurationSeconds) String() string {
+	return time.Duration(d).String()
+}
+
+func (d DurationSeconds) Bytes() []byte {
+	return encodeDuration(time.Duration(d), time.Second, 1)
+}
+
+func (d DurationSeconds) Size() uint16 {
+	return 1
+}
+
+func (DurationSeconds) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		d, err := decodeDuration(b, time.Second, 1)
+		if err != nil {
+			return nil, err
+		}
+		return DurationSeconds(d), nil
+	}
+}
+
+// Validate returns ErrDurationRange if d is negative or too long.
+func (d DurationSeconds) Validate() error {
+	return validateDuration(time.Duration(d), time.Second, 1)
+}
+
+// Duration returns d as time.Duration.
+func (d DurationSeconds) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// DurationSecondsType is provided for use as Type.
+const DurationSecondsType = DurationSeconds(0)
+
+// DurationDeciseconds is a time interval stored in a single register as an unsigned
+// number of tenths of a second. Bytes() rounds to the nearest unit and clamps
+// values out of range; use Validate to reject them instead.
+type DurationDeciseconds time.Duration
+
+func (d DurationDeciseconds) String() string {
+	return time.Duration(d).String()
+}
+
+func (d DurationDeciseconds) Bytes() []byte {
+	return encodeDuration(time.Duration(d), 100*time.Millisecond, 1)
+}
+
+func (d DurationDeciseconds) Size() uint16 {
+	return 1
+}
+
+func (DurationDeciseconds) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		d, err := decodeDuration(b, 100*time.Millisecond, 1)
+		if err != nil {
+			return nil, err
+		}
+		return DurationDeciseconds(d), nil
+	}
+}
+
+// Validate returns ErrDurationRange if d is negative or too long.
+func (d DurationDeciseconds) Validate() error {
+	return validateDuration(time.Duration(d), 100*time.Millisecond, 1)
+}
+
+// Duration returns d as time.Duration.
+func (d DurationDeciseconds) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// DurationDecisecondsType is provided for use as Type.
+const DurationDecisecondsType = DurationDeciseconds(0)
+
+// DurationMinutes is a time interval stored in a single register as an unsigned
+// number of minutes. Bytes() rounds to the nearest unit and clamps
+// values out of range; use Validate to reject them instead.
+type DurationMinutes time.Duration
+
+func (d DurationMinutes) String() string {
+	return time.Duration(d).String()
+}
+
+func (d DurationMinutes) Bytes() []byte {
+	return encodeDuration(time.Duration(d), time.Minute, 1)
+}
+
+func (d DurationMinutes) Size() uint16 {
+	return 1
+}
+
+func (DurationMinutes) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		d, err := decodeDuration(b, time.Minute, 1)
+		if err != nil {
+			return nil, err
+		}
+		return DurationMinutes(d), nil
+	}
+}
+
+// Validate returns ErrDurationRange if d is negative or too long.
+func (d DurationMinutes) Validate() error {
+	return validateDuration(time.Duration(d), time.Minute, 1)
+}
+
+// Duration returns d as time.Duration.
+func (d DurationMinutes) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// DurationMinutesType is provided for use as Type.
+const DurationMinutesType = DurationMinutes(0)
+
+// DurationSeconds32 is a time interval stored in two registers (ABCD) as an unsigned
+// number of seconds. Bytes() rounds to the nearest unit and clamps
+// values out of range; use Validate to reject them instead.
+type DurationSeconds32 time.Duration
+
+func (d DurationSeconds32) String() string {
+	return time.Duration(d).String()
+}
+
+func (d DurationSeconds32) Bytes() []byte {
+	return encodeDuration(time.Duration(d), time.Second, 2)
+}
+
+func (d DurationSeconds32) Size() uint16 {
+	return 2
+}
+
+func (DurationSeconds32) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		d, err := decodeDuration(b, time.Second, 2)
+		if err != nil {
+			return nil, err
+		}
+		return DurationSeconds32(d), nil
+	}
+}
+
+// Validate returns ErrDurationRange if d is negative or too long.
+func (d DurationSeconds32) Validate() error {
+	return validateDuration(time.Duration(d), time.Second, 2)
+}
+
+// Duration returns d as time.Duration.
+func (d DurationSeconds32) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// DurationSeconds32Type is provided for use as Type.
+const DurationSeconds32Type = DurationSeconds32(0)
+
+// DurationDeciseconds32 is a time interval stored in two registers (ABCD) as an unsigned
+// number of tenths of a second. Bytes() rounds to the nearest unit and clamps
+// values out of range; use Validate to reject them instead.
+type DurationDeciseconds32 time.Duration
+
+func (d DurationDeciseconds32) String() string {
+	return time.Duration(d).String()
+}
+
+func (d DurationDeciseconds32) Bytes() []byte {
+	return encodeDuration(time.Duration(d), 100*time.Millisecond, 2)
+}
+
+func (d DurationDeciseconds32) Size() uint16 {
+	return 2
+}
+
+func (DurationDeciseconds32) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		d, err := decodeDuration(b, 100*time.Millisecond, 2)
+		if err != nil {
+			return nil, err
+		}
+		return DurationDeciseconds32(d), nil
+	}
+}
+
+// Validate returns ErrDurationRange if d is negative or too long.
+func (d DurationDeciseconds32) Validate() error {
+	return validateDuration(time.Duration(d), 100*time.Millisecond, 2)
+}
+
+// Duration returns d as time.Duration.
+func (d DurationDeciseconds32) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// DurationDeciseconds32Type is provided for use as Type.
+const DurationDeciseconds32Type = DurationDeciseconds32(0)
+
+// DurationMinutes32 is a time interval stored in two registers (ABCD) as an unsigned
+// number of minutes. Bytes() rounds to the nearest unit and clamps
+// values out of range; use Validate to reject them instead.
+type DurationMinutes32 time.Duration
+
+func (d DurationMinutes32) String() string {
+	return time.Duration(d).String()
+}
+
+func (d DurationMinutes32) Bytes() []byte {
+	return encodeDuration(time.Duration(d), time.Minute, 2)
+}
+
+func (d DurationMinutes32) Size() uint16 {
+	return 2
+}
+
+func (DurationMinutes32) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		d, err := decodeDuration(b, time.Minute, 2)
+		if err != nil {
+			return nil, err
+		}
+		return DurationMinutes32(d), nil
+	}
+}
+
+// Validate returns ErrDurationRange if d is negative or too long.
+func (d DurationMinutes32) Validate() error {
+	return validateDuration(time.Duration(d), time.Minute, 2)
+}
+
+// Duration returns d as time.Duration.
+func (d DurationMinutes32) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// DurationMinutes32Type is provided for use as Type.
+const DurationMinutes32Type = DurationMinutes32(0)
+
+// DurationType returns the duration Type for a unit as written in
+// register maps: "s", "0.1s" or "min". size is the number of registers,
+// 1 or 2.
+func DurationType(unit string, size uint16) (Type, error) {
+	types := map[string][2]Type{
+		"s":    {DurationSecondsType, DurationSeconds32Type},
+		"0.1s": {DurationDecisecondsType, DurationDeciseconds32Type},
+		"min":  {DurationMinutesType, DurationMinutes32Type},
+	}
+	t, ok := types[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown duration unit %q", unit)
+	}
+	if size != 1 && size != 2 {
+		return nil, fmt.Errorf("unsupported duration size %d", size)
+	}
+	return t[size-1], nil
+}
+
+// maxDuration returns the maximum number of units size registers hold.
+func maxDuration(size uint16) uint64 {
+	if size == 1 {
+		return math.MaxUint16
+	}
+	return math.MaxUint32
+}
+
+// durationUnits returns non-negative d in units, rounded to the nearest
+// one.
+func durationUnits(d, unit time.Duration) uint64 {
+	n := uint64(d / unit)
+	if d%unit >= unit/2 {
+		n++
+	}
+	return n
+}
+
+func validateDuration(d, unit time.Duration, size uint16) error {
+	if d < 0 {
+		return fmt.Errorf("%w: %v is negative", ErrDurationRange, d)
+	}
+	if n := durationUnits(d, unit); n > maxDuration(size) {
+		return fmt.Errorf("%w: %v exceeds %d units of %v", ErrDurationRange, d, maxDuration(size), unit)
+	}
+	return nil
+}
+
+func encodeDuration(d, unit time.Duration, size uint16) []byte {
+	var n uint64
+	if d > 0 {
+		n = durationUnits(d, unit)
+	}
+	if max := maxDuration(size); n > max {
+		n = max
+	}
+
+	r := make([]byte, size*2)
+	if size == 1 {
+		binary.BigEndian.PutUint16(r, uint16(n))
+	} else {
+		binary.BigEndian.PutUint32(r, uint32(n))
+	}
+	return r
+}
+
+func decodeDuration(b []byte, unit time.Duration, size uint16) (time.Duration, error) {
+	if l := len(b); l != int(size)*2 {
+		return 0, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+	}
+
+	var n uint64
+	if size == 1 {
+		n = uint64(binary.BigEndian.Uint16(b))
+	} else {
+		n = uint64(binary.BigEndian.Uint32(b))
+	}
+	if n > uint64(math.MaxInt64/int64(unit)) {
+		return 0, fmt.Errorf("%w: %d units of %v overflow time.Duration", ErrInvalidInput, n, unit)
+	}
+	return time.Duration(n) * unit, nil
+}
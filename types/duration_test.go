@@ -0,0 +1,80 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuration_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		t    Type
+		unit time.Duration
+		max  uint64
+	}{
+		{"seconds", DurationSecondsType, time.Second, math.MaxUint16},
+		{"deciseconds", DurationDecisecondsType, 100 * time.Millisecond, math.MaxUint16},
+		{"minutes", DurationMinutesType, time.Minute, math.MaxUint16},
+		{"seconds 32", DurationSeconds32Type, time.Second, math.MaxUint32},
+		{"deciseconds 32", DurationDeciseconds32Type, 100 * time.Millisecond, math.MaxUint32},
+	}
+	for _, tt := range tests {
+		for _, n := range []uint64{0, tt.max / 2, tt.max} {
+			d := time.Duration(n) * tt.unit
+			b := encodeDuration(d, tt.unit, tt.t.Size())
+			assert.Len(t, b, int(tt.t.Size())*2, tt.name)
+			v, err := tt.t.Converter()(b)
+			assert.NoError(t, err, tt.name)
+			if assert.Implements(t, (*interface{ Duration() time.Duration })(nil), v, tt.name) {
+				assert.Equal(t, d, v.(interface{ Duration() time.Duration }).Duration(), tt.name)
+			}
+			assert.Equal(t, b, v.Bytes(), tt.name)
+		}
+	}
+}
+
+func TestDuration_bytes(t *testing.T) {
+	assert.Equal(t, []byte{0, 90}, DurationSeconds(90*time.Second).Bytes())
+	assert.Equal(t, []byte{0, 15}, DurationDeciseconds(1500*time.Millisecond).Bytes())
+	assert.Equal(t, []byte{0, 2}, DurationMinutes(90*time.Second).Bytes(), "rounds to nearest")
+	assert.Equal(t, []byte{0, 0, 0x0E, 0x10}, DurationMinutes32(60*time.Hour).Bytes())
+	assert.Equal(t, []byte{0xFF, 0xFF}, DurationSeconds(100*time.Hour).Bytes(), "clamps")
+	assert.Equal(t, []byte{0, 0}, DurationSeconds(-time.Second).Bytes(), "clamps")
+}
+
+func TestDuration_Validate(t *testing.T) {
+	assert.NoError(t, DurationSeconds(math.MaxUint16*time.Second).Validate())
+	assert.ErrorIs(t, DurationSeconds((math.MaxUint16+1)*time.Second).Validate(), ErrDurationRange)
+	assert.ErrorIs(t, DurationMinutes(-time.Minute).Validate(), ErrDurationRange)
+	assert.NoError(t, DurationDeciseconds32(100*time.Hour).Validate())
+}
+
+func TestDuration_Converter(t *testing.T) {
+	_, err := DurationSecondsType.Converter()([]byte{0, 0, 0, 1})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = DurationMinutes32Type.Converter()([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+	assert.ErrorIs(t, err, ErrInvalidInput, "overflows time.Duration")
+}
+
+func TestDurationType(t *testing.T) {
+	for _, tt := range []struct {
+		unit string
+		size uint16
+		want Type
+	}{
+		{"s", 1, DurationSecondsType},
+		{"0.1s", 1, DurationDecisecondsType},
+		{"min", 2, DurationMinutes32Type},
+	} {
+		got, err := DurationType(tt.unit, tt.size)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+	_, err := DurationType("h", 1)
+	assert.Error(t, err)
+	_, err = DurationType("s", 4)
+	assert.Error(t, err)
+}
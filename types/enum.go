@@ -0,0 +1,120 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Enum is a register holding one of a few known codes, each with a
+// name, e.g. 0 for "Off" and 1 for "Auto".
+//
+// Enum is both a Type, as returned by NewEnum, and the Value produced by
+// its Converter, so that read values can name their code. Enums built by
+// the same NewEnum call compare equal if their codes are equal.
+type Enum struct {
+	Code  uint16
+	names *enumNames
+}
+
+type enumNames struct {
+	byCode map[uint16]string
+	byName map[string]uint16
+}
+
+// NewEnum returns an Enum type with the given names of codes. Names
+// must be unique.
+func NewEnum(names map[uint16]string) Enum {
+	n := &enumNames{make(map[uint16]string, len(names)), make(map[string]uint16, len(names))}
+	for code, name := range names {
+		n.byCode[code] = name
+		n.byName[name] = code
+	}
+	return Enum{names: n}
+}
+
+// WithCode returns a copy of e holding code, for use in writes.
+func (e Enum) WithCode(code uint16) Enum {
+	e.Code = code
+	return e
+}
+
+// WithName returns a copy of e holding the code named name, for use in
+// writes. It fails with ErrInvalidInput if there is no such name.
+func (e Enum) WithName(name string) (Enum, error) {
+	if e.names != nil {
+		if code, ok := e.names.byName[name]; ok {
+			return e.WithCode(code), nil
+		}
+	}
+	return e, fmt.Errorf("%w: unknown name %q, expected one of %s", ErrInvalidInput, name, e.known())
+}
+
+// Name returns the name of the code, or an empty string if the code is
+// not known.
+func (e Enum) Name() string {
+	if e.names == nil {
+		return ""
+	}
+	return e.names.byCode[e.Code]
+}
+
+func (e Enum) String() string {
+	if name := e.Name(); name != "" {
+		return fmt.Sprintf("%s (%d)", name, e.Code)
+	}
+	return fmt.Sprintf("unknown (%d)", e.Code)
+}
+
+func (e Enum) Bytes() []byte {
+	r := make([]byte, 2)
+	binary.BigEndian.PutUint16(r, e.Code)
+	return r
+}
+
+func (e Enum) Size() uint16 {
+	return 1
+}
+
+// Validate returns ErrInvalidInput if the code is not known.
+func (e Enum) Validate() error {
+	if e.names == nil {
+		return fmt.Errorf("%w: enum without names", ErrInvalidInput)
+	}
+	if _, ok := e.names.byCode[e.Code]; !ok {
+		return fmt.Errorf("%w: unknown code %d, expected one of %s", ErrInvalidInput, e.Code, e.known())
+	}
+	return nil
+}
+
+func (e Enum) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		v := e.WithCode(binary.BigEndian.Uint16(b))
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// known lists the known codes with their names.
+func (e Enum) known() string {
+	if e.names == nil {
+		return "nothing"
+	}
+	codes := make([]int, 0, len(e.names.byCode))
+	for code := range e.names.byCode {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	known := make([]string, len(codes))
+	for i, code := range codes {
+		known[i] = fmt.Sprintf("%d (%s)", code, e.names.byCode[uint16(code)])
+	}
+	return strings.Join(known, ", ")
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testMode = NewEnum(map[uint16]string{0: "Off", 1: "Auto", 2: "Manual", 3: "Fault"})
+
+func TestEnum(t *testing.T) {
+	assert.Equal(t, uint16(1), testMode.Size())
+
+	v, err := testMode.Converter()([]byte{0, 2})
+	assert.NoError(t, err)
+	mode := v.(Enum)
+	assert.Equal(t, uint16(2), mode.Code)
+	assert.Equal(t, "Manual", mode.Name())
+	assert.Equal(t, "Manual (2)", mode.String())
+	assert.Equal(t, testMode.WithCode(2), mode, "enums of the same type compare equal")
+	assert.True(t, testMode.WithCode(2) == mode)
+	assert.Equal(t, []byte{0, 2}, mode.Bytes())
+}
+
+func TestEnum_write(t *testing.T) {
+	byName, err := testMode.WithName("Fault")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 3}, byName.Bytes())
+	assert.NoError(t, byName.Validate())
+	assert.Equal(t, byName, testMode.WithCode(3))
+
+	_, err = testMode.WithName("Standby")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.EqualError(t, err, `invalid byte input: unknown name "Standby", expected one of 0 (Off), 1 (Auto), 2 (Manual), 3 (Fault)`)
+	assert.ErrorIs(t, testMode.WithCode(7).Validate(), ErrInvalidInput)
+	assert.Equal(t, "unknown (7)", testMode.WithCode(7).String())
+}
+
+func TestEnum_Converter_invalidInput(t *testing.T) {
+	v, err := testMode.Converter()([]byte{0, 4})
+	assert.Nil(t, v)
+	assert.EqualError(t, err, "invalid byte input: unknown code 4, expected one of 0 (Off), 1 (Auto), 2 (Manual), 3 (Fault)")
+
+	for _, input := range [][]byte{nil, {1}, {0, 1, 0}} {
+		_, err := testMode.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+	_, err = Enum{}.Converter()([]byte{0, 0})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
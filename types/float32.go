@@ -10,13 +10,12 @@ import (
 // order is swapped from ABCD to CDAB before transmission.
 type Float32CDAB float32
 
+func (f Float32CDAB) String() string {
+	return fmt.Sprint(float32(f))
+}
+
 func (f Float32CDAB) Bytes() []byte {
-	r := make([]byte, 4)
-	binary.BigEndian.PutUint32(r, math.Float32bits(float32(f)))
-	fp := make([]byte, 4)
-	copy(fp[0:2], r[2:4])
-	copy(fp[2:4], r[0:2])
-	return fp
+	return CDAB.apply(Float32(f).Bytes())
 }
 
 func (f Float32CDAB) Size() uint16 {
@@ -25,14 +24,11 @@ func (f Float32CDAB) Size() uint16 {
 
 func (Float32CDAB) Converter() Converter {
 	return func(b []byte) (Value, error) {
-		if l := len(b); l != 4 {
-			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		v, err := Float32Type.Converter()(CDAB.apply(b))
+		if err != nil {
+			return nil, err
 		}
-
-		fp := make([]byte, 4)
-		copy(fp[0:2], b[2:4])
-		copy(fp[2:4], b[0:2])
-		return Float32CDAB(math.Float32frombits(binary.BigEndian.Uint32(fp))), nil
+		return Float32CDAB(v.(Float32)), nil
 	}
 }
 
@@ -43,6 +39,10 @@ const Float32CDABType = Float32CDAB(0)
 // byte order.
 type Float32 float32
 
+func (f Float32) String() string {
+	return fmt.Sprint(float32(f))
+}
+
 func (f Float32) Bytes() []byte {
 	r := make([]byte, 4)
 	binary.BigEndian.PutUint32(r, math.Float32bits(float32(f)))
@@ -65,3 +65,61 @@ func (Float32) Converter() Converter {
 
 // Float32Type is provided for use as Type.
 const Float32Type = Float32(0)
+
+// Float32BADC is a 32-bit IEEE floating point value where the bytes of
+// every word are swapped from ABCD to BADC before transmission.
+type Float32BADC float32
+
+func (f Float32BADC) String() string {
+	return fmt.Sprint(float32(f))
+}
+
+func (f Float32BADC) Bytes() []byte {
+	return BADC.apply(Float32(f).Bytes())
+}
+
+func (f Float32BADC) Size() uint16 {
+	return 2
+}
+
+func (Float32BADC) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		v, err := Float32Type.Converter()(BADC.apply(b))
+		if err != nil {
+			return nil, err
+		}
+		return Float32BADC(v.(Float32)), nil
+	}
+}
+
+// Float32BADCType is provided for use as Type.
+const Float32BADCType = Float32BADC(0)
+
+// Float32DCBA is a 32-bit IEEE floating point value where the 4 bytes
+// order is reversed from ABCD to DCBA before transmission.
+type Float32DCBA float32
+
+func (f Float32DCBA) String() string {
+	return fmt.Sprint(float32(f))
+}
+
+func (f Float32DCBA) Bytes() []byte {
+	return DCBA.apply(Float32(f).Bytes())
+}
+
+func (f Float32DCBA) Size() uint16 {
+	return 2
+}
+
+func (Float32DCBA) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		v, err := Float32Type.Converter()(DCBA.apply(b))
+		if err != nil {
+			return nil, err
+		}
+		return Float32DCBA(v.(Float32)), nil
+	}
+}
+
+// Float32DCBAType is provided for use as Type.
+const Float32DCBAType = Float32DCBA(0)
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat32_byteOrders(t *testing.T) {
+	// 1.5 is 3F C0 00 00 in ABCD order
+	value := float32(1.5)
+	tests := []struct {
+		name  string
+		value Value
+		t     Type
+		bytes []byte
+	}{
+		{"ABCD", Float32(value), Float32Type, []byte{0x3F, 0xC0, 0x00, 0x00}},
+		{"CDAB", Float32CDAB(value), Float32CDABType, []byte{0x00, 0x00, 0x3F, 0xC0}},
+		{"BADC", Float32BADC(value), Float32BADCType, []byte{0xC0, 0x3F, 0x00, 0x00}},
+		{"DCBA", Float32DCBA(value), Float32DCBAType, []byte{0x00, 0x00, 0xC0, 0x3F}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bytes, tt.value.Bytes(), tt.name)
+		assert.Equal(t, uint16(2), tt.t.Size(), tt.name)
+		v, err := tt.t.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.value, v, tt.name)
+	}
+}
+
+func TestFloat32_crossConversion(t *testing.T) {
+	// the same wire bytes decode to different values in every byte order
+	wire := []byte{0x41, 0x20, 0x00, 0x01}
+	tests := []struct {
+		t    Type
+		bits uint32
+	}{
+		{Float32Type, 0x41200001},
+		{Float32CDABType, 0x00014120},
+		{Float32BADCType, 0x20410100},
+		{Float32DCBAType, 0x01002041},
+	}
+	seen := make(map[uint32]bool)
+	for _, tt := range tests {
+		v, err := tt.t.Converter()(wire)
+		assert.NoError(t, err)
+		var f float32
+		switch v := v.(type) {
+		case Float32:
+			f = float32(v)
+		case Float32CDAB:
+			f = float32(v)
+		case Float32BADC:
+			f = float32(v)
+		case Float32DCBA:
+			f = float32(v)
+		}
+		assert.Equal(t, tt.bits, math.Float32bits(f), "%T", v)
+		assert.Equal(t, wire, v.Bytes(), "%T", v)
+		seen[tt.bits] = true
+	}
+	assert.Len(t, seen, len(tests))
+}
+
+func TestFloat32_Converter_invalidInput(t *testing.T) {
+	for _, input := range [][]byte{nil, {1, 2}, make([]byte, 5)} {
+		for _, tt := range []Type{Float32Type, Float32CDABType, Float32BADCType, Float32DCBAType} {
+			_, err := tt.Converter()(input)
+			assert.ErrorIs(t, err, ErrInvalidInput, "%T", tt)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Float64 is a 64-bit IEEE floating point value with the regular byte
+// order.
+type Float64 float64
+
+func (f Float64) String() string {
+	return fmt.Sprint(float64(f))
+}
+
+func (f Float64) Bytes() []byte {
+	r := make([]byte, 8)
+	binary.BigEndian.PutUint64(r, math.Float64bits(float64(f)))
+	return r
+}
+
+func (f Float64) Size() uint16 {
+	return 4
+}
+
+func (Float64) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 8 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Float64(math.Float64frombits(binary.BigEndian.Uint64(b))), nil
+	}
+}
+
+// Float64Type is provided for use as Type.
+const Float64Type = Float64(0)
+
+// Float64Swapped is a 64-bit IEEE floating point value where the order
+// of 16-bit words is reversed from ABCDEFGH to GHEFCDAB before
+// transmission, like Float32CDAB does for 32-bit values.
+type Float64Swapped float64
+
+func (f Float64Swapped) String() string {
+	return fmt.Sprint(float64(f))
+}
+
+func (f Float64Swapped) Bytes() []byte {
+	return swapWords(Float64(f).Bytes())
+}
+
+func (f Float64Swapped) Size() uint16 {
+	return 4
+}
+
+func (Float64Swapped) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 8 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Float64Swapped(math.Float64frombits(binary.BigEndian.Uint64(swapWords(b)))), nil
+	}
+}
+
+// Float64SwappedType is provided for use as Type.
+const Float64SwappedType = Float64Swapped(0)
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat64(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+	}{
+		{"regular", 1234.5678},
+		{"negative zero", math.Copysign(0, -1)},
+		{"NaN", math.NaN()},
+		{"NaN with payload", math.Float64frombits(0x7FF8000000000123)},
+		{"smallest denormal", math.SmallestNonzeroFloat64},
+		{"denormal", math.Float64frombits(0x000FFFFFFFFFFFFF)},
+		{"infinity", math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		bits := math.Float64bits(tt.value)
+
+		b := Float64(tt.value).Bytes()
+		v, err := Float64Type.Converter()(b)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, bits, math.Float64bits(float64(v.(Float64))), tt.name)
+
+		swapped := Float64Swapped(tt.value).Bytes()
+		assert.Equal(t, []byte{b[6], b[7], b[4], b[5], b[2], b[3], b[0], b[1]}, swapped, tt.name)
+		v, err = Float64SwappedType.Converter()(swapped)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, bits, math.Float64bits(float64(v.(Float64Swapped))), tt.name)
+	}
+}
+
+func TestFloat64_Converter_invalidInput(t *testing.T) {
+	for _, input := range [][]byte{nil, {1, 2, 3, 4}, make([]byte, 9)} {
+		_, err := Float64Type.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		_, err = Float64SwappedType.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
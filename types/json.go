@@ -0,0 +1,111 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// UnmarshalValue decodes the JSON representation of a value of t. The
+// settings of t, such as the scaling of Scaled or the names of Enum, are
+// kept in the result.
+//
+// Values of all the types of this package marshal to JSON as their
+// natural representation: numbers, booleans, or strings for timestamps
+// and Enum names. As the representation doesn't tell the type, decoding
+// needs it.
+func UnmarshalValue(t Type, b []byte) (Value, error) {
+	if t == nil {
+		return nil, fmt.Errorf("%w: no type to unmarshal %s into", ErrInvalidInput, b)
+	}
+	ptr := reflect.New(reflect.TypeOf(t))
+	ptr.Elem().Set(reflect.ValueOf(t))
+	if err := json.Unmarshal(b, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: %T from %s: %v", ErrInvalidInput, t, b, err)
+	}
+	v, ok := ptr.Elem().Interface().(Value)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a value", ErrInvalidInput, t)
+	}
+	return v, nil
+}
+
+// MarshalJSON encodes the scaled value as a number.
+func (s Scaled) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Value)
+}
+
+// UnmarshalJSON sets the scaled value from a number, keeping the scaling
+// of s.
+func (s *Scaled) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &s.Value)
+}
+
+// MarshalJSON encodes the name of the code, or the code as a number if
+// it is not known.
+func (e Enum) MarshalJSON() ([]byte, error) {
+	if name := e.Name(); name != "" {
+		return json.Marshal(name)
+	}
+	return json.Marshal(e.Code)
+}
+
+// UnmarshalJSON sets the code from a name or a number, keeping the
+// names of e.
+func (e *Enum) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return json.Unmarshal(b, &e.Code)
+	}
+	named, err := e.WithName(name)
+	if err != nil {
+		return err
+	}
+	*e = named
+	return nil
+}
+
+// MarshalJSON encodes the bytes in base64.
+func (r Raw) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.data)
+}
+
+// UnmarshalJSON sets the bytes from base64, keeping the size of r.
+func (r *Raw) UnmarshalJSON(b []byte) error {
+	var data []byte
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	*r = r.With(data)
+	return nil
+}
+
+// MarshalJSON encodes the value of Inner.
+func (o Ordered) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Value)
+}
+
+// UnmarshalJSON sets the value of Inner.
+func (o *Ordered) UnmarshalJSON(b []byte) error {
+	v, err := UnmarshalValue(o.Inner, b)
+	if err != nil {
+		return err
+	}
+	o.Value = v
+	return nil
+}
+
+// MarshalJSON encodes the value of Inner.
+func (b Bounded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Value)
+}
+
+// UnmarshalJSON sets the value of Inner.
+func (b *Bounded) UnmarshalJSON(data []byte) error {
+	v, err := UnmarshalValue(b.Inner, data)
+	if err != nil {
+		return err
+	}
+	b.Value = v
+	return nil
+}
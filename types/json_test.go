@@ -0,0 +1,84 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalValue_roundTrip(t *testing.T) {
+	tests := map[string]Type{
+		"scaled":  NewScaled(Int16Type, 0.1, 0),
+		"enum":    NewEnum(map[uint16]string{1: "On"}),
+		"raw":     NewRaw(3),
+		"ordered": WithWordOrder(Float32Type, DCBA),
+		"bounded": NewBounded(Uint32Type, 0, 1e6),
+	}
+	for _, name := range TypeNames() {
+		tests[name], _ = LookupType(name)
+	}
+	for name, typ := range tests {
+		b := make([]byte, int(typ.Size())*2)
+		for i := range b {
+			b[i] = byte(i)
+		}
+		v, err := typ.Converter()(b)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+
+		data, err := json.Marshal(v)
+		assert.NoError(t, err, name)
+		got, err := UnmarshalValue(typ, data)
+		assert.NoError(t, err, "%s: %s", name, data)
+		if reflect.TypeOf(v) == reflect.TypeOf(typ) {
+			// BoolStrict converts to Bool
+			assert.Equal(t, v, got, "%s: %s", name, data)
+		}
+		assert.Equal(t, b, got.Bytes(), "%s: %s", name, data)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	enum := NewEnum(map[uint16]string{2: "Manual"})
+	tests := []struct {
+		value Value
+		json  string
+	}{
+		{Uint16(42), `42`},
+		{Float32(1.5), `1.5`},
+		{Float32CDAB(-0.25), `-0.25`},
+		{Float64(1e100), `1e+100`},
+		{Bool(true), `true`},
+		{NewScaled(Int16Type, 0.1, 0).With(21.5), `21.5`},
+		{enum.WithCode(2), `"Manual"`},
+		{enum.WithCode(7), `7`},
+		{NewRaw(1).With([]byte{0xFF, 0}), `"/wA="`},
+		{WithWordOrder(Uint32Type, CDAB).With(Uint32(7)), `7`},
+		{Timestamp{time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}, `"2021-03-04T05:06:07Z"`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.value)
+		assert.NoError(t, err, "%T", tt.value)
+		assert.JSONEq(t, tt.json, string(b), "%T", tt.value)
+	}
+}
+
+func TestUnmarshalValue_errors(t *testing.T) {
+	enum := NewEnum(map[uint16]string{2: "Manual"})
+	v, err := UnmarshalValue(enum, []byte(`2`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Manual", v.(Enum).Name(), "codes are accepted too")
+
+	_, err = UnmarshalValue(enum, []byte(`"Auto"`))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = UnmarshalValue(Uint16Type, []byte(`"x"`))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = UnmarshalValue(Uint16Type, []byte(`70000`))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = UnmarshalValue(nil, []byte(`1`))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
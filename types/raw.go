@@ -0,0 +1,62 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRawSize is returned by Validate of Raw if its bytes don't fill
+// exactly its registers.
+var ErrRawSize = errors.New("raw bytes don't match size")
+
+// Raw is an opaque block of registers kept as bytes, e.g. a
+// configuration block that is only stored and written back. Raw values
+// own their bytes: neither conversion nor Bytes() share memory with the
+// caller.
+type Raw struct {
+	size uint16
+	data []byte
+}
+
+// NewRaw returns a zero-filled Raw of n registers. Use it as Type to
+// read blocks of n registers.
+func NewRaw(n uint16) Raw {
+	return Raw{size: n, data: make([]byte, int(n)*2)}
+}
+
+// With returns a Raw of the same size holding a copy of b.
+func (r Raw) With(b []byte) Raw {
+	return Raw{size: r.size, data: append([]byte{}, b...)}
+}
+
+func (r Raw) String() string {
+	return fmt.Sprintf("0x%X", r.data)
+}
+
+// Bytes returns a copy of the bytes.
+func (r Raw) Bytes() []byte {
+	return append([]byte{}, r.data...)
+}
+
+func (r Raw) Size() uint16 {
+	return r.size
+}
+
+// Validate rejects bytes of odd length or not filling exactly Size()
+// registers.
+func (r Raw) Validate() error {
+	if l := len(r.data); l%2 != 0 || l != int(r.size)*2 {
+		return fmt.Errorf("%w: %d bytes for %d registers", ErrRawSize, l, r.size)
+	}
+	return nil
+}
+
+func (r Raw) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != int(r.size)*2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return r.With(b), nil
+	}
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaw(t *testing.T) {
+	raw := NewRaw(2)
+	assert.Equal(t, uint16(2), raw.Size())
+	assert.Equal(t, []byte{0, 0, 0, 0}, raw.Bytes())
+	assert.NoError(t, raw.Validate())
+
+	input := []byte{1, 2, 3, 4}
+	v, err := raw.Converter()(input)
+	assert.NoError(t, err)
+	input[0] = 0xFF
+	assert.Equal(t, []byte{1, 2, 3, 4}, v.Bytes(), "conversion copies input")
+
+	b := v.Bytes()
+	b[1] = 0xFF
+	assert.Equal(t, []byte{1, 2, 3, 4}, v.Bytes(), "Bytes returns a copy")
+
+	for _, l := range []int{0, 2, 6} {
+		_, err := raw.Converter()(make([]byte, l))
+		assert.True(t, errors.Is(err, ErrInvalidInput), "%d bytes: %v", l, err)
+	}
+}
+
+func TestRaw_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Raw
+		ok    bool
+	}{
+		{"exact", NewRaw(2).With([]byte{1, 2, 3, 4}), true},
+		{"short", NewRaw(2).With([]byte{1, 2}), false},
+		{"long", NewRaw(1).With([]byte{1, 2, 3, 4}), false},
+		{"odd", NewRaw(1).With([]byte{1}), false},
+		{"empty", NewRaw(0), true},
+	}
+	for _, tt := range tests {
+		err := tt.value.Validate()
+		if tt.ok {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.True(t, errors.Is(err, ErrRawSize), "%s: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrDuplicateType is returned by RegisterType for names already taken.
+var ErrDuplicateType = errors.New("type name already registered")
+
+var registry = struct {
+	sync.RWMutex
+	types map[string]Type
+}{types: map[string]Type{
+	"uint16":                 Uint16Type,
+	"int16":                  Int16Type,
+	"uint16_le":              Uint16LEType,
+	"uint32":                 Uint32Type,
+	"int32":                  Int32Type,
+	"uint32_cdab":            Uint32CDABType,
+	"int32_cdab":             Int32CDABType,
+	"uint64":                 Uint64Type,
+	"int64":                  Int64Type,
+	"float32":                Float32Type,
+	"float32_cdab":           Float32CDABType,
+	"float32_badc":           Float32BADCType,
+	"float32_dcba":           Float32DCBAType,
+	"float64":                Float64Type,
+	"float64_swapped":        Float64SwappedType,
+	"bool":                   BoolType,
+	"bool_strict":            BoolStrictType,
+	"bitfield16":             Bitfield16Type,
+	"bcd16":                  BCD16Type,
+	"bcd32":                  BCD32Type,
+	"uint8_pair":             Uint8PairType,
+	"duration_seconds":       DurationSecondsType,
+	"duration_deciseconds":   DurationDecisecondsType,
+	"duration_minutes":       DurationMinutesType,
+	"duration_seconds32":     DurationSeconds32Type,
+	"duration_deciseconds32": DurationDeciseconds32Type,
+	"duration_minutes32":     DurationMinutes32Type,
+	"timestamp":              TimestampType,
+	"timestamp_cdab":         TimestampCDABType,
+}}
+
+// RegisterType makes t resolvable by name with LookupType, e.g. for
+// types configured in files. Built-in types are registered under lower
+// case names with words separated by underscores, such as
+// "float32_cdab"; see TypeNames.
+func RegisterType(name string, t Type) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.types[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateType, name)
+	}
+	registry.types[name] = t
+	return nil
+}
+
+// LookupType returns the type registered under name.
+func LookupType(name string) (Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	t, ok := registry.types[name]
+	return t, ok
+}
+
+// TypeNames returns the names of all registered types in ascending
+// order.
+func TypeNames() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.types))
+	for name := range registry.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupType(t *testing.T) {
+	tests := []struct {
+		name string
+		t    Type
+	}{
+		{"uint16", Uint16Type},
+		{"float32_cdab", Float32CDABType},
+		{"int64", Int64Type},
+		{"timestamp", TimestampType},
+	}
+	for _, tt := range tests {
+		got, ok := LookupType(tt.name)
+		assert.True(t, ok, tt.name)
+		assert.Equal(t, tt.t, got, tt.name)
+	}
+
+	_, ok := LookupType("float128")
+	assert.False(t, ok)
+}
+
+func TestRegisterType(t *testing.T) {
+	scaled := NewScaled(Int16Type, 0.1, 0)
+	assert.NoError(t, RegisterType("test_temperature", scaled))
+	got, ok := LookupType("test_temperature")
+	assert.True(t, ok)
+	assert.Equal(t, scaled, got)
+	assert.Contains(t, TypeNames(), "test_temperature")
+
+	assert.ErrorIs(t, RegisterType("test_temperature", Uint16Type), ErrDuplicateType)
+	assert.ErrorIs(t, RegisterType("uint16", Int16Type), ErrDuplicateType)
+	got, _ = LookupType("uint16")
+	assert.Equal(t, Uint16Type, got, "not overwritten")
+}
+
+func TestTypeNames_builtin(t *testing.T) {
+	for _, name := range TypeNames() {
+		typ, _ := LookupType(name)
+		v, err := typ.Converter()(make([]byte, int(typ.Size())*2))
+		assert.NoError(t, err, name)
+		assert.Len(t, v.Bytes(), int(typ.Size())*2, name)
+	}
+}
@@ -0,0 +1,171 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ErrScaledRange is returned by Validate of Scaled if the raw value
+// doesn't fit the underlying type.
+var ErrScaledRange = errors.New("scaled value out of range")
+
+// Scaled is a fixed-point value stored in an integer type, e.g. Int16:
+// the value is Raw * Gain + Offset. Writes apply the inverse and round
+// to the nearest integer. Bytes() clamps values that don't fit the
+// underlying type; use Validate to reject them instead.
+//
+// Scaled is both a Type, as returned by NewScaled, and the Value
+// produced by its Converter, so that read values can be written back
+// with the same scaling.
+type Scaled struct {
+	Raw    Type
+	Gain   float64
+	Offset float64
+	Value  float64
+}
+
+// NewScaled returns a Scaled type stored as t, which must be one of the
+// integer types of this package.
+func NewScaled(t Type, gain, offset float64) Scaled {
+	return Scaled{Raw: t, Gain: gain, Offset: offset}
+}
+
+// With returns a copy of s holding value, for use in writes.
+func (s Scaled) With(value float64) Scaled {
+	s.Value = value
+	return s
+}
+
+// Float64 returns the scaled value.
+func (s Scaled) Float64() float64 {
+	return s.Value
+}
+
+func (s Scaled) String() string {
+	return fmt.Sprint(s.Value)
+}
+
+func (s Scaled) Size() uint16 {
+	if s.Raw == nil {
+		return 0
+	}
+	return s.Raw.Size()
+}
+
+func (s Scaled) Bytes() []byte {
+	raw, err := s.raw(true)
+	if err != nil {
+		return make([]byte, s.Size()*2)
+	}
+	return raw.Interface().(Value).Bytes()
+}
+
+// Validate returns ErrScaledRange if the value doesn't fit the
+// underlying type after scaling, or ErrInvalidInput if the underlying
+// type is not an integer or Gain is zero.
+func (s Scaled) Validate() error {
+	_, err := s.raw(false)
+	return err
+}
+
+func (s Scaled) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if err := s.check(); err != nil {
+			return nil, err
+		}
+		v, err := s.Raw.Converter()(b)
+		if err != nil {
+			return nil, err
+		}
+		rv := reflect.ValueOf(v)
+		var raw float64
+		if isSigned(rv.Kind()) {
+			raw = float64(rv.Int())
+		} else {
+			raw = float64(rv.Uint())
+		}
+		return s.With(raw*s.Gain + s.Offset), nil
+	}
+}
+
+func (s Scaled) check() error {
+	if s.Raw == nil || s.Gain == 0 || math.IsNaN(s.Gain) || math.IsInf(s.Gain, 0) {
+		return fmt.Errorf("%w: scaling %v by %v", ErrInvalidInput, s.Raw, s.Gain)
+	}
+	if _, ok := s.Raw.(Value); !ok {
+		return fmt.Errorf("%w: underlying type %T is not a value", ErrInvalidInput, s.Raw)
+	}
+	kind := reflect.ValueOf(s.Raw).Kind()
+	if !isSigned(kind) && !isUnsigned(kind) {
+		return fmt.Errorf("%w: underlying type %T is not an integer", ErrInvalidInput, s.Raw)
+	}
+	return nil
+}
+
+// raw returns the underlying value, clamped to its range if clamp is
+// set.
+func (s Scaled) raw(clamp bool) (reflect.Value, error) {
+	if err := s.check(); err != nil {
+		return reflect.Value{}, err
+	}
+	t := reflect.TypeOf(s.Raw)
+	raw := reflect.New(t).Elem()
+	x := math.Round((s.Value - s.Offset) / s.Gain)
+	if math.IsNaN(x) {
+		if !clamp {
+			return raw, fmt.Errorf("%w: %v", ErrScaledRange, s.Value)
+		}
+		return raw, nil
+	}
+
+	bits := uint(t.Bits())
+	var min, max float64
+	if isSigned(t.Kind()) {
+		min, max = -math.Ldexp(1, int(bits)-1), math.Ldexp(1, int(bits)-1)-1
+	} else {
+		min, max = 0, math.Ldexp(1, int(bits))-1
+	}
+	if x < min || x > max {
+		if !clamp {
+			return raw, fmt.Errorf("%w: %v encodes to %v, out of %v..%v", ErrScaledRange, s.Value, x, min, max)
+		}
+		x = math.Max(min, math.Min(max, x))
+	}
+
+	if isSigned(t.Kind()) {
+		// max of 64-bit types is not exactly representable as float64
+		if x >= math.Ldexp(1, 63) {
+			raw.SetInt(math.MaxInt64)
+		} else {
+			raw.SetInt(int64(x))
+		}
+	} else {
+		if x >= math.Ldexp(1, 64) {
+			raw.SetUint(math.MaxUint64)
+		} else {
+			raw.SetUint(uint64(x))
+		}
+	}
+	if v, ok := raw.Interface().(Validator); ok && !clamp {
+		return raw, v.Validate()
+	}
+	return raw, nil
+}
+
+func isSigned(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaled(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     Scaled
+		bytes []byte
+		want  float64
+	}{
+		{"tenths", NewScaled(Int16Type, 0.1, 0), []byte{0xFF, 0x85}, -12.3},
+		{"offset", NewScaled(Uint16Type, 0.01, -40), []byte{0x1F, 0x40}, 40},
+		{"32-bit", NewScaled(Uint32Type, 0.001, 0), []byte{0x00, 0x01, 0xE2, 0x40}, 123.456},
+		{"gain above one", NewScaled(Int32Type, 4, 1), []byte{0xFF, 0xFF, 0xFF, 0xFE}, -7},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.t.Raw.Size(), tt.t.Size(), tt.name)
+		v, err := tt.t.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.InDelta(t, tt.want, v.(Scaled).Float64(), 1e-9, tt.name)
+		assert.Equal(t, tt.bytes, v.(Scaled).Bytes(), "%s: round trip", tt.name)
+		assert.Equal(t, tt.bytes, tt.t.With(tt.want).Bytes(), tt.name)
+	}
+}
+
+func TestScaled_rounding(t *testing.T) {
+	scaled := NewScaled(Int16Type, 0.1, 0)
+	tests := []struct {
+		value float64
+		raw   int16
+	}{
+		{1.04, 10},
+		{1.05, 11},
+		{1.06, 11},
+		{-1.05, -11},
+		{-1.04, -10},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, Int16(tt.raw).Bytes(), scaled.With(tt.value).Bytes(), "%v", tt.value)
+		assert.NoError(t, scaled.With(tt.value).Validate(), "%v", tt.value)
+	}
+}
+
+func TestScaled_range(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Scaled
+		bytes []byte
+	}{
+		{"int16 above", NewScaled(Int16Type, 0.1, 0).With(3276.8), []byte{0x7F, 0xFF}},
+		{"int16 below", NewScaled(Int16Type, 0.1, 0).With(-3276.9), []byte{0x80, 0x00}},
+		{"uint16 negative", NewScaled(Uint16Type, 1, 0).With(-1), []byte{0, 0}},
+		{"uint16 offset", NewScaled(Uint16Type, 1, 100).With(99), []byte{0, 0}},
+		{"NaN", NewScaled(Uint16Type, 1, 0).With(math.NaN()), []byte{0, 0}},
+		{"underlying validation", NewScaled(BCD16Type, 1, 0).With(10000), []byte{0x99, 0x99}},
+	}
+	for _, tt := range tests {
+		assert.Error(t, tt.value.Validate(), tt.name)
+		assert.Equal(t, tt.bytes, tt.value.Bytes(), "%s: clamped", tt.name)
+	}
+	assert.ErrorIs(t, NewScaled(Int16Type, 0.1, 0).With(4000).Validate(), ErrScaledRange)
+	assert.NoError(t, NewScaled(Int16Type, 0.1, 0).With(3276.7).Validate())
+	assert.NoError(t, NewScaled(Uint64Type, 1, 0).With(1e19).Validate())
+}
+
+func TestScaled_invalid(t *testing.T) {
+	for _, s := range []Scaled{
+		NewScaled(Float32Type, 1, 0),
+		NewScaled(Int16Type, 0, 0),
+		NewScaled(Int16Type, math.Inf(1), 0),
+		{},
+	} {
+		_, err := s.Converter()([]byte{0, 0})
+		assert.ErrorIs(t, err, ErrInvalidInput, "%#v", s)
+		assert.ErrorIs(t, s.Validate(), ErrInvalidInput, "%#v", s)
+	}
+	_, err := NewScaled(Int16Type, 1, 0).Converter()([]byte{0})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
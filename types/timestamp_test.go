@@ -0,0 +1,57 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestamp(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name  string
+		t     Type
+		bytes []byte
+	}{
+		{"ABCD", TimestampType, []byte{0x60, 0x40, 0x6A, 0xBF}},
+		{"CDAB", TimestampCDABType, []byte{0x6A, 0xBF, 0x60, 0x40}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, uint16(2), tt.t.Size(), tt.name)
+		v, err := tt.t.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, at, v.(interface{ UTC() time.Time }).UTC(), tt.name)
+		assert.Equal(t, tt.bytes, v.Bytes(), "%s: round trip", tt.name)
+
+		for _, l := range []int{0, 2, 6} {
+			_, err := tt.t.Converter()(make([]byte, l))
+			assert.ErrorIs(t, err, ErrInvalidInput, "%s: %d bytes", tt.name, l)
+		}
+	}
+	assert.Equal(t, []byte{0x6A, 0xBF, 0x60, 0x40}, TimestampCDAB{at.Add(500 * time.Millisecond)}.Bytes(), "truncates")
+}
+
+func TestTimestamp_range(t *testing.T) {
+	tests := []struct {
+		name  string
+		time  time.Time
+		bytes []byte
+		ok    bool
+	}{
+		{"epoch", time.Unix(0, 0), []byte{0, 0, 0, 0}, true},
+		{"last", time.Unix(math.MaxUint32, 0), []byte{0xFF, 0xFF, 0xFF, 0xFF}, true},
+		{"before epoch", time.Unix(-1, 0), []byte{0, 0, 0, 0}, false},
+		{"after last", time.Unix(math.MaxUint32+1, 0), []byte{0xFF, 0xFF, 0xFF, 0xFF}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bytes, Timestamp{tt.time}.Bytes(), "%s: clamps", tt.name)
+		if tt.ok {
+			assert.NoError(t, Timestamp{tt.time}.Validate(), tt.name)
+		} else {
+			assert.ErrorIs(t, Timestamp{tt.time}.Validate(), ErrTimestampRange, tt.name)
+			assert.ErrorIs(t, TimestampCDAB{tt.time}.Validate(), ErrTimestampRange, tt.name)
+		}
+	}
+}
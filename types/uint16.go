@@ -9,6 +9,10 @@ import (
 // Modbus register.
 type Uint16 uint16
 
+func (u Uint16) String() string {
+	return fmt.Sprint(uint16(u))
+}
+
 func (u Uint16) Bytes() []byte {
 	r := make([]byte, 2)
 	binary.BigEndian.PutUint16(r, uint16(u))
@@ -31,3 +35,63 @@ func (Uint16) Converter() Converter {
 
 // Uint16Type is provided for use as Type.
 const Uint16Type = Uint16(0)
+
+// Int16 is a regular signed big endian int that fits in a single
+// Modbus register.
+type Int16 int16
+
+func (i Int16) String() string {
+	return fmt.Sprint(int16(i))
+}
+
+func (i Int16) Bytes() []byte {
+	return Uint16(i).Bytes()
+}
+
+func (i Int16) Size() uint16 {
+	return 1
+}
+
+func (Int16) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Int16(binary.BigEndian.Uint16(b)), nil
+	}
+}
+
+// Int16Type is provided for use as Type.
+const Int16Type = Int16(0)
+
+// Uint16LE is an unsigned int that fits in a single Modbus register
+// with its two bytes swapped, i.e. transmitted in little endian order.
+type Uint16LE uint16
+
+func (u Uint16LE) String() string {
+	return fmt.Sprint(uint16(u))
+}
+
+func (u Uint16LE) Bytes() []byte {
+	r := make([]byte, 2)
+	binary.LittleEndian.PutUint16(r, uint16(u))
+	return r
+}
+
+func (u Uint16LE) Size() uint16 {
+	return 1
+}
+
+func (Uint16LE) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Uint16LE(binary.LittleEndian.Uint16(b)), nil
+	}
+}
+
+// Uint16LEType is provided for use as Type.
+const Uint16LEType = Uint16LE(0)
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUint16LE(t *testing.T) {
+	assert.Equal(t, []byte{0x34, 0x12}, Uint16LE(0x1234).Bytes())
+	assert.Equal(t, []byte{0x12, 0x34}, Uint16(0x1234).Bytes())
+
+	v, err := Uint16LEType.Converter()([]byte{0x34, 0x12})
+	assert.NoError(t, err)
+	assert.Equal(t, Uint16LE(0x1234), v)
+	v, err = Uint16Type.Converter()([]byte{0x34, 0x12})
+	assert.NoError(t, err)
+	assert.Equal(t, Uint16(0x3412), v)
+
+	for _, input := range [][]byte{nil, {1}, {1, 2, 3}} {
+		_, err := Uint16LEType.Converter()(input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
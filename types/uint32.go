@@ -0,0 +1,134 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Uint32 is a regular unsigned big endian int that spans two Modbus
+// registers.
+type Uint32 uint32
+
+func (u Uint32) String() string {
+	return fmt.Sprint(uint32(u))
+}
+
+func (u Uint32) Bytes() []byte {
+	r := make([]byte, 4)
+	binary.BigEndian.PutUint32(r, uint32(u))
+	return r
+}
+
+func (u Uint32) Size() uint16 {
+	return 2
+}
+
+func (Uint32) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 4 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Uint32(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+// Uint32Type is provided for use as Type.
+const Uint32Type = Uint32(0)
+
+// Int32 is a regular signed big endian int that spans two Modbus
+// registers.
+type Int32 int32
+
+func (i Int32) String() string {
+	return fmt.Sprint(int32(i))
+}
+
+func (i Int32) Bytes() []byte {
+	return Uint32(i).Bytes()
+}
+
+func (i Int32) Size() uint16 {
+	return 2
+}
+
+func (Int32) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 4 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Int32(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+// Int32Type is provided for use as Type.
+const Int32Type = Int32(0)
+
+// Uint32CDAB is an unsigned 32-bit int where the 4 bytes order is
+// swapped from ABCD to CDAB before transmission.
+type Uint32CDAB uint32
+
+func (u Uint32CDAB) String() string {
+	return fmt.Sprint(uint32(u))
+}
+
+func (u Uint32CDAB) Bytes() []byte {
+	return swapWords(Uint32(u).Bytes())
+}
+
+func (u Uint32CDAB) Size() uint16 {
+	return 2
+}
+
+func (Uint32CDAB) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 4 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Uint32CDAB(binary.BigEndian.Uint32(swapWords(b))), nil
+	}
+}
+
+// Uint32CDABType is provided for use as Type.
+const Uint32CDABType = Uint32CDAB(0)
+
+// Int32CDAB is a signed 32-bit int where the 4 bytes order is swapped
+// from ABCD to CDAB before transmission.
+type Int32CDAB int32
+
+func (i Int32CDAB) String() string {
+	return fmt.Sprint(int32(i))
+}
+
+func (i Int32CDAB) Bytes() []byte {
+	return swapWords(Uint32(i).Bytes())
+}
+
+func (i Int32CDAB) Size() uint16 {
+	return 2
+}
+
+func (Int32CDAB) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 4 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Int32CDAB(binary.BigEndian.Uint32(swapWords(b))), nil
+	}
+}
+
+// Int32CDABType is provided for use as Type.
+const Int32CDABType = Int32CDAB(0)
+
+// swapWords returns a copy of b with the order of 16-bit words
+// reversed.
+func swapWords(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := 0; i+1 < len(b); i += 2 {
+		copy(r[len(b)-i-2:len(b)-i], b[i:i+2])
+	}
+	return r
+}
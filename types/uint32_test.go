@@ -0,0 +1,82 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUint32(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Uint32
+		bytes []byte
+	}{
+		{"zero", 0, []byte{0, 0, 0, 0}},
+		{"above 0xFFFF", 0x12345678, []byte{0x12, 0x34, 0x56, 0x78}},
+		{"maximum", 0xFFFFFFFF, []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bytes, tt.value.Bytes(), tt.name)
+		v, err := Uint32Type.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.value, v, tt.name)
+	}
+}
+
+func TestInt32(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Int32
+		bytes []byte
+	}{
+		{"positive above 0xFFFF", 0x10000, []byte{0, 1, 0, 0}},
+		{"minus one", -1, []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+		{"negative above 0xFFFF", -70000, []byte{0xFF, 0xFE, 0xEE, 0x90}},
+		{"minimum", -0x80000000, []byte{0x80, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bytes, tt.value.Bytes(), tt.name)
+		v, err := Int32Type.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.value, v, tt.name)
+	}
+}
+
+func TestUint32_Converter_invalidInput(t *testing.T) {
+	for _, c := range []Converter{Uint32Type.Converter(), Int32Type.Converter()} {
+		v, err := c([]byte{1, 2})
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		assert.Nil(t, v)
+	}
+}
+
+func TestUint32CDAB(t *testing.T) {
+	for _, v := range []uint32{0, 1, 0x12345678, 0xFFFF0000} {
+		swapped := Uint32CDAB(v).Bytes()
+		straight := Uint32(v).Bytes()
+		assert.Equal(t, straight[2:4], swapped[0:2], "%#x", v)
+		assert.Equal(t, straight[0:2], swapped[2:4], "%#x", v)
+
+		decoded, err := Uint32CDABType.Converter()(swapped)
+		assert.NoError(t, err)
+		assert.Equal(t, Uint32CDAB(v), decoded)
+	}
+	assert.Equal(t, []byte{0x56, 0x78, 0x12, 0x34}, Uint32CDAB(0x12345678).Bytes())
+}
+
+func TestInt32CDAB(t *testing.T) {
+	for _, v := range []int32{0, -1, -70000, 0x10000, -0x80000000} {
+		swapped := Int32CDAB(v).Bytes()
+		straight := Int32(v).Bytes()
+		assert.Equal(t, straight[2:4], swapped[0:2], "%d", v)
+		assert.Equal(t, straight[0:2], swapped[2:4], "%d", v)
+
+		decoded, err := Int32CDABType.Converter()(swapped)
+		assert.NoError(t, err)
+		assert.Equal(t, Int32CDAB(v), decoded)
+	}
+
+	_, err := Int32CDABType.Converter()([]byte{1, 2, 3})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
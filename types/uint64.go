@@ -0,0 +1,66 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Uint64 is a regular unsigned big endian int that spans four Modbus
+// registers.
+type Uint64 uint64
+
+func (u Uint64) String() string {
+	return fmt.Sprint(uint64(u))
+}
+
+func (u Uint64) Bytes() []byte {
+	r := make([]byte, 8)
+	binary.BigEndian.PutUint64(r, uint64(u))
+	return r
+}
+
+func (u Uint64) Size() uint16 {
+	return 4
+}
+
+func (Uint64) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 8 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Uint64(binary.BigEndian.Uint64(b)), nil
+	}
+}
+
+// Uint64Type is provided for use as Type.
+const Uint64Type = Uint64(0)
+
+// Int64 is a regular signed big endian int that spans four Modbus
+// registers.
+type Int64 int64
+
+func (i Int64) String() string {
+	return fmt.Sprint(int64(i))
+}
+
+func (i Int64) Bytes() []byte {
+	return Uint64(i).Bytes()
+}
+
+func (i Int64) Size() uint16 {
+	return 4
+}
+
+func (Int64) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 8 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return Int64(binary.BigEndian.Uint64(b)), nil
+	}
+}
+
+// Int64Type is provided for use as Type.
+const Int64Type = Int64(0)
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUint64(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+		t     Type
+		bytes []byte
+	}{
+		{"uint64 above 32 bits", Uint64(0x0102030405060708), Uint64Type, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{"uint64 maximum", Uint64(0xFFFFFFFFFFFFFFFF), Uint64Type, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"int64 minus one", Int64(-1), Int64Type, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"int64 minimum", Int64(-0x8000000000000000), Int64Type, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"int64 negative", Int64(-0x100000000), Int64Type, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bytes, tt.value.Bytes(), tt.name)
+		v, err := tt.t.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.value, v, tt.name)
+		_, err = tt.t.Converter()(tt.bytes[:4])
+		assert.ErrorIs(t, err, ErrInvalidInput, tt.name)
+	}
+}
@@ -0,0 +1,47 @@
+package types
+
+import "fmt"
+
+// Uint8Pair is a pair of unsigned 8-bit values packed into a single
+// Modbus register, the high byte first.
+type Uint8Pair uint16
+
+// NewUint8Pair returns a Uint8Pair of hi and lo.
+func NewUint8Pair(hi, lo byte) Uint8Pair {
+	return Uint8Pair(uint16(hi)<<8 | uint16(lo))
+}
+
+// High returns the high byte.
+func (p Uint8Pair) High() byte {
+	return byte(p >> 8)
+}
+
+// Low returns the low byte.
+func (p Uint8Pair) Low() byte {
+	return byte(p)
+}
+
+func (p Uint8Pair) String() string {
+	return fmt.Sprintf("%d:%d", p.High(), p.Low())
+}
+
+func (p Uint8Pair) Bytes() []byte {
+	return []byte{p.High(), p.Low()}
+}
+
+func (p Uint8Pair) Size() uint16 {
+	return 1
+}
+
+func (Uint8Pair) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if l := len(b); l != 2 {
+			return nil, fmt.Errorf("%w: bytes of size %v", ErrInvalidInput, l)
+		}
+
+		return NewUint8Pair(b[0], b[1]), nil
+	}
+}
+
+// Uint8PairType is provided for use as Type.
+const Uint8PairType = Uint8Pair(0)
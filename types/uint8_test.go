@@ -0,0 +1,26 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUint8Pair(t *testing.T) {
+	p := NewUint8Pair(14, 7)
+	assert.Equal(t, byte(14), p.High())
+	assert.Equal(t, byte(7), p.Low())
+	assert.Equal(t, []byte{14, 7}, p.Bytes())
+	assert.Equal(t, []byte{0, 0}, Uint8PairType.Bytes())
+	assert.Equal(t, uint16(1), p.Size())
+
+	v, err := Uint8PairType.Converter()([]byte{0xAB, 0xCD})
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0xAB), v.(Uint8Pair).High())
+	assert.Equal(t, byte(0xCD), v.(Uint8Pair).Low())
+
+	for _, l := range []int{0, 1, 4} {
+		_, err := Uint8PairType.Converter()(make([]byte, l))
+		assert.ErrorIs(t, err, ErrInvalidInput, "%d bytes", l)
+	}
+}
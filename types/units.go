@@ -0,0 +1,263 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownUnit is returned for units not registered with
+	// RegisterUnit.
+	ErrUnknownUnit = errors.New("unknown unit")
+	// ErrIncompatibleUnits is returned when converting between units
+	// of different quantities, e.g. kW and kWh.
+	ErrIncompatibleUnits = errors.New("incompatible units")
+	// ErrDuplicateUnit is returned by RegisterUnit for names already
+	// taken.
+	ErrDuplicateUnit = errors.New("unit name already registered")
+)
+
+// unit is a linear function of a base unit: a value x of the unit is
+// x * gain + offset in the base unit.
+type unit struct {
+	base         string
+	gain, offset float64
+}
+
+var units = struct {
+	sync.RWMutex
+	units map[string]unit
+}{units: map[string]unit{
+	// power
+	"W":  {"W", 1, 0},
+	"mW": {"W", 1e-3, 0},
+	"kW": {"W", 1e3, 0},
+	"MW": {"W", 1e6, 0},
+	// apparent and reactive power
+	"VA":   {"VA", 1, 0},
+	"kVA":  {"VA", 1e3, 0},
+	"MVA":  {"VA", 1e6, 0},
+	"var":  {"var", 1, 0},
+	"kvar": {"var", 1e3, 0},
+	"Mvar": {"var", 1e6, 0},
+	// energy
+	"J":     {"J", 1, 0},
+	"kJ":    {"J", 1e3, 0},
+	"MJ":    {"J", 1e6, 0},
+	"GJ":    {"J", 1e9, 0},
+	"Wh":    {"J", 3600, 0},
+	"kWh":   {"J", 3.6e6, 0},
+	"MWh":   {"J", 3.6e9, 0},
+	"VAh":   {"VAh", 1, 0},
+	"kVAh":  {"VAh", 1e3, 0},
+	"varh":  {"varh", 1, 0},
+	"kvarh": {"varh", 1e3, 0},
+	// voltage, current, resistance and frequency
+	"V":    {"V", 1, 0},
+	"mV":   {"V", 1e-3, 0},
+	"kV":   {"V", 1e3, 0},
+	"A":    {"A", 1, 0},
+	"mA":   {"A", 1e-3, 0},
+	"kA":   {"A", 1e3, 0},
+	"Ohm":  {"Ohm", 1, 0},
+	"kOhm": {"Ohm", 1e3, 0},
+	"MOhm": {"Ohm", 1e6, 0},
+	"Hz":   {"Hz", 1, 0},
+	"mHz":  {"Hz", 1e-3, 0},
+	"kHz":  {"Hz", 1e3, 0},
+	// temperature
+	"K":    {"K", 1, 0},
+	"degC": {"K", 1, 273.15},
+	"°C":   {"K", 1, 273.15},
+	"degF": {"K", 5.0 / 9, 273.15 - 32*5.0/9},
+	"°F":   {"K", 5.0 / 9, 273.15 - 32*5.0/9},
+}}
+
+// RegisterUnit makes name convertible from and to base: a value x in
+// name is x * gain + offset in base. base must already be registered;
+// if it is empty, name starts a new quantity incompatible with all the
+// others, and gain and offset are ignored. Built-in units cover common
+// electrical and thermal quantities, see UnitNames.
+func RegisterUnit(name, base string, gain, offset float64) error {
+	units.Lock()
+	defer units.Unlock()
+
+	if _, ok := units.units[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateUnit, name)
+	}
+	if base == "" {
+		units.units[name] = unit{name, 1, 0}
+		return nil
+	}
+	if gain == 0 || math.IsNaN(gain) || math.IsInf(gain, 0) || math.IsNaN(offset) || math.IsInf(offset, 0) {
+		return fmt.Errorf("%w: unit %q of gain %v and offset %v", ErrInvalidInput, name, gain, offset)
+	}
+	b, ok := units.units[base]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownUnit, base)
+	}
+	// store units relative to the root of their quantity
+	units.units[name] = unit{b.base, gain * b.gain, offset*b.gain + b.offset}
+	return nil
+}
+
+// ConvertUnit converts v from one unit to another, failing with
+// ErrUnknownUnit or ErrIncompatibleUnits.
+func ConvertUnit(v float64, from, to string) (float64, error) {
+	f, t, err := lookupUnits(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return (v*f.gain + f.offset - t.offset) / t.gain, nil
+}
+
+func lookupUnits(from, to string) (unit, unit, error) {
+	units.RLock()
+	defer units.RUnlock()
+
+	f, ok := units.units[from]
+	if !ok {
+		return unit{}, unit{}, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	t, ok := units.units[to]
+	if !ok {
+		return unit{}, unit{}, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if f.base != t.base {
+		return unit{}, unit{}, fmt.Errorf("%w: %s and %s", ErrIncompatibleUnits, from, to)
+	}
+	return f, t, nil
+}
+
+// UnitNames returns the names of all registered units in ascending
+// order.
+func UnitNames() []string {
+	units.RLock()
+	defer units.RUnlock()
+
+	names := make([]string, 0, len(units.units))
+	for name := range units.units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InUnit is a numeric value stored as Raw in the unit Device and
+// presented in the unit App, e.g. power stored in kW and used in W.
+// Reads convert from Device to App, writes from App to Device, which
+// rounds for integer types as Scaled does.
+//
+// Like Scaled, InUnit is both a Type, as returned by NewInUnit, and the
+// Value produced by its Converter. Raw must be an integer or
+// floating-point type of this package, or a Scaled.
+type InUnit struct {
+	Raw    Type
+	Device string
+	App    string
+	Value  float64
+}
+
+// NewInUnit returns an InUnit type stored as t, failing with
+// ErrUnknownUnit or ErrIncompatibleUnits if the units can't be
+// converted.
+func NewInUnit(t Type, device, app string) (InUnit, error) {
+	if _, _, err := lookupUnits(device, app); err != nil {
+		return InUnit{}, err
+	}
+	return InUnit{Raw: t, Device: device, App: app}, nil
+}
+
+// With returns a copy of u holding value in the App unit, for use in
+// writes.
+func (u InUnit) With(value float64) InUnit {
+	u.Value = value
+	return u
+}
+
+// Float64 returns the value in the App unit.
+func (u InUnit) Float64() float64 {
+	return u.Value
+}
+
+func (u InUnit) String() string {
+	return fmt.Sprintf("%v %s", u.Value, u.App)
+}
+
+func (u InUnit) Size() uint16 {
+	if u.Raw == nil {
+		return 0
+	}
+	return u.Raw.Size()
+}
+
+func (u InUnit) Bytes() []byte {
+	raw, err := u.raw()
+	if err != nil {
+		return make([]byte, u.Size()*2)
+	}
+	return raw.Bytes()
+}
+
+// Validate returns an error if the units can't be converted, Raw is not
+// numeric, or the converted value doesn't fit Raw.
+func (u InUnit) Validate() error {
+	raw, err := u.raw()
+	if err != nil {
+		return err
+	}
+	if v, ok := raw.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (u InUnit) Converter() Converter {
+	return func(b []byte) (Value, error) {
+		if u.Raw == nil {
+			return nil, fmt.Errorf("%w: no underlying type", ErrInvalidInput)
+		}
+		v, err := u.Raw.Converter()(b)
+		if err != nil {
+			return nil, err
+		}
+		x, ok := Float64Of(v)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T is not numeric", ErrInvalidInput, v)
+		}
+		x, err = ConvertUnit(x, u.Device, u.App)
+		if err != nil {
+			return nil, err
+		}
+		return u.With(x), nil
+	}
+}
+
+// raw returns the value converted to the Device unit as Raw.
+func (u InUnit) raw() (Value, error) {
+	x, err := ConvertUnit(u.Value, u.App, u.Device)
+	if err != nil {
+		return nil, err
+	}
+	switch t := u.Raw.(type) {
+	case nil:
+		return nil, fmt.Errorf("%w: no underlying type", ErrInvalidInput)
+	case Scaled:
+		return t.With(x), nil
+	}
+	if _, ok := u.Raw.(Value); ok {
+		switch kind := reflect.ValueOf(u.Raw).Kind(); {
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			raw := reflect.New(reflect.TypeOf(u.Raw)).Elem()
+			raw.SetFloat(x)
+			return raw.Interface().(Value), nil
+		case isSigned(kind) || isUnsigned(kind):
+			return NewScaled(u.Raw, 1, 0).With(x), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: underlying type %T is not numeric", ErrInvalidInput, u.Raw)
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		v        float64
+		from, to string
+		want     float64
+	}{
+		{1.5, "kW", "W", 1500},
+		{1500, "W", "kW", 1.5},
+		{2, "MW", "kW", 2000},
+		{1, "kWh", "J", 3.6e6},
+		{7.2e6, "J", "kWh", 2},
+		{250, "var", "kvar", 0.25},
+		{0.4, "kV", "V", 400},
+		{1200, "mA", "A", 1.2},
+		{50, "Hz", "mHz", 50000},
+		{0, "degC", "K", 273.15},
+		{100, "degC", "degF", 212},
+		{-40, "degF", "°C", -40},
+		{300, "K", "degC", 26.85},
+		{42, "W", "W", 42},
+	}
+	for _, tt := range tests {
+		got, err := ConvertUnit(tt.v, tt.from, tt.to)
+		assert.NoError(t, err, "%v %s to %s", tt.v, tt.from, tt.to)
+		assert.InDelta(t, tt.want, got, 1e-9, "%v %s to %s", tt.v, tt.from, tt.to)
+	}
+}
+
+func TestConvertUnit_errors(t *testing.T) {
+	tests := []struct {
+		from, to string
+		err      error
+	}{
+		{"kW", "kWh", ErrIncompatibleUnits},
+		{"VA", "W", ErrIncompatibleUnits},
+		{"degC", "V", ErrIncompatibleUnits},
+		{"kW", "horsepower", ErrUnknownUnit},
+		{"furlong", "m", ErrUnknownUnit},
+	}
+	for _, tt := range tests {
+		_, err := ConvertUnit(1, tt.from, tt.to)
+		assert.ErrorIs(t, err, tt.err, "%s to %s", tt.from, tt.to)
+		_, err = NewInUnit(Uint16Type, tt.from, tt.to)
+		assert.ErrorIs(t, err, tt.err, "%s to %s", tt.from, tt.to)
+	}
+}
+
+func TestRegisterUnit(t *testing.T) {
+	assert.NoError(t, RegisterUnit("test_GW", "MW", 1000, 0))
+	v, err := ConvertUnit(1.5, "test_GW", "kW")
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.5e6, v, 1e-6)
+
+	assert.NoError(t, RegisterUnit("test_rpm", "", 0, 0))
+	assert.NoError(t, RegisterUnit("test_rps", "test_rpm", 60, 0))
+	v, err = ConvertUnit(3, "test_rps", "test_rpm")
+	assert.NoError(t, err)
+	assert.InDelta(t, 180, v, 1e-9)
+	_, err = ConvertUnit(3, "test_rpm", "Hz")
+	assert.ErrorIs(t, err, ErrIncompatibleUnits)
+
+	assert.ErrorIs(t, RegisterUnit("kW", "W", 1000, 0), ErrDuplicateUnit)
+	assert.ErrorIs(t, RegisterUnit("test_x", "test_unknown", 1, 0), ErrUnknownUnit)
+	assert.ErrorIs(t, RegisterUnit("test_y", "W", 0, 0), ErrInvalidInput)
+	assert.Contains(t, UnitNames(), "test_rps")
+}
+
+func TestInUnit(t *testing.T) {
+	kw, err := NewInUnit(Uint16Type, "kW", "W")
+	assert.NoError(t, err)
+	tenths, err := NewInUnit(NewScaled(Int16Type, 0.1, 0), "degC", "K")
+	assert.NoError(t, err)
+	float, err := NewInUnit(Float32Type, "kWh", "Wh")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		t     InUnit
+		bytes []byte
+		want  float64
+	}{
+		{"integer", kw, []byte{0x00, 0x0C}, 12000},
+		{"scaled", tenths, []byte{0x00, 0xFB}, 298.25},
+		{"float", float, Float32(1.5).Bytes(), 1500},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.t.Raw.Size(), tt.t.Size(), tt.name)
+		// device to application unit
+		v, err := tt.t.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.InDelta(t, tt.want, v.(InUnit).Float64(), 1e-9, tt.name)
+		assert.Equal(t, tt.bytes, v.(InUnit).Bytes(), "%s: round trip", tt.name)
+		// application to device unit
+		assert.Equal(t, tt.bytes, tt.t.With(tt.want).Bytes(), tt.name)
+		assert.NoError(t, tt.t.With(tt.want).Validate(), tt.name)
+	}
+
+	assert.Equal(t, "12000 W", kw.With(12000).String())
+	// 12.4 kW rounds to the device resolution
+	assert.Equal(t, []byte{0x00, 0x0C}, kw.With(12400).Bytes())
+	assert.ErrorIs(t, kw.With(-1000).Validate(), ErrScaledRange)
+	assert.ErrorIs(t, InUnit{Raw: BoolType, Device: "W", App: "W"}.Validate(), ErrInvalidInput)
+	assert.ErrorIs(t, InUnit{Raw: Uint16Type, Device: "W", App: "kWh"}.Validate(), ErrIncompatibleUnits)
+}
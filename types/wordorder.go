@@ -0,0 +1,112 @@
+package types
+
+import "fmt"
+
+// WordOrder is the order of bytes of a multi-register value on the wire,
+// named after the order of the bytes ABCD of a big endian 32-bit value.
+// For longer values the orders generalize as reversing the words, the
+// bytes of every word, or both.
+type WordOrder int
+
+const (
+	// ABCD is the regular big endian order.
+	ABCD WordOrder = iota
+	// CDAB reverses the order of words.
+	CDAB
+	// BADC swaps the bytes of every word.
+	BADC
+	// DCBA reverses the order of bytes, i.e. little endian.
+	DCBA
+)
+
+func (o WordOrder) String() string {
+	switch o {
+	case ABCD:
+		return "ABCD"
+	case CDAB:
+		return "CDAB"
+	case BADC:
+		return "BADC"
+	case DCBA:
+		return "DCBA"
+	}
+	return fmt.Sprintf("WordOrder(%d)", int(o))
+}
+
+// apply converts b between the regular order and o. Every order is its
+// own inverse, so apply converts both ways.
+func (o WordOrder) apply(b []byte) []byte {
+	switch o {
+	case CDAB:
+		return swapWords(b)
+	case BADC:
+		return swapBytes(b)
+	case DCBA:
+		return swapBytes(swapWords(b))
+	}
+	return append([]byte(nil), b...)
+}
+
+// swapBytes returns a copy of b with the bytes of every 16-bit word
+// swapped.
+func swapBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := 0; i+1 < len(b); i += 2 {
+		r[i], r[i+1] = b[i+1], b[i]
+	}
+	return r
+}
+
+// Ordered is a value of Inner transmitted in a different word order.
+type Ordered struct {
+	Inner Type
+	Order WordOrder
+	// Value is the value of Inner, nil for the zero value.
+	Value Value
+}
+
+// WithWordOrder returns a Type reading and writing values of inner in
+// order.
+func WithWordOrder(inner Type, order WordOrder) Ordered {
+	return Ordered{Inner: inner, Order: order}
+}
+
+// With returns the type holding v, a value of Inner.
+func (o Ordered) With(v Value) Ordered {
+	o.Value = v
+	return o
+}
+
+func (o Ordered) Bytes() []byte {
+	if o.Value == nil {
+		return make([]byte, int(o.Size())*2)
+	}
+	return o.Order.apply(o.Value.Bytes())
+}
+
+func (o Ordered) Size() uint16 {
+	return o.Inner.Size()
+}
+
+func (o Ordered) String() string {
+	return fmt.Sprintf("%v (%v)", o.Value, o.Order)
+}
+
+// Validate delegates to Value if it implements Validator.
+func (o Ordered) Validate() error {
+	if v, ok := o.Value.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (o Ordered) Converter() Converter {
+	convert := o.Inner.Converter()
+	return func(b []byte) (Value, error) {
+		v, err := convert(o.Order.apply(b))
+		if err != nil {
+			return nil, err
+		}
+		return o.With(v), nil
+	}
+}
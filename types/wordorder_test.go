@@ -0,0 +1,66 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithWordOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		inner Type
+		value Value
+		order WordOrder
+		bytes []byte
+	}{
+		{"uint32 ABCD", Uint32Type, Uint32(0x01020304), ABCD, []byte{1, 2, 3, 4}},
+		{"uint32 CDAB", Uint32Type, Uint32(0x01020304), CDAB, []byte{3, 4, 1, 2}},
+		{"uint32 BADC", Uint32Type, Uint32(0x01020304), BADC, []byte{2, 1, 4, 3}},
+		{"uint32 DCBA", Uint32Type, Uint32(0x01020304), DCBA, []byte{4, 3, 2, 1}},
+		{"float32 ABCD", Float32Type, Float32(-2.5), ABCD, []byte{0xC0, 0x20, 0, 0}},
+		{"float32 CDAB", Float32Type, Float32(-2.5), CDAB, []byte{0, 0, 0xC0, 0x20}},
+		{"float32 BADC", Float32Type, Float32(-2.5), BADC, []byte{0x20, 0xC0, 0, 0}},
+		{"float32 DCBA", Float32Type, Float32(-2.5), DCBA, []byte{0, 0, 0x20, 0xC0}},
+		{"uint64 CDAB", Uint64Type, Uint64(0x0102030405060708), CDAB, []byte{7, 8, 5, 6, 3, 4, 1, 2}},
+	}
+	for _, tt := range tests {
+		ordered := WithWordOrder(tt.inner, tt.order)
+		assert.Equal(t, tt.inner.Size(), ordered.Size(), tt.name)
+		assert.Equal(t, tt.bytes, ordered.With(tt.value).Bytes(), tt.name)
+
+		v, err := ordered.Converter()(tt.bytes)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.value, v.(Ordered).Value, tt.name)
+		assert.Equal(t, tt.bytes, v.Bytes(), "%s: round trip", tt.name)
+	}
+}
+
+func TestWithWordOrder_errors(t *testing.T) {
+	ordered := WithWordOrder(Uint32Type, DCBA)
+	assert.Equal(t, []byte{0, 0, 0, 0}, ordered.Bytes(), "zero value")
+
+	_, err := ordered.Converter()([]byte{1, 2})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	bcd := WithWordOrder(BCD32Type, CDAB)
+	assert.ErrorIs(t, bcd.With(BCD32(100000000)).Validate(), ErrBCDRange)
+	assert.NoError(t, bcd.With(BCD32(1234)).Validate())
+}
+
+func TestFloat32_wordOrders(t *testing.T) {
+	tests := []struct {
+		t     Type
+		order WordOrder
+	}{
+		{Float32CDABType, CDAB},
+		{Float32BADCType, BADC},
+		{Float32DCBAType, DCBA},
+	}
+	for _, tt := range tests {
+		b := WithWordOrder(Float32Type, tt.order).With(Float32(3.25)).Bytes()
+		v, err := tt.t.Converter()(b)
+		assert.NoError(t, err, "%v", tt.order)
+		assert.Equal(t, b, v.Bytes(), "%v", tt.order)
+	}
+}
@@ -0,0 +1,102 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrUnitConflict is returned for batches of ops addressed to different
+// units.
+var ErrUnitConflict = errors.New("ops of a batch addressed to different units")
+
+// ErrUnitUnsupported is returned for unit batches on handlers whose
+// slave ID can't be switched, see UnitHandler.
+var ErrUnitUnsupported = errors.New("handler can't switch units")
+
+// Unit may be implemented by Read and Write ops addressed to a unit
+// (slave ID) other than the one of the handler, e.g. meters behind a
+// shared RS-485 gateway. BatchRead and BatchWrite address such ops to
+// their unit. All the ops of a batch must be addressed to the same
+// unit, so requests are never merged across units; batch ops of
+// different units separately, or with BatchReadUnit and BatchWriteUnit.
+type Unit interface {
+	UnitID() byte
+}
+
+// UnitHandler may be implemented by handlers to have the client switch
+// units. Otherwise, the client sets the SlaveId field of the handler,
+// as found in the TCP, RTU and ASCII handlers of goburrow/modbus.
+type UnitHandler interface {
+	SlaveID() byte
+	SetSlaveID(id byte)
+}
+
+// BatchReadUnit is BatchRead addressed to unit. Ops implementing Unit
+// must agree with it.
+func (c *Client) BatchReadUnit(unit byte, ops []Read) (Registers, error) {
+	return c.BatchReadWith(ops, BatchOptions{Unit: &unit})
+}
+
+// BatchWriteUnit is BatchWrite addressed to unit. Ops implementing Unit
+// must agree with it.
+func (c *Client) BatchWriteUnit(unit byte, ops []Write, oldData Registers) error {
+	return c.BatchWriteWith(ops, oldData, BatchOptions{Unit: &unit})
+}
+
+// batchUnit returns the unit a batch of n ops is addressed to, if any:
+// unit if not nil, otherwise the unit of the ops implementing Unit.
+func batchUnit(unit *byte, n int, op func(i int) interface{}) (*byte, error) {
+	for i := 0; i < n; i++ {
+		u, ok := op(i).(Unit)
+		if !ok {
+			continue
+		}
+		id := u.UnitID()
+		if unit == nil {
+			unit = &id
+		}
+		if id != *unit {
+			return nil, fmt.Errorf("%w: unit %d and %d: %v", ErrUnitConflict, *unit, id, op(i))
+		}
+	}
+	return unit, nil
+}
+
+// address switches the handler to unit if not nil until restore is
+// called. Chunks read meanwhile are cached for unit, apart from the
+// chunks of the unit of the handler. The caller must hold the client
+// mutex.
+func (c *Client) address(unit *byte) (restore func(), err error) {
+	if unit == nil {
+		return func() {}, nil
+	}
+	get, set, ok := slaveID(c.ClientHandler)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnitUnsupported, c.ClientHandler)
+	}
+	previous := get()
+	set(*unit)
+	id := *unit
+	c.unit = &id
+	return func() {
+		set(previous)
+		c.unit = nil
+	}, nil
+}
+
+// slaveID returns accessors of the slave ID of handler.
+func slaveID(handler interface{}) (get func() byte, set func(byte), ok bool) {
+	if h, ok := handler.(UnitHandler); ok {
+		return h.SlaveID, h.SetSlaveID, true
+	}
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, nil, false
+	}
+	f := v.Elem().FieldByName("SlaveId")
+	if !f.IsValid() || !f.CanSet() || f.Kind() != reflect.Uint8 {
+		return nil, nil, false
+	}
+	return func() byte { return byte(f.Uint()) }, func(id byte) { f.SetUint(uint64(id)) }, true
+}
@@ -0,0 +1,131 @@
+package modbus_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// unitSlave routes requests to the testSlave of the unit selected by
+// SlaveId, like a gateway in front of several slaves.
+type unitSlave struct {
+	SlaveId byte
+	units   map[byte]*testSlave
+}
+
+func newUnitSlave(units ...byte) *unitSlave {
+	s := &unitSlave{units: make(map[byte]*testSlave)}
+	for _, u := range units {
+		s.units[u] = newTestSlave()
+	}
+	return s
+}
+
+func (s *unitSlave) Encode(pdu *goburrow.ProtocolDataUnit) ([]byte, error) {
+	return s.units[s.SlaveId].Encode(pdu)
+}
+
+func (s *unitSlave) Decode(adu []byte) (*goburrow.ProtocolDataUnit, error) {
+	return s.units[s.SlaveId].Decode(adu)
+}
+
+func (s *unitSlave) Verify(aduRequest, aduResponse []byte) error {
+	return nil
+}
+
+func (s *unitSlave) Send(adu []byte) ([]byte, error) {
+	return s.units[s.SlaveId].Send(adu)
+}
+
+type unitRead struct {
+	modbus.Read
+	unit byte
+}
+
+func (r unitRead) UnitID() byte { return r.unit }
+
+type unitWrite struct {
+	modbus.Write
+	unit byte
+}
+
+func (w unitWrite) UnitID() byte { return w.unit }
+
+func TestClient_BatchReadUnit(t *testing.T) {
+	slave := newUnitSlave(0, 1, 2)
+	for u, s := range slave.units {
+		s.set(10, 0, u, 0, u+10)
+	}
+	client := modbus.NewClient(slave, modbus.WithChunkCache(time.Minute))
+	ops := []modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint16Type}}
+
+	res, err := client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(0), 11: types.Uint16(10)}, res)
+	res, err = client.BatchReadUnit(1, ops)
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(1), 11: types.Uint16(11)}, res, "chunks cached for unit 0 are not used")
+	_, err = client.BatchReadUnit(1, ops)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, slave.units[1].calls(), "chunks are cached per unit")
+	res, err = client.BatchRead([]modbus.Read{unitRead{ops[0], 2}, unitRead{ops[1], 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, modbus.Registers{10: types.Uint16(2), 11: types.Uint16(12)}, res)
+	assert.Equal(t, 1, slave.units[2].calls(), "ops of one unit are merged")
+	assert.Zero(t, slave.SlaveId, "the unit is restored")
+
+	assert.NoError(t, client.BatchWriteUnit(2, []modbus.Write{testWrite{10, types.Uint16(5)}}, nil))
+	assert.Equal(t, []byte{0, 5}, slave.units[2].get(10, 1))
+	assert.Equal(t, []byte{0, 0}, slave.units[0].get(10, 1))
+
+	_, err = client.BatchRead([]modbus.Read{unitRead{ops[0], 1}, unitRead{ops[1], 2}})
+	assert.ErrorIs(t, err, modbus.ErrUnitConflict)
+	_, err = client.BatchReadUnit(1, []modbus.Read{unitRead{ops[0], 2}})
+	assert.ErrorIs(t, err, modbus.ErrUnitConflict)
+	err = client.BatchWrite([]modbus.Write{unitWrite{testWrite{10, types.Uint16(1)}, 1}, unitWrite{testWrite{11, types.Uint16(1)}, 2}}, nil)
+	assert.ErrorIs(t, err, modbus.ErrUnitConflict)
+
+	_, err = modbus.NewClient(newTestSlave()).BatchReadUnit(1, ops)
+	assert.ErrorIs(t, err, modbus.ErrUnitUnsupported)
+}
+
+func TestClient_BatchReadUnit_concurrent(t *testing.T) {
+	const rounds = 100
+	modbus.SweepChaos(t, modbus.ChaosOptions{}, func(t *testing.T, chaos func(*modbus.Client)) {
+		slave := newUnitSlave(0, 1, 2)
+		client := modbus.NewClient(slave)
+		chaos(client)
+
+		// both units have the same registers, each written by its own
+		// goroutine only
+		var wg sync.WaitGroup
+		for _, unit := range []byte{1, 2} {
+			unit := unit
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 1; i <= rounds; i++ {
+					v := types.Uint16(int(unit)*1000 + i)
+					err := client.BatchWriteUnit(unit, []modbus.Write{testWrite{10, v}, testWrite{11, v}}, nil)
+					if !assert.NoError(t, err) {
+						return
+					}
+					res, err := client.BatchRead([]modbus.Read{unitRead{testRead{10, types.Uint16Type}, unit}, unitRead{testRead{11, types.Uint16Type}, unit}})
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.Equal(t, modbus.Registers{10: v, 11: v}, res, "unit %d", unit)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Zero(t, slave.units[0].calls(), "nothing is sent to the default unit")
+		assert.Zero(t, slave.SlaveId)
+	})
+}
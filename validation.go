@@ -0,0 +1,119 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrValidationFailed is returned when decoded values violate a
+// ValidationRule.
+var ErrValidationFailed = errors.New("response validation failed")
+
+// ValidationRule is an invariant of decoded values of a register range,
+// e.g. that a status register holds one of a few known values. A
+// violation marks all the values of the range read by the batch as
+// bad.
+type ValidationRule struct {
+	Name  string
+	Range RegisterRange
+	// Check is called with the decoded values of ops starting within
+	// Range whenever a batch reads any of them, and returns an error if
+	// they are not valid. Values of ops not in the batch are missing
+	// from window.
+	Check func(window Registers) error
+	// CheckContext is Check receiving the context of the batch, see
+	// BatchOptions.Context. It is called instead of Check if set, and
+	// must not block past cancellation of ctx.
+	CheckContext func(ctx context.Context, window Registers) error
+	// Strict makes a violation fail the whole batch instead of only
+	// dropping the values of Range.
+	Strict bool
+}
+
+// WithValidationRules adds rules checked against the decoded values of
+// every BatchRead. Values of ranges violating a rule are dropped from
+// the result, which is returned along with a *ValidationError.
+func WithValidationRules(rules ...ValidationRule) Option {
+	return func(c *Config) {
+		c.ValidationRules = append(c.ValidationRules[:len(c.ValidationRules):len(c.ValidationRules)], rules...)
+	}
+}
+
+// Violation is a failed ValidationRule.
+type Violation struct {
+	Rule ValidationRule
+	Err  error
+}
+
+// ValidationError lists the rules violated by a batch. BatchRead
+// returns it along with the values that passed validation, unless a
+// strict rule was violated.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s (registers %v): %v", v.Rule.Name, v.Rule.Range.rng(), v.Err)
+	}
+	return fmt.Sprintf("%v: %s", ErrValidationFailed, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidationFailed
+}
+
+// Strict reports whether a strict rule was violated.
+func (e *ValidationError) Strict() bool {
+	for _, v := range e.Violations {
+		if v.Rule.Strict {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks res against rules and drops the values of violated
+// ranges from it.
+func validate(ctx context.Context, rules []ValidationRule, res Registers) *ValidationError {
+	var violations []Violation
+	var bad []RegisterRange
+	for _, rule := range rules {
+		window := make(Registers)
+		for register, value := range res {
+			if rule.Range.Contains(register) {
+				window[register] = value
+			}
+		}
+		if len(window) == 0 {
+			continue
+		}
+		if err := rule.check(ctx, window); err != nil {
+			violations = append(violations, Violation{rule, err})
+			bad = append(bad, rule.Range)
+		}
+	}
+	if violations == nil {
+		return nil
+	}
+
+	for register := range res {
+		for _, r := range bad {
+			if r.Contains(register) {
+				delete(res, register)
+				break
+			}
+		}
+	}
+	return &ValidationError{violations}
+}
+
+func (r ValidationRule) check(ctx context.Context, window Registers) error {
+	if r.CheckContext != nil {
+		return r.CheckContext(ctx, window)
+	}
+	return r.Check(window)
+}
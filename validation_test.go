@@ -0,0 +1,185 @@
+package modbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchRead_validationRules(t *testing.T) {
+	status := modbus.ValidationRule{
+		Name:  "status block",
+		Range: modbus.RegisterRange{Register: 1000, Quantity: 10},
+		Check: func(w modbus.Registers) error {
+			if s, ok := w[1000]; ok && s.(types.Uint16) > 2 {
+				return fmt.Errorf("status %d", s)
+			}
+			if c, ok := w[1002]; ok && c.(types.Uint16) > 500 {
+				return fmt.Errorf("count %d", c)
+			}
+			return nil
+		},
+	}
+	ops := []modbus.Read{
+		testRead{1000, types.Uint16Type},
+		testRead{1002, types.Uint16Type},
+		testRead{1005, types.Uint32Type},
+		testRead{2000, types.Uint16Type},
+	}
+	tests := []struct {
+		name       string
+		status     uint16
+		count      uint16
+		strict     bool
+		ops        []modbus.Read
+		want       modbus.Registers
+		violations int
+	}{
+		{
+			name:   "passing",
+			status: 2,
+			count:  500,
+			ops:    ops,
+			want: modbus.Registers{
+				1000: types.Uint16(2),
+				1002: types.Uint16(500),
+				1005: types.Uint32(7),
+				2000: types.Uint16(9),
+			},
+		},
+		{
+			name:       "failing status",
+			status:     3,
+			ops:        ops,
+			want:       modbus.Registers{2000: types.Uint16(9)},
+			violations: 1,
+		},
+		{
+			name:       "failing count",
+			count:      501,
+			ops:        ops,
+			want:       modbus.Registers{2000: types.Uint16(9)},
+			violations: 1,
+		},
+		{
+			name:       "strict",
+			count:      501,
+			strict:     true,
+			ops:        ops,
+			violations: 1,
+		},
+		{
+			name:   "partial window",
+			status: 3,
+			ops:    []modbus.Read{testRead{1002, types.Uint16Type}, testRead{2000, types.Uint16Type}},
+			want:   modbus.Registers{1002: types.Uint16(0), 2000: types.Uint16(9)},
+		},
+		{
+			name:   "not read",
+			status: 3,
+			strict: true,
+			ops:    []modbus.Read{testRead{2000, types.Uint16Type}},
+			want:   modbus.Registers{2000: types.Uint16(9)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := newTestSlave()
+			slave.set(1000, types.Uint16(tt.status).Bytes()...)
+			slave.set(1002, types.Uint16(tt.count).Bytes()...)
+			slave.set(1005, types.Uint32(7).Bytes()...)
+			slave.set(2000, types.Uint16(9).Bytes()...)
+			rule := status
+			rule.Strict = tt.strict
+			client := modbus.NewClient(slave, modbus.WithValidationRules(rule))
+
+			res, err := client.BatchRead(tt.ops)
+			assert.Equal(t, tt.want, res)
+			if tt.violations == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, modbus.ErrValidationFailed))
+			var verr *modbus.ValidationError
+			if assert.True(t, errors.As(err, &verr)) {
+				assert.Len(t, verr.Violations, tt.violations)
+				assert.Equal(t, "status block", verr.Violations[0].Rule.Name)
+				assert.Equal(t, tt.strict, verr.Strict())
+			}
+		})
+	}
+}
+
+func TestClient_BatchRead_validationRulesCrossRange(t *testing.T) {
+	slave := newTestSlave()
+	slave.set(10, 0, 5, 0, 3)
+	client := modbus.NewClient(slave, modbus.WithValidationRules(
+		modbus.ValidationRule{
+			Name:  "min below max",
+			Range: modbus.RegisterRange{Register: 10, Quantity: 2},
+			Check: func(w modbus.Registers) error {
+				if w[10].(types.Uint16) > w[11].(types.Uint16) {
+					return errors.New("min exceeds max")
+				}
+				return nil
+			},
+		},
+		modbus.ValidationRule{
+			Name:  "always valid",
+			Range: modbus.RegisterRange{Register: 12, Quantity: 1},
+			Check: func(modbus.Registers) error { return nil },
+		},
+	))
+
+	res, err := client.BatchRead([]modbus.Read{
+		testRead{10, types.Uint16Type},
+		testRead{11, types.Uint16Type},
+		testRead{12, types.Uint16Type},
+	})
+	assert.EqualError(t, err, "response validation failed: min below max (registers 10-11): min exceeds max")
+	assert.Equal(t, modbus.Registers{12: types.Uint16(0)}, res)
+}
+
+type contextKey struct{}
+
+func TestClient_BatchReadWith_context(t *testing.T) {
+	var observed []interface{}
+	client := modbus.NewClient(newTestSlave(), modbus.WithValidationRules(
+		modbus.ValidationRule{
+			Name:  "with context",
+			Range: modbus.RegisterRange{Register: 10, Quantity: 1},
+			CheckContext: func(ctx context.Context, _ modbus.Registers) error {
+				observed = append(observed, ctx.Value(contextKey{}))
+				return nil
+			},
+			Check: func(modbus.Registers) error {
+				t.Error("Check is called instead of CheckContext")
+				return nil
+			},
+		},
+		modbus.ValidationRule{
+			Name:  "without context",
+			Range: modbus.RegisterRange{Register: 11, Quantity: 1},
+			Check: func(modbus.Registers) error {
+				observed = append(observed, "legacy")
+				return nil
+			},
+		},
+	))
+	ops := []modbus.Read{testRead{10, types.Uint16Type}, testRead{11, types.Uint16Type}}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "trace")
+	_, err := client.BatchReadWith(ops, modbus.BatchOptions{Context: ctx})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"trace", "legacy"}, observed)
+
+	observed = nil
+	_, err = client.BatchRead(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, "legacy"}, observed, "background context by default")
+}
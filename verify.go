@@ -0,0 +1,329 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriteVerificationFailed is wrapped by a *VerificationError.
+var ErrWriteVerificationFailed = errors.New("write verification failed")
+
+// VerificationError is returned when written registers don't read back
+// the written values.
+type VerificationError struct {
+	// Registers are the registers that didn't read back their written
+	// values on the last verification read, in ascending order.
+	Registers []uint16
+	// Reads is the number of verification reads made.
+	Reads int
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%v: registers %v after %d reads", ErrWriteVerificationFailed, e.Registers, e.Reads)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return ErrWriteVerificationFailed
+}
+
+// Unverified may be implemented by Write ops for registers that
+// legitimately don't read back the written value, such as registers
+// the slave modifies on its own. Their registers are excluded from
+// verification, see Verify.
+type Unverified interface {
+	Unverified() bool
+}
+
+// UnverifiedWrite excludes the registers of w from verification, see
+// Unverified.
+func UnverifiedWrite(w Write) Write {
+	return unverifiedWrite{w}
+}
+
+type unverifiedWrite struct {
+	Write
+}
+
+func (unverifiedWrite) Unverified() bool {
+	return true
+}
+
+func (w unverifiedWrite) String() string {
+	return fmt.Sprintf("unverified %v", w.Write)
+}
+
+// unverifiedRanges returns the registers of ops excluded from
+// verification.
+func unverifiedRanges(ops []Write) []RegisterRange {
+	var res []RegisterRange
+	for _, op := range ops {
+		if u, ok := op.(Unverified); ok && u.Unverified() && op.Value() != nil {
+			res = append(res, RegisterRange{op.Register(), uint16(len(op.Value().Bytes()) / 2)})
+		}
+	}
+	return res
+}
+
+// Verify configures reading back registers after writes, for slaves
+// that acknowledge function 16 before the values are applied
+// internally, or that may not apply them at all. Verification never
+// writes registers again: once all the verification reads are done
+// without seeing the written values, the write fails with a
+// *VerificationError.
+//
+// Written registers are read back with function 3 requests merged as in
+// BatchRead, except for the registers of Unverified ops.
+//
+// The client mutex is held until verification is complete, so other
+// operations of the client never see registers that are not settled
+// yet.
+type Verify struct {
+	// Settle is slept before the first verification read.
+	Settle time.Duration
+	// Retries is the number of verification reads made after the first
+	// one if it doesn't match.
+	Retries int
+	// Interval is slept between verification reads.
+	Interval time.Duration
+	// Adaptive delays the first verification read by the settle time
+	// learned from previous verifications if it is longer than Settle.
+	// See Client.SettleEstimate.
+	Adaptive bool
+}
+
+// WithVerify enables verification of writes.
+func WithVerify(v Verify) Option {
+	return func(c *Config) {
+		c.Verify = &v
+	}
+}
+
+// settleTracker learns the time the slave needs to apply writes. The
+// estimate follows the time the values were first seen after a write,
+// and shrinks slowly while they are seen on the first read, so that it
+// doesn't stay overestimated.
+type settleTracker struct {
+	estimate int64
+}
+
+func (s *settleTracker) get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.estimate))
+}
+
+func (s *settleTracker) observe(d time.Duration, first bool) {
+	current := s.get()
+	if first {
+		d = current - current/8
+		if d < 0 {
+			d = 0
+		}
+	}
+	atomic.StoreInt64(&s.estimate, int64(d))
+}
+
+// SettleEstimate returns the time the slave was observed to need to
+// apply writes, as learned from write verification.
+func (c *Client) SettleEstimate() time.Duration {
+	return c.settle.get()
+}
+
+// verify reads back ops written at written as set by cfg, stopping
+// once ctx is done. Ops are read with the requests of plan containing
+// them, and with merged requests otherwise. Requests of plan not
+// containing any op are not made. The caller must hold the client
+// mutex.
+func (c *Client) verify(ctx context.Context, cfg batchConfig, ops []writeOp, plan []readOp, written time.Time) error {
+	v := cfg.Verify
+	if v == nil {
+		return nil
+	}
+	ops = verifiable(ops, cfg.unverified)
+	if len(ops) == 0 {
+		return nil
+	}
+	delay := v.Settle
+	if estimate := c.settle.get(); v.Adaptive && estimate > delay {
+		delay = estimate
+	}
+	time.Sleep(time.Until(written.Add(delay)))
+
+	for i := 0; ; i++ {
+		pending := ops[:0:0]
+		var mismatched []uint16
+		chunks, err := verifyPlan(ops, plan, c.readRegions(), cfg.Limits.read())
+		if err != nil {
+			return fmt.Errorf("verification: %w", err)
+		}
+		for n, chunk := range chunks {
+			if err := c.pace(ctx, n); err != nil {
+				return fmt.Errorf("verification: %w", err)
+			}
+			b, err := c.read(chunk.read, cfg.Retry)
+			if err != nil {
+				return fmt.Errorf("verification read at %d: %w", chunk.read.register, err)
+			}
+			for _, op := range chunk.ops {
+				if registers := mismatches(b, chunk.read, op, cfg.unverified); len(registers) > 0 {
+					pending = append(pending, op)
+					mismatched = append(mismatched, registers...)
+				}
+			}
+		}
+		if len(pending) == 0 {
+			c.settle.observe(time.Since(written), i == 0)
+			return nil
+		}
+		if i >= v.Retries {
+			sort.Slice(mismatched, func(i, j int) bool { return mismatched[i] < mismatched[j] })
+			return &VerificationError{mismatched, i + 1}
+		}
+		ops = pending
+		time.Sleep(v.Interval)
+	}
+}
+
+// verifiable returns ops with registers outside of unverified.
+func verifiable(ops []writeOp, unverified []RegisterRange) []writeOp {
+	if len(unverified) == 0 {
+		return ops
+	}
+	res := ops[:0:0]
+	for _, op := range ops {
+		for r := int(op.register); r < int(op.register)+int(op.quantity); r++ {
+			if !excluded(unverified, uint16(r)) {
+				res = append(res, op)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// mismatches returns the registers of op not holding the written values
+// in b, the response to read.
+func mismatches(b []byte, read readOp, op writeOp, unverified []RegisterRange) []uint16 {
+	var res []uint16
+	for k := 0; k < int(op.quantity); k++ {
+		register := op.register + uint16(k)
+		offset := int(register-read.register) * 2
+		if !excluded(unverified, register) && !bytes.Equal(b[offset:offset+2], op.value[k*2:k*2+2]) {
+			res = append(res, register)
+		}
+	}
+	return res
+}
+
+func excluded(unverified []RegisterRange, register uint16) bool {
+	for _, r := range unverified {
+		if r.Contains(register) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyChunk is a verification read request and the ops it covers.
+type verifyChunk struct {
+	read readOp
+	ops  []writeOp
+}
+
+// verifyPlan assigns ops to the first request of plan containing them,
+// in the order of plan, followed by requests merging the ops not
+// contained in any, as BatchRead would with regions and max. Requests
+// of more than max registers are split, as are the ops they cover.
+func verifyPlan(ops []writeOp, plan []readOp, regions []SlowRange, max uint16) ([]verifyChunk, error) {
+	chunks := make([]verifyChunk, len(plan))
+	for i, r := range plan {
+		chunks[i].read = r
+	}
+	var rest []writeOp
+	var reads []readOp
+	for _, op := range ops {
+		if i := containing(plan, op); i >= 0 {
+			chunks[i].ops = append(chunks[i].ops, op)
+			continue
+		}
+		rest = append(rest, op)
+		reads = append(reads, readOp{op.register, op.quantity, HoldingRegisters})
+	}
+
+	res := chunks[:0]
+	for _, chunk := range chunks {
+		if len(chunk.ops) > 0 {
+			res = append(res, chunk)
+		}
+	}
+	merged := optimizeRead(reads, regions, max, 0)
+	own := make([]verifyChunk, len(merged))
+	for i, r := range merged {
+		own[i].read = r
+	}
+	for _, op := range rest {
+		if i := containing(merged, op); i >= 0 {
+			own[i].ops = append(own[i].ops, op)
+			continue
+		}
+		own = append(own, verifyChunk{readOp{op.register, op.quantity, HoldingRegisters}, []writeOp{op}})
+	}
+	for _, chunk := range own {
+		if len(chunk.ops) > 0 {
+			res = append(res, chunk)
+		}
+	}
+
+	split := res[:0:0]
+	for _, chunk := range res {
+		if chunk.read.quantity <= max {
+			split = append(split, chunk)
+			continue
+		}
+		pieces, err := splitRead(chunk.read, max)
+		if err != nil {
+			return nil, err
+		}
+		for _, piece := range pieces {
+			if c := cutChunk(chunk.ops, piece); len(c.ops) > 0 {
+				split = append(split, c)
+			}
+		}
+	}
+	return split, nil
+}
+
+// cutChunk returns the chunk of read with the registers of ops it
+// covers.
+func cutChunk(ops []writeOp, read readOp) verifyChunk {
+	res := verifyChunk{read: read}
+	for _, op := range ops {
+		start, end := int(op.register), op.rng().End()
+		if int(read.register) > start {
+			start = int(read.register)
+		}
+		if read.rng().End() < end {
+			end = read.rng().End()
+		}
+		if start >= end {
+			continue
+		}
+		offset := (start - int(op.register)) * 2
+		res.ops = append(res.ops, writeOp{uint16(start), uint16(end - start), op.value[offset : offset+(end-start)*2]})
+	}
+	return res
+}
+
+// containing returns the index of the first holding register read of
+// plan containing op, or -1.
+func containing(plan []readOp, op writeOp) int {
+	for i, r := range plan {
+		if r.space == HoldingRegisters && r.rng().ContainsRange(op.rng()) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,216 @@
+package modbus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	goburrow "github.com/goburrow/modbus"
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/modbustest"
+	"github.com/tdemin/opmodbus/types"
+)
+
+func TestClient_BatchWrite_verify(t *testing.T) {
+	slave := newTestSlave()
+	slave.lag = 30 * time.Millisecond
+	client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{
+		Retries:  20,
+		Interval: 5 * time.Millisecond,
+		Adaptive: true,
+	}))
+	ops := []modbus.Write{
+		testWrite{10, types.Uint16(1)},
+		testWrite{11, types.Uint16(2)},
+		testWrite{20, types.Uint32(3)},
+	}
+
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.Equal(t, 2, slave.writes(), "nothing is written again")
+	assert.Equal(t, []byte{0, 1, 0, 2}, slave.get(10, 2))
+	estimate := client.SettleEstimate()
+	assert.GreaterOrEqual(t, int64(estimate), int64(slave.lag))
+	first := slave.calls()
+
+	// the learned settle time delays the first verification read
+	ops[0] = testWrite{10, types.Uint16(4)}
+	assert.NoError(t, client.BatchWrite(ops, nil))
+	assert.Equal(t, 4, slave.writes())
+	assert.Less(t, slave.calls()-first, first)
+
+	assert.NoError(t, client.Write(30, types.Uint16(5)))
+	assert.Equal(t, 5, slave.writes())
+	assert.Equal(t, []byte{0, 5}, slave.get(30, 1))
+}
+
+func TestClient_BatchWrite_verifyFailed(t *testing.T) {
+	slave := newTestSlave()
+	slave.lag = time.Second
+	client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{
+		Settle:   5 * time.Millisecond,
+		Retries:  2,
+		Interval: 5 * time.Millisecond,
+	}))
+
+	err := client.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil)
+	assert.True(t, errors.Is(err, modbus.ErrWriteVerificationFailed), "%v", err)
+	assert.Equal(t, 1, slave.writes())
+	assert.Equal(t, 4, slave.calls(), "one write and three verification reads")
+	assert.Equal(t, time.Duration(0), client.SettleEstimate())
+}
+
+// clampingSlave is a testSlave silently clamping written register
+// values to max.
+type clampingSlave struct {
+	*testSlave
+	max uint16
+}
+
+func (s *clampingSlave) Send(adu []byte) ([]byte, error) {
+	if adu[0] == goburrow.FuncCodeWriteMultipleRegisters {
+		adu = append([]byte(nil), adu...)
+		for i := 6; i+1 < len(adu); i += 2 {
+			if binary.BigEndian.Uint16(adu[i:]) > s.max {
+				binary.BigEndian.PutUint16(adu[i:], s.max)
+			}
+		}
+	}
+	return s.testSlave.Send(adu)
+}
+
+func TestClient_BatchWrite_verifyClamped(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     []modbus.Write
+		wantErr *modbus.VerificationError
+	}{
+		{"clamped", []modbus.Write{
+			testWrite{10, types.Uint16(50)},
+			testWrite{11, types.Uint16(500)},
+			modbus.UnverifiedWrite(testWrite{12, types.Uint16(700)}),
+			testWrite{13, types.Uint16(7)},
+		}, &modbus.VerificationError{Registers: []uint16{11}, Reads: 1}},
+		{"clamped several", []modbus.Write{
+			testWrite{10, types.Uint32(0x00FF00FF)},
+			testWrite{12, types.Uint16(500)},
+			testWrite{13, types.Uint16(1)},
+		}, &modbus.VerificationError{Registers: []uint16{10, 11, 12}, Reads: 1}},
+		{"clamped unverified", []modbus.Write{
+			testWrite{10, types.Uint16(50)},
+			modbus.UnverifiedWrite(testWrite{11, types.Uint16(700)}),
+			testWrite{12, types.Uint16(7)},
+			testWrite{13, types.Uint16(100)},
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := &clampingSlave{newTestSlave(), 100}
+			client := modbus.NewClient(slave,
+				modbus.WithVerify(modbus.Verify{}),
+				modbus.WithLimits(modbus.Limits{MaxWriteQuantity: 2}))
+
+			err := client.BatchWrite(tt.ops, nil)
+			if tt.wantErr != nil {
+				var verr *modbus.VerificationError
+				if assert.True(t, errors.As(err, &verr), "%v", err) {
+					assert.Equal(t, tt.wantErr, verr)
+				}
+				assert.ErrorIs(t, err, modbus.ErrWriteVerificationFailed)
+			} else {
+				assert.NoError(t, err)
+			}
+			if assert.Len(t, slave.requests, 3, "two writes and a merged verification read") {
+				assert.Equal(t, []byte{0, 10, 0, 4}, slave.requests[2].Data)
+			}
+		})
+	}
+}
+
+func TestClient_BatchWrite_verifySplit(t *testing.T) {
+	slave := &clampingSlave{newTestSlave(), 100}
+	client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{}), modbus.WithMaxReadQuantity(10))
+	var ops []modbus.Write
+	for r := uint16(10); r < 30; r += 2 {
+		ops = append(ops, testWrite{r, types.Uint32(uint32(r))})
+	}
+	ops[7] = testWrite{24, types.Uint32(500)}
+
+	err := client.BatchWrite(ops, nil)
+	var verr *modbus.VerificationError
+	if assert.True(t, errors.As(err, &verr), "%v", err) {
+		assert.Equal(t, &modbus.VerificationError{Registers: []uint16{25}, Reads: 1}, verr)
+	}
+	if assert.Len(t, slave.requests, 3, "a write and two verification reads") {
+		assert.Equal(t, []byte{0, 10, 0, 20}, slave.requests[0].Data[:4])
+		assert.Equal(t, []byte{0, 10, 0, 10}, slave.requests[1].Data)
+		assert.Equal(t, []byte{0, 20, 0, 10}, slave.requests[2].Data)
+	}
+
+	plan, err := client.PlanWrite(ops, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, plan, 3) {
+		assert.Equal(t, modbus.RegisterRange{Register: 20, Quantity: 10}, plan[2].RegisterRange)
+	}
+}
+
+func TestClient_BatchWrite_verifyUnverifiedOnly(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{}))
+
+	assert.NoError(t, client.BatchWrite([]modbus.Write{modbus.UnverifiedWrite(testWrite{10, types.Uint16(1)})}, nil))
+	assert.Equal(t, 1, slave.calls(), "nothing to verify")
+}
+
+func TestClient_BatchWrite_verifyApplies(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{}), modbus.WithApplyRules(modbus.ApplyRule{
+		Guarded:  modbus.RegisterRange{Register: 10, Quantity: 1},
+		Register: 100,
+		Value:    types.Uint16(1),
+	}))
+
+	assert.NoError(t, client.BatchWrite([]modbus.Write{testWrite{10, types.Uint16(1)}}, nil))
+	if assert.Len(t, slave.requests, 3) {
+		assert.Equal(t, []byte{0, 100, 0, 1, 2, 0, 1}, slave.requests[1].Data, "apply writes go before verification")
+		assert.Equal(t, []byte{0, 10, 0, 1}, slave.requests[2].Data, "apply writes are not verified")
+	}
+}
+
+func TestClient_BatchWriteWith_readBack(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []modbus.Write
+		want []modbustest.WireExpectation
+	}{
+		{"unchanged op in the middle", []modbus.Write{
+			testWrite{10, types.Uint16(1)}, testWrite{11, types.Uint16(2)}, testWrite{12, types.Uint16(3)},
+		}, []modbustest.WireExpectation{
+			modbustest.Read(10, 3),
+			modbustest.Write(10, 0, 1),
+			modbustest.Write(12, 0, 3),
+			// one verification read reusing the read-back request
+			modbustest.Read(10, 3),
+		}},
+		{"all unchanged", []modbus.Write{
+			testWrite{11, types.Uint16(2)}, testWrite{20, types.Uint16(0)},
+		}, []modbustest.WireExpectation{
+			modbustest.Read(11, 1),
+			modbustest.Read(20, 1),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slave := modbustest.NewSlave()
+			slave.Seed(modbus.Registers{11: types.Uint16(2)})
+			client := modbus.NewClient(slave, modbus.WithVerify(modbus.Verify{}))
+
+			assert.NoError(t, client.BatchWriteWith(tt.ops, nil, modbus.BatchOptions{ReadBack: true}))
+			assertCoilRequests(t, slave.Requests(), tt.want)
+			for _, op := range tt.ops {
+				assert.Equal(t, op.Value().Bytes(), slave.Get(op.Register(), 1), "register %d", op.Register())
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueClosed is returned for ops submitted to a closed WriteQueue.
+var ErrQueueClosed = errors.New("write queue is closed")
+
+// ErrWriteSuperseded is returned for a queued op overwritten by a later
+// op of the same window before being written.
+var ErrWriteSuperseded = errors.New("write superseded by a later one")
+
+// WriteQueueOptions configures a WriteQueue.
+type WriteQueueOptions struct {
+	// Linger is how long the first op of a window waits for other ops
+	// before the window is written.
+	Linger time.Duration
+	// MaxOps writes a window as soon as it holds this many ops. Zero
+	// means no bound.
+	MaxOps int
+}
+
+// WriteQueue coalesces write ops submitted from multiple goroutines
+// into batches. Ops are collected into a window, written with a single
+// BatchWrite once the window has lingered or is full. Windows are
+// written in order, one at a time. It is safe for concurrent use.
+//
+// A submitted op overlapping registers of an op pending in the same
+// window supersedes it: the earlier op is dropped and completes with
+// ErrWriteSuperseded.
+type WriteQueue struct {
+	client *Client
+	opts   WriteQueueOptions
+
+	mtx     sync.Mutex
+	pending []queuedWrite
+	// window counts cut windows, so that a stale linger timer is ignored
+	window  int
+	timer   *time.Timer
+	windows []writeWindow
+	closed  bool
+
+	signal chan struct{}
+	done   chan struct{}
+}
+
+type queuedWrite struct {
+	ctx  context.Context
+	op   Write
+	rng  RegisterRange
+	done chan error
+}
+
+type writeWindow struct {
+	ctx    context.Context
+	writes []queuedWrite
+}
+
+// NewWriteQueue returns a queue writing ops with c. The queue is closed
+// by c.Shutdown, which writes the pending window first.
+func NewWriteQueue(c *Client, opts WriteQueueOptions) *WriteQueue {
+	q := &WriteQueue{
+		client: c,
+		opts:   opts,
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	c.OnShutdown("write queue", q.Close)
+	return q
+}
+
+// Submit queues op, returning a channel receiving the error of writing
+// it once its window is written. An op whose ctx is done before its
+// window is written is dropped and completes with the error of ctx;
+// once the window is being written, ctx has no effect.
+//
+// Ops of no registers are rejected right away with ErrEmptyOperation.
+// If the batch of a window fails, ops written by completed requests
+// complete with nil and the others with the error of the batch, so an op
+// failing the checks of BatchWrite fails its whole window.
+func (q *WriteQueue) Submit(ctx context.Context, op Write) <-chan error {
+	done := make(chan error, 1)
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		done <- ErrQueueClosed
+		return done
+	}
+	if err := checkEmpty(op); err != nil {
+		done <- err
+		return done
+	}
+	w := queuedWrite{ctx, op, RegisterRange{op.Register(), uint16(len(op.Value().Bytes()) / 2)}, done}
+	pending := q.pending[:0]
+	for _, p := range q.pending {
+		if p.rng.Overlaps(w.rng) {
+			p.done <- ErrWriteSuperseded
+			continue
+		}
+		pending = append(pending, p)
+	}
+	q.pending = append(pending, w)
+
+	if q.opts.MaxOps > 0 && len(q.pending) >= q.opts.MaxOps {
+		q.cut(context.Background())
+	} else if q.timer == nil {
+		window := q.window
+		q.timer = time.AfterFunc(q.opts.Linger, func() { q.linger(window) })
+	}
+	return done
+}
+
+// Close writes the pending window with ctx and stops the queue, waiting
+// for the windows left to be written until ctx is done. Ops submitted
+// afterwards complete with ErrQueueClosed. If ctx is done first, Close
+// returns its error and the pending window fails with it.
+func (q *WriteQueue) Close(ctx context.Context) error {
+	q.mtx.Lock()
+	if !q.closed {
+		q.closed = true
+		q.cut(ctx)
+		q.notify()
+	}
+	q.mtx.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *WriteQueue) linger(window int) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.window == window {
+		q.cut(context.Background())
+	}
+}
+
+// cut queues the pending ops as a window to be written with ctx. q.mtx
+// must be held.
+func (q *WriteQueue) cut(ctx context.Context) {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	q.window++
+	if len(q.pending) == 0 {
+		return
+	}
+	q.windows = append(q.windows, writeWindow{ctx, q.pending})
+	q.pending = nil
+	q.notify()
+}
+
+func (q *WriteQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *WriteQueue) run() {
+	defer close(q.done)
+	for {
+		q.mtx.Lock()
+		windows, closed := q.windows, q.closed
+		q.windows = nil
+		q.mtx.Unlock()
+
+		for _, w := range windows {
+			q.write(w)
+		}
+		if len(windows) == 0 {
+			if closed {
+				return
+			}
+			<-q.signal
+		}
+	}
+}
+
+// write writes the ops of w, completing each of them.
+func (q *WriteQueue) write(w writeWindow) {
+	ops := make([]Write, 0, len(w.writes))
+	writes := w.writes[:0:0]
+	for _, qw := range w.writes {
+		if err := qw.ctx.Err(); err != nil {
+			qw.done <- err
+			continue
+		}
+		ops = append(ops, qw.op)
+		writes = append(writes, qw)
+	}
+	if len(ops) == 0 {
+		return
+	}
+
+	var sent []sentWrite
+	err := q.batch(w.ctx, ops, &sent)
+	written := completedRegisters(sent)
+	for _, qw := range writes {
+		if err != nil && !applied(written, qw.rng.Register, int(qw.rng.Quantity)) {
+			qw.done <- err
+		} else {
+			qw.done <- nil
+		}
+	}
+}
+
+func (q *WriteQueue) batch(ctx context.Context, ops []Write, sent *[]sentWrite) (err error) {
+	defer recoverPanic(&err)
+	return q.client.batchWriteWith(ops, nil, BatchOptions{Context: ctx, drain: true}, sent)
+}
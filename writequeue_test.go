@@ -0,0 +1,147 @@
+package modbus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	modbus "github.com/tdemin/opmodbus"
+	"github.com/tdemin/opmodbus/types"
+)
+
+// wait returns the completion of a queued op, failing t after a second.
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("op not completed")
+	}
+	return nil
+}
+
+func TestWriteQueue(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	q := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: 50 * time.Millisecond})
+	defer q.Close(context.Background())
+
+	var wg sync.WaitGroup
+	for i := uint16(0); i < 3; i++ {
+		wg.Add(1)
+		go func(i uint16) {
+			defer wg.Done()
+			assert.NoError(t, wait(t, q.Submit(context.Background(), testWrite{10 + i, types.Uint16(i + 1)})))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, slave.writes(), "coalesced")
+	assert.Equal(t, []byte{0, 1, 0, 2, 0, 3}, slave.get(10, 3))
+}
+
+func TestWriteQueue_superseded(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	q := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: time.Hour})
+
+	first := q.Submit(context.Background(), testWrite{10, types.Uint32(1)})
+	second := q.Submit(context.Background(), testWrite{11, types.Uint16(2)})
+	assert.ErrorIs(t, wait(t, first), modbus.ErrWriteSuperseded)
+	assert.NoError(t, q.Close(context.Background()))
+	assert.NoError(t, wait(t, second))
+	assert.Equal(t, 1, slave.writes())
+	assert.Equal(t, []byte{0, 0, 0, 2}, slave.get(10, 2), "only the later op is written")
+}
+
+func TestWriteQueue_maxOps(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	q := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: time.Hour, MaxOps: 2})
+	defer q.Close(context.Background())
+
+	first := q.Submit(context.Background(), testWrite{10, types.Uint16(1)})
+	second := q.Submit(context.Background(), testWrite{20, types.Uint16(2)})
+	assert.NoError(t, wait(t, first))
+	assert.NoError(t, wait(t, second))
+	assert.Equal(t, 2, slave.writes())
+}
+
+func TestWriteQueue_failed(t *testing.T) {
+	slave := newTestSlave()
+	slave.readOnly[20] = true
+	client := modbus.NewClient(slave)
+	q := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: time.Hour})
+
+	ok := q.Submit(context.Background(), testWrite{10, types.Uint16(1)})
+	failed := q.Submit(context.Background(), testWrite{20, types.Uint16(2)})
+	assert.NoError(t, q.Close(context.Background()))
+	assert.NoError(t, wait(t, ok))
+	assert.Error(t, wait(t, failed))
+}
+
+func TestWriteQueue_context(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	q := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := q.Submit(ctx, testWrite{10, types.Uint16(1)})
+	written := q.Submit(context.Background(), testWrite{20, types.Uint16(2)})
+	cancel()
+	assert.NoError(t, q.Close(context.Background()))
+	assert.ErrorIs(t, wait(t, canceled), context.Canceled)
+	assert.NoError(t, wait(t, written))
+	assert.Equal(t, 1, slave.writes())
+	assert.Equal(t, []byte{0, 0}, slave.get(10, 1))
+}
+
+func TestWriteQueue_empty(t *testing.T) {
+	slave := newTestSlave()
+	q := modbus.NewWriteQueue(modbus.NewClient(slave), modbus.WriteQueueOptions{Linger: time.Hour})
+
+	assert.ErrorIs(t, wait(t, q.Submit(context.Background(), testWrite{10, nil})), modbus.ErrEmptyOperation)
+	written := q.Submit(context.Background(), testWrite{20, types.Uint16(1)})
+	assert.NoError(t, q.Close(context.Background()))
+	assert.NoError(t, wait(t, written), "the window is written without the empty op")
+}
+
+func TestWriteQueue_Close(t *testing.T) {
+	t.Run("flushes", func(t *testing.T) {
+		slave := newTestSlave()
+		q := modbus.NewWriteQueue(modbus.NewClient(slave), modbus.WriteQueueOptions{Linger: time.Hour})
+
+		done := q.Submit(context.Background(), testWrite{10, types.Uint16(1)})
+		assert.NoError(t, q.Close(context.Background()))
+		assert.NoError(t, wait(t, done))
+		assert.Equal(t, 1, slave.writes())
+		assert.ErrorIs(t, wait(t, q.Submit(context.Background(), testWrite{10, types.Uint16(2)})), modbus.ErrQueueClosed)
+		assert.NoError(t, q.Close(context.Background()), "closed twice")
+	})
+	t.Run("canceled", func(t *testing.T) {
+		slave := newTestSlave()
+		q := modbus.NewWriteQueue(modbus.NewClient(slave), modbus.WriteQueueOptions{Linger: time.Hour})
+
+		done := q.Submit(context.Background(), testWrite{10, types.Uint16(1)})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = q.Close(ctx)
+		assert.ErrorIs(t, wait(t, done), context.Canceled)
+		assert.Equal(t, 0, slave.writes())
+	})
+}
+
+func TestWriteQueue_Shutdown(t *testing.T) {
+	slave := newTestSlave()
+	client := modbus.NewClient(slave)
+	q := modbus.NewWriteQueue(client, modbus.WriteQueueOptions{Linger: time.Hour})
+
+	done := q.Submit(context.Background(), testWrite{10, types.Uint16(1)})
+	assert.NoError(t, client.Shutdown(context.Background()))
+	assert.NoError(t, wait(t, done), "the pending window is flushed")
+	assert.Equal(t, []byte{0, 1}, slave.get(10, 1))
+	assert.ErrorIs(t, wait(t, q.Submit(context.Background(), testWrite{10, types.Uint16(2)})), modbus.ErrQueueClosed)
+	assert.ErrorIs(t, client.Write(10, types.Uint16(3)), modbus.ErrClientClosed)
+}